@@ -0,0 +1,184 @@
+package octo
+
+import (
+	"math"
+	"net/http"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// AdaptiveLimitConfig configures an AdaptiveLimiter
+type AdaptiveLimitConfig struct {
+	// InitialLimit is the starting concurrency limit (20 when zero)
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit (1 and 1000 when zero)
+	MinLimit int
+	MaxLimit int
+	// TargetLatency is the latency above which a request counts as an
+	// overload signal (250ms when zero)
+	TargetLatency time.Duration
+	// Backoff multiplies the limit on an overload signal (0.9 when zero)
+	Backoff float64
+	// CPUUsage optionally reports CPU utilisation in [0, 1]; at or above
+	// CPUThreshold (0.9 when zero) completions count as overload signals.
+	// See ProcessCPUUsage.
+	CPUUsage     func() float64
+	CPUThreshold float64
+	// RetryAfter is sent with 503s (1s when zero)
+	RetryAfter time.Duration
+}
+
+// AdaptiveLimiter is an AIMD concurrency limiter: the limit grows by one per
+// window of fast completions while the limit is in use, and shrinks
+// multiplicatively on slow completions, 5xx responses or high CPU, at most
+// once per target latency.
+type AdaptiveLimiter struct {
+	cfg        AdaptiveLimitConfig
+	retryAfter string
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	lastDecrease time.Time
+	rejected     int64
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter
+func NewAdaptiveLimiter(cfg AdaptiveLimitConfig) *AdaptiveLimiter {
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = 20
+	}
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 1000
+	}
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = 250 * time.Millisecond
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+	if cfg.CPUThreshold <= 0 {
+		cfg.CPUThreshold = 0.9
+	}
+	return &AdaptiveLimiter{
+		cfg:        cfg,
+		retryAfter: retryAfterSeconds(cfg.RetryAfter),
+		limit:      math.Min(math.Max(float64(cfg.InitialLimit), float64(cfg.MinLimit)), float64(cfg.MaxLimit)),
+	}
+}
+
+// Limit returns the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of admitted requests still running
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Rejected returns how many requests were turned away
+func (l *AdaptiveLimiter) Rejected() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rejected
+}
+
+// acquire admits a request if the limit allows it
+func (l *AdaptiveLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight >= int(l.limit) {
+		l.rejected++
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+// release records the completion of an admitted request
+func (l *AdaptiveLimiter) release(latency time.Duration, failed bool) {
+	overloaded := failed || latency > l.cfg.TargetLatency
+	if !overloaded && l.cfg.CPUUsage != nil {
+		overloaded = l.cfg.CPUUsage() >= l.cfg.CPUThreshold
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	utilised := float64(l.inFlight) >= l.limit/2
+	l.inFlight--
+	now := time.Now()
+	switch {
+	case overloaded:
+		if now.Sub(l.lastDecrease) < l.cfg.TargetLatency {
+			return
+		}
+		l.lastDecrease = now
+		l.limit = math.Max(float64(l.cfg.MinLimit), l.limit*l.cfg.Backoff)
+	case utilised:
+		l.limit = math.Min(float64(l.cfg.MaxLimit), l.limit+1/l.limit)
+	}
+}
+
+// AdaptiveLimitMiddleware rejects requests above the adaptive limit with 503 and feeds
+// the limiter with the latency and status of admitted ones
+func AdaptiveLimitMiddleware[V any](l *AdaptiveLimiter) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if !l.acquire() {
+				ctx.SetHeader("Retry-After", l.retryAfter)
+				ctx.SendError("err_overloaded", nil)
+				return
+			}
+			start := time.Now()
+			failed := true
+			defer func() {
+				l.release(time.Since(start), failed)
+			}()
+			next(ctx)
+			failed = ctx.ResponseWriter.Status >= http.StatusInternalServerError
+		}
+	}
+}
+
+// ProcessCPUUsage returns a sampler of the CPU utilisation of the Go process
+// (busy CPU time over available CPU time, as estimated by the runtime),
+// refreshed at most once per interval
+func ProcessCPUUsage(interval time.Duration) func() float64 {
+	samples := []metrics.Sample{
+		{Name: "/cpu/classes/total:cpu-seconds"},
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+	}
+	var (
+		mu                  sync.Mutex
+		last                time.Time
+		lastTotal, lastIdle float64
+		usage               float64
+	)
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(last) < interval {
+			return usage
+		}
+		metrics.Read(samples)
+		if samples[0].Value.Kind() != metrics.KindFloat64 {
+			return 0
+		}
+		total, idle := samples[0].Value.Float64(), samples[1].Value.Float64()
+		if dt := total - lastTotal; !last.IsZero() && dt > 0 {
+			usage = math.Min(1, math.Max(0, 1-(idle-lastIdle)/dt))
+		}
+		last, lastTotal, lastIdle = now, total, idle
+		return usage
+	}
+}
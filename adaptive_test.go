@@ -0,0 +1,86 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimitConfig{InitialLimit: 10, MinLimit: 2, MaxLimit: 11, TargetLatency: time.Millisecond})
+
+	// Fast completions under load grow the limit additively
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			l.acquire()
+		}
+		for j := 0; j < 10; j++ {
+			l.release(0, false)
+		}
+	}
+	if l.Limit() != 11 {
+		t.Errorf("Expected limit to grow to the max, got %d", l.Limit())
+	}
+
+	// Slow completions shrink it multiplicatively, at most once per target latency
+	l.acquire()
+	l.release(10*time.Millisecond, false)
+	l.acquire()
+	l.release(10*time.Millisecond, false)
+	if l.Limit() != 9 {
+		t.Errorf("Expected a single backoff to 9, got %d", l.Limit())
+	}
+	time.Sleep(2 * time.Millisecond)
+	l.acquire()
+	l.release(0, true)
+	if l.Limit() != 8 {
+		t.Errorf("Expected failure to back off to 8, got %d", l.Limit())
+	}
+
+	cpu := NewAdaptiveLimiter(AdaptiveLimitConfig{InitialLimit: 10, TargetLatency: time.Millisecond, CPUUsage: func() float64 { return 0.95 }})
+	cpu.acquire()
+	cpu.release(0, false)
+	if cpu.Limit() != 9 {
+		t.Errorf("Expected high CPU to back off, got %d", cpu.Limit())
+	}
+	if usage := ProcessCPUUsage(0)(); usage < 0 || usage > 1 {
+		t.Errorf("Unexpected CPU usage %f", usage)
+	}
+}
+
+func TestAdaptiveLimitMiddleware(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimitConfig{InitialLimit: 1, MaxLimit: 1})
+	router := NewRouter[CustomData]()
+	router.Use(AdaptiveLimitMiddleware[CustomData](l))
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	router.GET("/slow", func(ctx *Ctx[CustomData]) {
+		close(entered)
+		<-release
+		ctx.SendString(http.StatusOK, "ok")
+	})
+	router.GET("/fast", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+		close(done)
+	}()
+	<-entered
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 above the limit, got %d", w.Code)
+	}
+	close(release)
+	<-done
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK || l.InFlight() != 0 || l.Rejected() != 1 {
+		t.Errorf("Unexpected state: %d in flight %d rejected %d", w.Code, l.InFlight(), l.Rejected())
+	}
+}
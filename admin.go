@@ -0,0 +1,205 @@
+package octo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// RuntimeSettings are the settings that can change while serving. They are
+// swapped atomically: a request reads one consistent snapshot.
+type RuntimeSettings struct {
+	LogLevel        string `json:"log_level"`
+	SecurityHeaders bool   `json:"security_headers"`
+	MaxBodySize     int64  `json:"max_body_size"`
+	Maintenance     bool   `json:"maintenance"`
+	// MaintenanceRetryAfter is sent as Retry-After (seconds) on 503s
+	MaintenanceRetryAfter int `json:"maintenance_retry_after,omitempty"`
+
+	// securityHeadersSet is true once SecurityHeaders was changed at
+	// runtime; until then EnableSecurityHeaders applies
+	securityHeadersSet bool
+}
+
+// runtimeSettings is nil until the first update; the package globals apply
+// until then
+var runtimeSettings atomic.Pointer[RuntimeSettings]
+
+// settingsMu serializes updates, so the snapshot and the log level and body
+// size derived from it change together
+var settingsMu sync.Mutex
+
+// Settings returns the current runtime settings
+func Settings() RuntimeSettings {
+	if s := runtimeSettings.Load(); s != nil {
+		settings := *s
+		settings.SecurityHeaders = securityHeadersEnabled(s)
+		return settings
+	}
+	return RuntimeSettings{
+		LogLevel:        zerolog.GlobalLevel().String(),
+		SecurityHeaders: EnableSecurityHeaders,
+		MaxBodySize:     GetMaxBodySize(),
+	}
+}
+
+// UpdateSettings applies fn to a copy of the current settings, validates the
+// result and installs it. Concurrent updates are serialized. Changing
+// SecurityHeaders here takes over from EnableSecurityHeaders for good.
+func UpdateSettings(fn func(*RuntimeSettings)) (RuntimeSettings, error) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	current := Settings()
+	next := current
+	fn(&next)
+	if next.SecurityHeaders != current.SecurityHeaders {
+		next.securityHeadersSet = true
+	}
+
+	level, err := zerolog.ParseLevel(next.LogLevel)
+	if err != nil {
+		return current, fmt.Errorf("invalid log level %q", next.LogLevel)
+	}
+	if next.MaxBodySize <= 0 {
+		return current, fmt.Errorf("max body size must be positive")
+	}
+	if next.MaintenanceRetryAfter < 0 {
+		return current, fmt.Errorf("maintenance retry after must not be negative")
+	}
+	runtimeSettings.Store(&next)
+	zerolog.SetGlobalLevel(level)
+	maxBodySize.Store(next.MaxBodySize)
+	return next, nil
+}
+
+// SetMaintenance turns maintenance mode on or off. While on, every route
+// answers 503 except those marked with Route.AllowDuringMaintenance.
+func SetMaintenance(on bool, retryAfter int) error {
+	_, err := UpdateSettings(func(s *RuntimeSettings) {
+		s.Maintenance = on
+		s.MaintenanceRetryAfter = retryAfter
+	})
+	return err
+}
+
+// SetSecurityHeaders turns the security headers on or off while serving.
+// From then on the runtime settings decide and EnableSecurityHeaders is no
+// longer read.
+func SetSecurityHeaders(on bool) error {
+	_, err := UpdateSettings(func(s *RuntimeSettings) {
+		s.SecurityHeaders = on
+		s.securityHeadersSet = true
+	})
+	return err
+}
+
+// SetMaxBodySize sets the maximum request body size through UpdateSettings
+func SetMaxBodySize(mbs int64) error {
+	_, err := UpdateSettings(func(s *RuntimeSettings) { s.MaxBodySize = mbs })
+	return err
+}
+
+// securityHeadersEnabled reads the runtime snapshot once security headers
+// were set at runtime, EnableSecurityHeaders otherwise
+func securityHeadersEnabled(s *RuntimeSettings) bool {
+	if s != nil && s.securityHeadersSet {
+		return s.SecurityHeaders
+	}
+	return EnableSecurityHeaders
+}
+
+// AllowDuringMaintenance keeps the route served in maintenance mode (health
+// checks, admin endpoints)
+func (rt *Route[V]) AllowDuringMaintenance() *Route[V] {
+	rt.update(func(entry *routeEntry[V]) {
+		entry.bypassMaintenance = true
+	})
+	return rt
+}
+
+// maintenanceHandler answers requests blocked by maintenance mode
+func maintenanceHandler[V any](retryAfter int) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		if retryAfter > 0 {
+			ctx.SetHeader("Retry-After", strconv.Itoa(retryAfter))
+		}
+		ctx.SendError("err_maintenance", nil)
+	}
+}
+
+// settingsPatch is the body of PATCH /settings; absent fields are unchanged
+type settingsPatch struct {
+	LogLevel              *string `json:"log_level"`
+	SecurityHeaders       *bool   `json:"security_headers"`
+	MaxBodySize           *int64  `json:"max_body_size"`
+	Maintenance           *bool   `json:"maintenance"`
+	MaintenanceRetryAfter *int    `json:"maintenance_retry_after"`
+}
+
+// MountSettings registers GET and PATCH /settings on g. authorize must
+// accept the caller, otherwise 403 is returned; both routes stay available
+// during maintenance.
+func MountSettings[V any](g *Group[V], authorize func(*Ctx[V]) bool) {
+	if authorize == nil {
+		panic("octo: MountSettings requires an authorize function")
+	}
+	g.GET("/settings", func(ctx *Ctx[V]) {
+		if !authorize(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		ctx.SendJSON(http.StatusOK, Settings())
+	}).AllowDuringMaintenance()
+
+	g.PATCH("/settings", func(ctx *Ctx[V]) {
+		if !authorize(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		var patch settingsPatch
+		if err := ctx.NeedBody(); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		if err := json.Unmarshal(ctx.Body, &patch); err != nil {
+			ctx.SendError("err_json_error", err)
+			return
+		}
+		settings, err := UpdateSettings(func(s *RuntimeSettings) {
+			if patch.LogLevel != nil {
+				s.LogLevel = *patch.LogLevel
+			}
+			if patch.SecurityHeaders != nil {
+				s.SecurityHeaders = *patch.SecurityHeaders
+				s.securityHeadersSet = true
+			}
+			if patch.MaxBodySize != nil {
+				s.MaxBodySize = *patch.MaxBodySize
+			}
+			if patch.Maintenance != nil {
+				s.Maintenance = *patch.Maintenance
+			}
+			if patch.MaintenanceRetryAfter != nil {
+				s.MaintenanceRetryAfter = *patch.MaintenanceRetryAfter
+			}
+		})
+		if err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Info().Interface("settings", settings).Str("ip", ctx.ClientIP()).Msg("[octo] runtime settings updated")
+			}
+		} else {
+			serverLogger.Info().Interface("settings", settings).Str("ip", ctx.ClientIP()).Msg("[octo] runtime settings updated")
+		}
+		ctx.SendJSON(http.StatusOK, settings)
+	}).AllowDuringMaintenance()
+}
@@ -0,0 +1,166 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func resetRuntimeSettings() func() {
+	level, mbs := zerolog.GlobalLevel(), GetMaxBodySize()
+	return func() {
+		runtimeSettings.Store(nil)
+		zerolog.SetGlobalLevel(level)
+		maxBodySize.Store(mbs)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	defer resetRuntimeSettings()()
+
+	if _, err := UpdateSettings(func(s *RuntimeSettings) { s.LogLevel = "chatty" }); err == nil {
+		t.Error("Expected invalid log level to be rejected")
+	}
+	if runtimeSettings.Load() != nil {
+		t.Error("Expected rejected update to leave settings untouched")
+	}
+
+	s, err := UpdateSettings(func(s *RuntimeSettings) {
+		s.LogLevel = "warn"
+		s.MaxBodySize = 4
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if zerolog.GlobalLevel() != zerolog.WarnLevel || GetMaxBodySize() != 4 || s.LogLevel != "warn" {
+		t.Errorf("Expected settings to be applied, got %+v", s)
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/echo", func(ctx *Ctx[CustomData]) {
+		if err := ctx.NeedBody(); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		ctx.SendString(http.StatusOK, string(ctx.Body))
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("too long")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected runtime body limit to apply, got %d", w.Code)
+	}
+
+	UpdateSettings(func(s *RuntimeSettings) { s.SecurityHeaders = true })
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("ok")))
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Body.String() != "ok" {
+		t.Errorf("Expected security headers toggled on, got %v", w.Header())
+	}
+
+	if err := SetSecurityHeaders(false); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/echo", strings.NewReader("ok")))
+	if w.Header().Get("X-Content-Type-Options") != "" {
+		t.Errorf("Expected SetSecurityHeaders to apply after a runtime update, got %v", w.Header())
+	}
+	if err := SetMaxBodySize(0); err == nil || GetMaxBodySize() != 4 {
+		t.Errorf("Expected an invalid body size to be rejected, got %v", err)
+	}
+}
+
+func TestMaintenanceAndSettingsEndpoint(t *testing.T) {
+	defer resetRuntimeSettings()()
+
+	router := NewRouter[CustomData]()
+	router.GET("/orders", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "orders")
+	})
+	router.GET("/health", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	}).AllowDuringMaintenance()
+	MountSettings(router.Group("/admin"), func(ctx *Ctx[CustomData]) bool {
+		return ctx.GetHeader("Authorization") == "Bearer admin"
+	})
+
+	do := func(method, path, body, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("PATCH", "/admin/settings", `{"maintenance": true}`, "Bearer nope"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	w := do("PATCH", "/admin/settings", `{"maintenance": true, "maintenance_retry_after": 120, "log_level": "error"}`, "Bearer admin")
+	var s RuntimeSettings
+	json.Unmarshal(w.Body.Bytes(), &s)
+	if w.Code != http.StatusOK || !s.Maintenance || s.LogLevel != "error" {
+		t.Errorf("Unexpected update response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("GET", "/orders", "", "")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected 503 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if w := do("GET", "/health", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected health to bypass maintenance, got %d", w.Code)
+	}
+	if w := do("GET", "/missing", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown routes, got %d", w.Code)
+	}
+	if w := do("PATCH", "/admin/settings", `{"max_body_size": -1}`, "Bearer admin"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid update to fail, got %d", w.Code)
+	}
+
+	if w := do("PATCH", "/admin/settings", `{"maintenance": false}`, "Bearer admin"); w.Code != http.StatusOK {
+		t.Errorf("Expected maintenance off, got %d", w.Code)
+	}
+	if w := do("GET", "/orders", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected route served again, got %d", w.Code)
+	}
+}
+
+func TestSecurityHeadersGlobalAfterSetup(t *testing.T) {
+	defer resetRuntimeSettings()()
+	defer func(on bool) { EnableSecurityHeaders = on }(EnableSecurityHeaders)
+
+	SetupOCto(GetLogger(), GetMaxBodySize())
+	EnableSecurityHeaders = true
+	router := NewRouter[CustomData]()
+	router.GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || !Settings().SecurityHeaders {
+		t.Errorf("Expected EnableSecurityHeaders to apply after SetupOCto, got %v", w.Header())
+	}
+}
+
+func TestUpdateSettingsConcurrent(t *testing.T) {
+	defer resetRuntimeSettings()()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(size int64) {
+			defer wg.Done()
+			SetMaxBodySize(size)
+		}(int64(i))
+	}
+	wg.Wait()
+	if GetMaxBodySize() != Settings().MaxBodySize {
+		t.Errorf("Expected the body size %d to match the settings %d", GetMaxBodySize(), Settings().MaxBodySize)
+	}
+}
@@ -0,0 +1,143 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"time"
+	"unsafe"
+
+	"github.com/google/uuid"
+)
+
+// arenaSlabSize is the number of bytes reserved per request for small strings
+// (request UUID, short header copies). Anything that does not fit falls back
+// to a regular heap allocation.
+const arenaSlabSize = 512
+
+// requestArena groups every request-scoped object octo allocates so they can be
+// recycled wholesale once the request is finished. Only used when
+// EnableRequestArena is set.
+type requestArena[V any] struct {
+	ctx         Ctx[V]
+	rw          ResponseWriterWrapper
+	params      map[string]string
+	paramValues []string
+	slab        [arenaSlabSize]byte
+	off         int
+}
+
+func newRequestArena[V any]() *requestArena[V] {
+	return &requestArena[V]{
+		params:      make(map[string]string, 4),
+		paramValues: make([]string, 0, 4),
+	}
+}
+
+// alloc reserves n bytes from the slab. It returns nil when the slab is exhausted.
+func (a *requestArena[V]) alloc(n int) []byte {
+	if a.off+n > len(a.slab) {
+		return nil
+	}
+	b := a.slab[a.off : a.off+n : a.off+n]
+	a.off += n
+	return b
+}
+
+// String copies s into the slab and returns an arena-backed string.
+// The result is only valid until the arena is released.
+func (a *requestArena[V]) String(s string) string {
+	b := a.alloc(len(s))
+	if b == nil {
+		return s
+	}
+	copy(b, s)
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// newUUID formats a random UUID into the slab.
+func (a *requestArena[V]) newUUID() string {
+	b := a.alloc(36)
+	if b == nil {
+		return uuid.NewString()
+	}
+	encodeUUID(b, uuid.New())
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// newCtx initializes the arena-owned Ctx for the given request.
+func (a *requestArena[V]) newCtx(w http.ResponseWriter, req *http.Request, params map[string]string) *Ctx[V] {
+	a.rw = ResponseWriterWrapper{
+		ResponseWriter: w,
+		Status:         http.StatusOK,
+	}
+	if !DeferBufferAllocation {
+		a.rw.Body = &bytes.Buffer{}
+	}
+	a.ctx = Ctx[V]{
+		ResponseWriter: &a.rw,
+		Request:        req,
+		Params:         params,
+		StartTime:      time.Now().UnixNano(),
+		UUID:           a.newUUID(),
+		Query:          req.URL.Query(),
+		arena:          true,
+	}
+	return &a.ctx
+}
+
+// reusable reports whether nothing outlived the request and the arena can go
+// back to the pool.
+func (a *requestArena[V]) reusable() bool {
+	return !a.ctx.retained && !a.rw.hijacked
+}
+
+func (a *requestArena[V]) reset() {
+	var zero Ctx[V]
+	a.ctx = zero
+	a.rw = ResponseWriterWrapper{}
+	clear(a.params)
+	clear(a.paramValues[:cap(a.paramValues)])
+	a.paramValues = a.paramValues[:0]
+	a.off = 0
+}
+
+func (r *Router[V]) acquireArena() *requestArena[V] {
+	if a, ok := r.arenaPool.Get().(*requestArena[V]); ok {
+		return a
+	}
+	return newRequestArena[V]()
+}
+
+// releaseArena returns the arena to the pool unless the Ctx was retained or the
+// connection hijacked, in which case it is left to the garbage collector.
+func (r *Router[V]) releaseArena(a *requestArena[V]) {
+	if !a.reusable() {
+		return
+	}
+	a.reset()
+	r.arenaPool.Put(a)
+}
+
+// Retain marks the Ctx as escaping the request lifetime (e.g. handed to a
+// goroutine). A retained Ctx is never recycled by the request arena.
+func (c *Ctx[V]) Retain() {
+	c.retained = true
+}
+
+// InArena reports whether the Ctx was allocated from the request arena.
+func (c *Ctx[V]) InArena() bool {
+	return c.arena
+}
+
+func encodeUUID(dst []byte, u uuid.UUID) {
+	hex.Encode(dst, u[:4])
+	dst[8] = '-'
+	hex.Encode(dst[9:13], u[4:6])
+	dst[13] = '-'
+	hex.Encode(dst[14:18], u[6:8])
+	dst[18] = '-'
+	hex.Encode(dst[19:23], u[8:10])
+	dst[23] = '-'
+	hex.Encode(dst[24:], u[10:])
+}
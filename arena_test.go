@@ -0,0 +1,71 @@
+package octo
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestArena(t *testing.T) {
+	oldVal := EnableRequestArena
+	EnableRequestArena = true
+	defer func() { EnableRequestArena = oldVal }()
+
+	router := NewRouter[CustomData]()
+
+	var seenUUIDs []string
+	router.GET("/user/:id", func(ctx *Ctx[CustomData]) {
+		if !ctx.InArena() {
+			t.Errorf("Expected Ctx to be allocated from the arena")
+		}
+		if ctx.Custom.UserID != "" {
+			t.Errorf("Expected zeroed Custom data, got '%s'", ctx.Custom.UserID)
+		}
+		ctx.Custom.UserID = ctx.Param("id")
+		seenUUIDs = append(seenUUIDs, string([]byte(ctx.UUID)))
+		ctx.ResponseWriter.Write([]byte("User: " + ctx.Param("id")))
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		req := httptest.NewRequest("GET", "/user/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		body, _ := io.ReadAll(w.Result().Body)
+		if string(body) != "User: "+id {
+			t.Errorf("Expected 'User: %s', got '%s'", id, string(body))
+		}
+	}
+
+	if len(seenUUIDs) != 3 || seenUUIDs[0] == seenUUIDs[1] || len(seenUUIDs[0]) != 36 {
+		t.Errorf("Expected distinct UUIDs per request, got %v", seenUUIDs)
+	}
+}
+
+func TestRequestArenaRetain(t *testing.T) {
+	oldVal := EnableRequestArena
+	EnableRequestArena = true
+	defer func() { EnableRequestArena = oldVal }()
+
+	router := NewRouter[CustomData]()
+
+	var retained *Ctx[CustomData]
+	router.GET("/keep/:id", func(ctx *Ctx[CustomData]) {
+		if retained == nil {
+			ctx.Retain()
+			retained = ctx
+		}
+		ctx.ResponseWriter.Write([]byte("OK"))
+	})
+
+	req := httptest.NewRequest("GET", "/keep/first", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	uuidBefore := retained.UUID
+
+	req = httptest.NewRequest("GET", "/keep/second", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if retained.Param("id") != "first" || retained.UUID != uuidBefore {
+		t.Errorf("Retained Ctx was recycled: id=%s", retained.Param("id"))
+	}
+}
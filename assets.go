@@ -0,0 +1,224 @@
+package octo
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// assetHashLength is the number of hex characters of the content hash kept in
+// fingerprinted file names
+const assetHashLength = 8
+
+type assetEntry struct {
+	Name   string `json:"-"`
+	Hashed string `json:"hashed"`
+	// SRI is the subresource integrity value, e.g. "sha384-..."
+	SRI string `json:"integrity,omitempty"`
+}
+
+// AssetManifest maps logical asset names (app.js) to fingerprinted names
+// (app.3f9a2c1d.js) computed from their content.
+type AssetManifest struct {
+	fsys     fs.FS
+	prefix   string
+	mu       sync.RWMutex
+	byName   map[string]*assetEntry
+	byHashed map[string]*assetEntry
+}
+
+// NewAssetManifest hashes every file of fsys. prefix is the URL path the assets
+// are served under (e.g. "/static").
+func NewAssetManifest(fsys fs.FS, prefix string) (*AssetManifest, error) {
+	m := &AssetManifest{
+		fsys:     fsys,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		byName:   make(map[string]*assetEntry),
+		byHashed: make(map[string]*assetEntry),
+	}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		sri := sha512.New384()
+		if _, err := io.Copy(io.MultiWriter(h, sri), f); err != nil {
+			return err
+		}
+		e := m.add(p, hex.EncodeToString(h.Sum(nil)))
+		e.SRI = "sha384-" + base64.StdEncoding.EncodeToString(sri.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadAssetManifest reads a manifest previously written by WriteManifest (build
+// time hashing) instead of hashing files at startup. Manifests mapping names
+// to plain strings, written before integrity values were recorded, are
+// accepted; their integrity values are computed on first use.
+func LoadAssetManifest(fsys fs.FS, prefix string, r io.Reader) (*AssetManifest, error) {
+	var entries map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	m := &AssetManifest{
+		fsys:     fsys,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		byName:   make(map[string]*assetEntry, len(entries)),
+		byHashed: make(map[string]*assetEntry, len(entries)),
+	}
+	for name, raw := range entries {
+		e := &assetEntry{Name: name}
+		if err := json.Unmarshal(raw, &e.Hashed); err != nil {
+			if err := json.Unmarshal(raw, e); err != nil {
+				return nil, err
+			}
+		}
+		m.byName[name] = e
+		m.byHashed[e.Hashed] = e
+	}
+	return m, nil
+}
+
+func (m *AssetManifest) add(name, sum string) *assetEntry {
+	ext := path.Ext(name)
+	e := &assetEntry{
+		Name:   name,
+		Hashed: strings.TrimSuffix(name, ext) + "." + sum[:assetHashLength] + ext,
+	}
+	m.byName[name] = e
+	m.byHashed[e.Hashed] = e
+	return e
+}
+
+// WriteManifest writes the fingerprinted name and integrity value of every
+// logical name as JSON
+func (m *AssetManifest) WriteManifest(w io.Writer) error {
+	m.mu.RLock()
+	entries := make(map[string]assetEntry, len(m.byName))
+	for name, e := range m.byName {
+		entries[name] = *e
+	}
+	m.mu.RUnlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// Path returns the URL of the fingerprinted asset, or the plain URL when the
+// asset is unknown.
+func (m *AssetManifest) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	m.mu.RLock()
+	e, ok := m.byName[name]
+	m.mu.RUnlock()
+	if !ok {
+		return m.prefix + "/" + name
+	}
+	return m.prefix + "/" + e.Hashed
+}
+
+// Integrity returns the subresource integrity value of the asset, for the
+// integrity attribute of script and link tags, or "" when the asset is
+// unknown or unreadable.
+func (m *AssetManifest) Integrity(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	m.mu.RLock()
+	e, ok := m.byName[name]
+	var sri string
+	if ok {
+		sri = e.SRI
+	}
+	m.mu.RUnlock()
+	if !ok || sri != "" {
+		return sri
+	}
+	// Loaded from a manifest without integrity values
+	data, err := fs.ReadFile(m.fsys, e.Name)
+	if err != nil {
+		return ""
+	}
+	sum := sha512.Sum384(data)
+	sri = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	m.mu.Lock()
+	e.SRI = sri
+	m.mu.Unlock()
+	return sri
+}
+
+// FuncMap exposes the manifest to html/template as {{ asset "app.js" }} and
+// {{ asset_sri "app.js" }}
+func (m *AssetManifest) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset":     m.Path,
+		"asset_sri": m.Integrity,
+	}
+}
+
+// AssetHandler serves the files of the manifest. Fingerprinted names are served
+// with immutable caching, logical names with revalidation. Register it on a
+// wildcard route below the manifest prefix, e.g. GET /static/*filepath.
+func AssetHandler[V any](m *AssetManifest) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		name := strings.TrimPrefix(ctx.Request.URL.Path, m.prefix+"/")
+		m.mu.RLock()
+		e, hashed := m.byHashed[name]
+		if !hashed {
+			e = m.byName[name]
+		}
+		m.mu.RUnlock()
+		if e == nil {
+			ctx.Send404()
+			return
+		}
+		if hashed {
+			ctx.SetHeader("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			ctx.SetHeader("Cache-Control", "no-cache")
+		}
+		http.ServeFileFS(ctx.ResponseWriter, ctx.Request, m.fsys, e.Name)
+		ctx.Done()
+	}
+}
+
+var defaultAssets *AssetManifest
+
+// SetupAssets sets the manifest used by the package level AssetPath
+func SetupAssets(m *AssetManifest) {
+	defaultAssets = m
+}
+
+// AssetPath returns the fingerprinted URL of name from the manifest installed
+// with SetupAssets.
+func AssetPath(name string) string {
+	if defaultAssets == nil {
+		return "/" + strings.TrimPrefix(name, "/")
+	}
+	return defaultAssets.Path(name)
+}
+
+// AssetSRI returns the subresource integrity value of name from the manifest
+// installed with SetupAssets, or "" without one.
+func AssetSRI(name string) string {
+	if defaultAssets == nil {
+		return ""
+	}
+	return defaultAssets.Integrity(name)
+}
@@ -0,0 +1,112 @@
+package octo
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":       {Data: []byte("console.log('octo')")},
+		"css/site.css": {Data: []byte("body{}")},
+	}
+	m, err := NewAssetManifest(fsys, "/static")
+	if err != nil {
+		t.Fatalf("NewAssetManifest failed: %v", err)
+	}
+	SetupAssets(m)
+	defer SetupAssets(nil)
+
+	hashed := AssetPath("app.js")
+	if !regexp.MustCompile(`^/static/app\.[0-9a-f]{8}\.js$`).MatchString(hashed) {
+		t.Errorf("Unexpected fingerprinted path: %s", hashed)
+	}
+	if AssetPath("missing.js") != "/static/missing.js" {
+		t.Errorf("Expected plain path for unknown asset, got %s", AssetPath("missing.js"))
+	}
+
+	router := NewRouter[CustomData]()
+	router.GET("/static/*filepath", AssetHandler[CustomData](m))
+
+	req := httptest.NewRequest("GET", hashed, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('octo')" {
+		t.Errorf("Unexpected response: %d '%s'", w.Code, w.Body.String())
+	}
+	if w.Result().Header.Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected immutable caching, got '%s'", w.Result().Header.Get("Cache-Control"))
+	}
+
+	req = httptest.NewRequest("GET", m.Path("css/site.css"), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected nested asset to be served, got %d", w.Code)
+	}
+
+	// Round trip through a build-time manifest
+	var buf bytes.Buffer
+	if err := m.WriteManifest(&buf); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+	loaded, err := LoadAssetManifest(fsys, "/static", &buf)
+	if err != nil {
+		t.Fatalf("LoadAssetManifest failed: %v", err)
+	}
+	if loaded.Path("app.js") != hashed {
+		t.Errorf("Expected %s from loaded manifest, got %s", hashed, loaded.Path("app.js"))
+	}
+}
+
+func TestAssetSRI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js": {Data: []byte("console.log('octo')")},
+	}
+	sum := sha512.Sum384([]byte("console.log('octo')"))
+	want := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	m, err := NewAssetManifest(fsys, "/static")
+	if err != nil {
+		t.Fatalf("NewAssetManifest failed: %v", err)
+	}
+	SetupAssets(m)
+	defer SetupAssets(nil)
+	if AssetSRI("app.js") != want {
+		t.Errorf("Expected %s, got %s", want, AssetSRI("app.js"))
+	}
+	if AssetSRI("missing.js") != "" {
+		t.Errorf("Expected no integrity for unknown asset, got %s", AssetSRI("missing.js"))
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteManifest(&buf); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected integrity in manifest, got %s", buf.String())
+	}
+	loaded, err := LoadAssetManifest(fsys, "/static", &buf)
+	if err != nil {
+		t.Fatalf("LoadAssetManifest failed: %v", err)
+	}
+	if loaded.Integrity("app.js") != want {
+		t.Errorf("Expected integrity from loaded manifest, got %s", loaded.Integrity("app.js"))
+	}
+
+	// Manifests without integrity values compute them on demand
+	legacy, err := LoadAssetManifest(fsys, "/static", strings.NewReader(`{"app.js":"app.12345678.js"}`))
+	if err != nil {
+		t.Fatalf("LoadAssetManifest failed: %v", err)
+	}
+	if legacy.Path("app.js") != "/static/app.12345678.js" || legacy.Integrity("app.js") != want {
+		t.Errorf("Unexpected legacy manifest entry: %s %s", legacy.Path("app.js"), legacy.Integrity("app.js"))
+	}
+}
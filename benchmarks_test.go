@@ -904,3 +904,76 @@ func BenchmarkRouter_MemoryProfiled(b *testing.B) {
 		resp.Body.Close()
 	}
 }
+
+// benchmarkServeHTTP measures in-process dispatch of a parameterized route,
+// without the network stack, so allocation differences are visible.
+func benchmarkServeHTTP(b *testing.B, arena bool) {
+	oldVal := EnableRequestArena
+	EnableRequestArena = arena
+	defer func() { EnableRequestArena = oldVal }()
+
+	router := NewRouter[CustomData]()
+	router.GET("/users/:id/posts/:postId", func(ctx *Ctx[CustomData]) {
+		ctx.ResponseWriter.Write([]byte("OK"))
+	})
+
+	req := httptest.NewRequest("GET", "/users/42/posts/7", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
+
+// BenchmarkRouter_ServeHTTP_NoArena is the baseline for BenchmarkRouter_ServeHTTP_Arena.
+func BenchmarkRouter_ServeHTTP_NoArena(b *testing.B) {
+	benchmarkServeHTTP(b, false)
+}
+
+// BenchmarkRouter_ServeHTTP_Arena measures dispatch with the request arena enabled.
+func BenchmarkRouter_ServeHTTP_Arena(b *testing.B) {
+	benchmarkServeHTTP(b, true)
+}
+
+func newEmbeddedParamsRouter() *Router[CustomData] {
+	router := NewRouter[CustomData]()
+	handler := func(ctx *Ctx[CustomData]) {
+		ctx.ResponseWriter.Write([]byte(ctx.Params["id"] + "/" + ctx.Params["postId"]))
+	}
+	router.GET("/user:id-post:postId", handler)
+	router.GET("/user:id-comment:commentId", handler)
+	router.GET("/user:id", handler)
+	router.GET("/team:id-member:memberId", handler)
+	router.GET("/org-:org/repo-:repo.git", handler)
+	return router
+}
+
+// BenchmarkRouter_EmbeddedParamsSearch measures matching of multi-parameter
+// segments like /user:id-post:postId. With the arena the match itself does
+// not allocate.
+func BenchmarkRouter_EmbeddedParamsSearch(b *testing.B) {
+	b.ReportAllocs()
+	router := newEmbeddedParamsRouter()
+	arena := newRequestArena[CustomData]()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, ok := router.search("GET", "/user42-post7", arena); !ok {
+			b.Fatal("route not found")
+		}
+		arena.reset()
+	}
+}
+
+// BenchmarkRouter_EmbeddedParams measures full dispatch of an embedded-parameter route.
+func BenchmarkRouter_EmbeddedParams(b *testing.B) {
+	b.ReportAllocs()
+	router := newEmbeddedParamsRouter()
+	req := httptest.NewRequest("GET", "/org-coffyg/repo-octo.git", nil)
+	w := httptest.NewRecorder()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
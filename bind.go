@@ -0,0 +1,109 @@
+package octo
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// CodedError is an error sent with one of the APIErrors codes by Handler
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+// ErrorCode wraps err so Handler sends it with code (e.g. "err_not_found")
+// instead of err_internal_error
+func ErrorCode(code string, err error) error {
+	return &CodedError{Code: code, Err: err}
+}
+
+func (e *CodedError) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return e.Code + ": " + e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// Bind decodes the request into a new T: the body according to its
+// Content-Type, or the query parameters when there is no body, then the path
+// parameters, which take precedence.
+func Bind[T any, V any](ctx *Ctx[V]) (T, error) {
+	var v T
+	if hasRequestBody(ctx.Request) {
+		mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		if !isBindableMediaType(mediaType) {
+			return v, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, mediaType)
+		}
+		if err := ctx.ShouldBind(&v); err != nil {
+			return v, err
+		}
+	} else if len(ctx.Request.URL.RawQuery) > 0 {
+		if err := ctx.ShouldBindQuery(&v); err != nil {
+			return v, err
+		}
+	}
+	if len(ctx.Params) > 0 {
+		if err := ctx.ShouldBindParams(&v); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+func hasRequestBody(r *http.Request) bool {
+	if r.ContentLength > 0 {
+		return true
+	}
+	return r.ContentLength < 0 && r.Header.Get("Content-Type") != ""
+}
+
+// Handler adapts fn into a HandlerFunc: the request is bound into T (see
+// Bind) and the R returned by fn is sent as a success result. Binding errors
+// are sent as 415, 422 (ValidationErrors) or 400; errors of fn as
+// err_internal_error unless wrapped with ErrorCode. fn may also send its own
+// response, in which case R is ignored.
+func Handler[T, R any, V any](fn func(ctx *Ctx[V], req T) (R, error)) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		req, err := Bind[T](ctx)
+		if err != nil {
+			sendBindError(ctx, err)
+			return
+		}
+		res, err := fn(ctx, req)
+		if err != nil {
+			var coded *CodedError
+			if errors.As(err, &coded) {
+				ctx.SendError(coded.Code, coded.Err)
+			} else {
+				ctx.SendError("err_internal_error", err)
+			}
+			return
+		}
+		ctx.NewJSONResult(res, nil)
+	}
+}
+
+func sendBindError[V any](ctx *Ctx[V], err error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		ctx.SendError("err_unsupported_media_type", err)
+	case errors.Is(err, ErrValidation):
+		ctx.SendValidationErrors(err)
+	default:
+		ctx.SendError("err_invalid_request", err)
+	}
+}
+
+func isBindableMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "application/xml", "text/xml", "application/x-www-form-urlencoded", "multipart/form-data":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package octo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindRequest struct {
+	ID    string `json:"id" form:"id"`
+	Name  string `json:"name" form:"name"`
+	Limit int    `json:"limit" form:"limit"`
+}
+
+type bindResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func TestBindAndHandler(t *testing.T) {
+	router := NewRouter[CustomData]()
+	greet := Handler(func(ctx *Ctx[CustomData], req bindRequest) (bindResponse, error) {
+		if req.Name == "nobody" {
+			return bindResponse{}, ErrorCode("err_not_found", errors.New("no such user"))
+		}
+		if req.Name == "crash" {
+			return bindResponse{}, errors.New("boom")
+		}
+		return bindResponse{Greeting: "hello " + req.Name + " #" + req.ID}, nil
+	})
+	router.GET("/users/:id", greet)
+	router.POST("/users/:id", greet)
+
+	do := func(method, path, contentType, body string) (*httptest.ResponseRecorder, BaseResult) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var res BaseResult
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w, res
+	}
+
+	w, res := do("GET", "/users/7?name=ada&limit=3", "", "")
+	if w.Code != http.StatusOK || res.Data.(map[string]interface{})["greeting"] != "hello ada #7" {
+		t.Errorf("Unexpected query bind: %d %s", w.Code, w.Body.String())
+	}
+	// Path parameters win over the body
+	w, res = do("POST", "/users/7", "application/json", `{"id":"9","name":"grace"}`)
+	if w.Code != http.StatusOK || res.Data.(map[string]interface{})["greeting"] != "hello grace #7" {
+		t.Errorf("Unexpected body bind: %d %s", w.Code, w.Body.String())
+	}
+
+	if w, _ := do("POST", "/users/7", "application/json", `{"name":`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed JSON, got %d", w.Code)
+	}
+	if w, _ := do("POST", "/users/7", "text/plain", `name`); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for an unsupported body, got %d", w.Code)
+	}
+	if w, res := do("GET", "/users/7?name=nobody", "", ""); w.Code != http.StatusNotFound || res.Token != "err_not_found" {
+		t.Errorf("Expected the coded error, got %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := do("GET", "/users/7?name=crash", "", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for an uncoded error, got %d", w.Code)
+	}
+
+	strict := NewRouter[CustomData]()
+	strict.SetStrictJSON(true)
+	strict.POST("/users/:id", greet)
+	req := httptest.NewRequest("POST", "/users/1", strings.NewReader(`{"nmae":"ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	strict.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for unknown fields, got %d", w.Code)
+	}
+}
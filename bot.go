@@ -0,0 +1,161 @@
+package octo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BotVerdict is the outcome of a BotDetector, ordered by severity
+type BotVerdict int
+
+const (
+	BotAllow BotVerdict = iota
+	// BotChallenge asks the client to solve a challenge (403 err_challenge_required)
+	BotChallenge
+	// BotThrottle slows the client down (429 err_too_many_requests)
+	BotThrottle
+	// BotBlock rejects the request (403 err_bot_detected)
+	BotBlock
+)
+
+// ChallengeHeader is set on responses asking for a challenge token
+const ChallengeHeader = "X-Octo-Challenge"
+
+// BotDetector inspects a request before it reaches the handler. Detectors that
+// fail return an error and are treated as BotAllow (fail open).
+type BotDetector interface {
+	Inspect(req *http.Request, clientIP string) (BotVerdict, error)
+}
+
+// BotDetectorFunc adapts a function to BotDetector
+type BotDetectorFunc func(req *http.Request, clientIP string) (BotVerdict, error)
+
+func (f BotDetectorFunc) Inspect(req *http.Request, clientIP string) (BotVerdict, error) {
+	return f(req, clientIP)
+}
+
+// UserAgentDetector blocks user agents containing one of Deny (case-insensitive)
+type UserAgentDetector struct {
+	Deny       []string
+	BlockEmpty bool
+}
+
+func (d UserAgentDetector) Inspect(req *http.Request, clientIP string) (BotVerdict, error) {
+	ua := req.UserAgent()
+	if ua == "" {
+		if d.BlockEmpty {
+			return BotBlock, nil
+		}
+		return BotAllow, nil
+	}
+	ua = strings.ToLower(ua)
+	for _, deny := range d.Deny {
+		if strings.Contains(ua, strings.ToLower(deny)) {
+			return BotBlock, nil
+		}
+	}
+	return BotAllow, nil
+}
+
+// IPReputationDetector maps a reputation score (0 good .. 1 bad) to a verdict
+type IPReputationDetector struct {
+	Score          func(clientIP string) (float64, error)
+	ThrottleAbove  float64
+	BlockAbove     float64
+	ChallengeAbove float64
+}
+
+func (d IPReputationDetector) Inspect(req *http.Request, clientIP string) (BotVerdict, error) {
+	score, err := d.Score(clientIP)
+	if err != nil {
+		return BotAllow, err
+	}
+	switch {
+	case d.BlockAbove > 0 && score > d.BlockAbove:
+		return BotBlock, nil
+	case d.ThrottleAbove > 0 && score > d.ThrottleAbove:
+		return BotThrottle, nil
+	case d.ChallengeAbove > 0 && score > d.ChallengeAbove:
+		return BotChallenge, nil
+	}
+	return BotAllow, nil
+}
+
+// ChallengeTokenDetector requires a token (e.g. from a JS challenge) in Header
+// or Cookie, checked by Verify
+type ChallengeTokenDetector struct {
+	Header string
+	Cookie string
+	Verify func(token, clientIP string) bool
+}
+
+func (d ChallengeTokenDetector) Inspect(req *http.Request, clientIP string) (BotVerdict, error) {
+	var token string
+	if d.Header != "" {
+		token = req.Header.Get(d.Header)
+	}
+	if token == "" && d.Cookie != "" {
+		if c, err := req.Cookie(d.Cookie); err == nil {
+			token = c.Value
+		}
+	}
+	if token == "" || !d.Verify(token, clientIP) {
+		return BotChallenge, nil
+	}
+	return BotAllow, nil
+}
+
+// BotProtectionConfig configures BotProtectionMiddleware
+type BotProtectionConfig struct {
+	Detectors []BotDetector
+	// RetryAfter is sent with throttled responses in seconds (default 30)
+	RetryAfter int
+}
+
+// BotProtectionMiddleware runs every detector and applies the most severe
+// verdict with a standard error envelope.
+func BotProtectionMiddleware[V any](cfg BotProtectionConfig) MiddlewareFunc[V] {
+	if cfg.RetryAfter == 0 {
+		cfg.RetryAfter = 30
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			clientIP := ctx.ClientIP()
+			verdict := BotAllow
+			for _, detector := range cfg.Detectors {
+				v, err := detector.Inspect(ctx.Request, clientIP)
+				if err != nil {
+					routerLogger := SubsystemLogger(LogRouter)
+					if EnableLoggerCheck {
+						if routerLogger != nil {
+							routerLogger.Warn().Err(err).Str("ip", clientIP).Msg("[octo] bot detector failed")
+						}
+					} else {
+						routerLogger.Warn().Err(err).Str("ip", clientIP).Msg("[octo] bot detector failed")
+					}
+					continue
+				}
+				if v > verdict {
+					verdict = v
+				}
+				if verdict == BotBlock {
+					break
+				}
+			}
+
+			switch verdict {
+			case BotBlock:
+				ctx.SendError("err_bot_detected", nil)
+			case BotThrottle:
+				ctx.SetHeader("Retry-After", strconv.Itoa(cfg.RetryAfter))
+				ctx.SendError("err_too_many_requests", nil)
+			case BotChallenge:
+				ctx.SetHeader(ChallengeHeader, "required")
+				ctx.SendError("err_challenge_required", nil)
+			default:
+				next(ctx)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package octo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBotProtectionMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	group := router.Group("/signup", BotProtectionMiddleware[CustomData](BotProtectionConfig{
+		Detectors: []BotDetector{
+			UserAgentDetector{Deny: []string{"curl"}},
+			IPReputationDetector{
+				Score: func(ip string) (float64, error) {
+					switch ip {
+					case "10.0.0.66":
+						return 0.9, nil
+					case "10.0.0.99":
+						return 0, errors.New("reputation service down")
+					}
+					return 0.1, nil
+				},
+				ThrottleAbove: 0.8,
+			},
+			ChallengeTokenDetector{
+				Header: "X-Challenge",
+				Verify: func(token, ip string) bool { return token == "solved" },
+			},
+		},
+	}))
+	group.POST("", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "welcome")
+	})
+
+	serve := func(ua, ip, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/signup", nil)
+		req.Header.Set("User-Agent", ua)
+		req.Header.Set("X-Real-IP", ip)
+		if token != "" {
+			req.Header.Set("X-Challenge", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("curl/8.0", "10.0.0.1", "solved"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected blocked user agent, got %d", w.Code)
+	}
+	if w := serve("Mozilla", "10.0.0.66", "solved"); w.Code != http.StatusTooManyRequests || w.Result().Header.Get("Retry-After") != "30" {
+		t.Errorf("Expected throttled IP, got %d", w.Code)
+	}
+	if w := serve("Mozilla", "10.0.0.1", ""); w.Code != http.StatusForbidden || w.Result().Header.Get(ChallengeHeader) != "required" {
+		t.Errorf("Expected challenge, got %d", w.Code)
+	}
+	if w := serve("Mozilla", "10.0.0.99", "solved"); w.Code != http.StatusOK {
+		t.Errorf("Expected detector failure to fail open, got %d", w.Code)
+	}
+}
@@ -0,0 +1,221 @@
+package octo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExceeded is wrapped by the errors of Budget.Spend
+var ErrBudgetExceeded = errors.New("request budget exceeded")
+
+// BudgetConfig sets the limits of a route budget. Zero fields are unlimited.
+type BudgetConfig struct {
+	// Time bounds the request: the request context gets the deadline, so
+	// database and HTTP clients using ctx.Context() stop with it
+	Time time.Duration
+	// MaxBytes is the response size handlers should stay under
+	MaxBytes int64
+	// Calls bounds named resources spent through Budget.Spend, e.g.
+	// {"db": 20, "upstream": 3}
+	Calls map[string]int64
+}
+
+// Budget is the share of resources a request may use, set per route with
+// Route.Budget and read by handlers and libraries through Ctx.Budget. It does
+// not stop the handler by itself: the deadline cancels the request context,
+// Spend returns errors once a limit is reached, and overruns are logged when
+// the request ends. A nil Budget is unlimited.
+type Budget struct {
+	deadline time.Time
+	maxBytes int64
+	written  atomic.Int64
+
+	mu    sync.Mutex
+	calls map[string]*budgetCounter
+}
+
+// budgetCounter counts the uses of a resource; a negative limit is unlimited
+type budgetCounter struct {
+	limit int64
+	used  atomic.Int64
+}
+
+type budgetLocalKey struct{}
+
+func newBudget(cfg BudgetConfig) *Budget {
+	b := &Budget{maxBytes: cfg.MaxBytes}
+	if cfg.Time > 0 {
+		b.deadline = time.Now().Add(cfg.Time)
+	}
+	if len(cfg.Calls) > 0 {
+		b.calls = make(map[string]*budgetCounter, len(cfg.Calls))
+		for name, limit := range cfg.Calls {
+			b.calls[name] = &budgetCounter{limit: limit}
+		}
+	}
+	return b
+}
+
+// Budget returns the budget of the route, or nil (unlimited) when the route
+// has none
+func (c *Ctx[V]) Budget() *Budget {
+	b, _ := c.getLocal(budgetLocalKey{}).(*Budget)
+	return b
+}
+
+// Deadline returns the time the request must be done by, if any
+func (b *Budget) Deadline() (time.Time, bool) {
+	if b == nil || b.deadline.IsZero() {
+		return time.Time{}, false
+	}
+	return b.deadline, true
+}
+
+// Remaining returns the time left before the deadline (negative once passed),
+// or the largest duration without one
+func (b *Budget) Remaining() time.Duration {
+	deadline, ok := b.Deadline()
+	if !ok {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Until(deadline)
+}
+
+// BytesRemaining returns how many more response bytes may be written, or -1
+// without a size limit
+func (b *Budget) BytesRemaining() int64 {
+	if b == nil || b.maxBytes <= 0 {
+		return -1
+	}
+	return max(0, b.maxBytes-b.written.Load())
+}
+
+// Spend counts one use of the named resource and returns an error wrapping
+// ErrBudgetExceeded once its limit is passed. Resources without a limit are
+// counted but never refused.
+func (b *Budget) Spend(name string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	counter := b.calls[name]
+	if counter == nil {
+		if b.calls == nil {
+			b.calls = make(map[string]*budgetCounter)
+		}
+		counter = &budgetCounter{limit: -1}
+		b.calls[name] = counter
+	}
+	b.mu.Unlock()
+	if used := counter.used.Add(1); counter.limit >= 0 && used > counter.limit {
+		return fmt.Errorf("%w: %s used %d of %d", ErrBudgetExceeded, name, used, counter.limit)
+	}
+	return nil
+}
+
+// Used returns how many times the named resource was spent
+func (b *Budget) Used(name string) int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if counter := b.calls[name]; counter != nil {
+		return counter.used.Load()
+	}
+	return 0
+}
+
+// overruns lists the limits the request went past
+func (b *Budget) overruns() []string {
+	var out []string
+	if deadline, ok := b.Deadline(); ok && time.Now().After(deadline) {
+		out = append(out, "time")
+	}
+	if b.maxBytes > 0 && b.written.Load() > b.maxBytes {
+		out = append(out, "bytes")
+	}
+	b.mu.Lock()
+	for name, counter := range b.calls {
+		if counter.limit >= 0 && counter.used.Load() > counter.limit {
+			out = append(out, name)
+		}
+	}
+	b.mu.Unlock()
+	sort.Strings(out)
+	return out
+}
+
+// budgetWriter counts the bytes written against a budget
+type budgetWriter struct {
+	http.ResponseWriter
+	budget *Budget
+}
+
+func (w *budgetWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.budget.written.Add(int64(n))
+	return n, err
+}
+
+func (w *budgetWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *budgetWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, errors.New("ResponseWriter does not implement http.Hijacker")
+}
+
+// Budget gives every request of the route a Budget with the configured
+// limits, available from Ctx.Budget. Requests that end over budget are
+// logged with the exceeded limits.
+func (rt *Route[V]) Budget(cfg BudgetConfig) *Route[V] {
+	rt.useInner(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			b := newBudget(cfg)
+			ctx.setLocal(budgetLocalKey{}, b)
+			if !b.deadline.IsZero() {
+				reqCtx, cancel := context.WithDeadline(ctx.Request.Context(), b.deadline)
+				defer cancel()
+				ctx.Request = ctx.Request.WithContext(reqCtx)
+			}
+			original := ctx.ResponseWriter.ResponseWriter
+			ctx.ResponseWriter.ResponseWriter = &budgetWriter{ResponseWriter: original, budget: b}
+			next(ctx)
+			ctx.ResponseWriter.ResponseWriter = original
+
+			if over := b.overruns(); len(over) > 0 {
+				routerLogger := SubsystemLogger(LogRouter)
+				if EnableLoggerCheck {
+					if routerLogger != nil {
+						routerLogger.Warn().
+							Str("method", ctx.Request.Method).
+							Str("route", ctx.RoutePath()).
+							Strs("exceeded", over).
+							Msg("[octo] request over budget")
+					}
+				} else {
+					routerLogger.Warn().
+						Str("method", ctx.Request.Method).
+						Str("route", ctx.RoutePath()).
+						Strs("exceeded", over).
+						Msg("[octo] request over budget")
+				}
+			}
+		}
+	})
+	return rt
+}
@@ -0,0 +1,68 @@
+package octo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteBudget(t *testing.T) {
+	router := NewRouter[CustomData]()
+	var (
+		remaining time.Duration
+		deadline  bool
+		bytesLeft int64
+		spendErr  error
+		used      int64
+		cacheErr  error
+		cacheUsed int64
+	)
+	router.GET("/report", func(ctx *Ctx[CustomData]) {
+		b := ctx.Budget()
+		remaining = b.Remaining()
+		_, deadline = ctx.Context().Deadline()
+		ctx.SendString(http.StatusOK, "0123456789")
+		bytesLeft = b.BytesRemaining()
+		for i := 0; i < 3 && spendErr == nil; i++ {
+			spendErr = b.Spend("db")
+		}
+		used = b.Used("db")
+		for i := 0; i < 5 && cacheErr == nil; i++ {
+			cacheErr = b.Spend("cache")
+		}
+		cacheUsed = b.Used("cache")
+	}).Budget(BudgetConfig{Time: time.Second, MaxBytes: 64, Calls: map[string]int64{"db": 2}})
+	router.GET("/free", func(ctx *Ctx[CustomData]) {
+		b := ctx.Budget()
+		if b != nil || b.Spend("db") != nil || b.BytesRemaining() != -1 {
+			t.Error("Expected a nil, unlimited budget without Route.Budget")
+		}
+		ctx.SendString(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/report", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("Unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if remaining <= 0 || remaining > time.Second || !deadline {
+		t.Errorf("Expected a one second deadline on the request, got %v (deadline %v)", remaining, deadline)
+	}
+	if bytesLeft != 54 {
+		t.Errorf("Expected 54 bytes left, got %d", bytesLeft)
+	}
+	if !errors.Is(spendErr, ErrBudgetExceeded) || used != 3 {
+		t.Errorf("Expected the third db call to exceed the budget, got %v after %d", spendErr, used)
+	}
+	if cacheErr != nil || cacheUsed != 5 {
+		t.Errorf("Expected resources without a limit counted but never refused, got %v after %d", cacheErr, cacheUsed)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/free", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package octo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaptchaTokenHeader carries the captcha token for non-form requests
+const CaptchaTokenHeader = "X-Captcha-Token"
+
+// CaptchaProvider describes a siteverify-compatible captcha service
+type CaptchaProvider struct {
+	Name      string
+	VerifyURL string
+	Secret    string
+	// FormField is the form field holding the token
+	FormField string
+	// MinScore rejects reCAPTCHA v3 results below this score (0 disables)
+	MinScore float64
+	// CacheTTL caches verification results per token (default 2 minutes)
+	CacheTTL time.Duration
+	// HTTPClient used for verification (default 5s timeout)
+	HTTPClient *http.Client
+
+	cache captchaCache
+}
+
+// RecaptchaProvider returns a Google reCAPTCHA provider
+func RecaptchaProvider(secret string) *CaptchaProvider {
+	return &CaptchaProvider{
+		Name:      "recaptcha",
+		VerifyURL: "https://www.google.com/recaptcha/api/siteverify",
+		Secret:    secret,
+		FormField: "g-recaptcha-response",
+	}
+}
+
+// HCaptchaProvider returns an hCaptcha provider
+func HCaptchaProvider(secret string) *CaptchaProvider {
+	return &CaptchaProvider{
+		Name:      "hcaptcha",
+		VerifyURL: "https://api.hcaptcha.com/siteverify",
+		Secret:    secret,
+		FormField: "h-captcha-response",
+	}
+}
+
+// TurnstileProvider returns a Cloudflare Turnstile provider
+func TurnstileProvider(secret string) *CaptchaProvider {
+	return &CaptchaProvider{
+		Name:      "turnstile",
+		VerifyURL: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+		Secret:    secret,
+		FormField: "cf-turnstile-response",
+	}
+}
+
+type captchaResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+type captchaCache struct {
+	mu      sync.Mutex
+	entries map[string]captchaCacheEntry
+}
+
+type captchaCacheEntry struct {
+	ok      bool
+	expires time.Time
+}
+
+// maxCaptchaCacheEntries bounds the memory used by cached results
+const maxCaptchaCacheEntries = 10000
+
+func (c *captchaCache) get(token string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.entries[token]
+	if !found || time.Now().After(e.expires) {
+		return false, false
+	}
+	return e.ok, true
+}
+
+func (c *captchaCache) put(token string, ok bool, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]captchaCacheEntry)
+	}
+	if len(c.entries) >= maxCaptchaCacheEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCaptchaCacheEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[token] = captchaCacheEntry{ok: ok, expires: time.Now().Add(ttl)}
+}
+
+// Verify checks token with the provider. Results are cached for CacheTTL so a
+// retried submission does not hit the provider twice.
+func (p *CaptchaProvider) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	if ok, found := p.cache.get(token); found {
+		return ok, nil
+	}
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	form := url.Values{"secret": {p.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.VerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(p.Name + " verification returned " + resp.Status)
+	}
+	var result captchaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	ok := result.Success
+	if ok && p.MinScore > 0 && result.Score != nil && *result.Score < p.MinScore {
+		ok = false
+	}
+	ttl := p.CacheTTL
+	if ttl == 0 {
+		ttl = 2 * time.Minute
+	}
+	p.cache.put(token, ok, ttl)
+	return ok, nil
+}
+
+// VerifyCaptcha verifies token with provider for the current client IP
+func (c *Ctx[V]) VerifyCaptcha(provider *CaptchaProvider, token string) (bool, error) {
+	return provider.Verify(c.Context(), token, c.ClientIP())
+}
+
+// CaptchaMiddleware requires a valid captcha token, read from the provider form
+// field or the X-Captcha-Token header. The request body stays available to the
+// handler.
+func CaptchaMiddleware[V any](provider *CaptchaProvider) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			token := ctx.GetHeader(CaptchaTokenHeader)
+			if token == "" {
+				if err := ctx.NeedBody(); err != nil {
+					ctx.SendError("err_invalid_request", err)
+					return
+				}
+				if values, err := url.ParseQuery(string(ctx.Body)); err == nil {
+					token = values.Get(provider.FormField)
+				}
+			}
+			ok, err := ctx.VerifyCaptcha(provider, token)
+			if err != nil {
+				ctx.SendError("err_captcha_unavailable", err)
+				return
+			}
+			if !ok {
+				ctx.SendError("err_captcha_failed", nil)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCaptchaMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	verifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		r.ParseForm()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": r.PostForm.Get("secret") == "key" && r.PostForm.Get("response") == "good",
+		})
+	}))
+	defer verifier.Close()
+
+	provider := TurnstileProvider("key")
+	provider.VerifyURL = verifier.URL
+
+	router := NewRouter[CustomData]()
+	router.POST("/register", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.FormValue("email"))
+	}, CaptchaMiddleware[CustomData](provider))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"email": {"a@b.c"}, "cf-turnstile-response": {token}}
+		req := httptest.NewRequest("POST", "/register", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("bad"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for invalid token, got %d", w.Code)
+	}
+	if w := serve("good"); w.Code != http.StatusOK || w.Body.String() != "a@b.c" {
+		t.Errorf("Expected valid token to pass with body intact, got %d '%s'", w.Code, w.Body.String())
+	}
+	serve("good")
+	if calls.Load() != 2 {
+		t.Errorf("Expected cached result for repeated token, got %d provider calls", calls.Load())
+	}
+}
@@ -0,0 +1,68 @@
+package octo
+
+import "strings"
+
+// CaseInsensitive makes static path segments match regardless of case, so
+// "/Users/Me" reaches the route registered as "/users/me". Route keys are
+// lowercased once, at registration, so requests pay no extra cost when
+// their path is already lowercase. Parameter values keep their case, and the
+// literal parts of embedded parameter segments ("img-:id") still match
+// exactly. Existing routes are converted; it panics if two of them differ
+// only by case. Turning it off again keeps the lowercased keys.
+func (r *Router[V]) CaseInsensitive(on bool) {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+	setFoldCase(r.root, on)
+	r.treeChanged()
+}
+
+// CaseInsensitive applies Router.CaseInsensitive to the routes below the
+// group prefix. The prefix itself keeps the case sensitivity of the router.
+func (g *Group[V]) CaseInsensitive(on bool) {
+	g.router.treeMu.Lock()
+	defer g.router.treeMu.Unlock()
+	setFoldCase(g.node(), on)
+	g.router.treeChanged()
+}
+
+// node returns the tree node of the group prefix
+func (g *Group[V]) node() *node[V] {
+	current := g.router.root
+	for _, part := range splitPath(g.prefix) {
+		if strings.Contains(part, ":") {
+			current, _ = g.router.segmentNode(current, part)
+		} else {
+			current = current.staticChild(part)
+		}
+	}
+	return current
+}
+
+// setFoldCase sets the case folding of n and the nodes below it, re-keying
+// static children in lowercase when folding is turned on
+func setFoldCase[V any](n *node[V], on bool) {
+	n.foldCase = on
+	if on && len(n.staticChildren) > 0 {
+		folded := make(map[string]*node[V], len(n.staticChildren))
+		for part, child := range n.staticChildren {
+			key := strings.ToLower(part)
+			if _, dup := folded[key]; dup {
+				panic("octo: case-insensitive routes collide on segment: " + part)
+			}
+			folded[key] = child
+		}
+		n.staticChildren = folded
+	}
+	for _, child := range n.staticChildren {
+		setFoldCase(child, on)
+	}
+	for _, p := range n.patterns {
+		setFoldCase(p.child, on)
+	}
+	if n.paramChild != nil {
+		setFoldCase(n.paramChild, on)
+	}
+	if n.wildcardChild != nil {
+		setFoldCase(n.wildcardChild, on)
+	}
+}
@@ -0,0 +1,56 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCaseInsensitiveRouting(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/Users/:id", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "user "+ctx.Param("id"))
+	})
+	router.CaseInsensitive(true)
+	router.GET("/Docs/Guide", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "guide")
+	})
+	api := NewRouter[CustomData]()
+	group := api.Group("/api")
+	group.CaseInsensitive(true)
+	group.GET("/Items", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "items")
+	})
+
+	tests := []struct {
+		router *Router[CustomData]
+		path   string
+		code   int
+		body   string
+	}{
+		{router, "/users/AbC", http.StatusOK, "user AbC"},
+		{router, "/USERS/AbC", http.StatusOK, "user AbC"},
+		{router, "/docs/guide", http.StatusOK, "guide"},
+		{router, "/DOCS/gUiDe", http.StatusOK, "guide"},
+		{api, "/api/items", http.StatusOK, "items"},
+		{api, "/api/ITEMS", http.StatusOK, "items"},
+		{api, "/API/items", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected routes differing only by case to panic")
+		}
+	}()
+	clash := NewRouter[CustomData]()
+	clash.GET("/a", func(ctx *Ctx[CustomData]) {})
+	clash.GET("/A", func(ctx *Ctx[CustomData]) {})
+	clash.CaseInsensitive(true)
+}
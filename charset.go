@@ -0,0 +1,164 @@
+package octo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedMediaType is returned by the binders when StrictContentType is
+// enabled and the request Content-Type does not match the binder.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// CharsetDecoder wraps a reader producing the given charset into one producing UTF-8
+type CharsetDecoder func(io.Reader) io.Reader
+
+var (
+	charsetMu       sync.RWMutex
+	charsetDecoders = map[string]CharsetDecoder{
+		"utf-8":      nil,
+		"utf8":       nil,
+		"us-ascii":   nil,
+		"ascii":      nil,
+		"iso-8859-1": latin1Decoder,
+		"latin1":     latin1Decoder,
+	}
+)
+
+// RegisterCharset adds a decoder for a charset label used by the form and XML
+// binders (e.g. windows-1252 from golang.org/x/text). Labels are case-insensitive.
+func RegisterCharset(label string, decoder CharsetDecoder) {
+	charsetMu.Lock()
+	charsetDecoders[strings.ToLower(label)] = decoder
+	charsetMu.Unlock()
+}
+
+// lookupCharset returns the decoder for label; a nil decoder means the input is
+// already UTF-8 compatible.
+func lookupCharset(label string) (CharsetDecoder, error) {
+	charsetMu.RLock()
+	decoder, ok := charsetDecoders[strings.ToLower(strings.TrimSpace(label))]
+	charsetMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset: %s", label)
+	}
+	return decoder, nil
+}
+
+// xmlCharsetReader satisfies xml.Decoder.CharsetReader
+func xmlCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	decoder, err := lookupCharset(label)
+	if err != nil {
+		return nil, err
+	}
+	if decoder == nil {
+		return input, nil
+	}
+	return decoder(input), nil
+}
+
+// decodeCharset converts body from label to UTF-8
+func decodeCharset(label string, body []byte) ([]byte, error) {
+	if label == "" {
+		return body, nil
+	}
+	decoder, err := lookupCharset(label)
+	if err != nil {
+		return nil, err
+	}
+	if decoder == nil {
+		return body, nil
+	}
+	return io.ReadAll(decoder(bytes.NewReader(body)))
+}
+
+func latin1Decoder(r io.Reader) io.Reader {
+	return &latin1Reader{r: r}
+}
+
+// latin1Reader converts ISO-8859-1 to UTF-8 as it is read. Every byte maps
+// to the rune of the same value, taking one or two UTF-8 bytes.
+type latin1Reader struct {
+	r   io.Reader
+	raw []byte
+	// pending holds the second byte of a rune that did not fit in p
+	pending []byte
+	err     error
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(l.pending) > 0 {
+		n := copy(p, l.pending)
+		l.pending = l.pending[n:]
+		return n, nil
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	n := 0
+	// Each byte takes at most two bytes once converted
+	want := max(1, len(p)/2)
+	if cap(l.raw) < want {
+		l.raw = make([]byte, want)
+	}
+	m, err := l.r.Read(l.raw[:want])
+	l.err = err
+	for _, b := range l.raw[:m] {
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		var buf [2]byte
+		utf8.EncodeRune(buf[:], rune(b))
+		c := copy(p[n:], buf[:])
+		// Only a one byte p can cut a rune
+		l.pending = append(l.pending[:0], buf[c:]...)
+		n += c
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, err
+}
+
+// trimBOM strips a leading UTF-8 byte order mark
+func trimBOM(body []byte) []byte {
+	return bytes.TrimPrefix(body, utf8BOM)
+}
+
+// checkContentType parses the request Content-Type and, in strict mode, makes
+// sure it is one of the accepted media types. It returns the charset parameter.
+func checkContentType(header string, accepted ...string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		if StrictContentType {
+			return "", fmt.Errorf("%w: %q", ErrUnsupportedMediaType, header)
+		}
+		return "", nil
+	}
+	if StrictContentType {
+		ok := false
+		for _, a := range accepted {
+			if mediaType == a || (a == "application/json" && strings.HasSuffix(mediaType, "+json")) ||
+				(a == "application/xml" && strings.HasSuffix(mediaType, "+xml")) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+		}
+	}
+	return params["charset"], nil
+}
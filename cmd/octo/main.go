@@ -0,0 +1,78 @@
+// Command octo is the octo toolbox.
+//
+//	octo gen client --lang ts|go --in <discovery.json|URL> [--out file] [--package name]
+//
+// The discovery document is the one served by octo.DiscoveryHandler.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo/gen"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "octo:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) < 2 || args[0] != "gen" || args[1] != "client" {
+		return fmt.Errorf("usage: octo gen client --lang ts|go --in <discovery.json|URL> [--out file] [--package name]")
+	}
+	fs := flag.NewFlagSet("octo gen client", flag.ContinueOnError)
+	lang := fs.String("lang", "ts", "target language: ts or go")
+	in := fs.String("in", "", "discovery document file or URL")
+	out := fs.String("out", "", "output file (stdout when empty)")
+	pkg := fs.String("package", "client", "Go package name")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("--in is required")
+	}
+
+	src, err := open(*in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	doc, err := gen.Load(src)
+	if err != nil {
+		return err
+	}
+	code, err := gen.Generate(doc, gen.Options{Lang: *lang, Package: *pkg})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(*out, code, 0o644)
+}
+
+// open reads the discovery document from a file or an http(s) URL
+func open(in string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(in, "http://") && !strings.HasPrefix(in, "https://") {
+		return os.Open(in)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", in, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,111 @@
+package compat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coffyg/octo"
+)
+
+type testData struct{}
+
+func TestGinHandlers(t *testing.T) {
+	router := octo.NewRouter[testData]()
+	router.Use(GinMiddleware(func(c *GinContext[testData]) {
+		if c.GetHeader("X-Token") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "token"})
+			return
+		}
+		c.Header("X-Before", "1")
+		c.Next()
+	}))
+	router.GET("/users/:id", Gin(func(c *GinContext[testData]) {
+		page, ok := c.GetQuery("page")
+		if !ok {
+			page = "1"
+		}
+		c.String(http.StatusOK, "user %s page %s sort %s", c.Param("id"), page, c.DefaultQuery("sort", "name"))
+	}))
+	router.POST("/users", Gin(func(c *GinContext[testData]) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			return
+		}
+		c.Set("name", body.Name)
+		c.JSON(http.StatusCreated, map[string]string{"name": c.GetString("name")})
+	}))
+
+	tests := []struct {
+		method, path, body, token string
+		code                      int
+		want                      string
+	}{
+		{"GET", "/users/7?page=2", "", "t", http.StatusOK, "user 7 page 2 sort name"},
+		{"GET", "/users/7", "", "", http.StatusUnauthorized, `{"error":"token"}`},
+		{"POST", "/users", `{"name":"ada"}`, "t", http.StatusCreated, `{"name":"ada"}`},
+		{"POST", "/users", `{bad`, "t", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("X-Token", tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.want {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.code, tt.want, w.Code, w.Body.String())
+		}
+		if tt.code == http.StatusOK && w.Header().Get("X-Before") != "1" {
+			t.Errorf("Expected middleware header before Next")
+		}
+	}
+}
+
+func TestEchoHandlers(t *testing.T) {
+	router := octo.NewRouter[testData]()
+	router.GET("/items/:id", Echo[testData](func(c EchoContext) error {
+		var q struct {
+			ID    int    `form:"id"`
+			Limit int    `form:"limit"`
+			Tag   string `form:"tag"`
+		}
+		if err := c.Bind(&q); err != nil {
+			return err
+		}
+		if q.ID == 0 {
+			return NewHTTPError(http.StatusNotFound, "no such item")
+		}
+		return c.JSON(http.StatusOK, q)
+	}))
+	router.DELETE("/items/:id", Echo[testData](func(c EchoContext) error {
+		if c.Param("id") == "locked" {
+			return errors.New("database down")
+		}
+		return c.NoContent(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path string
+		code         int
+		want         string
+	}{
+		{"GET", "/items/3?limit=10&tag=x", http.StatusOK, `{"ID":3,"Limit":10,"Tag":"x"}`},
+		{"GET", "/items/0", http.StatusNotFound, `{"message":"no such item"}`},
+		{"GET", "/items/abc", http.StatusBadRequest, ""},
+		{"DELETE", "/items/9", http.StatusNoContent, ""},
+		{"DELETE", "/items/locked", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || (tt.want != "" && w.Body.String() != tt.want) {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.code, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package compat
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/coffyg/octo"
+)
+
+// EchoContext mirrors the commonly used methods of echo.Context
+type EchoContext interface {
+	Request() *http.Request
+	Response() http.ResponseWriter
+	Param(name string) string
+	QueryParam(name string) string
+	QueryParams() url.Values
+	FormValue(name string) string
+	Cookie(name string) (*http.Cookie, error)
+	SetCookie(cookie *http.Cookie)
+	RealIP() string
+	Get(key string) any
+	Set(key string, value any)
+	Bind(i any) error
+	JSON(code int, i any) error
+	String(code int, s string) error
+	Blob(code int, contentType string, b []byte) error
+	NoContent(code int) error
+	Redirect(code int, url string) error
+}
+
+// HTTPError is an error carrying a status code, like echo.HTTPError
+type HTTPError struct {
+	Code    int
+	Message any
+}
+
+// NewHTTPError creates an HTTPError; the message defaults to the status text
+func NewHTTPError(code int, message ...any) *HTTPError {
+	e := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("code=%d, message=%v", e.Code, e.Message)
+}
+
+// echoContext implements EchoContext on an octo.Ctx
+type echoContext[V any] struct {
+	ctx  *octo.Ctx[V]
+	keys map[string]any
+}
+
+// Echo adapts an Echo-style handler. A returned *HTTPError is sent as
+// {"message": ...} with its code, other errors through octo's
+// err_internal_error envelope, unless a response was already written.
+func Echo[V any](fn func(c EchoContext) error) octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		err := fn(&echoContext[V]{ctx: ctx})
+		if err == nil || ctx.IsDone() {
+			return
+		}
+		if he, ok := err.(*HTTPError); ok {
+			ctx.JSON(he.Code, map[string]any{"message": he.Message})
+			return
+		}
+		ctx.SendError("err_internal_error", err)
+	}
+}
+
+func (c *echoContext[V]) Request() *http.Request {
+	return c.ctx.Request
+}
+
+func (c *echoContext[V]) Response() http.ResponseWriter {
+	return c.ctx.ResponseWriter
+}
+
+func (c *echoContext[V]) Param(name string) string {
+	return c.ctx.Param(name)
+}
+
+func (c *echoContext[V]) QueryParam(name string) string {
+	return c.ctx.QueryValue(name)
+}
+
+func (c *echoContext[V]) QueryParams() url.Values {
+	return c.ctx.Request.URL.Query()
+}
+
+func (c *echoContext[V]) FormValue(name string) string {
+	return c.ctx.FormValue(name)
+}
+
+func (c *echoContext[V]) Cookie(name string) (*http.Cookie, error) {
+	return c.ctx.Request.Cookie(name)
+}
+
+func (c *echoContext[V]) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.ctx.ResponseWriter, cookie)
+}
+
+func (c *echoContext[V]) RealIP() string {
+	return c.ctx.ClientIP()
+}
+
+func (c *echoContext[V]) Get(key string) any {
+	return c.keys[key]
+}
+
+func (c *echoContext[V]) Set(key string, value any) {
+	if c.keys == nil {
+		c.keys = make(map[string]any)
+	}
+	c.keys[key] = value
+}
+
+// Bind fills i from the path parameters, the query string (GET, HEAD and
+// DELETE) and the body, as Echo's default binder does. Path and query values
+// are matched by `form` tags rather than Echo's `param` and `query` tags.
+// Binding failures are returned as 400 HTTPErrors.
+func (c *echoContext[V]) Bind(i any) error {
+	if len(c.ctx.Params) > 0 {
+		if err := c.ctx.ShouldBindParams(i); err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	switch c.ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		if err := c.ctx.ShouldBindQuery(i); err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if c.ctx.Request.ContentLength == 0 {
+		return nil
+	}
+	if err := c.ctx.ShouldBind(i); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+func (c *echoContext[V]) JSON(code int, i any) error {
+	c.ctx.JSON(code, i)
+	return nil
+}
+
+func (c *echoContext[V]) String(code int, s string) error {
+	c.ctx.SendString(code, s)
+	return nil
+}
+
+func (c *echoContext[V]) Blob(code int, contentType string, b []byte) error {
+	c.ctx.SendData(code, contentType, b)
+	return nil
+}
+
+func (c *echoContext[V]) NoContent(code int) error {
+	c.ctx.SetStatus(code)
+	c.ctx.Done()
+	return nil
+}
+
+func (c *echoContext[V]) Redirect(code int, url string) error {
+	c.ctx.Redirect(code, url)
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package compat eases route-by-route migrations to octo from Gin and Echo.
+// Handlers keep their body and only change their signature:
+//
+//	func(c *gin.Context)        ->  func(c *compat.GinContext[V])
+//	func(c echo.Context) error  ->  func(c compat.EchoContext) error
+//
+// and are registered through Gin, GinMiddleware or Echo. Only the commonly
+// used methods are mapped; the underlying octo.Ctx stays reachable.
+package compat
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/coffyg/octo"
+)
+
+// GinContext mimics *gin.Context on top of an octo.Ctx
+type GinContext[V any] struct {
+	*octo.Ctx[V]
+	// Keys holds the values set with Set, as in Gin
+	Keys    map[string]any
+	Errors  []error
+	next    func()
+	aborted bool
+}
+
+// Gin adapts a Gin-style handler
+func Gin[V any](fn func(c *GinContext[V])) octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		fn(&GinContext[V]{Ctx: ctx})
+	}
+}
+
+// GinMiddleware adapts a Gin-style middleware: c.Next runs the rest of the
+// chain and c.Abort stops it when Next is never called
+func GinMiddleware[V any](fn func(c *GinContext[V])) octo.MiddlewareFunc[V] {
+	return func(next octo.HandlerFunc[V]) octo.HandlerFunc[V] {
+		return func(ctx *octo.Ctx[V]) {
+			c := &GinContext[V]{Ctx: ctx}
+			called := false
+			c.next = func() {
+				if called || c.aborted {
+					return
+				}
+				called = true
+				next(ctx)
+			}
+			fn(c)
+			// Gin runs the rest of the chain after a middleware that neither
+			// called Next nor aborted
+			if !called && !c.aborted && !ctx.IsDone() {
+				c.next()
+			}
+		}
+	}
+}
+
+// Next runs the remaining handlers (middleware only)
+func (c *GinContext[V]) Next() {
+	if c.next != nil {
+		c.next()
+	}
+}
+
+// Abort stops the chain after the current middleware
+func (c *GinContext[V]) Abort() {
+	c.aborted = true
+}
+
+func (c *GinContext[V]) IsAborted() bool {
+	return c.aborted
+}
+
+func (c *GinContext[V]) AbortWithStatus(code int) {
+	c.Abort()
+	c.Ctx.SetStatus(code)
+	c.Ctx.Done()
+}
+
+func (c *GinContext[V]) AbortWithStatusJSON(code int, obj any) {
+	c.Abort()
+	c.Ctx.JSON(code, obj)
+}
+
+func (c *GinContext[V]) AbortWithError(code int, err error) error {
+	c.Errors = append(c.Errors, err)
+	c.AbortWithStatus(code)
+	return err
+}
+
+func (c *GinContext[V]) Set(key string, value any) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]any)
+	}
+	c.Keys[key] = value
+}
+
+func (c *GinContext[V]) Get(key string) (any, bool) {
+	value, ok := c.Keys[key]
+	return value, ok
+}
+
+func (c *GinContext[V]) MustGet(key string) any {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	panic(fmt.Sprintf("key %q does not exist", key))
+}
+
+func (c *GinContext[V]) GetString(key string) string {
+	s, _ := c.Keys[key].(string)
+	return s
+}
+
+// Query returns the first value of a query parameter (path params excluded)
+func (c *GinContext[V]) Query(key string) string {
+	return c.Ctx.QueryValue(key)
+}
+
+func (c *GinContext[V]) GetQuery(key string) (string, bool) {
+	values, ok := c.Request.URL.Query()[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (c *GinContext[V]) PostForm(key string) string {
+	return c.Request.PostFormValue(key)
+}
+
+// Header sets a response header, as in Gin
+func (c *GinContext[V]) Header(key, value string) {
+	if value == "" {
+		c.Ctx.DelHeader(key)
+		return
+	}
+	c.Ctx.SetHeader(key, value)
+}
+
+func (c *GinContext[V]) Status(code int) {
+	c.Ctx.SetStatus(code)
+}
+
+func (c *GinContext[V]) String(code int, format string, values ...any) {
+	if len(values) > 0 {
+		format = fmt.Sprintf(format, values...)
+	}
+	c.Ctx.SendString(code, format)
+}
+
+func (c *GinContext[V]) Data(code int, contentType string, data []byte) {
+	c.Ctx.SendData(code, contentType, data)
+}
+
+func (c *GinContext[V]) IndentedJSON(code int, obj any) {
+	c.Ctx.JSON(code, obj)
+}
+
+// BindJSON binds the body and aborts with 400 on failure, as in Gin
+func (c *GinContext[V]) BindJSON(obj any) error {
+	if err := c.Ctx.ShouldBindJSON(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return err
+	}
+	return nil
+}
+
+// Bind binds by Content-Type and aborts with 400 on failure
+func (c *GinContext[V]) Bind(obj any) error {
+	if err := c.Ctx.ShouldBind(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return err
+	}
+	return nil
+}
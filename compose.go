@@ -0,0 +1,152 @@
+package octo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type composeNextKey struct{}
+
+// Compose chains handlers into one pipeline. Each router serves the routes
+// it has; unmatched requests pass through its global middleware to the next
+// handler instead of getting a 404. An edge router holding only security and
+// rate-limit middleware in front of an app router is the typical use.
+// Handlers other than octo routers end the pipeline.
+func Compose(handlers ...http.Handler) http.Handler {
+	if len(handlers) == 0 {
+		return http.NotFoundHandler()
+	}
+	// The last handler gets a nil next so it does not see the slot set for
+	// the handler before it
+	var next http.Handler
+	if len(handlers) > 1 {
+		next = Compose(handlers[1:]...)
+	}
+	first := handlers[0]
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		first.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), composeNextKey{}, next)))
+	})
+}
+
+// composedNext returns the handler following the current router in a
+// Compose pipeline, if any
+func composedNext(req *http.Request) http.Handler {
+	next, _ := req.Context().Value(composeNextKey{}).(http.Handler)
+	return next
+}
+
+// Fallback sets the handler serving requests no route matches, through the
+// global middleware, instead of the 404 response. This lets octo front an
+// existing net/http mux during a migration. Inside a Compose pipeline the
+// next handler takes precedence.
+func (r *Router[V]) Fallback(h http.Handler) {
+	r.fallback = h
+}
+
+// notFoundNext returns the handler for unmatched requests, if any
+func (r *Router[V]) notFoundNext(req *http.Request) http.Handler {
+	if next := composedNext(req); next != nil {
+		return next
+	}
+	return r.fallback
+}
+
+// Mount delegates every request under prefix to h, with the prefix stripped
+// from the path, after the global middleware of the router. h can be any
+// http.Handler: another octo router, a net/http mux, a gRPC gateway. A 404 of
+// h is final: it does not continue to the Fallback or Compose pipeline of r.
+func (r *Router[V]) Mount(prefix string, h http.Handler, middleware ...MiddlewareFunc[V]) *Route[V] {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		panic("octo: Mount needs a prefix, use Fallback for the root")
+	}
+	prefixSegments := len(splitPath(prefix))
+	handler := func(ctx *Ctx[V]) {
+		h.ServeHTTP(ctx.ResponseWriter, mountedRequest(ctx.Request, prefixSegments))
+		ctx.Done()
+	}
+	route := &Route[V]{Method: "ANY", Path: prefix + "/*path", router: r}
+	for _, path := range []string{prefix, prefix + "/*path"} {
+		route.entries = append(route.entries, r.newRoute(anyMethod, path, handler, middleware...).entries...)
+	}
+	r.listRoute(route)
+	return route
+}
+
+// mountedRequest returns a shallow copy of req with the first prefixSegments
+// segments stripped from its path, outside of any Compose pipeline. The path
+// is normalized like the router matched it, duplicate slashes collapsed and
+// dot segments resolved, while keeping its escaping (%2F stays in RawPath).
+func mountedRequest(req *http.Request, prefixSegments int) *http.Request {
+	ctx := req.Context()
+	if composedNext(req) != nil {
+		ctx = context.WithValue(ctx, composeNextKey{}, http.Handler(nil))
+	}
+	out := req.WithContext(ctx)
+	var segments []string
+	for _, segment := range splitPath(req.URL.EscapedPath()) {
+		switch decoded, _ := url.PathUnescape(segment); decoded {
+		case ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	rest := "/"
+	if len(segments) > prefixSegments {
+		rest += strings.Join(segments[prefixSegments:], "/")
+		if strings.HasSuffix(req.URL.Path, "/") {
+			rest += "/"
+		}
+	}
+	u := *req.URL
+	u.Path, u.RawPath = rest, ""
+	if decoded, err := url.PathUnescape(rest); err == nil {
+		u.Path = decoded
+	}
+	if u.EscapedPath() != rest {
+		u.RawPath = rest
+	}
+	out.URL = &u
+	return out
+}
+
+// bridgeKey carries a router's custom data across a Compose pipeline; the
+// type parameter keeps every data type in its own slot
+type bridgeKey[T any] struct{}
+
+// ExportCustom hands ctx.Custom to the following routers of a Compose
+// pipeline. Use it on the upstream router, before the pass-through happens.
+func ExportCustom[V any]() MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), bridgeKey[V]{}, ctx.Custom))
+			next(ctx)
+		}
+	}
+}
+
+// ImportCustom copies the custom data exported by an upstream router with
+// data type From into ctx.Custom through convert
+func ImportCustom[From, V any](convert func(from From, to *V)) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if from, ok := BridgedCustom[From](ctx.Request); ok {
+				convert(from, &ctx.Custom)
+			}
+			next(ctx)
+		}
+	}
+}
+
+// BridgedCustom returns the custom data of type T exported upstream, for
+// handlers outside octo
+func BridgedCustom[T any](req *http.Request) (T, bool) {
+	v, ok := req.Context().Value(bridgeKey[T]{}).(T)
+	return v, ok
+}
@@ -0,0 +1,174 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type edgeData struct {
+	Tenant string
+}
+
+func TestCompose(t *testing.T) {
+	edge := NewRouter[edgeData]()
+	edge.Use(func(next HandlerFunc[edgeData]) HandlerFunc[edgeData] {
+		return func(ctx *Ctx[edgeData]) {
+			tenant := ctx.GetHeader("X-Tenant")
+			if tenant == "" {
+				ctx.Send401()
+				return
+			}
+			ctx.Custom.Tenant = tenant
+			ctx.SetHeader("X-Edge", "1")
+			next(ctx)
+		}
+	})
+	edge.Use(ExportCustom[edgeData]())
+	edge.GET("/edge/health", func(ctx *Ctx[edgeData]) {
+		ctx.SendString(http.StatusOK, "edge ok")
+	})
+
+	app := NewRouter[CustomData]()
+	app.Use(ImportCustom(func(from edgeData, to *CustomData) {
+		to.UserID = from.Tenant
+	}))
+	app.GET("/orders/:id", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Custom.UserID+":"+ctx.Param("id"))
+	})
+
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/legacy", func(w http.ResponseWriter, req *http.Request) {
+		data, _ := BridgedCustom[edgeData](req)
+		w.Write([]byte("legacy " + data.Tenant))
+	})
+
+	pipeline := Compose(edge, app, legacy)
+	serve := func(path, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		pipeline.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("/orders/7", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected edge middleware to reject, got %d", w.Code)
+	}
+	w := serve("/orders/7", "acme")
+	if w.Body.String() != "acme:7" || w.Header().Get("X-Edge") != "1" {
+		t.Errorf("Expected app route with bridged data, got %s %v", w.Body.String(), w.Header())
+	}
+	if w := serve("/edge/health", "acme"); w.Body.String() != "edge ok" {
+		t.Errorf("Expected edge route to be served by edge, got %s", w.Body.String())
+	}
+	if w := serve("/legacy", "acme"); w.Body.String() != "legacy acme" {
+		t.Errorf("Expected fallthrough to the plain mux, got %s", w.Body.String())
+	}
+	if w := serve("/missing", "acme"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 at the end of the pipeline, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	Compose(edge, app).ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected edge middleware on pass-through, got %d", w.Code)
+	}
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Header.Set("X-Tenant", "acme")
+	w = httptest.NewRecorder()
+	Compose(edge, app).ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the last router to answer 404, got %d", w.Code)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/old/report", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("legacy report"))
+	})
+
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			ctx.SetHeader("X-Octo", "1")
+			next(ctx)
+		}
+	})
+	router.GET("/new/report", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "new report")
+	})
+	router.Fallback(legacy)
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/new/report", http.StatusOK, "new report"},
+		{"GET", "/old/report", http.StatusOK, "legacy report"},
+		{"GET", "/unknown", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("X-Octo") != "1" {
+			t.Errorf("%s %s: expected %d %q through middleware, got %d %q %v", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String(), w.Header())
+		}
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	admin := NewRouter[CustomData]()
+	admin.GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "admin home")
+	})
+	admin.POST("/users/:id", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "user "+ctx.Param("id")+" at "+ctx.Request.URL.Path)
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/{name}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("file " + req.PathValue("name") + " " + req.URL.RawPath))
+	})
+
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			ctx.SetHeader("X-Octo", "1")
+			next(ctx)
+		}
+	})
+	router.Mount("/admin", admin)
+	router.Mount("/legacy/", mux)
+	router.Fallback(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("fallback"))
+	}))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/admin", http.StatusOK, "admin home"},
+		{"GET", "/admin/", http.StatusOK, "admin home"},
+		{"POST", "/admin/users/7", http.StatusOK, "user 7 at /users/7"},
+		{"GET", "/legacy/files/a%2Fb", http.StatusOK, "file a/b /files/a%2Fb"},
+		{"PROPFIND", "/legacy/files/x", http.StatusOK, "file x "},
+		{"POST", "//admin//users/7", http.StatusOK, "user 7 at /users/7"},
+		{"POST", "/admin/x/../users/8", http.StatusOK, "user 8 at /users/8"},
+		{"POST", "/admin/../admin/users/9", http.StatusOK, "user 9 at /users/9"},
+		// 404s of the mounted handler are final
+		{"GET", "/admin/missing", http.StatusNotFound, ""},
+		{"GET", "/administrator", http.StatusOK, "fallback"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body || w.Header().Get("X-Octo") != "1" {
+			t.Errorf("%s %s: expected %d %q through middleware, got %d %q %v", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String(), w.Header())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package octo
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errQueueFull is returned by acquire when no more waiters are accepted
+var errQueueFull = errors.New("queue full")
+
+// semaphoreWaiter is a queued acquire
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// weightedSemaphore bounds the total weight held at once. Waiters are served
+// in FIFO order so a heavy request is not starved by light ones.
+type weightedSemaphore struct {
+	mu       sync.Mutex
+	size     int64
+	cur      int64
+	maxQueue int
+	waiters  list.List
+}
+
+func newWeightedSemaphore(size int64, maxQueue int) *weightedSemaphore {
+	return &weightedSemaphore{size: size, maxQueue: maxQueue}
+}
+
+// acquire takes n units, waiting in the queue until ctx is done
+func (s *weightedSemaphore) acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size || s.waiters.Len() >= s.maxQueue {
+		s.mu.Unlock()
+		return errQueueFull
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// Acquired just as ctx was cancelled; hand the units back
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release returns n units and wakes the waiters that now fit
+func (s *weightedSemaphore) release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("octo: semaphore released more than held")
+	}
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+func (s *weightedSemaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
+
+// retryAfterSeconds rounds d up to whole seconds, at least one
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
+
+// MaxConcurrent bounds the number of requests running the handler at once.
+// Up to queue excess requests wait for a slot, at most timeout each (no limit
+// when zero, besides the client going away); anything beyond gets a 503 with
+// Retry-After. The limit is shared by every method of the route.
+func (rt *Route[V]) MaxConcurrent(n int, queue int, timeout time.Duration) *Route[V] {
+	return rt.MaxConcurrentWeighted(int64(n), queue, timeout, nil)
+}
+
+// MaxConcurrentWeighted is MaxConcurrent where each request holds weight(ctx)
+// units of capacity (one when weight is nil), e.g. by report size
+func (rt *Route[V]) MaxConcurrentWeighted(capacity int64, queue int, timeout time.Duration, weight func(*Ctx[V]) int64) *Route[V] {
+	if capacity <= 0 {
+		panic("octo: MaxConcurrent requires a positive capacity")
+	}
+	sem := newWeightedSemaphore(capacity, queue)
+	retryAfter := retryAfterSeconds(timeout)
+	rt.useInner(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			units := int64(1)
+			if weight != nil {
+				if units = weight(ctx); units < 1 {
+					units = 1
+				}
+			}
+			waitCtx := ctx.Context()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				waitCtx, cancel = context.WithTimeout(waitCtx, timeout)
+				defer cancel()
+			}
+			if err := sem.acquire(waitCtx, units); err != nil {
+				ctx.SetHeader("Retry-After", retryAfter)
+				ctx.SendError("err_overloaded", err)
+				return
+			}
+			defer sem.release(units)
+			next(ctx)
+		}
+	})
+	return rt
+}
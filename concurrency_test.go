@@ -0,0 +1,101 @@
+package octo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouteMaxConcurrent(t *testing.T) {
+	router := NewRouter[CustomData]()
+	release := make(chan struct{})
+	entered := make(chan struct{}, 10)
+	router.GET("/report", func(ctx *Ctx[CustomData]) {
+		entered <- struct{}{}
+		<-release
+		ctx.SendString(http.StatusOK, "done")
+	}).MaxConcurrent(1, 1, time.Second)
+
+	codes := make(chan int, 3)
+	var wg sync.WaitGroup
+	serve := func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/report", nil))
+		codes <- w.Code
+	}
+
+	wg.Add(1)
+	go serve()
+	<-entered
+	wg.Add(1)
+	go serve()
+	// Let the second request queue before the third arrives
+	time.Sleep(20 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/report", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected overflow to get 503 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected running and queued requests to succeed, got %d", code)
+		}
+	}
+}
+
+func TestRouteMaxConcurrentTimeout(t *testing.T) {
+	router := NewRouter[CustomData]()
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	router.GET("/slow", func(ctx *Ctx[CustomData]) {
+		close(entered)
+		<-release
+	}).MaxConcurrent(1, 5, 10*time.Millisecond)
+
+	go router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	<-entered
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	close(release)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected queued request to time out with 503, got %d", w.Code)
+	}
+}
+
+func TestWeightedSemaphoreFIFO(t *testing.T) {
+	sem := newWeightedSemaphore(4, 10)
+	if err := sem.acquire(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	heavy := make(chan struct{})
+	go func() {
+		sem.acquire(context.Background(), 4)
+		close(heavy)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// A light request fits but must not jump ahead of the queued heavy one
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := sem.acquire(ctx, 1); err == nil {
+		t.Error("Expected light acquire to wait behind the heavy one")
+	}
+	sem.release(3)
+	select {
+	case <-heavy:
+	case <-time.After(time.Second):
+		t.Fatal("Expected heavy waiter to acquire after release")
+	}
+	if err := sem.acquire(context.Background(), 5); err != errQueueFull {
+		t.Errorf("Expected oversized acquire to fail, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package octo
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notModified reports whether a GET or HEAD request's If-Modified-Since
+// covers lastModified. If-None-Match takes precedence per RFC 9110, so its
+// presence disables the date check.
+func (c *Ctx[V]) notModified(lastModified time.Time) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	if c.Request.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// SendJSONIfModified sends v as JSON with a Last-Modified header, or a bare
+// 304 Not Modified when the client's If-Modified-Since is not older than
+// lastModified, in which case v is never encoded. A zero lastModified always
+// sends the payload.
+func (c *Ctx[V]) SendJSONIfModified(statusCode int, v interface{}, lastModified time.Time) {
+	if c.done {
+		return
+	}
+	if !lastModified.IsZero() {
+		c.SetHeader("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		if c.notModified(lastModified) {
+			c.SetStatus(http.StatusNotModified)
+			c.Done()
+			return
+		}
+	}
+	c.SendJSON(statusCode, v)
+}
+
+// QuoteETag turns a version (revision number, hash) into a strong entity
+// tag. Already quoted or weak tags are returned as is.
+func QuoteETag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+	return `"` + version + `"`
+}
+
+// etagListMatches reports whether header (an If-Match value) strongly
+// matches etag. "*" matches any existing representation.
+func etagListMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPreconditions implements optimistic concurrency for writes. The
+// current version of the resource is given as etag (quoted or not, empty if
+// unknown) and lastModified (zero if unknown). If-Match is checked first and
+// If-Unmodified-Since only without it. On mismatch a 412 error is sent and
+// false returned; the handler should stop.
+func (c *Ctx[V]) CheckPreconditions(etag string, lastModified time.Time) bool {
+	if etag != "" {
+		etag = QuoteETag(etag)
+	}
+	if ifMatch := c.Request.Header.Get("If-Match"); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag) {
+			c.SendError("err_precondition_failed", nil)
+			return false
+		}
+		return true
+	}
+	if ius := c.Request.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ius)
+		if err == nil && lastModified.Truncate(time.Second).After(since) {
+			c.SendError("err_precondition_failed", nil)
+			return false
+		}
+	}
+	return true
+}
+
+// RequirePreconditions is CheckPreconditions for endpoints that refuse blind
+// overwrites: a request without If-Match or If-Unmodified-Since gets a 428
+func (c *Ctx[V]) RequirePreconditions(etag string, lastModified time.Time) bool {
+	if c.Request.Header.Get("If-Match") == "" && c.Request.Header.Get("If-Unmodified-Since") == "" {
+		c.SendError("err_precondition_required", nil)
+		return false
+	}
+	return c.CheckPreconditions(etag, lastModified)
+}
@@ -0,0 +1,113 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingPayload struct {
+	encoded *int
+}
+
+func (p countingPayload) MarshalJSON() ([]byte, error) {
+	*p.encoded++
+	return []byte(`{"ok":true}`), nil
+}
+
+func TestSendJSONIfModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 30, 15, 500, time.UTC)
+	encoded := 0
+	router := NewRouter[CustomData]()
+	router.GET("/feed", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSONIfModified(http.StatusOK, countingPayload{&encoded}, modified)
+	})
+	router.POST("/feed", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSONIfModified(http.StatusOK, countingPayload{&encoded}, modified)
+	})
+
+	tests := []struct {
+		name, method string
+		headers      map[string]string
+		code         int
+		encodes      int
+	}{
+		{"no validator", "GET", nil, http.StatusOK, 1},
+		{"same second", "GET", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 12:30:15 GMT"}, http.StatusNotModified, 0},
+		{"newer", "GET", map[string]string{"If-Modified-Since": "Thu, 02 May 2024 00:00:00 GMT"}, http.StatusNotModified, 0},
+		{"older", "GET", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 12:30:14 GMT"}, http.StatusOK, 1},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK, 1},
+		{"if-none-match wins", "GET", map[string]string{"If-Modified-Since": "Thu, 02 May 2024 00:00:00 GMT", "If-None-Match": `"x"`}, http.StatusOK, 1},
+		{"post ignored", "POST", map[string]string{"If-Modified-Since": "Thu, 02 May 2024 00:00:00 GMT"}, http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		encoded = 0
+		req := httptest.NewRequest(tt.method, "/feed", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code || encoded != tt.encodes {
+			t.Errorf("%s: expected %d with %d encodes, got %d with %d", tt.name, tt.code, tt.encodes, w.Code, encoded)
+		}
+		if w.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:30:15 GMT" {
+			t.Errorf("%s: unexpected Last-Modified %q", tt.name, w.Header().Get("Last-Modified"))
+		}
+		if tt.code == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("Content-Type") != "") {
+			t.Errorf("%s: expected empty 304", tt.name)
+		}
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	router := NewRouter[CustomData]()
+	router.PUT("/docs/:id", func(ctx *Ctx[CustomData]) {
+		if !ctx.CheckPreconditions("v7", modified) {
+			return
+		}
+		ctx.SetHeader("ETag", QuoteETag("v8"))
+		ctx.SendString(http.StatusOK, "saved")
+	})
+	router.PATCH("/docs/:id", func(ctx *Ctx[CustomData]) {
+		if !ctx.RequirePreconditions(`"v7"`, time.Time{}) {
+			return
+		}
+		ctx.SendString(http.StatusOK, "patched")
+	})
+
+	tests := []struct {
+		name, method string
+		headers      map[string]string
+		code         int
+	}{
+		{"no preconditions", "PUT", nil, http.StatusOK},
+		{"matching etag", "PUT", map[string]string{"If-Match": `"v7"`}, http.StatusOK},
+		{"etag in list", "PUT", map[string]string{"If-Match": `"v6", "v7"`}, http.StatusOK},
+		{"star", "PUT", map[string]string{"If-Match": "*"}, http.StatusOK},
+		{"stale etag", "PUT", map[string]string{"If-Match": `"v6"`}, http.StatusPreconditionFailed},
+		{"weak etag never matches", "PUT", map[string]string{"If-Match": `W/"v7"`}, http.StatusPreconditionFailed},
+		{"unmodified since", "PUT", map[string]string{"If-Unmodified-Since": "Wed, 01 May 2024 12:00:00 GMT"}, http.StatusOK},
+		{"modified since", "PUT", map[string]string{"If-Unmodified-Since": "Wed, 01 May 2024 11:59:59 GMT"}, http.StatusPreconditionFailed},
+		{"if-match wins", "PUT", map[string]string{"If-Match": `"v7"`, "If-Unmodified-Since": "Tue, 30 Apr 2024 00:00:00 GMT"}, http.StatusOK},
+		{"required missing", "PATCH", nil, http.StatusPreconditionRequired},
+		{"required present", "PATCH", map[string]string{"If-Match": `"v7"`}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/docs/1", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d %s", tt.name, tt.code, w.Code, w.Body.String())
+		}
+		if tt.code == http.StatusPreconditionFailed && !strings.Contains(w.Body.String(), `"token":"err_precondition_failed"`) {
+			t.Errorf("%s: expected standard error envelope, got %s", tt.name, w.Body.String())
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package octo
 
 import (
+	"sync/atomic"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
 )
@@ -8,7 +10,11 @@ import (
 var logger *zerolog.Logger
 
 // Max body size for all requests
-var maxBodySize int64 = 10 * 1024 * 1024
+var maxBodySize atomic.Int64
+
+func init() {
+	maxBodySize.Store(10 * 1024 * 1024)
+}
 
 // 1) Defer buffer allocation in rwriter.go
 var DeferBufferAllocation = true
@@ -16,9 +22,20 @@ var DeferBufferAllocation = true
 // 2) Check logger != nil in ctx.go (guard logging statements)
 var EnableLoggerCheck = true
 
-// 3) Add simple security headers in router.go
+// 3) Add simple security headers in router.go. Set it before serving; once
+// SetSecurityHeaders or the settings endpoint change them, it is no longer read.
 var EnableSecurityHeaders = false
 
+// 4) Experimental: recycle Ctx, params and small strings through a per-router
+// request arena instead of allocating them per request
+var EnableRequestArena = false
+
+// 5) Reject bodies whose Content-Type does not match the binder (ErrUnsupportedMediaType)
+var StrictContentType = false
+
+// 6) Development mode: enables dev-only checks such as response contract validation
+var DevMode = false
+
 func SetupOctoLogger(l *zerolog.Logger) {
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 	logger = l
@@ -27,17 +44,28 @@ func SetupOctoLogger(l *zerolog.Logger) {
 func SetupOCto(l *zerolog.Logger, mbs int64) {
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 	logger = l
-	maxBodySize = mbs
+	ChangeMaxBodySize(mbs)
 }
 
 func GetLogger() *zerolog.Logger {
 	return logger
 }
 
+// ChangeMaxBodySize sets the maximum request body size, logging invalid
+// sizes; see SetMaxBodySize for the error
 func ChangeMaxBodySize(mbs int64) {
-	maxBodySize = mbs
+	if err := SetMaxBodySize(mbs); err != nil {
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Error().Err(err).Msg("[octo] invalid max body size")
+			}
+		} else {
+			serverLogger.Error().Err(err).Msg("[octo] invalid max body size")
+		}
+	}
 }
 
 func GetMaxBodySize() int64 {
-	return maxBodySize
+	return maxBodySize.Load()
 }
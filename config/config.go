@@ -0,0 +1,377 @@
+// Package config loads octo server settings from defaults, configuration
+// files (YAML, TOML or JSON), environment variables and command-line flags,
+// in increasing order of precedence.
+//
+// Settings are described by struct tags, so applications can embed Config in
+// their own struct and load everything in one pass:
+//
+//	type AppConfig struct {
+//		config.Config
+//		DatabaseURL string `config:"database.url" validate:"required"`
+//	}
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo"
+	"github.com/rs/zerolog"
+)
+
+// Config holds the settings octo itself understands
+type Config struct {
+	Addr            string        `config:"server.addr" default:":8080"`
+	ReadTimeout     time.Duration `config:"server.read_timeout" default:"15s"`
+	WriteTimeout    time.Duration `config:"server.write_timeout" default:"30s"`
+	IdleTimeout     time.Duration `config:"server.idle_timeout" default:"60s"`
+	ShutdownTimeout time.Duration `config:"server.shutdown_timeout" default:"30s"`
+	MaxBodySize     ByteSize      `config:"limits.max_body_size" default:"10MB"`
+	SecurityHeaders bool          `config:"security.headers"`
+	LogLevel        string        `config:"log.level" default:"info"`
+	DevMode         bool          `config:"dev_mode"`
+}
+
+// Validate checks the loaded values
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, &FieldError{Key: "server.addr", Err: errors.New("must not be empty")})
+	}
+	for key, d := range map[string]time.Duration{
+		"server.read_timeout":     c.ReadTimeout,
+		"server.write_timeout":    c.WriteTimeout,
+		"server.idle_timeout":     c.IdleTimeout,
+		"server.shutdown_timeout": c.ShutdownTimeout,
+	} {
+		if d < 0 {
+			errs = append(errs, &FieldError{Key: key, Err: errors.New("must not be negative")})
+		}
+	}
+	if c.MaxBodySize <= 0 {
+		errs = append(errs, &FieldError{Key: "limits.max_body_size", Err: errors.New("must be positive")})
+	}
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, &FieldError{Key: "log.level", Err: err})
+	}
+	sortFieldErrors(errs)
+	return errors.Join(errs...)
+}
+
+// Apply installs the settings on the octo runtime settings and package
+// globals, and the zerolog global level
+func (c *Config) Apply() error {
+	octo.DevMode = c.DevMode
+	if level, err := zerolog.ParseLevel(c.LogLevel); err == nil {
+		zerolog.SetGlobalLevel(level)
+	}
+	octo.EnableSecurityHeaders = c.SecurityHeaders
+	return octo.SetMaxBodySize(int64(c.MaxBodySize))
+}
+
+// Server returns an http.Server for handler with the configured address and
+// timeouts
+func (c *Config) Server(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         c.Addr,
+		Handler:      handler,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		IdleTimeout:  c.IdleTimeout,
+	}
+}
+
+// ServeConfig returns the octo.ServeConfig matching the settings
+func (c *Config) ServeConfig(handler http.Handler) octo.ServeConfig {
+	return octo.ServeConfig{Server: c.Server(handler), ShutdownTimeout: c.ShutdownTimeout}
+}
+
+// ByteSize is a size in bytes that parses suffixes such as 512KB or 10MB
+// (powers of 1024)
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(strings.TrimSpace(string(text)))
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", text)
+	}
+	*b = ByteSize(n * mult)
+	return nil
+}
+
+// FieldError reports a setting that could not be loaded or failed validation
+type FieldError struct {
+	Key    string
+	Source string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s (from %s): %v", e.Key, e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validator is implemented by configuration structs that check their values
+// after loading
+type Validator interface {
+	Validate() error
+}
+
+// Options selects the configuration sources
+type Options struct {
+	// Files are read in order; later files override earlier ones. The format
+	// follows the extension (.yaml/.yml, .toml, .json).
+	Files []string
+	// EnvPrefix namespaces environment variables: server.addr is read from
+	// OCTO_SERVER_ADDR with the default prefix "OCTO"
+	EnvPrefix string
+	// Environ replaces os.Environ, mainly for tests
+	Environ []string
+	// Args are parsed as --key=value flags (e.g. --server.addr=:9000); nil
+	// skips flags
+	Args []string
+	// AllowUnknown ignores file keys that match no setting instead of
+	// reporting them
+	AllowUnknown bool
+}
+
+// setting is a tagged leaf field of the destination struct
+type setting struct {
+	key   string
+	value reflect.Value
+	field reflect.StructField
+}
+
+func collectSettings(v reflect.Value, out []setting) []setting {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		key, tagged := f.Tag.Lookup("config")
+		if !tagged {
+			if f.Type.Kind() == reflect.Struct {
+				out = collectSettings(fv, out)
+			}
+			continue
+		}
+		out = append(out, setting{key: key, value: fv, field: f})
+	}
+	return out
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// set parses raw into the field
+func (s setting) set(raw string) error {
+	v := s.value
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// envName maps a setting key to its environment variable
+func envName(prefix, key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if prefix == "" {
+		return name
+	}
+	return strings.ToUpper(prefix) + "_" + name
+}
+
+// Load returns a Config loaded from opts and validated
+func Load(opts Options) (*Config, error) {
+	cfg := &Config{}
+	if err := LoadInto(cfg, opts); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadInto populates dst, a pointer to a struct with `config` tags, from the
+// defaults, files, environment and flags, then runs its Validate method when
+// it has one (embedded Config included). Every problem is reported, joined in
+// a single error of FieldErrors.
+func LoadInto(dst any, opts Options) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config: destination must be a pointer to a struct")
+	}
+	settings := collectSettings(rv.Elem(), nil)
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var errs []error
+	apply := func(s setting, raw, source string) {
+		if err := s.set(raw); err != nil {
+			errs = append(errs, &FieldError{Key: s.key, Source: source, Err: err})
+		}
+	}
+
+	for _, s := range settings {
+		if def, ok := s.field.Tag.Lookup("default"); ok {
+			apply(s, def, "default")
+		}
+	}
+
+	for _, file := range opts.Files {
+		values, err := readFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %w", file, err))
+			continue
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s, ok := byKey[key]
+			if !ok {
+				if !opts.AllowUnknown {
+					errs = append(errs, &FieldError{Key: key, Source: file, Err: errors.New("unknown setting")})
+				}
+				continue
+			}
+			apply(s, values[key], file)
+		}
+	}
+
+	prefix := opts.EnvPrefix
+	if prefix == "" {
+		prefix = "OCTO"
+	}
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	for _, s := range settings {
+		name := envName(prefix, s.key)
+		if raw, ok := env[name]; ok {
+			apply(s, raw, "$"+name)
+		}
+	}
+
+	if opts.Args != nil {
+		fs := flag.NewFlagSet("octo", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		flags := make(map[string]*string, len(settings))
+		for _, s := range settings {
+			flags[s.key] = fs.String(s.key, "", s.field.Tag.Get("usage"))
+		}
+		if err := fs.Parse(opts.Args); err != nil {
+			errs = append(errs, fmt.Errorf("config: flags: %w", err))
+		} else {
+			fs.Visit(func(f *flag.Flag) {
+				apply(byKey[f.Name], *flags[f.Name], "--"+f.Name)
+			})
+		}
+	}
+
+	for _, s := range settings {
+		if s.field.Tag.Get("validate") == "required" && s.value.IsZero() {
+			errs = append(errs, &FieldError{Key: s.key, Err: errors.New("is required")})
+		}
+	}
+	if len(errs) == 0 {
+		if v, ok := dst.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortFieldErrors(errs []error) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+}
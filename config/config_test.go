@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octo"
+	"github.com/rs/zerolog"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(Options{Environ: []string{}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Addr != ":8080" || cfg.ReadTimeout != 15*time.Second || cfg.MaxBodySize != 10<<20 || cfg.LogLevel != "info" {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yaml := writeFile(t, "octo.yaml", `
+# base settings
+server:
+  addr: ":9000"
+  read_timeout: 5s   # short
+limits:
+  max_body_size: 512KB
+log:
+  level: "debug"
+`)
+	toml := writeFile(t, "override.toml", `
+[server]
+write_timeout = "10s"
+
+[security]
+headers = true
+`)
+	cfg, err := Load(Options{
+		Files:   []string{yaml, toml},
+		Environ: []string{"OCTO_SERVER_ADDR=:9100", "OCTO_LOG_LEVEL=warn", "OTHER=1"},
+		Args:    []string{"--log.level=error"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Addr != ":9100" {
+		t.Errorf("Expected env to override file, got %s", cfg.Addr)
+	}
+	if cfg.LogLevel != "error" {
+		t.Errorf("Expected flag to override env, got %s", cfg.LogLevel)
+	}
+	if cfg.ReadTimeout != 5*time.Second || cfg.WriteTimeout != 10*time.Second || cfg.IdleTimeout != 60*time.Second {
+		t.Errorf("Unexpected timeouts: %+v", cfg)
+	}
+	if cfg.MaxBodySize != 512<<10 || !cfg.SecurityHeaders {
+		t.Errorf("Unexpected limits: %+v", cfg)
+	}
+}
+
+func TestLoadValidationErrors(t *testing.T) {
+	file := writeFile(t, "bad.yml", `
+server:
+  read_timeout: soon
+  adrr: ":1"
+log:
+  level: loud
+`)
+	_, err := Load(Options{Files: []string{file}, Environ: []string{"OCTO_LIMITS_MAX_BODY_SIZE=huge"}})
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+	for _, want := range []string{
+		`server.read_timeout (from ` + file + `): invalid duration "soon"`,
+		`server.adrr (from ` + file + `): unknown setting`,
+		`limits.max_body_size (from $OCTO_LIMITS_MAX_BODY_SIZE): invalid size "huge"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q in:\n%v", want, err)
+		}
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Errorf("Expected FieldError, got %T", err)
+	}
+
+	_, err = Load(Options{Environ: []string{"OCTO_LOG_LEVEL=loud"}})
+	if err == nil || !strings.Contains(err.Error(), "log.level") {
+		t.Errorf("Expected validation of log level, got %v", err)
+	}
+}
+
+type appConfig struct {
+	Config
+	DatabaseURL string   `config:"database.url" validate:"required"`
+	Origins     []string `config:"cors.origins"`
+	Workers     int      `config:"workers" default:"4"`
+}
+
+func TestLoadIntoEmbedded(t *testing.T) {
+	file := writeFile(t, "app.json", `{"database": {"url": "postgres://db"}, "cors": {"origins": ["a.com", "b.com"]}}`)
+	var cfg appConfig
+	if err := LoadInto(&cfg, Options{Files: []string{file}, Environ: []string{"APP_WORKERS=8"}, EnvPrefix: "APP"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://db" || cfg.Workers != 8 || cfg.Addr != ":8080" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Origins, []string{"a.com", "b.com"}) {
+		t.Errorf("Unexpected origins: %v", cfg.Origins)
+	}
+
+	yaml := writeFile(t, "list.yaml", "cors:\n  origins:\n    - x.com\n    - 'y.com'\n")
+	var listed appConfig
+	err := LoadInto(&listed, Options{Files: []string{yaml}, Environ: []string{}})
+	if err == nil || !strings.Contains(err.Error(), "database.url: is required") {
+		t.Errorf("Expected required error, got %v", err)
+	}
+	if !reflect.DeepEqual(listed.Origins, []string{"x.com", "y.com"}) {
+		t.Errorf("Unexpected yaml list: %v", listed.Origins)
+	}
+}
+
+func TestApply(t *testing.T) {
+	defer func(mbs int64, sh, dev bool, level zerolog.Level) {
+		octo.ChangeMaxBodySize(mbs)
+		octo.EnableSecurityHeaders = sh
+		octo.DevMode = dev
+		zerolog.SetGlobalLevel(level)
+	}(octo.GetMaxBodySize(), octo.EnableSecurityHeaders, octo.DevMode, zerolog.GlobalLevel())
+
+	cfg, err := Load(Options{Environ: []string{"OCTO_LIMITS_MAX_BODY_SIZE=1MB", "OCTO_SECURITY_HEADERS=true", "OCTO_DEV_MODE=1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Earlier runtime updates must not shadow the applied settings
+	if err := octo.SetMaintenance(false, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if octo.GetMaxBodySize() != 1<<20 || !octo.Settings().SecurityHeaders || !octo.EnableSecurityHeaders || !octo.DevMode {
+		t.Errorf("Expected settings to be applied")
+	}
+	srv := cfg.Server(nil)
+	if srv.Addr != ":8080" || srv.WriteTimeout != 30*time.Second {
+		t.Errorf("Unexpected server: %+v", srv)
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coffyg/octo/internal/yaml"
+)
+
+// readFile flattens a configuration file into dotted keys
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return parseYAML(data)
+	case ".toml":
+		return parseTOML(data)
+	case ".json":
+		return parseJSON(data)
+	}
+	return nil, fmt.Errorf("unsupported file format %q", filepath.Ext(path))
+}
+
+func parseJSON(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(joinKey(prefix, k), child)
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[prefix] = strings.Join(items, ",")
+		case nil:
+			out[prefix] = ""
+		default:
+			out[prefix] = fmt.Sprint(v)
+		}
+	}
+	walk("", doc)
+	return out, nil
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// stripComment removes a trailing # comment outside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// scalar unquotes a scalar value and turns inline lists into comma lists
+func scalar(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '[' && raw[len(raw)-1] == ']' {
+		var items []string
+		for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			v, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, v)
+		}
+		return strings.Join(items, ","), nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		return strconv.Unquote(raw)
+	}
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	return raw, nil
+}
+
+// parseYAML reads a YAML file with the parser of the route files and
+// flattens it: nested maps become dotted keys and sequences of scalars comma
+// lists
+func parseYAML(data []byte) (map[string]string, error) {
+	tree, err := yaml.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	if tree == nil {
+		return out, nil
+	}
+	root, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: expected a mapping at the top level")
+	}
+	if err := flattenYAML(out, "", root); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func flattenYAML(out map[string]string, prefix string, m map[string]interface{}) error {
+	for key, v := range m {
+		full := joinKey(prefix, key)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flattenYAML(out, full, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, ok := yamlString(item)
+				if !ok {
+					return fmt.Errorf("yaml: %s: only sequences of scalars are supported", full)
+				}
+				items[i] = s
+			}
+			out[full] = strings.Join(items, ",")
+		default:
+			out[full], _ = yamlString(v)
+		}
+	}
+	return nil
+}
+
+// yamlString formats a parsed YAML scalar, false for maps and sequences
+func yamlString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// parseTOML reads the subset of TOML used by configuration files: [table]
+// headers, key = value pairs with dotted keys, strings, numbers, booleans and
+// single-line arrays
+func parseTOML(data []byte) (map[string]string, error) {
+	out := make(map[string]string)
+	table := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: unsupported table header", n+1)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key, err := scalar(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		v, err := scalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		out[joinKey(table, key)] = v
+	}
+	return out, nil
+}
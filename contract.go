@@ -0,0 +1,89 @@
+package octo
+
+import (
+	"mime"
+)
+
+// ResponseContract maps status codes to the schema of the JSON body a route
+// promises. Key 0 is the fallback for statuses without their own schema.
+type ResponseContract map[int]*Schema
+
+// ContractViolation is reported when a response does not match its contract
+type ContractViolation struct {
+	Method string
+	Path   string
+	Status int
+	Err    error
+}
+
+// OnContractViolation is called for every mismatch found by
+// ResponseContractMiddleware (default: log a warning)
+var OnContractViolation = func(v ContractViolation) {
+	routerLogger := SubsystemLogger(LogRouter)
+	if EnableLoggerCheck {
+		if routerLogger != nil {
+			routerLogger.Warn().Err(v.Err).
+				Str("method", v.Method).
+				Str("path", v.Path).
+				Int("status", v.Status).
+				Msg("[octo] response contract violation")
+		}
+	} else {
+		routerLogger.Warn().Err(v.Err).
+			Str("method", v.Method).
+			Str("path", v.Path).
+			Int("status", v.Status).
+			Msg("[octo] response contract violation")
+	}
+}
+
+// ResponseContractMiddleware validates outgoing JSON responses against the
+// route's contract and reports mismatches through OnContractViolation. The
+// response itself is never altered. It only runs when DevMode is set, so it
+// can stay registered in production at the cost of a flag check.
+func ResponseContractMiddleware[V any](contract ResponseContract) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if !DevMode {
+				next(ctx)
+				return
+			}
+			rw := ctx.ResponseWriter
+			capture := rw.CaptureBody
+			start := 0
+			if rw.Body != nil {
+				start = rw.Body.Len()
+			}
+			rw.CaptureBody = true
+			next(ctx)
+			rw.CaptureBody = capture
+
+			schema, ok := contract[rw.Status]
+			if !ok {
+				schema = contract[0]
+			}
+			if schema == nil {
+				return
+			}
+			violation := ContractViolation{
+				Method: ctx.Request.Method,
+				Path:   ctx.Request.URL.Path,
+				Status: rw.Status,
+			}
+			mediaType, _, _ := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+			if mediaType != "application/json" {
+				violation.Err = ValidationErrors{{Message: "expected application/json response, got " + mediaType}}
+				OnContractViolation(violation)
+				return
+			}
+			var body []byte
+			if rw.Body != nil {
+				body = rw.Body.Bytes()[start:]
+			}
+			if err := schema.ValidateJSON(body); err != nil {
+				violation.Err = err
+				OnContractViolation(violation)
+			}
+		}
+	}
+}
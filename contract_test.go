@@ -0,0 +1,47 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseContractMiddleware(t *testing.T) {
+	oldDev, oldHook := DevMode, OnContractViolation
+	defer func() { DevMode, OnContractViolation = oldDev, oldHook }()
+
+	var violations []ContractViolation
+	OnContractViolation = func(v ContractViolation) { violations = append(violations, v) }
+
+	contract := ResponseContract{
+		http.StatusOK: MustCompileSchema([]byte(`{"type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`)),
+	}
+	router := NewRouter[CustomData]()
+	router.GET("/good", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSON(http.StatusOK, map[string]int{"id": 1})
+	}, ResponseContractMiddleware[CustomData](contract))
+	router.GET("/drift", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSON(http.StatusOK, map[string]string{"id": "1"})
+	}, ResponseContractMiddleware[CustomData](contract))
+
+	serve := func(path string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected response to be untouched, got %d", w.Code)
+		}
+	}
+
+	DevMode = false
+	serve("/drift")
+	if len(violations) != 0 {
+		t.Errorf("Expected no validation outside DevMode, got %v", violations)
+	}
+
+	DevMode = true
+	serve("/good")
+	serve("/drift")
+	if len(violations) != 1 || violations[0].Path != "/drift" {
+		t.Errorf("Expected one violation for /drift, got %v", violations)
+	}
+}
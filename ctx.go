@@ -35,6 +35,13 @@ type Ctx[V any] struct {
 	Custom         V                      // Generic Custom Field
 	done           bool
 	hasReadBody    bool
+	arena          bool
+	retained       bool
+	routePath      string
+	routeMeta      map[string]interface{}
+	strictJSON     bool
+	errorStatuses  map[string]int
+	locals         map[interface{}]interface{}
 }
 
 func (c *Ctx[V]) SetHeader(key, value string) {
@@ -79,12 +86,13 @@ func (c *Ctx[V]) SendJSON(statusCode int, v interface{}) {
 	c.SetStatus(statusCode)
 	_, err = c.ResponseWriter.Write(response)
 	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
 		if EnableLoggerCheck {
-			if logger != nil {
-				logger.Error().Err(err).Msg("[octo] failed to write response")
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Msg("[octo] failed to write response")
 			}
 		} else {
-			logger.Error().Err(err).Msg("[octo] failed to write response") // Potential panic if logger==nil
+			routerLogger.Error().Err(err).Msg("[octo] failed to write response") // Potential panic if routerLogger==nil
 		}
 	}
 	c.Done()
@@ -158,6 +166,36 @@ func (c *Ctx[V]) IsDone() bool {
 	return c.done
 }
 
+// RoutePath returns the pattern of the matched route (e.g. "/users/:id"), or
+// "" when no route matched
+func (c *Ctx[V]) RoutePath() string {
+	return c.routePath
+}
+
+// Meta returns the value set with Route.Meta for key on the matched route, or
+// nil
+func (c *Ctx[V]) Meta(key string) interface{} {
+	return c.routeMeta[key]
+}
+
+// MetaString returns the route metadata for key when it is a string, or ""
+func (c *Ctx[V]) MetaString(key string) string {
+	s, _ := c.routeMeta[key].(string)
+	return s
+}
+
+// setLocal stores request-scoped state used by octo helpers
+func (c *Ctx[V]) setLocal(key, value interface{}) {
+	if c.locals == nil {
+		c.locals = make(map[interface{}]interface{})
+	}
+	c.locals[key] = value
+}
+
+func (c *Ctx[V]) getLocal(key interface{}) interface{} {
+	return c.locals[key]
+}
+
 // ClientIP returns the client's IP address, even if behind a proxy
 func (c *Ctx[V]) ClientIP() string {
 	ip := c.GetHeader("X-Forwarded-For")
@@ -220,34 +258,75 @@ func (c *Ctx[V]) SetCookie(name, value string, maxAge int, path, domain string,
 
 // BindJSON binds the request body into an interface.
 func (c *Ctx[V]) ShouldBindJSON(obj interface{}) error {
-	err := c.NeedBody()
+	charset, err := checkContentType(c.GetHeader("Content-Type"), "application/json")
 	if err != nil {
 		return err
 	}
-	if len(c.Body) == 0 {
+	err = c.NeedBody()
+	if err != nil {
+		return err
+	}
+	body := trimBOM(c.Body)
+	if len(body) == 0 {
 		return errors.New("request body is empty")
 	}
-	return json.Unmarshal(c.Body, obj)
+	body, err = decodeCharset(charset, body)
+	if err != nil {
+		return err
+	}
+	if c.strictJSON {
+		return unmarshalJSONStrict(body, obj)
+	}
+	return json.Unmarshal(body, obj)
 }
 
 // ShouldBindXML binds the XML request body into the provided object.
 func (c *Ctx[V]) ShouldBindXML(obj interface{}) error {
-	err := c.NeedBody()
+	charset, err := checkContentType(c.GetHeader("Content-Type"), "application/xml", "text/xml")
+	if err != nil {
+		return err
+	}
+	err = c.NeedBody()
 	if err != nil {
 		return err
 	}
-	if len(c.Body) == 0 {
+	body := trimBOM(c.Body)
+	if len(body) == 0 {
 		return errors.New("request body is empty")
 	}
-	return xml.Unmarshal(c.Body, obj)
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = xmlCharsetReader
+	if charset != "" {
+		// The Content-Type charset wins over the document's declaration
+		body, err = decodeCharset(charset, body)
+		if err != nil {
+			return err
+		}
+		decoder = xml.NewDecoder(bytes.NewReader(body))
+		decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	return decoder.Decode(obj)
 }
 
 // ShouldBindForm binds form data into the provided object.
 func (c *Ctx[V]) ShouldBindForm(obj interface{}) error {
-	err := c.NeedBody()
+	charset, err := checkContentType(c.GetHeader("Content-Type"), "application/x-www-form-urlencoded")
+	if err != nil {
+		return err
+	}
+	err = c.NeedBody()
 	if err != nil {
 		return err
 	}
+	if charset != "" {
+		body, err := decodeCharset(charset, c.Body)
+		if err != nil {
+			return err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
 	if err := c.Request.ParseForm(); err != nil {
 		return err
 	}
@@ -257,7 +336,11 @@ func (c *Ctx[V]) ShouldBindForm(obj interface{}) error {
 
 // ShouldBindMultipartForm binds multipart form data into the provided object.
 func (c *Ctx[V]) ShouldBindMultipartForm(obj interface{}) error {
-	err := c.NeedBody()
+	_, err := checkContentType(c.GetHeader("Content-Type"), "multipart/form-data")
+	if err != nil {
+		return err
+	}
+	err = c.NeedBody()
 	if err != nil {
 		return err
 	}
@@ -268,6 +351,20 @@ func (c *Ctx[V]) ShouldBindMultipartForm(obj interface{}) error {
 	return mapForm(obj, values)
 }
 
+// ShouldBindQuery binds the URL query parameters into the provided object.
+func (c *Ctx[V]) ShouldBindQuery(obj interface{}) error {
+	return mapForm(obj, c.Request.URL.Query())
+}
+
+// ShouldBindParams binds the path parameters into the provided object.
+func (c *Ctx[V]) ShouldBindParams(obj interface{}) error {
+	values := make(url.Values, len(c.Params))
+	for k, v := range c.Params {
+		values.Set(k, v)
+	}
+	return mapForm(obj, values)
+}
+
 // mapForm maps form values into the provided struct.
 func mapForm(ptr interface{}, formData url.Values) error {
 	return formDecoder.Decode(ptr, formData)
@@ -300,27 +397,30 @@ func (c *Ctx[V]) NeedBody() error {
 	c.hasReadBody = true
 	c.ResponseWriter.CaptureBody = true
 
-	limitedReader := io.LimitReader(c.Request.Body, maxBodySize+1)
+	limit := maxBodySize.Load()
+	limitedReader := io.LimitReader(c.Request.Body, limit+1)
 	body, err := io.ReadAll(limitedReader)
 	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
 		if EnableLoggerCheck {
-			if logger != nil {
-				logger.Error().Err(err).Msg("[octo] failed to read request body")
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Msg("[octo] failed to read request body")
 			}
 		} else {
-			logger.Error().Err(err).Msg("[octo] failed to read request body") // Potential panic if logger == nil
+			routerLogger.Error().Err(err).Msg("[octo] failed to read request body") // Potential panic if routerLogger == nil
 		}
 		return err
 	}
 
-	if int64(len(body)) > maxBodySize {
+	if int64(len(body)) > limit {
 		tooLargeErr := errors.New("request body too large")
+		routerLogger := SubsystemLogger(LogRouter)
 		if EnableLoggerCheck {
-			if logger != nil {
-				logger.Error().Err(tooLargeErr).Msg("[octo] request body exceeds maximum allowed size")
+			if routerLogger != nil {
+				routerLogger.Error().Err(tooLargeErr).Msg("[octo] request body exceeds maximum allowed size")
 			}
 		} else {
-			logger.Error().Err(tooLargeErr).Msg("[octo] request body exceeds maximum allowed size")
+			routerLogger.Error().Err(tooLargeErr).Msg("[octo] request body exceeds maximum allowed size")
 		}
 		return tooLargeErr
 	}
@@ -345,22 +445,25 @@ func (c *Ctx[V]) SendError(code string, err error) {
 		message += ": " + err.Error()
 		if pc, file, line, ok := runtime.Caller(1); ok {
 			funcName := runtime.FuncForPC(pc).Name()
+			errorLogger := SubsystemLogger(LogError)
 			if EnableLoggerCheck {
-				if logger != nil {
-					logger.Error().Err(err).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
+				if errorLogger != nil {
+					errorLogger.Error().Err(err).Str("code", code).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
 				}
 			} else {
-				logger.Error().Err(err).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
+				errorLogger.Error().Err(err).Str("code", code).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
 			}
 		}
 	}
-	result := BaseResult{
+	status := c.errorStatus(code, apiError)
+	result := buildResult(nil, ResultMeta{
+		Status:  status,
 		Result:  "error",
 		Message: message,
-		Token:   code,
+		Token:   publicToken(code),
 		Time:    float64(time.Now().UnixNano()-c.StartTime) / 1e9,
-	}
-	c.SendJSON(apiError.Code, result)
+	})
+	c.SendJSON(status, result)
 }
 
 // SendErrorStatus sends an error response with a specific HTTP status code
@@ -377,21 +480,23 @@ func (c *Ctx[V]) SendErrorStatus(statusCode int, code string, err error) {
 		message += ": " + err.Error()
 		if pc, file, line, ok := runtime.Caller(1); ok {
 			funcName := runtime.FuncForPC(pc).Name()
+			errorLogger := SubsystemLogger(LogError)
 			if EnableLoggerCheck {
-				if logger != nil {
-					logger.Error().Err(err).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
+				if errorLogger != nil {
+					errorLogger.Error().Err(err).Str("code", code).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
 				}
 			} else {
-				logger.Error().Err(err).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
+				errorLogger.Error().Err(err).Str("code", code).Msgf("[octo-error] error: %s in %s:%d %s", err.Error(), file, line, funcName)
 			}
 		}
 	}
-	result := BaseResult{
+	result := buildResult(nil, ResultMeta{
+		Status:  statusCode,
 		Result:  "error",
 		Message: message,
-		Token:   code,
+		Token:   publicToken(code),
 		Time:    float64(time.Now().UnixNano()-c.StartTime) / 1e9,
-	}
+	})
 	c.SendJSON(statusCode, result)
 }
 
@@ -432,12 +537,12 @@ func (c *Ctx[V]) NewJSONResult(data interface{}, pagination *octypes.Pagination)
 	if c.done {
 		return
 	}
-	result := BaseResult{
-		Data:   data,
+	result := buildResult(data, ResultMeta{
+		Status: http.StatusOK,
 		Time:   float64(time.Now().UnixNano()-c.StartTime) / 1e9,
 		Result: "success",
 		Paging: pagination,
-	}
+	})
 	c.SendJSON(http.StatusOK, result)
 }
 
@@ -451,17 +556,100 @@ func (c *Ctx[V]) SendData(statusCode int, contentType string, data []byte) {
 	c.SetStatus(statusCode)
 	_, err := c.ResponseWriter.Write(data)
 	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
 		if EnableLoggerCheck {
-			if logger != nil {
-				logger.Error().Err(err).Msg("[octo] failed to write data")
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Msg("[octo] failed to write data")
 			}
 		} else {
-			logger.Error().Err(err).Msg("[octo] failed to write data")
+			routerLogger.Error().Err(err).Msg("[octo] failed to write data")
 		}
 	}
 	c.Done()
 }
 
+// SendReader streams r as the response body. A size >= 0 is sent as
+// Content-Length and bounds what is read from r; a negative size streams the
+// body chunked. HEAD requests get the headers only and r is not read. A read
+// error before the first byte becomes a 500; once the body has started, read
+// errors are logged as server errors and write errors as client aborts.
+func (c *Ctx[V]) SendReader(statusCode int, contentType string, r io.Reader, size int64) {
+	if c.done {
+		return
+	}
+	c.SetHeader("Content-Type", contentType)
+	if size >= 0 {
+		c.SetHeader("Content-Length", strconv.FormatInt(size, 10))
+		r = io.LimitReader(r, size)
+	}
+	if c.Request.Method == http.MethodHead {
+		c.SetStatus(statusCode)
+		c.Done()
+		return
+	}
+
+	buf := make([]byte, 32*1024)
+	n, err := readSome(r, buf)
+	if err != nil && err != io.EOF {
+		c.ResponseWriter.Header().Del("Content-Length")
+		c.SendError("err_internal_error", err)
+		return
+	}
+	c.SetStatus(statusCode)
+	c.Done()
+
+	var written int64
+	var readErr, writeErr error
+	for n > 0 {
+		if _, writeErr = c.ResponseWriter.Write(buf[:n]); writeErr != nil {
+			break
+		}
+		written += int64(n)
+		if err != nil {
+			break
+		}
+		n, err = readSome(r, buf)
+	}
+	if err != nil && err != io.EOF {
+		readErr = err
+	}
+	if writeErr == nil && readErr == nil && size >= 0 && written < size {
+		readErr = io.ErrUnexpectedEOF
+	}
+
+	switch {
+	case writeErr != nil:
+		routerLogger := SubsystemLogger(LogRouter)
+		if EnableLoggerCheck {
+			if routerLogger != nil {
+				routerLogger.Debug().Err(writeErr).Int64("written", written).Msg("[octo] client aborted the response")
+			}
+		} else {
+			routerLogger.Debug().Err(writeErr).Int64("written", written).Msg("[octo] client aborted the response")
+		}
+	case readErr != nil:
+		routerLogger := SubsystemLogger(LogRouter)
+		if EnableLoggerCheck {
+			if routerLogger != nil {
+				routerLogger.Error().Err(readErr).Int64("written", written).Msg("[octo] failed to read response body")
+			}
+		} else {
+			routerLogger.Error().Err(readErr).Int64("written", written).Msg("[octo] failed to read response body")
+		}
+	}
+}
+
+// readSome reads into buf until it gets at least one byte or an error
+func readSome(r io.Reader, buf []byte) (int, error) {
+	for i := 0; i < 100; i++ {
+		n, err := r.Read(buf)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.ErrNoProgress
+}
+
 // Send a file as response
 func (c *Ctx[V]) File(urlPath string, filePath string) {
 	if c.done {
@@ -509,25 +697,66 @@ type APIError struct {
 
 // BaseResult is the standard response format
 type BaseResult struct {
-	Data    interface{}         `json:"data,omitempty"`
-	Time    float64             `json:"time"`
-	Result  string              `json:"result"`
-	Message string              `json:"message,omitempty"`
-	Paging  *octypes.Pagination `json:"paging,omitempty"`
-	Token   string              `json:"token,omitempty"`
+	Data       interface{}         `json:"data,omitempty"`
+	Time       float64             `json:"time"`
+	Result     string              `json:"result"`
+	Message    string              `json:"message,omitempty"`
+	Paging     *octypes.Pagination `json:"paging,omitempty"`
+	Token      string              `json:"token,omitempty"`
+	NextCursor string              `json:"next_cursor,omitempty"`
 }
 
 var APIErrors = map[string]*APIError{
 	"err_unknown_error":            {"Unknown error", http.StatusInternalServerError},
 	"err_internal_error":           {"Internal error", http.StatusInternalServerError},
+	"err_not_implemented":          {"Not implemented", http.StatusNotImplemented},
 	"err_db_error":                 {"Database error", http.StatusInternalServerError},
 	"err_invalid_request":          {"Invalid request", http.StatusBadRequest},
+	"err_invalid_path":             {"Invalid path", http.StatusBadRequest},
+	"err_invalid_query":            {"Invalid query", http.StatusBadRequest},
+	"err_validation":               {"Validation failed", http.StatusUnprocessableEntity},
 	"err_invalid_email_address":    {"Invalid email address", http.StatusBadRequest},
 	"err_all_fields_are_mandatory": {"Missing required fields", http.StatusBadRequest},
 	"err_email_not_configured":     {"Email not configured", http.StatusInternalServerError},
 	"err_unauthorized":             {"Unauthorized", http.StatusUnauthorized},
+	"err_forbidden":                {"Forbidden", http.StatusForbidden},
+	"err_invalid_signature":        {"Invalid signature", http.StatusForbidden},
+	"err_link_expired":             {"Link expired", http.StatusForbidden},
+	"err_bot_detected":             {"Automated traffic detected", http.StatusForbidden},
+	"err_challenge_required":       {"Challenge required", http.StatusForbidden},
+	"err_too_many_requests":        {"Too many requests", http.StatusTooManyRequests},
+	"err_precondition_failed":      {"Precondition failed", http.StatusPreconditionFailed},
+	"err_precondition_required":    {"Precondition required", http.StatusPreconditionRequired},
+	"err_maintenance":              {"Service under maintenance", http.StatusServiceUnavailable},
+	"err_overloaded":               {"Service overloaded", http.StatusServiceUnavailable},
+	"err_bad_gateway":              {"Bad gateway", http.StatusBadGateway},
+	"err_no_upstream":              {"No upstream available", http.StatusServiceUnavailable},
+	"err_quota_exceeded":           {"Quota exceeded", http.StatusTooManyRequests},
+	"err_captcha_failed":           {"Captcha verification failed", http.StatusForbidden},
+	"err_captcha_unavailable":      {"Captcha verification unavailable", http.StatusServiceUnavailable},
 	"err_not_found":                {"Not found", http.StatusNotFound},
+	"err_method_not_allowed":       {"Method not allowed", http.StatusMethodNotAllowed},
 	"err_invalid_uuid":             {"Invalid UUID", http.StatusBadRequest},
 	"err_json_error":               {"JSON error", http.StatusBadRequest},
+	"err_xml_error":                {"XML error", http.StatusInternalServerError},
+	"err_template_error":           {"Template error", http.StatusInternalServerError},
+	"err_response_too_large":       {"Response too large", http.StatusInternalServerError},
+	"err_unsupported_media_type":   {"Unsupported media type", http.StatusUnsupportedMediaType},
+	"err_invalid_patch":            {"Invalid patch", http.StatusUnprocessableEntity},
+	"err_patch_conflict":           {"Patch test failed", http.StatusConflict},
 	// Add other error codes as needed
 }
+
+// PublicTokens maps internal error codes to the token sent to clients in
+// BaseResult.Token. Codes keep their name as token when unmapped, so an
+// internal code can be renamed without breaking clients by mapping it to its
+// old name. Logs always carry the internal code.
+var PublicTokens = map[string]string{}
+
+// publicToken returns the token sent to clients for the error code
+func publicToken(code string) string {
+	if token, ok := PublicTokens[code]; ok {
+		return token
+	}
+	return code
+}
@@ -3,14 +3,17 @@ package octo
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestGlobalMiddlewareOrder(t *testing.T) {
@@ -256,3 +259,188 @@ func TestRequestBody(t *testing.T) {
 		t.Errorf("Expected 'request body', got '%s'", string(body))
 	}
 }
+
+func TestShouldBindStrictContentType(t *testing.T) {
+	oldVal := StrictContentType
+	defer func() { StrictContentType = oldVal }()
+
+	type TestData struct {
+		Name string `json:"name" form:"name" xml:"name"`
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/json", func(ctx *Ctx[CustomData]) {
+		var data TestData
+		if err := ctx.ShouldBindJSON(&data); err != nil {
+			if errors.Is(err, ErrUnsupportedMediaType) {
+				ctx.SendError("err_unsupported_media_type", err)
+				return
+			}
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		ctx.SendJSON(http.StatusOK, data)
+	})
+
+	// Lenient mode accepts any content type and strips the BOM
+	StrictContentType = false
+	req := httptest.NewRequest("POST", "/json", strings.NewReader("\xEF\xBB\xBF{\"name\":\"John\"}"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Strict mode rejects mismatched content types
+	StrictContentType = true
+	req = httptest.NewRequest("POST", "/json", strings.NewReader(`{"name":"John"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", w.Code)
+	}
+
+	// Strict mode accepts +json suffixes
+	req = httptest.NewRequest("POST", "/json", strings.NewReader(`{"name":"John"}`))
+	req.Header.Set("Content-Type", "application/vnd.api+json; charset=utf-8")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestShouldBindCharset(t *testing.T) {
+	type TestData struct {
+		Name string `form:"name" xml:"name"`
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/bind", func(ctx *Ctx[CustomData]) {
+		var data TestData
+		if err := ctx.ShouldBind(&data); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		ctx.SendString(http.StatusOK, data.Name)
+	})
+
+	// Latin-1 encoded form body: "José"
+	req := httptest.NewRequest("POST", "/bind", strings.NewReader("name=Jos\xe9"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=ISO-8859-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "José" {
+		t.Errorf("Expected 'José', got '%s'", w.Body.String())
+	}
+
+	// Latin-1 XML document declaring its encoding
+	xmlData := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><TestData><name>Jos\xe9</name></TestData>"
+	req = httptest.NewRequest("POST", "/bind", strings.NewReader(xmlData))
+	req.Header.Set("Content-Type", "application/xml")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "José" {
+		t.Errorf("Expected 'José', got '%s'", w.Body.String())
+	}
+}
+
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSendReader(t *testing.T) {
+	payload := []byte("\x00\x01binary\xffdata")
+	router := NewRouter[CustomData]()
+	router.GET("/known", func(ctx *Ctx[CustomData]) {
+		ctx.SendReader(http.StatusOK, "application/octet-stream", bytes.NewReader(payload), int64(len(payload)))
+	})
+	router.HEAD("/known", func(ctx *Ctx[CustomData]) {
+		ctx.SendReader(http.StatusOK, "application/octet-stream", &failingReader{err: errors.New("must not be read")}, 42)
+	})
+	router.GET("/unknown", func(ctx *Ctx[CustomData]) {
+		ctx.SendReader(http.StatusCreated, "application/octet-stream", bytes.NewReader(payload), -1)
+	})
+	router.GET("/broken", func(ctx *Ctx[CustomData]) {
+		ctx.SendReader(http.StatusOK, "application/octet-stream", &failingReader{err: errors.New("disk error")}, 10)
+	})
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do("GET", "/known")
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), payload) || w.Header().Get("Content-Length") != strconv.Itoa(len(payload)) {
+		t.Errorf("Unexpected known-size response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = do("HEAD", "/known")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "42" {
+		t.Errorf("Unexpected HEAD response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	w = do("GET", "/unknown")
+	if w.Code != http.StatusCreated || !bytes.Equal(w.Body.Bytes(), payload) || w.Header().Get("Content-Length") != "" {
+		t.Errorf("Unexpected streamed response: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	// A source failing before the first byte is a server error
+	w = do("GET", "/broken")
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Length") == "10" {
+		t.Errorf("Expected 500 for a failing reader, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestPublicTokens(t *testing.T) {
+	PublicTokens["err_db_error"] = "err_storage"
+	defer delete(PublicTokens, "err_db_error")
+
+	router := NewRouter[CustomData]()
+	router.GET("/db", func(ctx *Ctx[CustomData]) {
+		ctx.SendError("err_db_error", errors.New("connection reset"))
+	})
+	router.GET("/other", func(ctx *Ctx[CustomData]) {
+		ctx.SendError("err_not_found", nil)
+	})
+
+	tests := []struct {
+		path  string
+		code  int
+		token string
+	}{
+		{"/db", http.StatusInternalServerError, "err_storage"},
+		{"/other", http.StatusNotFound, "err_not_found"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		var body BaseResult
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != tt.code || body.Token != tt.token {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.path, tt.code, tt.token, w.Code, body.Token)
+		}
+	}
+}
+
+func TestLatin1Decoder(t *testing.T) {
+	latin1 := []byte("caf\xe9 na\xefve \xff!")
+	want := []byte("café naïve ÿ!")
+	if err := iotest.TestReader(latin1Decoder(bytes.NewReader(latin1)), want); err != nil {
+		t.Error(err)
+	}
+	got, err := io.ReadAll(latin1Decoder(iotest.OneByteReader(bytes.NewReader(latin1))))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Expected %q, got %q (%v)", want, got, err)
+	}
+}
@@ -0,0 +1,104 @@
+package octo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CursorParam is the query parameter read by Ctx.Cursor
+const CursorParam = "cursor"
+
+// ErrNoCursorSecret is returned by the cursor helpers before SetCursorSecret
+var ErrNoCursorSecret = errors.New("cursor secret not configured")
+
+var cursorSecret []byte
+
+// SetCursorSecret sets the HMAC key used by EncodeCursor and DecodeCursor
+func SetCursorSecret(key []byte) {
+	cursorSecret = key
+}
+
+func cursorMAC(payload string) string {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write([]byte("cursor|"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// EncodeCursor returns an opaque, signed token for keys, typically the sort
+// keys of the last item of a page:
+//
+//	next, _ := octo.EncodeCursor(struct{ CreatedAt time.Time; ID int64 }{last.CreatedAt, last.ID})
+//
+// The keys are readable by the client but cannot be forged.
+func EncodeCursor(keys any) (string, error) {
+	if len(cursorSecret) == 0 {
+		return "", ErrNoCursorSecret
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + cursorMAC(payload), nil
+}
+
+// DecodeCursor verifies token and decodes its keys into v. A tampered or
+// malformed token returns ErrInvalidSignature.
+func DecodeCursor(token string, v any) error {
+	if len(cursorSecret) == 0 {
+		return ErrNoCursorSecret
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(cursorMAC(payload)), []byte(sig)) {
+		return ErrInvalidSignature
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Cursor decodes the cursor query parameter into v. It returns false without
+// error on the first page (no cursor). An invalid cursor answers 400
+// err_invalid_query and returns the error.
+func (c *Ctx[V]) Cursor(v any) (bool, error) {
+	token, ok := c.queryRaw(CursorParam)
+	if !ok {
+		return false, nil
+	}
+	if err := DecodeCursor(token, v); err != nil {
+		if errors.Is(err, ErrNoCursorSecret) {
+			c.SendError("err_internal_error", err)
+		} else {
+			c.SendError("err_invalid_query", &QueryError{Key: CursorParam, Value: token, Err: err})
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// NewCursorResult sends a successful JSON response with the cursor of the
+// next page in the envelope; an empty next marks the last page
+func (c *Ctx[V]) NewCursorResult(data interface{}, next string) {
+	if c.done {
+		return
+	}
+	result := buildResult(data, ResultMeta{
+		Status:     http.StatusOK,
+		Time:       float64(time.Now().UnixNano()-c.StartTime) / 1e9,
+		Result:     "success",
+		NextCursor: next,
+	})
+	c.SendJSON(http.StatusOK, result)
+}
@@ -0,0 +1,89 @@
+package octo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testCursorKeys struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	SetCursorSecret(nil)
+	if _, err := EncodeCursor(1); !errors.Is(err, ErrNoCursorSecret) {
+		t.Fatalf("Expected ErrNoCursorSecret, got %v", err)
+	}
+	SetCursorSecret([]byte("0123456789abcdef0123456789abcdef"))
+	defer SetCursorSecret(nil)
+
+	keys := testCursorKeys{CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ID: 1 << 53}
+	token, err := EncodeCursor(keys)
+	if err != nil {
+		t.Fatalf("EncodeCursor: %v", err)
+	}
+	var got testCursorKeys
+	if err := DecodeCursor(token, &got); err != nil || got != keys {
+		t.Errorf("DecodeCursor = %+v, %v", got, err)
+	}
+
+	for _, bad := range []string{"", "abc", token + "x", "e30." + token[len(token)-10:]} {
+		if err := DecodeCursor(bad, &got); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidSignature", bad, err)
+		}
+	}
+}
+
+func TestCtxCursor(t *testing.T) {
+	SetCursorSecret([]byte("0123456789abcdef0123456789abcdef"))
+	defer SetCursorSecret(nil)
+
+	router := NewRouter[CustomData]()
+	router.GET("/items", func(ctx *Ctx[CustomData]) {
+		var after testCursorKeys
+		ok, err := ctx.Cursor(&after)
+		if err != nil {
+			return
+		}
+		next, _ := EncodeCursor(testCursorKeys{ID: after.ID + 10})
+		if ok && after.ID >= 20 {
+			next = ""
+		}
+		ctx.NewCursorResult([]int64{after.ID}, next)
+	})
+
+	var body struct {
+		Data       []int64 `json:"data"`
+		NextCursor string  `json:"next_cursor"`
+	}
+	cursor := ""
+	var pages []int64
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/items?cursor="+url.QueryEscape(cursor), nil))
+		if w.Code != 200 {
+			t.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+		}
+		body.NextCursor = ""
+		json.Unmarshal(w.Body.Bytes(), &body)
+		pages = append(pages, body.Data...)
+		if body.NextCursor == "" {
+			break
+		}
+		cursor = body.NextCursor
+	}
+	if len(pages) != 3 || pages[2] != 20 {
+		t.Errorf("Unexpected pages: %v", pages)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items?cursor=forged.sig", nil))
+	if w.Code != 400 {
+		t.Errorf("Expected 400 for a forged cursor, got %d", w.Code)
+	}
+}
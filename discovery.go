@@ -0,0 +1,149 @@
+package octo
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// routeDoc is the documentation attached to a Route for discovery
+type routeDoc struct {
+	name       string
+	summary    string
+	tags       []string
+	request    *Schema
+	responses  ResponseContract
+	deprecated bool
+	// checked is set once Response installed the DevMode contract check
+	checked bool
+}
+
+// Describe sets the human summary and tags of the route, as listed by the
+// discovery endpoint
+func (rt *Route[V]) Describe(summary string, tags ...string) *Route[V] {
+	rt.doc.summary = summary
+	rt.doc.tags = tags
+	return rt
+}
+
+// RequestSchema documents the JSON body the route accepts. It is metadata
+// only; validate with JSONSchemaMiddleware.
+func (rt *Route[V]) RequestSchema(s *Schema) *Route[V] {
+	rt.doc.request = s
+	return rt
+}
+
+// Responses documents the JSON bodies the route answers with. It is metadata
+// only; check them with ResponseContractMiddleware.
+func (rt *Route[V]) Responses(contract ResponseContract) *Route[V] {
+	rt.doc.responses = contract
+	return rt
+}
+
+// RouteDescription is a route as listed by the discovery endpoint
+type RouteDescription struct {
+	Method     string             `json:"method"`
+	Path       string             `json:"path"`
+	Name       string             `json:"name,omitempty"`
+	Summary    string             `json:"summary,omitempty"`
+	Tags       []string           `json:"tags,omitempty"`
+	Class      string             `json:"class"`
+	Deprecated bool               `json:"deprecated,omitempty"`
+	Stub       bool               `json:"stub,omitempty"`
+	Request    *Schema            `json:"request,omitempty"`
+	Responses  map[string]*Schema `json:"responses,omitempty"`
+}
+
+// Routes describes every registered route in registration order
+func (r *Router[V]) Routes() []RouteDescription {
+	r.treeMu.RLock()
+	defer r.treeMu.RUnlock()
+	out := make([]RouteDescription, 0, len(r.routes))
+	for _, rt := range r.routes {
+		d := RouteDescription{
+			Method:     rt.Method,
+			Path:       rt.Path,
+			Name:       rt.doc.name,
+			Summary:    rt.doc.summary,
+			Tags:       rt.doc.tags,
+			Class:      ClassInteractive.String(),
+			Deprecated: rt.doc.deprecated,
+			Request:    rt.doc.request,
+		}
+		if len(rt.entries) > 0 {
+			entry := rt.entries[0]
+			d.Class = entry.class.String()
+			d.Stub = entry.stub != nil && (entry.handler == nil || r.mockMode.Load())
+		}
+		if len(rt.doc.responses) > 0 {
+			d.Responses = make(map[string]*Schema, len(rt.doc.responses))
+			for status, schema := range rt.doc.responses {
+				key := "default"
+				if status != 0 {
+					key = strconv.Itoa(status)
+				}
+				d.Responses[key] = schema
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// DiscoveryInfo identifies the service in the discovery document
+type DiscoveryInfo struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+	// Features are service-specific flags merged over the router features
+	Features map[string]bool `json:"-"`
+}
+
+// Discovery is the machine-readable description of a service served by
+// DiscoveryHandler
+type Discovery struct {
+	DiscoveryInfo
+	// Status is "SERVING" once the router is ready, "NOT_SERVING" otherwise,
+	// following the gRPC health checking protocol
+	Status   string             `json:"status"`
+	Features map[string]bool    `json:"features"`
+	Routes   []RouteDescription `json:"routes"`
+}
+
+// Discover builds the discovery document of the router
+func (r *Router[V]) Discover(info DiscoveryInfo) Discovery {
+	d := Discovery{
+		DiscoveryInfo: info,
+		Status:        "SERVING",
+		Routes:        r.Routes(),
+	}
+	if ready, _ := r.Ready(); !ready {
+		d.Status = "NOT_SERVING"
+	}
+	settings := Settings()
+	d.Features = map[string]bool{
+		"security_headers":    settings.SecurityHeaders,
+		"request_arena":       EnableRequestArena,
+		"strict_content_type": StrictContentType,
+		"dev_mode":            DevMode,
+		"mock_mode":           r.mockMode.Load(),
+		"maintenance":         settings.Maintenance,
+		"load_shedding":       r.loadShedder != nil,
+	}
+	for k, v := range info.Features {
+		d.Features[k] = v
+	}
+	return d
+}
+
+// DiscoveryHandler serves the discovery document of r, for gateways and
+// client generators introspecting the service at runtime. Mount it on an
+// internal route, e.g. GET /.well-known/octo.
+func DiscoveryHandler[V any](r *Router[V], info DiscoveryInfo) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		d := r.Discover(info)
+		status := http.StatusOK
+		if d.Status != "SERVING" {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.SendJSON(status, d)
+	}
+}
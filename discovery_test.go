@@ -0,0 +1,94 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscoveryHandler(t *testing.T) {
+	router := NewRouter[CustomData]()
+	api := router.Group("/api")
+	api.POST("/users", func(ctx *Ctx[CustomData]) {}).
+		Name("users.create").
+		Describe("Create a user", "users").
+		RequestSchema(MustCompileSchema([]byte(`{"type": "object", "required": ["email"]}`))).
+		Responses(ResponseContract{201: MustCompileSchema([]byte(`{"type":"object"}`)), 0: MustCompileSchema([]byte(`{"type":"object"}`))})
+	api.GET("/legacy", func(ctx *Ctx[CustomData]) {}).Deprecated(time.Unix(0, 0), "")
+	router.ANY("/echo", func(ctx *Ctx[CustomData]) {}).Class(ClassBatch)
+	router.GET("/todo", nil).Stub(200, "ok", 0)
+	router.GET("/.well-known/octo", DiscoveryHandler(router, DiscoveryInfo{
+		Service:  "users",
+		Version:  "1.2.0",
+		Features: map[string]bool{"beta_signup": true},
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/octo", nil))
+	if w.Code != 200 {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var doc struct {
+		Service  string          `json:"service"`
+		Version  string          `json:"version"`
+		Status   string          `json:"status"`
+		Features map[string]bool `json:"features"`
+		Routes   []struct {
+			Method     string                     `json:"method"`
+			Path       string                     `json:"path"`
+			Name       string                     `json:"name"`
+			Summary    string                     `json:"summary"`
+			Tags       []string                   `json:"tags"`
+			Class      string                     `json:"class"`
+			Deprecated bool                       `json:"deprecated"`
+			Stub       bool                       `json:"stub"`
+			Request    map[string]interface{}     `json:"request"`
+			Responses  map[string]json.RawMessage `json:"responses"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.Service != "users" || doc.Version != "1.2.0" || doc.Status != "SERVING" {
+		t.Errorf("Unexpected header: %+v", doc)
+	}
+	if !doc.Features["beta_signup"] || doc.Features["mock_mode"] {
+		t.Errorf("Unexpected features: %v", doc.Features)
+	}
+	if len(doc.Routes) != 5 {
+		t.Fatalf("Expected 5 routes, got %d", len(doc.Routes))
+	}
+	users := doc.Routes[0]
+	if users.Method != "POST" || users.Path != "/api/users" || users.Name != "users.create" ||
+		users.Summary != "Create a user" || len(users.Tags) != 1 || users.Request["type"] != "object" {
+		t.Errorf("Unexpected users route: %+v", users)
+	}
+	if _, ok := users.Responses["201"]; !ok {
+		t.Errorf("Missing 201 response schema: %v", users.Responses)
+	}
+	if _, ok := users.Responses["default"]; !ok {
+		t.Errorf("Missing default response schema: %v", users.Responses)
+	}
+	if !doc.Routes[1].Deprecated {
+		t.Error("Expected /api/legacy to be deprecated")
+	}
+	if doc.Routes[2].Method != "ANY" || doc.Routes[2].Class != "batch" {
+		t.Errorf("Unexpected ANY route: %+v", doc.Routes[2])
+	}
+	if !doc.Routes[3].Stub {
+		t.Error("Expected /todo to be a stub")
+	}
+}
+
+func TestDiscoveryNotServing(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Warmup(func() error { return nil })
+	router.GET("/discovery", DiscoveryHandler(router, DiscoveryInfo{Service: "svc"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/discovery", nil))
+	if w.Code != 503 {
+		t.Errorf("Expected 503 before warm-up, got %d", w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package octo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxDispatchDepth bounds nested in-process dispatches
+const maxDispatchDepth = 8
+
+// ErrDispatchDepth is returned when dispatches nest too deeply (likely a loop)
+var ErrDispatchDepth = errors.New("dispatch nesting too deep")
+
+type dispatchDepthKey struct{}
+
+// DispatchResponse is the response of an in-process request
+type DispatchResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// dispatchWriter records a dispatched response
+type dispatchWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *dispatchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *dispatchWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *dispatchWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *dispatchWriter) Flush() {}
+
+// IsSubRequest reports whether the request was issued by Router.Dispatch
+func (c *Ctx[V]) IsSubRequest() bool {
+	depth, _ := c.Request.Context().Value(dispatchDepthKey{}).(int)
+	return depth > 0
+}
+
+// Dispatch runs method and path (with optional query) through the router
+// in-process, with the full middleware chain and parameter extraction. When
+// parent is set the sub-request inherits its context, headers (credentials
+// included) and remote address. body may be nil.
+func (r *Router[V]) Dispatch(parent *Ctx[V], method, path string, body io.Reader) (*DispatchResponse, error) {
+	ctx := context.Background()
+	if parent != nil {
+		ctx = parent.Request.Context()
+	}
+	depth, _ := ctx.Value(dispatchDepthKey{}).(int)
+	if depth >= maxDispatchDepth {
+		return nil, ErrDispatchDepth
+	}
+	ctx = context.WithValue(ctx, dispatchDepthKey{}, depth+1)
+
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.RequestURI = req.URL.RequestURI()
+	if parent != nil {
+		req.Header = parent.Request.Header.Clone()
+		req.Header.Del("Content-Length")
+		req.RemoteAddr = parent.Request.RemoteAddr
+		req.Host = parent.Request.Host
+		req.TLS = parent.Request.TLS
+	}
+	if body == nil {
+		req.Header.Del("Content-Type")
+	}
+
+	w := &dispatchWriter{header: make(http.Header)}
+	r.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return &DispatchResponse{Status: w.status, Header: w.header, Body: w.body.Bytes()}, nil
+}
@@ -0,0 +1,62 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterDispatch(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			if ctx.GetHeader("Authorization") == "" {
+				ctx.Send401()
+				return
+			}
+			next(ctx)
+		}
+	})
+	router.POST("/users/:id/echo", func(ctx *Ctx[CustomData]) {
+		ctx.NeedBody()
+		ctx.SendString(http.StatusCreated, ctx.Param("id")+":"+string(ctx.Body)+":"+ctx.QueryParam("q"))
+	})
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		resp, err := router.Dispatch(ctx, "POST", "/users/7/echo?q=x", strings.NewReader("hi"))
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		ctx.SendString(http.StatusOK, "page wraps "+string(resp.Body))
+	})
+	router.GET("/loop", func(ctx *Ctx[CustomData]) {
+		resp, err := router.Dispatch(ctx, "GET", "/loop", nil)
+		if err != nil {
+			ctx.SendString(http.StatusLoopDetected, err.Error())
+			return
+		}
+		ctx.SendString(resp.Status, string(resp.Body))
+	})
+
+	req := httptest.NewRequest("GET", "/page", nil)
+	req.Header.Set("Authorization", "Bearer t")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "page wraps 7:hi:x" {
+		t.Errorf("Unexpected dispatch result: %s", w.Body.String())
+	}
+
+	resp, err := router.Dispatch(nil, "POST", "/users/7/echo", nil)
+	if err != nil || resp.Status != http.StatusUnauthorized {
+		t.Errorf("Expected middleware to run on dispatch without credentials, got %v %v", resp, err)
+	}
+
+	req = httptest.NewRequest("GET", "/loop", nil)
+	req.Header.Set("Authorization", "Bearer t")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusLoopDetected || w.Body.String() != ErrDispatchDepth.Error() {
+		t.Errorf("Expected loop detection, got %d %s", w.Code, w.Body.String())
+	}
+}
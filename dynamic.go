@@ -0,0 +1,162 @@
+package octo
+
+import "strings"
+
+// Remove unregisters the route registered for method and path, as written at
+// registration ("/pages/:slug", including optional segments), and reports
+// whether it existed. It is safe while serving: requests already matched
+// finish with the removed handler. The path can then be registered again.
+func (r *Router[V]) Remove(method, path string) bool {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+	removed := map[*routeEntry[V]]*routeEntry[V]{}
+	for _, variant := range expandOptionalSegments(path) {
+		n := r.findNode(variant)
+		if n == nil {
+			continue
+		}
+		entry := n.handlers[method]
+		if entry == nil || entry.path != variant {
+			continue
+		}
+		delete(n.handlers, method)
+		if len(n.handlers) == 0 {
+			n.isLeaf = false
+		}
+		n.prune()
+		removed[entry] = nil
+	}
+	if len(removed) > 0 {
+		r.treeChanged()
+	}
+	r.updateRoutes(removed)
+	return len(removed) > 0
+}
+
+// Replace registers handler for method and path in place of the current
+// route, or as a new route, in one step: no request sees the path
+// unregistered. Requests already matched finish with the previous handler.
+// Options set on the previous Route (stubs, priority, timeouts...) must be
+// set again on the returned Route; setting them while serving is safe.
+func (r *Router[V]) Replace(method, path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+	replaced := map[*routeEntry[V]]*routeEntry[V]{}
+	var entries []*routeEntry[V]
+	for _, variant := range expandOptionalSegments(path) {
+		var old *routeEntry[V]
+		if n := r.findNode(variant); n != nil {
+			old = n.handlers[method]
+		}
+		entry := r.insertRoute(method, variant, handler, middleware, true).entries[0]
+		if old != nil {
+			replaced[old] = entry
+			// The new entry starts at the default priority
+			entry.node.updatePriorities()
+		}
+		entries = append(entries, entry)
+	}
+	r.updateRoutes(replaced)
+	for _, rt := range r.routes {
+		if rt.Method == method && rt.Path == path {
+			rt.entries = entries
+			return rt
+		}
+	}
+	route := &Route[V]{Method: method, Path: path, router: r, entries: entries}
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// updateRoutes swaps the entries of the listed routes according to changes,
+// dropping entries mapped to nil and routes left without entries. The caller
+// holds treeMu.
+func (r *Router[V]) updateRoutes(changes map[*routeEntry[V]]*routeEntry[V]) {
+	if len(changes) == 0 {
+		return
+	}
+	routes := r.routes[:0]
+	for _, rt := range r.routes {
+		entries := rt.entries[:0]
+		for _, e := range rt.entries {
+			next, changed := changes[e]
+			if !changed {
+				entries = append(entries, e)
+			} else if next != nil {
+				entries = append(entries, next)
+			}
+		}
+		rt.entries = entries
+		if len(entries) > 0 {
+			routes = append(routes, rt)
+			continue
+		}
+		for name, named := range r.names {
+			if named == rt {
+				delete(r.names, name)
+			}
+		}
+	}
+	clear(r.routes[len(routes):])
+	r.routes = routes
+}
+
+// findNode returns the node of a concrete route path without creating it
+func (r *Router[V]) findNode(path string) *node[V] {
+	cur := r.root
+	for _, part := range splitPath(path) {
+		switch {
+		case isPlainParam(part):
+			cur = cur.paramChild
+		case strings.Contains(part, ":"):
+			var child *node[V]
+			for _, p := range cur.patterns {
+				if p.source == part {
+					child = p.child
+					break
+				}
+			}
+			cur = child
+		case part[0] == '*':
+			cur = cur.wildcardChild
+		default:
+			if cur.foldCase {
+				part = strings.ToLower(part)
+			}
+			cur = cur.staticChildren[part]
+		}
+		if cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+// prune detaches n and its ancestors while they hold no route, children or
+// middleware, then recomputes the priorities of the nodes left so the
+// removed routes no longer weigh on the matching order
+func (n *node[V]) prune() {
+	for n.parent != nil && len(n.handlers) == 0 && !n.hasChildren() && len(n.middleware) == 0 {
+		parent := n.parent
+		switch {
+		case parent.paramChild == n:
+			parent.paramChild = nil
+		case parent.wildcardChild == n:
+			parent.wildcardChild = nil
+		default:
+			for key, child := range parent.staticChildren {
+				if child == n {
+					delete(parent.staticChildren, key)
+				}
+			}
+			for i, p := range parent.patterns {
+				if p.child == n {
+					parent.patterns = append(parent.patterns[:i], parent.patterns[i+1:]...)
+					break
+				}
+			}
+		}
+		n = parent
+	}
+	n.updatePriorities()
+}
@@ -0,0 +1,161 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouteRemoveReplace(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/pages/:slug", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "v1 "+ctx.Param("slug"))
+	}).Name("page")
+	router.GET("/posts/:id/:title?", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "post")
+	})
+	router.GET("/pages/:slug/edit", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "edit")
+	})
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	router.Replace("GET", "/pages/:slug", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "v2 "+ctx.Param("slug"))
+	})
+	if code, body := get("/pages/home"); code != http.StatusOK || body != "v2 home" {
+		t.Errorf("Expected replaced handler, got %d %q", code, body)
+	}
+	if router.NamedRoute("page") == nil {
+		t.Error("Expected the replaced route to keep its name")
+	}
+
+	if !router.Remove("GET", "/pages/:slug") {
+		t.Fatal("Expected Remove to find the route")
+	}
+	if router.Remove("GET", "/pages/:slug") {
+		t.Error("Expected second Remove to report a missing route")
+	}
+	if code, _ := get("/pages/home"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after removal, got %d", code)
+	}
+	if code, body := get("/pages/home/edit"); code != http.StatusOK || body != "edit" {
+		t.Errorf("Expected sibling route to survive, got %d %q", code, body)
+	}
+	if router.NamedRoute("page") != nil {
+		t.Error("Expected the route name to be released")
+	}
+	for _, rt := range router.Routes() {
+		if rt.Path == "/pages/:slug" {
+			t.Error("Expected removed route to be unlisted")
+		}
+	}
+
+	if !router.Remove("GET", "/posts/:id/:title?") {
+		t.Fatal("Expected Remove to find the optional segment route")
+	}
+	if code, _ := get("/posts/1/hello"); code != http.StatusNotFound {
+		t.Errorf("Expected every variant removed, got %d", code)
+	}
+
+	// Registering again after removal
+	router.GET("/pages/:slug", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "v3")
+	})
+	if _, body := get("/pages/x"); body != "v3" {
+		t.Errorf("Expected re-registered route, got %q", body)
+	}
+
+	// Concurrent replacement while serving
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if code, _ := get("/pages/x"); code != http.StatusOK {
+					t.Errorf("Expected route to stay registered during replacement, got %d", code)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		router.Replace("GET", "/pages/:slug", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, "v4")
+		})
+	}
+	wg.Wait()
+}
+
+func TestRouteRemoveMatchOrder(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/a/:x/b", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "param b")
+	}).Priority(5)
+	router.GET("/a/:x/c", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "param c")
+	})
+	router.GET("/a/p-:id/c", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "pattern c")
+	}).Priority(1)
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	if body := get("/a/p-7/c"); body != "param c" {
+		t.Errorf("Expected the higher priority parameter subtree first, got %q", body)
+	}
+	router.Remove("GET", "/a/:x/b")
+	if body := get("/a/p-7/c"); body != "pattern c" {
+		t.Errorf("Expected the pattern first once the priority 5 route is removed, got %q", body)
+	}
+	if body := get("/a/q/c"); body != "param c" {
+		t.Errorf("Expected the parameter route to still match, got %q", body)
+	}
+}
+
+func TestReplaceOptionsWhileServing(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/items/:id", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "v1")
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/1", nil))
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		router.Replace("GET", "/items/:id", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, "v2 "+ctx.MetaString("version"))
+		}).Meta("version", "2").Class(ClassCritical).AllowDuringMaintenance()
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items/1", nil))
+	if w.Body.String() != "v2 2" {
+		t.Errorf("Expected the replaced handler with its meta, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,50 @@
+package octo
+
+import (
+	"github.com/coffyg/octypes"
+)
+
+// ResultMeta describes a response built by NewJSONResult, SendError and the
+// other result helpers
+type ResultMeta struct {
+	// Status is the HTTP status the result is sent with
+	Status int
+	// Result is "success" or "error"
+	Result  string
+	Message string
+	// Token is the public token of the error code for error results (see
+	// PublicTokens)
+	Token  string
+	Time   float64
+	Paging *octypes.Pagination
+	// NextCursor is the cursor of the next page for cursor-paginated results
+	NextCursor string
+}
+
+// ResultEnvelope builds the JSON body of a result from its data (nil for
+// errors without details) and metadata
+type ResultEnvelope func(data interface{}, meta ResultMeta) interface{}
+
+var resultEnvelope ResultEnvelope
+
+// SetResultEnvelope replaces the default BaseResult shape used by the result
+// helpers, e.g. to emit JSON:API documents. Pass nil to restore BaseResult.
+// Call it during setup, before serving.
+func SetResultEnvelope(fn ResultEnvelope) {
+	resultEnvelope = fn
+}
+
+func buildResult(data interface{}, meta ResultMeta) interface{} {
+	if resultEnvelope != nil {
+		return resultEnvelope(data, meta)
+	}
+	return BaseResult{
+		Data:       data,
+		Time:       meta.Time,
+		Result:     meta.Result,
+		Message:    meta.Message,
+		Paging:     meta.Paging,
+		Token:      meta.Token,
+		NextCursor: meta.NextCursor,
+	}
+}
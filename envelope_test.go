@@ -0,0 +1,39 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetResultEnvelope(t *testing.T) {
+	defer SetResultEnvelope(nil)
+	SetResultEnvelope(func(data interface{}, meta ResultMeta) interface{} {
+		if meta.Result == "error" {
+			return map[string]interface{}{
+				"errors": []map[string]string{{"code": meta.Token, "status": http.StatusText(meta.Status)}},
+			}
+		}
+		return map[string]interface{}{"data": data}
+	})
+
+	router := NewRouter[CustomData]()
+	router.GET("/ok", func(ctx *Ctx[CustomData]) {
+		ctx.NewJSONResult(map[string]int{"id": 1}, nil)
+	})
+	router.GET("/missing", func(ctx *Ctx[CustomData]) {
+		ctx.Send404()
+	})
+
+	tests := []struct{ path, want string }{
+		{"/ok", `{"data":{"id":1}}`},
+		{"/missing", `{"errors":[{"code":"err_not_found","status":"Not Found"}]}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Body.String() != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.want, w.Body.String())
+		}
+	}
+}
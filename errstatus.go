@@ -0,0 +1,30 @@
+package octo
+
+// SetErrorStatus makes SendError answer the error code with status on this
+// router instead of the status in APIErrors, e.g. err_unauthorized as 404 on
+// public APIs that must not reveal which resources exist. The message and
+// token are unchanged. A status of zero restores the APIErrors mapping. Set it
+// before serving; Host routers keep the mapping they were created with.
+func (r *Router[V]) SetErrorStatus(code string, status int) {
+	statuses := make(map[string]int, len(r.errorStatus)+1)
+	for k, v := range r.errorStatus {
+		statuses[k] = v
+	}
+	if status == 0 {
+		delete(statuses, code)
+	} else {
+		statuses[code] = status
+	}
+	if len(statuses) == 0 {
+		statuses = nil
+	}
+	r.errorStatus = statuses
+}
+
+// errorStatus returns the status to send the error code with
+func (c *Ctx[V]) errorStatus(code string, apiError *APIError) int {
+	if status, ok := c.errorStatuses[code]; ok {
+		return status
+	}
+	return apiError.Code
+}
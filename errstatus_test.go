@@ -0,0 +1,47 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetErrorStatus(t *testing.T) {
+	public := NewRouter[CustomData]()
+	public.SetErrorStatus("err_unauthorized", http.StatusNotFound)
+	public.SetErrorStatus("err_forbidden", http.StatusNotFound)
+	public.SetErrorStatus("err_forbidden", 0)
+	internal := NewRouter[CustomData]()
+	for _, r := range []*Router[CustomData]{public, internal} {
+		r.GET("/secret", func(ctx *Ctx[CustomData]) {
+			ctx.SendError("err_unauthorized", nil)
+		})
+		r.GET("/admin", func(ctx *Ctx[CustomData]) {
+			ctx.SendError("err_forbidden", nil)
+		})
+	}
+
+	tests := []struct {
+		router *Router[CustomData]
+		path   string
+		code   int
+		token  string
+	}{
+		{public, "/secret", http.StatusNotFound, "err_unauthorized"},
+		{public, "/admin", http.StatusForbidden, "err_forbidden"},
+		{internal, "/secret", http.StatusUnauthorized, "err_unauthorized"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		var body BaseResult
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != tt.code || body.Token != tt.token {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.path, tt.code, tt.token, w.Code, body.Token)
+		}
+	}
+	if APIErrors["err_unauthorized"].Code != http.StatusUnauthorized {
+		t.Error("Expected APIErrors to be left unchanged")
+	}
+}
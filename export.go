@@ -0,0 +1,202 @@
+package octo
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"iter"
+	"mime"
+	"strconv"
+)
+
+// ExportOptions controls file export responses (CSV, spreadsheets)
+type ExportOptions struct {
+	// Filename used in Content-Disposition (attachment). Empty means inline.
+	Filename string
+	// FlushEvery flushes the response every N rows (default 100, <0 disables)
+	FlushEvery int
+	// Comma is the CSV field delimiter (default ',')
+	Comma rune
+}
+
+// SheetWriter streams rows into a spreadsheet format. Close must finalize the
+// document into the underlying writer.
+type SheetWriter interface {
+	WriteRow(row []string) error
+	Close() error
+}
+
+// SendCSV streams header and rows as a CSV attachment named export.csv
+func (c *Ctx[V]) SendCSV(statusCode int, header []string, rows iter.Seq[[]string]) {
+	c.SendCSVWithOptions(statusCode, header, rows, ExportOptions{Filename: "export.csv"})
+}
+
+// SendCSVWithOptions streams header and rows as CSV without buffering the file
+func (c *Ctx[V]) SendCSVWithOptions(statusCode int, header []string, rows iter.Seq[[]string], opts ExportOptions) {
+	c.SendSheet(statusCode, "text/csv; charset=utf-8", header, rows, opts, func(w io.Writer) (SheetWriter, error) {
+		cw := csv.NewWriter(w)
+		if opts.Comma != 0 {
+			cw.Comma = opts.Comma
+		}
+		return &csvSheetWriter{w: cw}, nil
+	})
+}
+
+// SendXLSX streams header and rows as a single-sheet xlsx workbook
+func (c *Ctx[V]) SendXLSX(statusCode int, header []string, rows iter.Seq[[]string], opts ExportOptions) {
+	if opts.Filename == "" {
+		opts.Filename = "export.xlsx"
+	}
+	c.SendSheet(statusCode, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", header, rows, opts, NewXLSXWriter)
+}
+
+// SendSheet streams rows through the SheetWriter built by newWriter. It is the
+// extension point for other spreadsheet encoders.
+func (c *Ctx[V]) SendSheet(statusCode int, contentType string, header []string, rows iter.Seq[[]string], opts ExportOptions, newWriter func(io.Writer) (SheetWriter, error)) {
+	if c.done {
+		return
+	}
+	flushEvery := opts.FlushEvery
+	if flushEvery == 0 {
+		flushEvery = 100
+	}
+
+	bw := bufio.NewWriter(c.ResponseWriter)
+	sw, err := newWriter(bw)
+	if err != nil {
+		c.SendError("err_internal_error", err)
+		return
+	}
+
+	c.SetHeader("Content-Type", contentType)
+	if opts.Filename != "" {
+		c.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": opts.Filename}))
+	}
+	c.SetStatus(statusCode)
+	c.Done()
+
+	if header != nil {
+		err = sw.WriteRow(header)
+	}
+	n := 0
+	if err == nil {
+		for row := range rows {
+			if err = sw.WriteRow(row); err != nil {
+				break
+			}
+			n++
+			if flushEvery > 0 && n%flushEvery == 0 {
+				if err = bw.Flush(); err != nil {
+					break
+				}
+				c.ResponseWriter.Flush()
+			}
+		}
+	}
+	if err == nil {
+		err = sw.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
+		if EnableLoggerCheck {
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Int("rows", n).Msg("[octo] failed to stream export")
+			}
+		} else {
+			routerLogger.Error().Err(err).Int("rows", n).Msg("[octo] failed to stream export")
+		}
+	}
+}
+
+type csvSheetWriter struct {
+	w *csv.Writer
+}
+
+func (s *csvSheetWriter) WriteRow(row []string) error {
+	return s.w.Write(row)
+}
+
+func (s *csvSheetWriter) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// xlsxWriter is a minimal streaming xlsx encoder: one sheet, inline strings
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+// NewXLSXWriter returns a SheetWriter producing a single-sheet xlsx workbook
+func NewXLSXWriter(w io.Writer) (SheetWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteRow(row []string) error {
+	x.row++
+	if _, err := io.WriteString(x.sheet, `<row r="`+strconv.Itoa(x.row)+`">`); err != nil {
+		return err
+	}
+	for _, cell := range row {
+		if _, err := io.WriteString(x.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(x.sheet, []byte(cell)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(x.sheet, `</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(x.sheet, `</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
@@ -0,0 +1,67 @@
+package octo
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSendCSV(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/export", func(ctx *Ctx[CustomData]) {
+		rows := [][]string{{"1", "Alice"}, {"2", "Bob, Jr."}}
+		ctx.SendCSV(http.StatusOK, []string{"id", "name"}, slices.Values(rows))
+	})
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.Header.Get("Content-Disposition") != "attachment; filename=export.csv" {
+		t.Errorf("Unexpected Content-Disposition: %s", resp.Header.Get("Content-Disposition"))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Errorf("Unexpected Content-Type: %s", resp.Header.Get("Content-Type"))
+	}
+	expected := "id,name\n1,Alice\n2,\"Bob, Jr.\"\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, w.Body.String())
+	}
+}
+
+func TestSendXLSX(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/export", func(ctx *Ctx[CustomData]) {
+		rows := [][]string{{"1", "<Alice>"}}
+		ctx.SendXLSX(http.StatusOK, []string{"id", "name"}, slices.Values(rows), ExportOptions{})
+	})
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	body := w.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("Response is not a valid zip archive: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		sheet, _ := io.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(sheet), "&lt;Alice&gt;") {
+			t.Errorf("Expected escaped cell in sheet, got '%s'", string(sheet))
+		}
+		return
+	}
+	t.Errorf("Expected sheet1.xml in archive")
+}
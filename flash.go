@@ -0,0 +1,65 @@
+package octo
+
+import (
+	"encoding/json"
+	"html/template"
+)
+
+// FlashCookieName is the cookie carrying flash messages across a redirect
+var FlashCookieName = "octo_flash"
+
+// FlashMessage is a one-shot message shown on the next rendered page
+type FlashMessage struct {
+	Kind    string `json:"k"`
+	Message string `json:"m"`
+}
+
+type flashLocalKey struct{}
+type flashReadLocalKey struct{}
+
+// Flash queues a message for the next request (typically after a redirect).
+// Messages are stored in a signed cookie, see SetCookieSecret.
+func (c *Ctx[V]) Flash(kind, message string) error {
+	pending, _ := c.getLocal(flashLocalKey{}).([]FlashMessage)
+	pending = append(pending, FlashMessage{Kind: kind, Message: message})
+	c.setLocal(flashLocalKey{}, pending)
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return c.SetSignedCookie(FlashCookieName, string(data), 0)
+}
+
+// Flashes returns the messages queued by the previous request and clears them.
+// Repeated calls during the same request return the same messages.
+func (c *Ctx[V]) Flashes() []FlashMessage {
+	if read, ok := c.getLocal(flashReadLocalKey{}).([]FlashMessage); ok {
+		return read
+	}
+	var flashes []FlashMessage
+	if value, err := c.SignedCookie(FlashCookieName); err == nil {
+		json.Unmarshal([]byte(value), &flashes)
+	}
+	if flashes == nil {
+		flashes = []FlashMessage{}
+	}
+	c.setLocal(flashReadLocalKey{}, flashes)
+	if _, err := c.Cookie(FlashCookieName); err == nil {
+		if _, pending := c.getLocal(flashLocalKey{}).([]FlashMessage); !pending {
+			c.ClearSignedCookie(FlashCookieName)
+		}
+	}
+	return flashes
+}
+
+// RegisterFlashFuncs exposes {{ flashes }} to templates of r
+func RegisterFlashFuncs[V any](r *Renderer[V]) error {
+	return r.RequestFuncs(func(ctx *Ctx[V]) template.FuncMap {
+		return template.FuncMap{
+			"flashes": ctx.Flashes,
+		}
+	}, template.FuncMap{
+		"flashes": func() []FlashMessage { return nil },
+	})
+}
@@ -0,0 +1,69 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFlashMessages(t *testing.T) {
+	SetCookieSecret([]byte("0123456789abcdef0123456789abcdef"))
+	defer SetCookieSecret(nil)
+
+	renderer := NewRenderer[CustomData](fstest.MapFS{
+		"list.html": {Data: []byte(`{{ range flashes }}[{{ .Kind }}:{{ .Message }}]{{ end }}`)},
+	}, "*.html")
+	if err := RegisterFlashFuncs(renderer); err != nil {
+		t.Fatalf("RegisterFlashFuncs failed: %v", err)
+	}
+	if err := renderer.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/save", func(ctx *Ctx[CustomData]) {
+		ctx.Flash("success", "Saved")
+		ctx.Flash("info", "Twice")
+		ctx.Redirect(http.StatusSeeOther, "/list")
+	})
+	router.GET("/list", func(ctx *Ctx[CustomData]) {
+		renderer.Render(ctx, http.StatusOK, "list.html", nil)
+	})
+
+	req := httptest.NewRequest("POST", "/save", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected a single flash cookie, got %d", len(cookies))
+	}
+
+	req = httptest.NewRequest("GET", "/list", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "[success:Saved][info:Twice]" {
+		t.Errorf("Unexpected flashes: '%s'", w.Body.String())
+	}
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("Expected flash cookie to be cleared, got %v", cleared)
+	}
+
+	// Tampered cookie is ignored
+	tampered := *cookies[0]
+	if strings.HasSuffix(tampered.Value, "A") {
+		tampered.Value = tampered.Value[:len(tampered.Value)-1] + "B"
+	} else {
+		tampered.Value = tampered.Value[:len(tampered.Value)-1] + "A"
+	}
+	req = httptest.NewRequest("GET", "/list", nil)
+	req.AddCookie(&tampered)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "" {
+		t.Errorf("Expected tampered flashes to be ignored, got '%s'", w.Body.String())
+	}
+}
@@ -0,0 +1,105 @@
+package octo
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// FormStateCookieName is the cookie carrying a failed submission across a redirect
+var FormStateCookieName = "octo_form"
+
+// FormStateExcludedFields are never persisted in the form state cookie
+var FormStateExcludedFields = []string{"password", "password_confirmation", "csrf_token"}
+
+// ErrFormStateTooLarge is returned when the form state does not fit in a cookie
+var ErrFormStateTooLarge = errors.New("form state exceeds cookie size")
+
+// maxFormStateCookie keeps the cookie below the common 4KB browser limit
+const maxFormStateCookie = 3800
+
+// FormState holds the values and field errors of a rejected form submission
+type FormState struct {
+	Values url.Values        `json:"v,omitempty"`
+	Errors map[string]string `json:"e,omitempty"`
+}
+
+type formStateLocalKey struct{}
+
+// Value returns the submitted value of field
+func (f *FormState) Value(field string) string {
+	return f.Values.Get(field)
+}
+
+// Error returns the error message of field
+func (f *FormState) Error(field string) string {
+	return f.Errors[field]
+}
+
+// HasErrors reports whether any field error was recorded
+func (f *FormState) HasErrors() bool {
+	return len(f.Errors) > 0
+}
+
+// SaveFormState stores submitted values and field errors in a signed cookie so
+// the page rendered after the redirect can re-populate the form (POST/Redirect/GET).
+func (c *Ctx[V]) SaveFormState(values url.Values, fieldErrors map[string]string) error {
+	state := FormState{Values: url.Values{}, Errors: fieldErrors}
+	for field, v := range values {
+		excluded := false
+		for _, ex := range FormStateExcludedFields {
+			if strings.EqualFold(field, ex) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			state.Values[field] = v
+		}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	signed, err := signValue(FormStateCookieName, data)
+	if err != nil {
+		return err
+	}
+	if len(signed) > maxFormStateCookie {
+		return ErrFormStateTooLarge
+	}
+	return c.SetSignedCookie(FormStateCookieName, string(data), 0)
+}
+
+// FormState returns the state saved by the previous request and clears it.
+// It never returns nil.
+func (c *Ctx[V]) FormState() *FormState {
+	if state, ok := c.getLocal(formStateLocalKey{}).(*FormState); ok {
+		return state
+	}
+	state := &FormState{}
+	if value, err := c.SignedCookie(FormStateCookieName); err == nil {
+		json.Unmarshal([]byte(value), state)
+		c.ClearSignedCookie(FormStateCookieName)
+	}
+	c.setLocal(formStateLocalKey{}, state)
+	return state
+}
+
+// RegisterFormFuncs exposes form_value, form_error and form_has_errors to
+// templates of r
+func RegisterFormFuncs[V any](r *Renderer[V]) error {
+	return r.RequestFuncs(func(ctx *Ctx[V]) template.FuncMap {
+		return template.FuncMap{
+			"form_value":      func(field string) string { return ctx.FormState().Value(field) },
+			"form_error":      func(field string) string { return ctx.FormState().Error(field) },
+			"form_has_errors": func() bool { return ctx.FormState().HasErrors() },
+		}
+	}, template.FuncMap{
+		"form_value":      func(field string) string { return "" },
+		"form_error":      func(field string) string { return "" },
+		"form_has_errors": func() bool { return false },
+	})
+}
@@ -0,0 +1,53 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFormState(t *testing.T) {
+	SetCookieSecret([]byte("0123456789abcdef0123456789abcdef"))
+	defer SetCookieSecret(nil)
+
+	renderer := NewRenderer[CustomData](fstest.MapFS{
+		"form.html": {Data: []byte(`<input name="email" value="{{ form_value "email" }}">{{ form_error "email" }}|{{ form_value "password" }}`)},
+	}, "*.html")
+	if err := RegisterFormFuncs(renderer); err != nil {
+		t.Fatalf("RegisterFormFuncs failed: %v", err)
+	}
+	if err := renderer.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/signup", func(ctx *Ctx[CustomData]) {
+		ctx.Request.ParseForm()
+		ctx.SaveFormState(ctx.Request.PostForm, map[string]string{"email": "Invalid email"})
+		ctx.Redirect(http.StatusSeeOther, "/signup")
+	})
+	router.GET("/signup", func(ctx *Ctx[CustomData]) {
+		renderer.Render(ctx, http.StatusOK, "form.html", nil)
+	})
+
+	form := url.Values{"email": {"bad\"mail"}, "password": {"secret"}}
+	req := httptest.NewRequest("POST", "/signup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	req = httptest.NewRequest("GET", "/signup", nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expected := `<input name="email" value="bad&#34;mail">Invalid email|`
+	if w.Body.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, w.Body.String())
+	}
+}
@@ -0,0 +1,327 @@
+// Package gen generates typed client SDKs from the discovery document of an
+// octo router (see octo.DiscoveryHandler). Route names become method names and
+// the request and response JSON schemas become types, so clients stay in sync
+// with the router definitions without a hand-maintained OpenAPI file.
+//
+// The octo command wraps it:
+//
+//	octo gen client --lang ts --in http://localhost:8080/.well-known/octo --out client.ts
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Document is the subset of the discovery document used by the generators
+type Document struct {
+	Service string  `json:"service"`
+	Version string  `json:"version"`
+	Routes  []Route `json:"routes"`
+}
+
+// Route is a route of the discovery document
+type Route struct {
+	Method     string                     `json:"method"`
+	Path       string                     `json:"path"`
+	Name       string                     `json:"name"`
+	Summary    string                     `json:"summary"`
+	Deprecated bool                       `json:"deprecated"`
+	Request    json.RawMessage            `json:"request"`
+	Responses  map[string]json.RawMessage `json:"responses"`
+}
+
+// Load decodes a discovery document
+func Load(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("gen: invalid discovery document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Options configures a generator
+type Options struct {
+	// Lang is "ts" or "go"
+	Lang string
+	// Package is the Go package name ("client" when empty)
+	Package string
+	// Include keeps only the routes it returns true for (all when nil), e.g.
+	// to leave out internal endpoints
+	Include func(Route) bool
+}
+
+// Generate emits the client source for doc
+func Generate(doc *Document, opts Options) ([]byte, error) {
+	api, err := buildAPI(doc, opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Lang {
+	case "ts", "typescript":
+		return generateTS(doc, api), nil
+	case "go":
+		pkg := opts.Package
+		if pkg == "" {
+			pkg = "client"
+		}
+		return generateGo(doc, api, pkg)
+	}
+	return nil, fmt.Errorf("gen: unsupported language %q", opts.Lang)
+}
+
+// schema is the subset of JSON Schema mapped to client types
+type schema struct {
+	Type                 interface{}        `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Description          string             `json:"description"`
+}
+
+// typeName returns the single non-null type of s, and whether null is allowed
+func (s *schema) typeName() (string, bool) {
+	switch t := s.Type.(type) {
+	case string:
+		return t, false
+	case []interface{}:
+		name, nullable := "", false
+		for _, v := range t {
+			if v == "null" {
+				nullable = true
+			} else if str, ok := v.(string); ok && name == "" {
+				name = str
+			}
+		}
+		return name, nullable
+	}
+	if len(s.Properties) > 0 {
+		return "object", false
+	}
+	return "", false
+}
+
+func (s *schema) isRequired(prop string) bool {
+	for _, r := range s.Required {
+		if r == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedProps returns the property names of s in a stable order
+func (s *schema) sortedProps() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseSchema(raw json.RawMessage) (*schema, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// pathPart is a literal or a parameter of a route path
+type pathPart struct {
+	literal  string
+	param    string
+	wildcard bool
+	optional bool
+}
+
+// parsePath splits a route path into literals and parameters
+func parsePath(path string) []pathPart {
+	var parts []pathPart
+	literal := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(parts); n > 0 && parts[n-1].param == "" {
+			parts[n-1].literal += s
+			return
+		}
+		parts = append(parts, pathPart{literal: s})
+	}
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if segment[0] == '*' {
+			literal("/")
+			parts = append(parts, pathPart{param: segment[1:], wildcard: true})
+			continue
+		}
+		if strings.HasSuffix(segment, "?") && segment[0] == ':' {
+			parts = append(parts, pathPart{param: segment[1 : len(segment)-1], optional: true})
+			continue
+		}
+		literal("/")
+		rest := segment
+		for {
+			idx := strings.IndexByte(rest, ':')
+			if idx == -1 {
+				literal(rest)
+				break
+			}
+			literal(rest[:idx])
+			rest = rest[idx+1:]
+			end := 0
+			for end < len(rest) && (rest[end] == '_' || unicode.IsLetter(rune(rest[end])) || unicode.IsDigit(rune(rest[end]))) {
+				end++
+			}
+			parts = append(parts, pathPart{param: rest[:end]})
+			rest = skipConstraint(rest[end:])
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, pathPart{literal: "/"})
+	}
+	return parts
+}
+
+// skipConstraint drops the "<regex:...>" constraint of a parameter at the
+// start of rest; a '>' inside brackets, parentheses or escaped does not end it
+func skipConstraint(rest string) string {
+	if !strings.HasPrefix(rest, "<regex:") {
+		return rest
+	}
+	depth := 0
+	inClass := false
+	for i := len("<regex:"); i < len(rest); i++ {
+		switch c := rest[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '>' && depth == 0:
+			return rest[i+1:]
+		}
+	}
+	return ""
+}
+
+// operation is a route prepared for code generation
+type operation struct {
+	Route
+	ident    string // exported method name
+	path     []pathPart
+	request  *schema
+	response *schema
+	hasBody  bool
+}
+
+// api is the set of operations of a document
+type api struct {
+	ops []*operation
+}
+
+func buildAPI(doc *Document, include func(Route) bool) (*api, error) {
+	a := &api{}
+	seen := make(map[string]int)
+	for _, rt := range doc.Routes {
+		if include != nil && !include(rt) {
+			continue
+		}
+		op := &operation{Route: rt, path: parsePath(rt.Path)}
+		var err error
+		if op.request, err = parseSchema(rt.Request); err != nil {
+			return nil, fmt.Errorf("gen: %s %s: request schema: %w", rt.Method, rt.Path, err)
+		}
+		if op.response, err = parseSchema(successResponse(rt.Responses)); err != nil {
+			return nil, fmt.Errorf("gen: %s %s: response schema: %w", rt.Method, rt.Path, err)
+		}
+		switch rt.Method {
+		case "POST", "PUT", "PATCH", "ANY":
+			op.hasBody = true
+		default:
+			op.hasBody = op.request != nil
+		}
+		method := rt.Method
+		if method == "ANY" {
+			method = "POST"
+		}
+		op.Method = method
+
+		op.ident = pascal(rt.Name)
+		if op.ident == "" {
+			op.ident = pascal(strings.ToLower(method) + " " + rt.Path)
+		}
+		if n := seen[op.ident]; n > 0 {
+			seen[op.ident]++
+			op.ident += strconv.Itoa(n + 1)
+		} else {
+			seen[op.ident] = 1
+		}
+		a.ops = append(a.ops, op)
+	}
+	return a, nil
+}
+
+// successResponse picks the schema of the lowest 2xx status, or the default
+func successResponse(responses map[string]json.RawMessage) json.RawMessage {
+	best := 0
+	for key := range responses {
+		if status, err := strconv.Atoi(key); err == nil && status >= 200 && status < 300 && (best == 0 || status < best) {
+			best = status
+		}
+	}
+	if best != 0 {
+		return responses[strconv.Itoa(best)]
+	}
+	return responses["default"]
+}
+
+// pascal turns "users.create", "get /api/users/:id" or "user_id" into
+// UsersCreate, GetApiUsersId and UserId
+func pascal(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	out := b.String()
+	if out != "" && unicode.IsDigit(rune(out[0])) {
+		out = "X" + out
+	}
+	return out
+}
+
+// camel is pascal with a lower-case first letter
+func camel(s string) string {
+	p := pascal(s)
+	if p == "" {
+		return p
+	}
+	return strings.ToLower(p[:1]) + p[1:]
+}
@@ -0,0 +1,123 @@
+package gen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// testDocument serves the discovery document of a small router
+func testDocument(t *testing.T) *Document {
+	t.Helper()
+	router := octo.NewRouter[any]()
+	noop := func(ctx *octo.Ctx[any]) {}
+	router.POST("/api/users", noop).
+		Name("users.create").
+		Describe("Create a user").
+		RequestSchema(octo.MustCompileSchema([]byte(`{
+			"type": "object",
+			"required": ["email"],
+			"properties": {
+				"email": {"type": "string", "description": "Login email"},
+				"age": {"type": "integer"},
+				"roles": {"type": "array", "items": {"enum": ["admin", "user"]}},
+				"profile": {"type": "object", "properties": {"bio": {"type": ["string", "null"]}}}
+			}
+		}`))).
+		Responses(octo.ResponseContract{201: octo.MustCompileSchema([]byte(`{"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}`))})
+	router.GET("/api/users/:id", noop)
+	router.GET("/api/posts/:id/:slug?", noop).Deprecated(time.Unix(0, 0), "")
+	router.GET("/files/*path", noop).Name("files")
+	router.GET("/img/thumb-:size.png", noop)
+	router.GET("/discovery", octo.DiscoveryHandler(router, octo.DiscoveryInfo{Service: "users", Version: "1.0.0"}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/discovery", nil))
+	doc, err := Load(w.Body)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return doc
+}
+
+func TestGenerateGo(t *testing.T) {
+	src, err := Generate(testDocument(t), Options{Lang: "go", Package: "users"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", src, 0); err != nil {
+		t.Fatalf("Generated Go does not parse: %v\n%s", err, src)
+	}
+	// compare with gofmt alignment collapsed
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"package users",
+		"type UsersCreateRequest struct",
+		"Email string `json:\"email\"`",
+		"Age *int64 `json:\"age,omitempty\"`",
+		"Bio *string `json:\"bio,omitempty\"`",
+		"Roles []any `json:\"roles,omitempty\"`",
+		"type UsersCreateResponse struct",
+		"func (c *Client) UsersCreate(ctx context.Context, body *UsersCreateRequest, query url.Values) (*UsersCreateResponse, error)",
+		"func (c *Client) GetApiUsersId(ctx context.Context, id string, query url.Values) (json.RawMessage, error)",
+		"func (c *Client) GetApiPostsIdSlug(ctx context.Context, id string, slug string, query url.Values)",
+		"// Deprecated:",
+		"func (c *Client) Files(ctx context.Context, pathParam string, query url.Values)",
+		`path += "/img/thumb-"`,
+		`path += ".png"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated Go lacks %q\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	src, err := Generate(testDocument(t), Options{Lang: "ts"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"// Service: users 1.0.0",
+		"export type UsersCreateRequest = {",
+		"  /** Login email */\n  email: string;",
+		"  age?: number;",
+		`  roles?: ("admin" | "user")[];`,
+		"    bio?: string | null;",
+		"usersCreate(body: UsersCreateRequest, opts?: RequestOptions): Promise<UsersCreateResponse>",
+		`return this.request<UsersCreateResponse>("POST", ` + "`/api/users`" + `, body, opts);`,
+		"getApiUsersId(id: string, opts?: RequestOptions): Promise<unknown>",
+		"`/api/users/${encodeURIComponent(id)}`",
+		"getApiPostsIdSlug(id: string, slug?: string, opts?: RequestOptions)",
+		`${slug !== undefined ? "/" + encodeURIComponent(slug) : ""}`,
+		"@deprecated",
+		"files(path: string, opts?: RequestOptions)",
+		"`/files/${encodeURI(path)}`",
+		"`/img/thumb-${encodeURIComponent(size)}.png`",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated TypeScript lacks %q\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateOptions(t *testing.T) {
+	doc := testDocument(t)
+	if _, err := Generate(doc, Options{Lang: "rust"}); err == nil {
+		t.Error("Expected an error for an unsupported language")
+	}
+	src, err := Generate(doc, Options{Lang: "ts", Include: func(r Route) bool { return r.Path != "/discovery" }})
+	if err != nil || bytes.Contains(src, []byte("getDiscovery")) {
+		t.Errorf("Include did not filter routes: %v", err)
+	}
+	if _, err := Load(strings.NewReader("{")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
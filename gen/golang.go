@@ -0,0 +1,284 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+func goParam(name string) string {
+	id := camel(name)
+	switch {
+	case id == "":
+		id = "param"
+	case token.IsKeyword(id), id == "ctx", id == "body", id == "query", id == "path", id == "c":
+		id += "Param"
+	}
+	return id
+}
+
+// goType returns the Go type of s; required reports whether a property is
+// required (nullable or optional scalars become pointers)
+func goType(s *schema, required bool) string {
+	if s == nil {
+		return "any"
+	}
+	name, nullable := s.typeName()
+	if len(s.Enum) > 0 && name == "" {
+		return "any"
+	}
+	var t string
+	scalar := true
+	switch name {
+	case "string":
+		t = "string"
+	case "integer":
+		t = "int64"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		t, scalar = "[]"+goType(s.Items, true), false
+	case "object":
+		scalar = false
+		if len(s.Properties) == 0 {
+			t = "map[string]any"
+			break
+		}
+		var b strings.Builder
+		b.WriteString("struct {\n")
+		writeGoFields(&b, s)
+		b.WriteString("}")
+		t = b.String()
+	default:
+		return "any"
+	}
+	if scalar && (nullable || !required) {
+		t = "*" + t
+	}
+	return t
+}
+
+func writeGoFields(b *strings.Builder, s *schema) {
+	used := make(map[string]bool)
+	for _, prop := range s.sortedProps() {
+		ps := s.Properties[prop]
+		field := pascal(prop)
+		if field == "" {
+			field = "Field"
+		}
+		for base, n := field, 2; used[field]; n++ {
+			field = base + strconv.Itoa(n)
+		}
+		used[field] = true
+		required := s.isRequired(prop)
+		tag := prop
+		if !required {
+			tag += ",omitempty"
+		}
+		if ps != nil && ps.Description != "" {
+			fmt.Fprintf(b, "// %s\n", ps.Description)
+		}
+		fmt.Fprintf(b, "%s %s `json:%s`\n", field, goType(ps, required), strconv.Quote(tag))
+	}
+}
+
+// goNamedType declares name for s
+func goNamedType(b *bytes.Buffer, name string, s *schema) {
+	t := goType(s, true)
+	if strings.HasPrefix(t, "struct {") {
+		fmt.Fprintf(b, "type %s %s\n\n", name, t)
+		return
+	}
+	fmt.Fprintf(b, "type %s = %s\n\n", name, t)
+}
+
+// goPath returns the statements building the path of op into path
+func goPath(op *operation) string {
+	var b strings.Builder
+	b.WriteString("path := \"\"\n")
+	for _, p := range op.path {
+		switch {
+		case p.param == "":
+			fmt.Fprintf(&b, "path += %q\n", p.literal)
+		case p.optional:
+			id := goParam(p.param)
+			fmt.Fprintf(&b, "if %s != \"\" {\npath += \"/\" + url.PathEscape(%s)\n}\n", id, id)
+		case p.wildcard:
+			fmt.Fprintf(&b, "path += escapeWildcard(%s)\n", goParam(p.param))
+		default:
+			fmt.Fprintf(&b, "path += url.PathEscape(%s)\n", goParam(p.param))
+		}
+	}
+	return b.String()
+}
+
+func generateGo(doc *Document, a *api, pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by octo gen client. DO NOT EDIT.\n\n")
+	if doc.Service != "" {
+		fmt.Fprintf(&b, "// Package %s is a client of the %s service %s\n", pkg, doc.Service, doc.Version)
+	}
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString(`import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+`)
+	for _, op := range a.ops {
+		if op.request != nil {
+			goNamedType(&b, op.ident+"Request", op.request)
+		}
+		if op.response != nil {
+			goNamedType(&b, op.ident+"Response", op.response)
+		}
+	}
+
+	b.WriteString(`// Client calls the service
+type Client struct {
+	// BaseURL is the service root, e.g. https://api.example.com
+	BaseURL string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Header is sent with every request
+	Header http.Header
+}
+
+// NewClient creates a client for the service at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Header: make(http.Header)}
+}
+
+// Error is a non-2xx response
+type Error struct {
+	Status  int
+	Token   string
+	Message string
+	Body    []byte
+}
+
+func (e *Error) Error() string {
+	if e.Token != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Token, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.Status, e.Message)
+}
+
+func escapeWildcard(s string) string {
+	parts := strings.Split(s, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{Status: resp.StatusCode, Message: resp.Status, Body: data}
+		var envelope struct {
+			Token   string ` + "`json:\"token\"`" + `
+			Message string ` + "`json:\"message\"`" + `
+		}
+		if json.Unmarshal(data, &envelope) == nil && envelope.Message != "" {
+			apiErr.Token, apiErr.Message = envelope.Token, envelope.Message
+		}
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+`)
+
+	for _, op := range a.ops {
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "// %s calls %s %s\n", op.ident, op.Method, op.Path)
+		if op.Summary != "" {
+			fmt.Fprintf(&b, "//\n// %s\n", op.Summary)
+		}
+		if op.Deprecated {
+			b.WriteString("//\n// Deprecated: the route is deprecated by the service.\n")
+		}
+		params := []string{"ctx context.Context"}
+		for _, p := range op.path {
+			if p.param != "" {
+				params = append(params, goParam(p.param)+" string")
+			}
+		}
+		bodyArg := "nil"
+		if op.hasBody {
+			bodyType := "any"
+			if op.request != nil {
+				bodyType = "*" + op.ident + "Request"
+			}
+			params = append(params, "body "+bodyType)
+			bodyArg = "body"
+		}
+		params = append(params, "query url.Values")
+		if op.response != nil {
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) (*%sResponse, error) {\n", op.ident, strings.Join(params, ", "), op.ident)
+			b.WriteString(goPath(op))
+			fmt.Fprintf(&b, "var out %sResponse\n", op.ident)
+			fmt.Fprintf(&b, "if err := c.do(ctx, %q, path, query, %s, &out); err != nil {\nreturn nil, err\n}\nreturn &out, nil\n}\n", op.Method, bodyArg)
+		} else {
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) (json.RawMessage, error) {\n", op.ident, strings.Join(params, ", "))
+			b.WriteString(goPath(op))
+			b.WriteString("var out json.RawMessage\n")
+			fmt.Fprintf(&b, "if err := c.do(ctx, %q, path, query, %s, &out); err != nil {\nreturn nil, err\n}\nreturn out, nil\n}\n", op.Method, bodyArg)
+		}
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: invalid Go output: %w", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,238 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var tsReserved = map[string]bool{
+	"body": true, "opts": true, "break": true, "case": true, "class": true, "const": true,
+	"default": true, "delete": true, "do": true, "else": true, "enum": true, "export": true,
+	"extends": true, "for": true, "function": true, "if": true, "import": true, "in": true,
+	"new": true, "return": true, "super": true, "switch": true, "this": true, "throw": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+}
+
+func tsParam(name string) string {
+	id := camel(name)
+	if tsReserved[id] || id == "" {
+		id += "Param"
+	}
+	return id
+}
+
+// tsType returns the TypeScript type of s, indented for nested objects
+func tsType(s *schema, indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			data, _ := json.Marshal(v)
+			values[i] = string(data)
+		}
+		return strings.Join(values, " | ")
+	}
+	name, nullable := s.typeName()
+	var t string
+	switch name {
+	case "string":
+		t = "string"
+	case "integer", "number":
+		t = "number"
+	case "boolean":
+		t = "boolean"
+	case "array":
+		item := tsType(s.Items, indent)
+		if strings.ContainsAny(item, " |") {
+			item = "(" + item + ")"
+		}
+		t = item + "[]"
+	case "object":
+		if len(s.Properties) == 0 {
+			t = "Record<string, unknown>"
+			break
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, prop := range s.sortedProps() {
+			ps := s.Properties[prop]
+			if ps != nil && ps.Description != "" {
+				fmt.Fprintf(&b, "%s  /** %s */\n", indent, ps.Description)
+			}
+			optional := "?"
+			if s.isRequired(prop) {
+				optional = ""
+			}
+			fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, tsKey(prop), optional, tsType(ps, indent+"  "))
+		}
+		b.WriteString(indent + "}")
+		t = b.String()
+	default:
+		t = "unknown"
+	}
+	if nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func tsKey(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
+// tsPath returns the template literal building the path of op
+func tsPath(op *operation) string {
+	var b strings.Builder
+	b.WriteByte('`')
+	for _, p := range op.path {
+		switch {
+		case p.param == "":
+			b.WriteString(strings.ReplaceAll(p.literal, "`", "\\`"))
+		case p.optional:
+			id := tsParam(p.param)
+			fmt.Fprintf(&b, `${%s !== undefined ? "/" + encodeURIComponent(%s) : ""}`, id, id)
+		case p.wildcard:
+			fmt.Fprintf(&b, "${encodeURI(%s)}", tsParam(p.param))
+		default:
+			fmt.Fprintf(&b, "${encodeURIComponent(%s)}", tsParam(p.param))
+		}
+	}
+	b.WriteByte('`')
+	return b.String()
+}
+
+func generateTS(doc *Document, a *api) []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by octo gen client. DO NOT EDIT.\n")
+	if doc.Service != "" {
+		fmt.Fprintf(&b, "// Service: %s %s\n", doc.Service, doc.Version)
+	}
+	b.WriteString("\n")
+
+	for _, op := range a.ops {
+		if op.request != nil {
+			fmt.Fprintf(&b, "export type %sRequest = %s;\n\n", op.ident, tsType(op.request, ""))
+		}
+		if op.response != nil {
+			fmt.Fprintf(&b, "export type %sResponse = %s;\n\n", op.ident, tsType(op.response, ""))
+		}
+	}
+
+	b.WriteString(`export class OctoError extends Error {
+  constructor(public status: number, public token: string, message: string, public body?: unknown) {
+    super(message);
+    this.name = "OctoError";
+  }
+}
+
+export interface RequestOptions {
+  query?: Record<string, string | number | boolean | Array<string | number | boolean>>;
+  headers?: Record<string, string>;
+  signal?: AbortSignal;
+}
+
+export class Client {
+  constructor(
+    private baseURL: string,
+    private headers: Record<string, string> = {},
+    private fetchFn: typeof fetch = (input, init) => fetch(input, init),
+  ) {}
+
+  private async request<T>(method: string, path: string, body: unknown, opts?: RequestOptions): Promise<T> {
+    let url = this.baseURL.replace(/\/$/, "") + path;
+    if (opts?.query) {
+      const params = new URLSearchParams();
+      for (const [key, value] of Object.entries(opts.query)) {
+        for (const v of Array.isArray(value) ? value : [value]) params.append(key, String(v));
+      }
+      const qs = params.toString();
+      if (qs) url += "?" + qs;
+    }
+    const headers: Record<string, string> = { Accept: "application/json", ...this.headers, ...opts?.headers };
+    const init: RequestInit = { method, headers, signal: opts?.signal };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    const res = await this.fetchFn(url, init);
+    const text = await res.text();
+    const data = text ? JSON.parse(text) : undefined;
+    if (!res.ok) {
+      throw new OctoError(res.status, data?.token ?? "", data?.message ?? res.statusText, data);
+    }
+    return data as T;
+  }
+`)
+
+	for _, op := range a.ops {
+		b.WriteString("\n")
+		var lines []string
+		if op.Summary != "" {
+			lines = append(lines, op.Summary)
+		}
+		lines = append(lines, op.Method+" "+op.Path)
+		if op.Deprecated {
+			lines = append(lines, "@deprecated")
+		}
+		b.WriteString("  /**\n")
+		for _, line := range lines {
+			fmt.Fprintf(&b, "   * %s\n", line)
+		}
+		b.WriteString("   */\n")
+
+		var params []string
+		for _, p := range op.path {
+			if p.param == "" {
+				continue
+			}
+			optional := ""
+			if p.optional {
+				optional = "?"
+			}
+			params = append(params, fmt.Sprintf("%s%s: string", tsParam(p.param), optional))
+		}
+		bodyArg := "undefined"
+		if op.hasBody {
+			bodyType := "unknown"
+			if op.request != nil {
+				bodyType = op.ident + "Request"
+			}
+			params = append(params, "body: "+bodyType)
+			bodyArg = "body"
+		}
+		params = append(params, "opts?: RequestOptions")
+		params = requiredAfterOptional(params)
+		result := "unknown"
+		if op.response != nil {
+			result = op.ident + "Response"
+		}
+		fmt.Fprintf(&b, "  %s(%s): Promise<%s> {\n", camel(op.ident), strings.Join(params, ", "), result)
+		fmt.Fprintf(&b, "    return this.request<%s>(%q, %s, %s, opts);\n", result, op.Method, tsPath(op), bodyArg)
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// requiredAfterOptional turns "slug?: string" into "slug: string | undefined"
+// unless only optional parameters follow, as TypeScript requires
+func requiredAfterOptional(params []string) []string {
+	out := make([]string, len(params))
+	for i, p := range params {
+		if name, typ, ok := strings.Cut(p, "?: "); ok && i+1 < len(params) && !strings.Contains(params[i+1], "?: ") {
+			p = name + ": " + typ + " | undefined"
+		}
+		out[i] = p
+	}
+	return out
+}
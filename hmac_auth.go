@@ -0,0 +1,162 @@
+package octo
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default headers of the HMAC signing scheme
+const (
+	SignatureKeyHeader  = "X-Octo-Key"
+	SignatureDateHeader = "X-Octo-Date"
+	SignatureHeader     = "X-Octo-Signature"
+)
+
+// HMACConfig configures HMACAuthMiddleware
+type HMACConfig struct {
+	// LookupKey returns the shared secret of a key ID
+	LookupKey func(keyID string) ([]byte, error)
+	// MaxSkew is the accepted clock difference (default 5 minutes)
+	MaxSkew time.Duration
+	// Canonicalize builds the string to sign; defaults to CanonicalRequest
+	Canonicalize func(req *http.Request, date string, body []byte) string
+	// DisableReplayCache accepts the same signature more than once
+	DisableReplayCache bool
+}
+
+type hmacKeyLocalKey struct{}
+
+// CanonicalRequest is the default string to sign:
+// METHOD \n path?query \n date \n hex(sha256(body))
+func CanonicalRequest(req *http.Request, date string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return req.Method + "\n" + req.URL.RequestURI() + "\n" + date + "\n" + hex.EncodeToString(sum[:])
+}
+
+// SignRequest signs an outgoing request with the default scheme. The body is
+// read and restored.
+func SignRequest(req *http.Request, keyID string, secret []byte) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	date := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(SignatureKeyHeader, keyID)
+	req.Header.Set(SignatureDateHeader, date)
+	req.Header.Set(SignatureHeader, computeSignature(secret, CanonicalRequest(req, date, body)))
+	return nil
+}
+
+func computeSignature(secret []byte, canonical string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// replayCache remembers signatures until they fall out of the skew window
+type replayCache struct {
+	mu      sync.Mutex
+	seen    map[string]int64
+	lastGC  int64
+	horizon int64
+}
+
+func (rc *replayCache) seenBefore(signature string, now int64) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if now-rc.lastGC > rc.horizon {
+		for sig, expires := range rc.seen {
+			if expires < now {
+				delete(rc.seen, sig)
+			}
+		}
+		rc.lastGC = now
+	}
+	if expires, ok := rc.seen[signature]; ok && expires >= now {
+		return true
+	}
+	rc.seen[signature] = now + 2*rc.horizon
+	return false
+}
+
+// HMACAuthMiddleware verifies HMAC request signatures over the method, path,
+// date and body for server-to-server APIs. The body is read through NeedBody
+// so handlers can still bind it.
+func HMACAuthMiddleware[V any](cfg HMACConfig) MiddlewareFunc[V] {
+	if cfg.LookupKey == nil {
+		panic("HMACAuthMiddleware requires LookupKey")
+	}
+	if cfg.MaxSkew == 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.Canonicalize == nil {
+		cfg.Canonicalize = CanonicalRequest
+	}
+	cache := &replayCache{seen: make(map[string]int64), horizon: int64(cfg.MaxSkew / time.Second)}
+
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			keyID := ctx.GetHeader(SignatureKeyHeader)
+			date := ctx.GetHeader(SignatureDateHeader)
+			signature := ctx.GetHeader(SignatureHeader)
+			if keyID == "" || date == "" || signature == "" {
+				ctx.SendError("err_unauthorized", errors.New("missing request signature"))
+				return
+			}
+			ts, err := strconv.ParseInt(date, 10, 64)
+			if err != nil {
+				ctx.SendError("err_unauthorized", errors.New("invalid signature date"))
+				return
+			}
+			now := time.Now().Unix()
+			skew := now - ts
+			if skew < 0 {
+				skew = -skew
+			}
+			if skew > int64(cfg.MaxSkew/time.Second) {
+				ctx.SendError("err_unauthorized", errors.New("signature date outside allowed skew"))
+				return
+			}
+			secret, err := cfg.LookupKey(keyID)
+			if err != nil || len(secret) == 0 {
+				ctx.SendError("err_unauthorized", errors.New("unknown signing key"))
+				return
+			}
+			if err := ctx.NeedBody(); err != nil {
+				ctx.SendError("err_invalid_request", err)
+				return
+			}
+			expected := computeSignature(secret, cfg.Canonicalize(ctx.Request, date, ctx.Body))
+			if !hmac.Equal([]byte(expected), []byte(signature)) {
+				ctx.SendError("err_unauthorized", errors.New("invalid request signature"))
+				return
+			}
+			if !cfg.DisableReplayCache && cache.seenBefore(signature, now) {
+				ctx.SendError("err_unauthorized", errors.New("replayed request signature"))
+				return
+			}
+			ctx.setLocal(hmacKeyLocalKey{}, keyID)
+			next(ctx)
+		}
+	}
+}
+
+// SignatureKeyID returns the key ID authenticated by HMACAuthMiddleware
+func (c *Ctx[V]) SignatureKeyID() string {
+	keyID, _ := c.getLocal(hmacKeyLocalKey{}).(string)
+	return keyID
+}
@@ -0,0 +1,73 @@
+package octo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHMACAuthMiddleware(t *testing.T) {
+	secret := []byte("s3cret")
+	router := NewRouter[CustomData]()
+	router.POST("/hook", func(ctx *Ctx[CustomData]) {
+		var payload map[string]string
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		ctx.SendString(http.StatusOK, ctx.SignatureKeyID()+":"+payload["event"])
+	}, HMACAuthMiddleware[CustomData](HMACConfig{
+		LookupKey: func(keyID string) ([]byte, error) {
+			if keyID == "partner" {
+				return secret, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}))
+
+	newSigned := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/hook?x=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if err := SignRequest(req, "partner", secret); err != nil {
+			t.Fatalf("SignRequest failed: %v", err)
+		}
+		return req
+	}
+
+	req := newSigned(`{"event":"paid"}`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "partner:paid" {
+		t.Errorf("Expected signed request to pass, got %d '%s'", w.Code, w.Body.String())
+	}
+
+	// Replaying the same signature is rejected
+	replay := httptest.NewRequest("POST", "/hook?x=1", strings.NewReader(`{"event":"paid"}`))
+	replay.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, replay)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected replay to be rejected, got %d", w.Code)
+	}
+
+	// Tampered body is rejected
+	req = newSigned(`{"event":"paid"}`)
+	tampered := httptest.NewRequest("POST", "/hook?x=1", strings.NewReader(`{"event":"refund"}`))
+	tampered.Header = req.Header.Clone()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, tampered)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected tampered body to be rejected, got %d", w.Code)
+	}
+
+	// Stale date is rejected
+	req = newSigned(`{}`)
+	req.Header.Set(SignatureDateHeader, "1000")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected stale date to be rejected, got %d", w.Code)
+	}
+}
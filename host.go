@@ -0,0 +1,79 @@
+package octo
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// hostRouter is a router serving the requests of a wildcard host pattern
+type hostRouter[V any] struct {
+	suffix string // ".example.com" for "*.example.com"
+	router *Router[V]
+}
+
+// Host returns the router serving the requests for host, a domain name such
+// as "api.example.com" or a wildcard such as "*.example.com" matching any
+// subdomain (but not example.com itself). Hosts are matched case
+// insensitively and without the port, before the path: a request for a
+// registered host is served by its router only, others by r. Exact names take
+// precedence over wildcards, and longer wildcards over shorter ones.
+//
+// The host router runs the global middleware of r before its own and starts
+// with the path, strict JSON, method-not-allowed and load-shedding settings of
+// r. Calling Host again with the same pattern returns the same router.
+func (r *Router[V]) Host(host string) *Router[V] {
+	host = normalizeHost(host)
+	if host == "" || strings.Contains(host[1:], "*") || host[0] == '*' && !strings.HasPrefix(host, "*.") {
+		panic("octo: invalid host pattern " + host)
+	}
+	if sub := r.hosts[host]; sub != nil {
+		return sub
+	}
+	sub := NewRouter[V]()
+	sub.parent = r
+	sub.pathOptions = r.pathOptions
+	sub.customPaths = r.customPaths
+	sub.strictJSON = r.strictJSON
+	sub.errorStatus = r.errorStatus
+	sub.legacyNotFound = r.legacyNotFound
+	sub.loadShedder = r.loadShedder
+	sub.memoryWatchdog = r.memoryWatchdog
+	sub.matchTrace.Store(r.matchTrace.Load())
+	if c := r.matchCache.Load(); c != nil {
+		sub.SetMatchCache(c.size)
+	}
+	if r.hosts == nil {
+		r.hosts = make(map[string]*Router[V])
+	}
+	r.hosts[host] = sub
+	if strings.HasPrefix(host, "*.") {
+		r.wildcardHosts = append(r.wildcardHosts, hostRouter[V]{suffix: host[1:], router: sub})
+		sort.SliceStable(r.wildcardHosts, func(i, j int) bool {
+			return len(r.wildcardHosts[i].suffix) > len(r.wildcardHosts[j].suffix)
+		})
+	}
+	return sub
+}
+
+// hostRouterFor returns the host router serving the request host, or nil
+func (r *Router[V]) hostRouterFor(host string) *Router[V] {
+	host = normalizeHost(host)
+	if sub := r.hosts[host]; sub != nil && !strings.HasPrefix(host, "*.") {
+		return sub
+	}
+	for _, h := range r.wildcardHosts {
+		if len(host) > len(h.suffix) && strings.HasSuffix(host, h.suffix) {
+			return h.router
+		}
+	}
+	return nil
+}
+
+// normalizeHost lowercases host and strips its port and trailing dot
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
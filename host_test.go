@@ -0,0 +1,78 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			ctx.SetHeader("X-Global", "1")
+			next(ctx)
+		}
+	})
+	router.GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "main")
+	})
+	api := router.Host("api.example.com")
+	api.GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "api")
+	})
+	if router.Host("API.example.com") != api {
+		t.Errorf("Expected the same router for the same host")
+	}
+	router.Host("*.example.com").GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "tenant")
+	})
+	router.Host("*.eu.example.com").GET("/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "eu")
+	})
+
+	tests := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"example.com", "/", http.StatusOK, "main"},
+		{"api.example.com", "/", http.StatusOK, "api"},
+		{"API.Example.com:8443", "/", http.StatusOK, "api"},
+		{"api.example.com.", "/", http.StatusOK, "api"},
+		{"acme.example.com", "/", http.StatusOK, "tenant"},
+		{"a.b.example.com", "/", http.StatusOK, "tenant"},
+		{"paris.eu.example.com", "/", http.StatusOK, "eu"},
+		{"other.org", "/", http.StatusOK, "main"},
+		// Host routers only serve their own routes
+		{"api.example.com", "/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s%s: expected %d, got %d", tt.host, tt.path, tt.code, w.Code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s%s: expected %q, got %q", tt.host, tt.path, tt.body, w.Body.String())
+		}
+		if w.Header().Get("X-Global") != "1" {
+			t.Errorf("%s%s: expected the global middleware to run", tt.host, tt.path)
+		}
+	}
+
+	for _, host := range []string{"", "api.*.com", "*example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q: expected a panic", host)
+				}
+			}()
+			router.Host(host)
+		}()
+	}
+}
@@ -0,0 +1,401 @@
+package octo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrHubClosed is returned when registering on a closed Hub
+var ErrHubClosed = errors.New("hub closed")
+
+// HubSender delivers messages to one connection: a WebSocket connection
+// wrapped around its write method, an SSE stream...
+type HubSender interface {
+	Send(data []byte) error
+	Close() error
+}
+
+// BackpressurePolicy decides what a broadcast does for a client whose queue
+// is full
+type BackpressurePolicy int
+
+const (
+	// DropNewest skips the message for that client
+	DropNewest BackpressurePolicy = iota + 1
+	// DropOldest discards the oldest queued message to make room
+	DropOldest
+	// DisconnectSlow closes the client
+	DisconnectSlow
+)
+
+// RoomOptions configures a room of a Hub
+type RoomOptions struct {
+	// Policy applies when a member's queue is full (the hub policy when
+	// zero)
+	Policy BackpressurePolicy
+}
+
+// HubConfig configures a Hub
+type HubConfig struct {
+	// QueueSize is the number of messages buffered per client (64 when zero)
+	QueueSize int
+	// Policy is the default backpressure policy (DropNewest when zero)
+	Policy BackpressurePolicy
+	// OnJoin is called when an ID becomes present in a room, i.e. its first
+	// client joins. OnLeave is called when its last client leaves or
+	// disconnects. Both run outside the hub lock.
+	OnJoin  func(room, id string)
+	OnLeave func(room, id string)
+}
+
+type hubRoom struct {
+	members map[*HubClient]struct{}
+	present map[string]int // clients per ID
+	opts    RoomOptions
+}
+
+// Hub broadcasts messages to registered clients, globally or per room, and
+// tracks which IDs are present in each room
+type Hub struct {
+	cfg HubConfig
+
+	mu      sync.Mutex
+	clients map[*HubClient]struct{}
+	rooms   map[string]*hubRoom
+	options map[string]RoomOptions
+	closed  bool
+
+	dropped      atomic.Int64
+	disconnected atomic.Int64
+}
+
+// NewHub creates an empty Hub
+func NewHub(cfg HubConfig) *Hub {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	if cfg.Policy == 0 {
+		cfg.Policy = DropNewest
+	}
+	return &Hub{
+		cfg:     cfg,
+		clients: make(map[*HubClient]struct{}),
+		rooms:   make(map[string]*hubRoom),
+		options: make(map[string]RoomOptions),
+	}
+}
+
+// HubClient is a connection registered on a Hub. Messages are written by a
+// dedicated goroutine so a slow connection never blocks a broadcast.
+type HubClient struct {
+	// ID identifies the user or session behind the connection; several
+	// clients may share one
+	ID string
+
+	hub    *Hub
+	sender HubSender
+	queue  chan []byte
+	rooms  map[string]struct{} // guarded by hub.mu
+
+	closeOnce sync.Once
+	closing   atomic.Bool
+	done      chan struct{}
+}
+
+// Register adds a connection to the hub and starts writing its queue
+func (h *Hub) Register(id string, s HubSender) (*HubClient, error) {
+	c := &HubClient{
+		ID:     id,
+		hub:    h,
+		sender: s,
+		queue:  make(chan []byte, h.cfg.QueueSize),
+		rooms:  make(map[string]struct{}),
+		done:   make(chan struct{}),
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	go c.writeLoop()
+	return c, nil
+}
+
+func (c *HubClient) writeLoop() {
+	for {
+		select {
+		case data := <-c.queue:
+			if err := c.sender.Send(data); err != nil {
+				c.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Done is closed when the client is closed, by Close, a failed write or the
+// DisconnectSlow policy
+func (c *HubClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Send queues data for this client only
+func (c *HubClient) Send(data []byte) {
+	c.hub.deliver(c, data, c.hub.cfg.Policy)
+}
+
+// Join adds the client to room
+func (c *HubClient) Join(room string) {
+	h := c.hub
+	h.mu.Lock()
+	if _, ok := h.clients[c]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	if _, ok := c.rooms[room]; ok {
+		h.mu.Unlock()
+		return
+	}
+	r := h.rooms[room]
+	if r == nil {
+		r = &hubRoom{members: make(map[*HubClient]struct{}), present: make(map[string]int), opts: h.options[room]}
+		h.rooms[room] = r
+	}
+	c.rooms[room] = struct{}{}
+	r.members[c] = struct{}{}
+	r.present[c.ID]++
+	joined := r.present[c.ID] == 1
+	h.mu.Unlock()
+	if joined && h.cfg.OnJoin != nil {
+		h.cfg.OnJoin(room, c.ID)
+	}
+}
+
+// Leave removes the client from room
+func (c *HubClient) Leave(room string) {
+	h := c.hub
+	h.mu.Lock()
+	left := h.leave(c, room)
+	h.mu.Unlock()
+	if left && h.cfg.OnLeave != nil {
+		h.cfg.OnLeave(room, c.ID)
+	}
+}
+
+// leave removes c from room and reports whether its ID left; h.mu must be
+// held
+func (h *Hub) leave(c *HubClient, room string) bool {
+	if _, ok := c.rooms[room]; !ok {
+		return false
+	}
+	delete(c.rooms, room)
+	r := h.rooms[room]
+	delete(r.members, c)
+	r.present[c.ID]--
+	left := r.present[c.ID] == 0
+	if left {
+		delete(r.present, c.ID)
+	}
+	if len(r.members) == 0 {
+		delete(h.rooms, room)
+	}
+	return left
+}
+
+// Rooms returns the rooms the client is in, sorted
+func (c *HubClient) Rooms() []string {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Close unregisters the client, leaving its rooms, and closes its connection
+func (c *HubClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		h := c.hub
+		h.mu.Lock()
+		delete(h.clients, c)
+		var left []string
+		for room := range c.rooms {
+			if h.leave(c, room) {
+				left = append(left, room)
+			}
+		}
+		h.mu.Unlock()
+		close(c.done)
+		err = c.sender.Close()
+		if h.cfg.OnLeave != nil {
+			sort.Strings(left)
+			for _, room := range left {
+				h.cfg.OnLeave(room, c.ID)
+			}
+		}
+	})
+	return err
+}
+
+// SetRoomOptions configures room, now and whenever it is created again
+func (h *Hub) SetRoomOptions(room string, opts RoomOptions) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.options[room] = opts
+	if r := h.rooms[room]; r != nil {
+		r.opts = opts
+	}
+}
+
+// deliver queues data for c, applying policy when its queue is full
+func (h *Hub) deliver(c *HubClient, data []byte, policy BackpressurePolicy) {
+	select {
+	case c.queue <- data:
+		return
+	case <-c.done:
+		return
+	default:
+	}
+	switch policy {
+	case DropOldest:
+		for {
+			select {
+			case <-c.queue:
+				h.dropped.Add(1)
+			default:
+			}
+			select {
+			case c.queue <- data:
+				return
+			case <-c.done:
+				return
+			default:
+			}
+		}
+	case DisconnectSlow:
+		if c.closing.CompareAndSwap(false, true) {
+			h.disconnected.Add(1)
+			go c.Close()
+		}
+	default:
+		h.dropped.Add(1)
+	}
+}
+
+// Broadcast queues data for every client
+func (h *Hub) Broadcast(data []byte) {
+	h.mu.Lock()
+	targets := make([]*HubClient, 0, len(h.clients))
+	for c := range h.clients {
+		targets = append(targets, c)
+	}
+	h.mu.Unlock()
+	for _, c := range targets {
+		h.deliver(c, data, h.cfg.Policy)
+	}
+}
+
+// BroadcastRoom queues data for the members of room except the given
+// clients (e.g. the sender)
+func (h *Hub) BroadcastRoom(room string, data []byte, except ...*HubClient) {
+	h.mu.Lock()
+	r := h.rooms[room]
+	if r == nil {
+		h.mu.Unlock()
+		return
+	}
+	policy := h.cfg.Policy
+	if r.opts.Policy != 0 {
+		policy = r.opts.Policy
+	}
+	targets := make([]*HubClient, 0, len(r.members))
+	for c := range r.members {
+		if !containsHubClient(except, c) {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.Unlock()
+	for _, c := range targets {
+		h.deliver(c, data, policy)
+	}
+}
+
+func containsHubClient(list []*HubClient, c *HubClient) bool {
+	for _, v := range list {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Presence returns the IDs present in room, sorted
+func (h *Hub) Presence(room string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[room]
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.present))
+	for id := range r.present {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Rooms returns the rooms with at least one member, sorted
+func (h *Hub) Rooms() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// HubStats counts clients and backpressure events
+type HubStats struct {
+	Clients      int   `json:"clients"`
+	Rooms        int   `json:"rooms"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"`
+}
+
+// Stats returns the current counters
+func (h *Hub) Stats() HubStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return HubStats{
+		Clients:      len(h.clients),
+		Rooms:        len(h.rooms),
+		Dropped:      h.dropped.Load(),
+		Disconnected: h.disconnected.Load(),
+	}
+}
+
+// Close closes every client and rejects new registrations
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*HubClient, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+	for _, c := range clients {
+		c.Close()
+	}
+}
@@ -0,0 +1,166 @@
+package octo
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chanSender records messages; block makes Send wait until released
+type chanSender struct {
+	msgs   chan string
+	block  chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newChanSender() *chanSender {
+	return &chanSender{msgs: make(chan string, 100), closed: make(chan struct{})}
+}
+
+func (s *chanSender) Send(data []byte) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.msgs <- string(data)
+	return nil
+}
+
+func (s *chanSender) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func (s *chanSender) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case m := <-s.msgs:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return ""
+	}
+}
+
+func (s *chanSender) none(t *testing.T) {
+	t.Helper()
+	select {
+	case m := <-s.msgs:
+		t.Errorf("Unexpected message %q", m)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestHubRoomsAndPresence(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	hub := NewHub(HubConfig{
+		OnJoin: func(room, id string) {
+			mu.Lock()
+			events = append(events, "join "+room+" "+id)
+			mu.Unlock()
+		},
+		OnLeave: func(room, id string) {
+			mu.Lock()
+			events = append(events, "leave "+room+" "+id)
+			mu.Unlock()
+		},
+	})
+	defer hub.Close()
+
+	sa, sb, sb2 := newChanSender(), newChanSender(), newChanSender()
+	alice, _ := hub.Register("alice", sa)
+	bob, _ := hub.Register("bob", sb)
+	bobTab, _ := hub.Register("bob", sb2)
+	alice.Join("general")
+	bob.Join("general")
+	bobTab.Join("general")
+	bob.Join("random")
+
+	if got := hub.Presence("general"); !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("Presence = %v", got)
+	}
+	hub.BroadcastRoom("general", []byte("hi"), alice)
+	if sb.next(t) != "hi" || sb2.next(t) != "hi" {
+		t.Error("Expected both bob connections to receive the message")
+	}
+	sa.none(t)
+
+	hub.BroadcastRoom("random", []byte("r"))
+	if sb.next(t) != "r" {
+		t.Error("Expected room members to receive room broadcasts")
+	}
+	sb2.none(t)
+
+	// Bob stays present while one of his connections is in the room
+	bobTab.Close()
+	bob.Close()
+	mu.Lock()
+	want := []string{"join general alice", "join general bob", "join random bob", "leave general bob", "leave random bob"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Events = %v, want %v", events, want)
+	}
+	mu.Unlock()
+	if got := hub.Rooms(); !reflect.DeepEqual(got, []string{"general"}) {
+		t.Errorf("Rooms = %v", got)
+	}
+	if hub.Stats().Clients != 1 {
+		t.Errorf("Expected one client left, got %+v", hub.Stats())
+	}
+}
+
+func TestHubBackpressure(t *testing.T) {
+	hub := NewHub(HubConfig{QueueSize: 2})
+	defer hub.Close()
+	hub.SetRoomOptions("strict", RoomOptions{Policy: DisconnectSlow})
+
+	slow := newChanSender()
+	slow.block = make(chan struct{})
+	c, _ := hub.Register("slow", slow)
+	c.Join("lossy")
+	// The writer holds the first message, the queue the next two
+	for _, m := range []string{"1", "2", "3", "4", "5"} {
+		hub.BroadcastRoom("lossy", []byte(m))
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(slow.block)
+	if got := []string{slow.next(t), slow.next(t), slow.next(t)}; !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("DropNewest delivered %v", got)
+	}
+	if hub.Stats().Dropped != 2 {
+		t.Errorf("Expected 2 dropped messages, got %+v", hub.Stats())
+	}
+
+	oldest := newChanSender()
+	oldest.block = make(chan struct{})
+	c2, _ := hub.Register("o", oldest)
+	hub.SetRoomOptions("latest", RoomOptions{Policy: DropOldest})
+	c2.Join("latest")
+	for _, m := range []string{"1", "2", "3", "4", "5"} {
+		hub.BroadcastRoom("latest", []byte(m))
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(oldest.block)
+	if got := []string{oldest.next(t), oldest.next(t), oldest.next(t)}; !reflect.DeepEqual(got, []string{"1", "4", "5"}) {
+		t.Errorf("DropOldest delivered %v", got)
+	}
+
+	strict := newChanSender()
+	strict.block = make(chan struct{})
+	defer close(strict.block)
+	c3, _ := hub.Register("s", strict)
+	c3.Join("strict")
+	for i := 0; i < 4; i++ {
+		hub.BroadcastRoom("strict", []byte("x"))
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case <-c3.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+	if hub.Stats().Disconnected != 1 {
+		t.Errorf("Expected one disconnection, got %+v", hub.Stats())
+	}
+}
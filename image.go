@@ -0,0 +1,288 @@
+package octo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidImageSpec is returned for malformed transformation specs
+	ErrInvalidImageSpec = errors.New("invalid image transformation")
+	// ErrUnsupportedImageFormat is returned by processors for formats they
+	// cannot decode or encode
+	ErrUnsupportedImageFormat = errors.New("unsupported image format")
+)
+
+// ImageSpec is an image transformation, written as colon-separated
+// operations such as "format=webp:scale_crop_center=380x190":
+//
+//   - format=jpeg|png|gif|webp converts the image
+//   - quality=1..100 sets the encoding quality
+//   - scale=WxH fits the image inside WxH, keeping its aspect ratio; either
+//     dimension may be 0 to derive it from the other
+//   - scale_crop_<gravity>=WxH fills WxH, cropping the overflow, where
+//     gravity is center, top, bottom, left or right
+type ImageSpec struct {
+	Format  string
+	Quality int
+	Width   int
+	Height  int
+	// Crop is the gravity of scale_crop, empty for scale
+	Crop string
+}
+
+// ParseImageSpec parses a transformation spec
+func ParseImageSpec(s string) (ImageSpec, error) {
+	var spec ImageSpec
+	seen := map[string]bool{}
+	for _, op := range strings.Split(s, ":") {
+		name, value, ok := strings.Cut(op, "=")
+		if !ok || value == "" {
+			return ImageSpec{}, fmt.Errorf("%w: %q", ErrInvalidImageSpec, op)
+		}
+		kind := name
+		if strings.HasPrefix(name, "scale") {
+			kind = "scale"
+		}
+		if seen[kind] {
+			return ImageSpec{}, fmt.Errorf("%w: duplicate %s", ErrInvalidImageSpec, kind)
+		}
+		seen[kind] = true
+		switch {
+		case name == "format":
+			switch value {
+			case "jpg":
+				value = "jpeg"
+			case "jpeg", "png", "gif", "webp":
+			default:
+				return ImageSpec{}, fmt.Errorf("%w: format %q", ErrInvalidImageSpec, value)
+			}
+			spec.Format = value
+		case name == "quality":
+			q, err := strconv.Atoi(value)
+			if err != nil || q < 1 || q > 100 {
+				return ImageSpec{}, fmt.Errorf("%w: quality %q", ErrInvalidImageSpec, value)
+			}
+			spec.Quality = q
+		case name == "scale" || strings.HasPrefix(name, "scale_crop_"):
+			w, h, ok := parseImageSize(value)
+			if !ok {
+				return ImageSpec{}, fmt.Errorf("%w: size %q", ErrInvalidImageSpec, value)
+			}
+			if name != "scale" {
+				spec.Crop = strings.TrimPrefix(name, "scale_crop_")
+				switch spec.Crop {
+				case "center", "top", "bottom", "left", "right":
+				default:
+					return ImageSpec{}, fmt.Errorf("%w: gravity %q", ErrInvalidImageSpec, spec.Crop)
+				}
+				if w == 0 || h == 0 {
+					return ImageSpec{}, fmt.Errorf("%w: crop needs both dimensions", ErrInvalidImageSpec)
+				}
+			}
+			spec.Width, spec.Height = w, h
+		default:
+			return ImageSpec{}, fmt.Errorf("%w: unknown operation %q", ErrInvalidImageSpec, name)
+		}
+	}
+	return spec, nil
+}
+
+func parseImageSize(s string) (int, int, bool) {
+	ws, hs, ok := strings.Cut(s, "x")
+	if !ok {
+		return 0, 0, false
+	}
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if err1 != nil || err2 != nil || w < 0 || h < 0 || w == 0 && h == 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// String returns the canonical form of the spec, with operations sorted, so
+// equivalent specs share a cache entry
+func (s ImageSpec) String() string {
+	var ops []string
+	if s.Format != "" {
+		ops = append(ops, "format="+s.Format)
+	}
+	if s.Quality > 0 {
+		ops = append(ops, "quality="+strconv.Itoa(s.Quality))
+	}
+	if s.Width > 0 || s.Height > 0 {
+		name := "scale"
+		if s.Crop != "" {
+			name = "scale_crop_" + s.Crop
+		}
+		ops = append(ops, name+"="+strconv.Itoa(s.Width)+"x"+strconv.Itoa(s.Height))
+	}
+	sort.Strings(ops)
+	return strings.Join(ops, ":")
+}
+
+// ImageProcessor applies a transformation to an encoded image and returns
+// the result with its content type
+type ImageProcessor interface {
+	Process(src []byte, spec ImageSpec) ([]byte, string, error)
+}
+
+// ImageConfig enables image transformations on a Static mount
+type ImageConfig struct {
+	// Param is the query parameter holding the spec ("vars" when empty)
+	Param string
+	// Processor applies the transformations (StdImageProcessor when nil)
+	Processor ImageProcessor
+	// MaxWidth and MaxHeight bound the requested size (4096 when zero)
+	MaxWidth  int
+	MaxHeight int
+}
+
+// maxImagePixels bounds the images StdImageProcessor decodes
+const maxImagePixels = 50 << 20
+
+// StdImageProcessor transforms JPEG, PNG and GIF images with the standard
+// library, resampling bilinearly. It cannot encode WebP: plug in a processor
+// backed by libvips or similar for that.
+type StdImageProcessor struct{}
+
+func (StdImageProcessor) Process(src []byte, spec ImageSpec) ([]byte, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImageFormat, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", fmt.Errorf("%w: %dx%d image too large", ErrUnsupportedImageFormat, cfg.Width, cfg.Height)
+	}
+	img, format, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImageFormat, err)
+	}
+	if spec.Width > 0 || spec.Height > 0 {
+		img = scaleImage(img, spec)
+	}
+	if spec.Format != "" {
+		format = spec.Format
+	}
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		quality := spec.Quality
+		if quality == 0 {
+			quality = 85
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedImageFormat, format)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// scaleImage resizes img as requested by spec: to fit, or to fill and crop
+func scaleImage(img image.Image, spec ImageSpec) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+	w, h := spec.Width, spec.Height
+	if spec.Crop == "" {
+		// Fit inside w x h
+		switch {
+		case w == 0:
+			w = max(1, sw*h/sh)
+		case h == 0:
+			h = max(1, sh*w/sw)
+		case sw*h > sh*w:
+			h = max(1, sh*w/sw)
+		default:
+			w = max(1, sw*h/sh)
+		}
+		return resample(img, b, w, h)
+	}
+	// Cover w x h, then crop the overflow according to gravity
+	crop := b
+	if sw*h > sh*w {
+		cw := max(1, sh*w/h)
+		x := b.Min.X + (sw-cw)/2
+		switch spec.Crop {
+		case "left":
+			x = b.Min.X
+		case "right":
+			x = b.Max.X - cw
+		}
+		crop = image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	} else if sw*h < sh*w {
+		ch := max(1, sw*h/w)
+		y := b.Min.Y + (sh-ch)/2
+		switch spec.Crop {
+		case "top":
+			y = b.Min.Y
+		case "bottom":
+			y = b.Max.Y - ch
+		}
+		crop = image.Rect(b.Min.X, y, b.Max.X, y+ch)
+	}
+	return resample(img, crop, w, h)
+}
+
+// resample scales the src area of img to w x h with bilinear interpolation
+func resample(img image.Image, src image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Dx(), src.Dy()
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)*float64(sh)/float64(h) - 0.5
+		y0 := clampInt(int(fy), 0, sh-1)
+		y1 := clampInt(y0+1, 0, sh-1)
+		ty := fy - float64(y0)
+		if ty < 0 {
+			ty = 0
+		}
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)*float64(sw)/float64(w) - 0.5
+			x0 := clampInt(int(fx), 0, sw-1)
+			x1 := clampInt(x0+1, 0, sw-1)
+			tx := fx - float64(x0)
+			if tx < 0 {
+				tx = 0
+			}
+			c00 := color.RGBA64Model.Convert(img.At(src.Min.X+x0, src.Min.Y+y0)).(color.RGBA64)
+			c10 := color.RGBA64Model.Convert(img.At(src.Min.X+x1, src.Min.Y+y0)).(color.RGBA64)
+			c01 := color.RGBA64Model.Convert(img.At(src.Min.X+x0, src.Min.Y+y1)).(color.RGBA64)
+			c11 := color.RGBA64Model.Convert(img.At(src.Min.X+x1, src.Min.Y+y1)).(color.RGBA64)
+			lerp := func(a, b, c, d uint16) uint8 {
+				top := float64(a)*(1-tx) + float64(b)*tx
+				bottom := float64(c)*(1-tx) + float64(d)*tx
+				return uint8((top*(1-ty) + bottom*ty) / 257)
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: lerp(c00.R, c10.R, c01.R, c11.R),
+				G: lerp(c00.G, c10.G, c01.G, c11.G),
+				B: lerp(c00.B, c10.B, c01.B, c11.B),
+				A: lerp(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+	return dst
+}
+
+func clampInt(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,129 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseImageSpec(t *testing.T) {
+	spec, err := ParseImageSpec("format=webp:scale_crop_center=380x190")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec != (ImageSpec{Format: "webp", Width: 380, Height: 190, Crop: "center"}) {
+		t.Errorf("Unexpected spec %+v", spec)
+	}
+	if spec.String() != "format=webp:scale_crop_center=380x190" {
+		t.Errorf("Unexpected canonical form %q", spec.String())
+	}
+	spec, _ = ParseImageSpec("scale=0x100:quality=70:format=jpg")
+	if spec.String() != "format=jpeg:quality=70:scale=0x100" {
+		t.Errorf("Unexpected canonical form %q", spec.String())
+	}
+	for _, bad := range []string{"", "format=bmp", "scale=10", "scale=0x0", "scale_crop_middle=10x10", "scale_crop_top=0x10", "quality=0", "scale=1x1:scale_crop_top=2x2", "blur=3"} {
+		if _, err := ParseImageSpec(bad); !errors.Is(err, ErrInvalidImageSpec) {
+			t.Errorf("%q: expected ErrInvalidImageSpec, got %v", bad, err)
+		}
+	}
+}
+
+// testImage is 400x100: red on the left half, blue on the right half
+func testImage(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStaticImages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"banner.png": {Data: testImage(t)},
+		"notes.txt":  {Data: []byte("not an image")},
+	}
+	router := NewRouter[CustomData]()
+	router.StaticWithConfig("/img", fsys, StaticConfig[CustomData]{CacheMaxBytes: 1 << 20, Images: &ImageConfig{MaxWidth: 1000}})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) image.Image {
+		t.Helper()
+		img, _, err := image.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("Decoding the response: %v (%d %s)", err, w.Code, w.Body.String())
+		}
+		return img
+	}
+
+	w := get("/img/banner.png?vars=format=jpeg:scale=200x200")
+	if w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+	if b := decode(w).Bounds(); b.Dx() != 200 || b.Dy() != 50 {
+		t.Errorf("Expected the image to fit in 200x200, got %v", b)
+	}
+
+	// Cropping 100x100 from the right keeps only blue
+	w = get("/img/banner.png?vars=scale_crop_right=100x100")
+	img := decode(w)
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Errorf("Expected 100x100, got %v", b)
+	}
+	if r, _, b, _ := img.At(0, 50).RGBA(); r != 0 || b != 0xffff {
+		t.Errorf("Expected blue on the left edge of the crop")
+	}
+	w = get("/img/banner.png?vars=scale_crop_center=100x100")
+	if r, _, _, _ := decode(w).At(10, 50).RGBA(); r != 0xffff {
+		t.Errorf("Expected red on the left of the center crop")
+	}
+
+	// Equivalent specs share a cache entry
+	get("/img/banner.png?vars=scale=200x200:format=jpeg")
+	s := router.StaticStats()[0]
+	if s.Entries != 3 || s.Hits != 1 || s.Misses != 3 {
+		t.Errorf("Unexpected cache stats %+v", s)
+	}
+
+	// Without a spec the file is served as is
+	if w := get("/img/banner.png"); !bytes.Equal(w.Body.Bytes(), fsys["banner.png"].Data) {
+		t.Errorf("Expected the original image")
+	}
+
+	tests := []struct {
+		path, code string
+	}{
+		{"/img/banner.png?vars=scale=2000x10", "err_invalid_query"},
+		{"/img/banner.png?vars=blur=3", "err_invalid_query"},
+		{"/img/banner.png?vars=format=webp", "err_unsupported_media_type"},
+		{"/img/notes.txt?vars=scale=10x10", "err_unsupported_media_type"},
+	}
+	for _, tt := range tests {
+		w := get(tt.path)
+		var result BaseResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if result.Token != tt.code || w.Code == http.StatusOK {
+			t.Errorf("%s: expected %s, got %d %s", tt.path, tt.code, w.Code, result.Token)
+		}
+	}
+}
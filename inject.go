@@ -0,0 +1,103 @@
+package octo
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// InjectPosition places a snippet relative to its marker
+type InjectPosition int
+
+const (
+	// InjectBefore inserts the snippet before the marker
+	InjectBefore InjectPosition = iota
+	// InjectAfter inserts the snippet after the marker. A marker that opens
+	// a tag, such as "<body", is extended to the end of that tag.
+	InjectAfter
+	// InjectReplace replaces the marker, typically a placeholder comment
+	InjectReplace
+)
+
+// HTMLSnippet is inserted into HTML responses at the first occurrence of its
+// marker, matched case-insensitively. Responses without the marker are left
+// as they are.
+type HTMLSnippet[V any] struct {
+	// Marker is the text to look for, e.g. "</head>" or "<!-- analytics -->"
+	Marker   string
+	Position InjectPosition
+	// Render returns the HTML to insert for the request; an empty result
+	// inserts nothing. It can read what the handler stored on the context,
+	// such as a CSP nonce.
+	Render func(ctx *Ctx[V]) string
+}
+
+// InjectConfig configures InjectHTMLMiddleware
+type InjectConfig[V any] struct {
+	Snippets []HTMLSnippet[V]
+	// MaxSize is the largest page buffered for injection; larger pages are
+	// sent as they are (1MB when zero)
+	MaxSize int
+}
+
+// InjectHTMLMiddleware inserts snippets (analytics tags, preload links,
+// nonce-bearing scripts) into text/html responses after they are rendered,
+// so templates do not have to carry them. Compressed, flushed (streaming)
+// and HEAD responses are left alone.
+func InjectHTMLMiddleware[V any](cfg InjectConfig[V]) MiddlewareFunc[V] {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1 << 20
+	}
+	types := []string{"text/html"}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if ctx.Request.Method == http.MethodHead || len(cfg.Snippets) == 0 {
+				next(ctx)
+				return
+			}
+			w := captureMedia(ctx, next, types, cfg.MaxSize)
+			if w == nil {
+				return
+			}
+			body := w.buf.Bytes()
+			for _, s := range cfg.Snippets {
+				if snippet := s.Render(ctx); snippet != "" {
+					body = injectHTML(body, s.Marker, s.Position, snippet)
+				}
+			}
+			w.finish(body)
+		}
+	}
+}
+
+// injectHTML inserts snippet into page at marker
+func injectHTML(page []byte, marker string, pos InjectPosition, snippet string) []byte {
+	if marker == "" {
+		return page
+	}
+	start := indexFold(page, marker)
+	if start < 0 {
+		return page
+	}
+	end := start + len(marker)
+	if pos == InjectAfter && marker[0] == '<' && marker[len(marker)-1] != '>' {
+		if i := bytes.IndexByte(page[end:], '>'); i >= 0 {
+			end += i + 1
+		}
+	}
+	out := make([]byte, 0, len(page)+len(snippet))
+	switch pos {
+	case InjectBefore:
+		out = append(out, page[:start]...)
+		out = append(out, snippet...)
+		out = append(out, page[start:]...)
+	case InjectAfter:
+		out = append(out, page[:end]...)
+		out = append(out, snippet...)
+		out = append(out, page[end:]...)
+	case InjectReplace:
+		out = append(out, page[:start]...)
+		out = append(out, snippet...)
+		out = append(out, page[end:]...)
+	}
+	return out
+}
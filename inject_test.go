@@ -0,0 +1,47 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInjectHTMLMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(InjectHTMLMiddleware(InjectConfig[CustomData]{
+		Snippets: []HTMLSnippet[CustomData]{
+			{Marker: "</head>", Render: func(ctx *Ctx[CustomData]) string {
+				return `<link rel="preload" href="/app.js" as="script">`
+			}},
+			{Marker: "<body", Position: InjectAfter, Render: func(ctx *Ctx[CustomData]) string {
+				return `<script nonce="` + ctx.Request.Header.Get("X-Nonce") + `">init()</script>`
+			}},
+			{Marker: "<!-- analytics -->", Position: InjectReplace, Render: func(ctx *Ctx[CustomData]) string {
+				return `<script src="/a.js"></script>`
+			}},
+		},
+	}))
+	page := `<html><head><title>t</title></HEAD><body class="x"><p>hi</p><!-- analytics --></body></html>`
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		ctx.SendData(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	})
+	router.GET("/raw", func(ctx *Ctx[CustomData]) {
+		ctx.SendData(http.StatusOK, "text/plain", []byte(page))
+	})
+
+	req := httptest.NewRequest("GET", "/page", nil)
+	req.Header.Set("X-Nonce", "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	want := `<html><head><title>t</title><link rel="preload" href="/app.js" as="script"></HEAD>` +
+		`<body class="x"><script nonce="abc">init()</script><p>hi</p><script src="/a.js"></script></body></html>`
+	if w.Body.String() != want {
+		t.Errorf("Expected injected page\n%s\ngot\n%s", want, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/raw", nil))
+	if w.Body.String() != page {
+		t.Errorf("Expected non-HTML response to be left alone, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,270 @@
+// Package yaml parses the subset of YAML used by the declarative route files
+// and the configuration files of octo
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// docLine is a significant line of a YAML document
+type docLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// Parse reads a YAML document into maps, slices and scalars: block mappings and sequences (including sequences of
+// mappings), flow sequences of scalars, quoted and plain scalars, booleans,
+// numbers, null and comments. Anchors, multi-line strings and flow mappings
+// other than {} are not supported.
+func Parse(data []byte) (interface{}, error) {
+	var lines []docLine
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(stripComment(raw), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed for indentation", n+1)
+		}
+		lines = append(lines, docLine{num: n + 1, indent: len(line) - len(text), text: text})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &parser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+type parser struct {
+	lines []docLine
+	pos   int
+}
+
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence starting at the current line
+func (p *parser) block(indent int) (interface{}, error) {
+	if isItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	out := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || isItem(line.text) && line.indent == indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", line.num)
+		}
+		key, value, ok := splitKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml line %d: expected key: value", line.num)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("yaml line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		if value != "" {
+			v, err := scalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("yaml line %d: %v", line.num, err)
+			}
+			out[key] = v
+			continue
+		}
+		// A nested block is indented deeper, or is a sequence at the same level
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || next.indent == indent && isItem(next.text) {
+				v, err := p.block(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = v
+				continue
+			}
+		}
+		out[key] = nil
+	}
+	return out, nil
+}
+
+func (p *parser) sequence(indent int) (interface{}, error) {
+	out := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("yaml line %d: unexpected indentation", line.num)
+			}
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				v, err := p.block(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			} else {
+				out = append(out, nil)
+			}
+			continue
+		}
+		if _, _, ok := splitKey(rest); ok || isItem(rest) {
+			// "- key: value" opens a mapping (or nested sequence) whose
+			// lines are aligned on the first key
+			itemIndent := line.indent + len(line.text) - len(rest)
+			p.lines[p.pos] = docLine{num: line.num, indent: itemIndent, text: rest}
+			v, err := p.block(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		v, err := scalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("yaml line %d: %v", line.num, err)
+		}
+		out = append(out, v)
+		p.pos++
+	}
+	return out, nil
+}
+
+// splitKey splits "key: value" (or "key:"); quoted and flow scalars are
+// never keys
+func splitKey(text string) (string, string, bool) {
+	if text == "" || strings.ContainsRune(`"'[{`, rune(text[0])) {
+		return "", "", false
+	}
+	idx := strings.Index(text, ": ")
+	if idx == -1 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		idx = len(text) - 1
+	}
+	key := strings.TrimSpace(text[:idx])
+	if key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(text[idx+1:]), true
+}
+
+// stripComment removes a # comment outside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// scalar converts a scalar or a flow sequence
+func scalar(raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %s", raw)
+		}
+		out := []interface{}{}
+		inner := strings.TrimSpace(raw[1 : len(raw)-1])
+		if inner == "" {
+			return out, nil
+		}
+		for _, item := range splitFlow(inner) {
+			v, err := scalar(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", raw)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	}
+	switch raw {
+	case "true", "True", "TRUE", "yes", "on":
+		return true, nil
+	case "false", "False", "FALSE", "no", "off":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return raw, nil
+}
+
+// splitFlow splits the items of a flow sequence on commas outside quotes
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(s[start:]))
+}
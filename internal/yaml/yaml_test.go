@@ -0,0 +1,45 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse([]byte(`
+name: "quoted # not a comment"
+count: 3
+ratio: 0.5
+empty:
+list: [a, 'b c', 1]
+nested:
+  flag: yes
+  items:
+  - x
+  - y: 1
+    z: null
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := map[string]interface{}{
+		"name":  "quoted # not a comment",
+		"count": int64(3),
+		"ratio": 0.5,
+		"empty": nil,
+		"list":  []interface{}{"a", "b c", int64(1)},
+		"nested": map[string]interface{}{
+			"flag":  true,
+			"items": []interface{}{"x", map[string]interface{}{"y": int64(1), "z": nil}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %#v", got)
+	}
+
+	for _, bad := range []string{"a: 1\n  b: 2", "a: 1\na: 2", "\tkey: 1", "just text", "a: [1, 2"} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobState is the lifecycle state of an async job
+type JobState string
+
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Finished reports whether the job reached a terminal state
+func (s JobState) Finished() bool {
+	return s == JobSucceeded || s == JobFailed
+}
+
+// Job is the status record of an async job, as served by the status route
+type Job struct {
+	ID        string    `json:"id"`
+	State     JobState  `json:"state"`
+	Progress  float64   `json:"progress"`
+	Message   string    `json:"message,omitempty"`
+	Result    any       `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrJobNotFound is returned by a JobStore for unknown job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStore persists job status records. Implementations must be safe for
+// concurrent use.
+type JobStore interface {
+	Save(job Job) error
+	Get(id string) (Job, error)
+}
+
+// MemoryJobStore is an in-process JobStore. Finished jobs are dropped once
+// they are older than the retention.
+type MemoryJobStore struct {
+	mu        sync.Mutex
+	jobs      map[string]Job
+	retention time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryJobStore creates a MemoryJobStore keeping finished jobs for
+// retention (one hour when zero)
+func NewMemoryJobStore(retention time.Duration) *MemoryJobStore {
+	if retention <= 0 {
+		retention = time.Hour
+	}
+	return &MemoryJobStore{
+		jobs:      make(map[string]Job),
+		retention: retention,
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryJobStore) Save(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for id, j := range s.jobs {
+			if j.State.Finished() && now.Sub(j.UpdatedAt) > s.retention {
+				delete(s.jobs, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryJobStore) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// JobFunc is the body of an async job. The returned value becomes the job
+// result; it must be JSON serializable.
+type JobFunc func(job *JobRun) (any, error)
+
+// JobRun is handed to a running JobFunc
+type JobRun struct {
+	ctx  context.Context
+	jobs *Jobs
+	mu   sync.Mutex
+	job  Job
+}
+
+// ID returns the job ID
+func (j *JobRun) ID() string {
+	return j.job.ID
+}
+
+// Context is cancelled when the registry shuts down
+func (j *JobRun) Context() context.Context {
+	return j.ctx
+}
+
+// SetProgress records progress (0..1) and an optional message
+func (j *JobRun) SetProgress(progress float64, message string) {
+	j.mu.Lock()
+	j.job.Progress = progress
+	j.job.Message = message
+	j.job.UpdatedAt = time.Now()
+	job := j.job
+	j.mu.Unlock()
+	j.jobs.save(job)
+}
+
+// Jobs runs async jobs and tracks their status in a JobStore
+type Jobs struct {
+	// Path is the status route prefix; statuses are served at Path + "/:id"
+	Path  string
+	store JobStore
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// NewJobs creates a job registry on store (in-memory when nil) with status
+// routes under /jobs
+func NewJobs(store JobStore) *Jobs {
+	if store == nil {
+		store = NewMemoryJobStore(0)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Jobs{Path: "/jobs", store: store, ctx: ctx, cancel: cancel}
+}
+
+// DefaultJobs is the registry used by Ctx.AcceptAsync
+var DefaultJobs = NewJobs(nil)
+
+func (js *Jobs) save(job Job) {
+	if err := js.store.Save(job); err != nil {
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Warn().Err(err).Str("job", job.ID).Msg("[octo] job store failed")
+			}
+		} else {
+			serverLogger.Warn().Err(err).Str("job", job.ID).Msg("[octo] job store failed")
+		}
+	}
+}
+
+// Submit starts fn in the background and returns its initial status
+func (js *Jobs) Submit(fn JobFunc) Job {
+	now := time.Now()
+	run := &JobRun{
+		ctx:  js.ctx,
+		jobs: js,
+		job:  Job{ID: uuid.NewString(), State: JobPending, CreatedAt: now, UpdatedAt: now},
+	}
+	initial := run.job
+	js.save(initial)
+
+	js.running.Add(1)
+	go func() {
+		defer js.running.Done()
+		run.mu.Lock()
+		run.job.State = JobRunning
+		run.job.UpdatedAt = time.Now()
+		job := run.job
+		run.mu.Unlock()
+		js.save(job)
+
+		result, err := js.execute(run, fn)
+
+		run.mu.Lock()
+		if err != nil {
+			run.job.State = JobFailed
+			run.job.Error = err.Error()
+		} else {
+			run.job.State = JobSucceeded
+			run.job.Progress = 1
+			run.job.Result = result
+		}
+		run.job.UpdatedAt = time.Now()
+		job = run.job
+		run.mu.Unlock()
+		js.save(job)
+	}()
+	return initial
+}
+
+// execute runs fn, turning a panic into a job failure
+func (js *Jobs) execute(run *JobRun, fn JobFunc) (result any, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+	return fn(run)
+}
+
+// Get returns the status of a job
+func (js *Jobs) Get(id string) (Job, error) {
+	return js.store.Get(id)
+}
+
+// Shutdown waits for running jobs until ctx is done, then cancels their
+// contexts. Jobs submitted afterwards start with a cancelled context.
+func (js *Jobs) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		js.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		js.cancel()
+		return nil
+	case <-ctx.Done():
+		js.cancel()
+		return ctx.Err()
+	}
+}
+
+// Location returns the status URL of a job
+func (js *Jobs) Location(id string) string {
+	return strings.TrimRight(js.Path, "/") + "/" + id
+}
+
+// MountJobs registers the status route of jobs on router. Unknown jobs get
+// a 404; finished jobs carry their result or error.
+func MountJobs[V any](router *Router[V], jobs *Jobs) *Route[V] {
+	return router.GET(strings.TrimRight(jobs.Path, "/")+"/:id", func(ctx *Ctx[V]) {
+		job, err := jobs.Get(ctx.Param("id"))
+		if errors.Is(err, ErrJobNotFound) {
+			ctx.Send404()
+			return
+		}
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		ctx.SendJSON(http.StatusOK, job)
+	})
+}
+
+// AcceptAsync submits fn to DefaultJobs and answers 202 Accepted with a
+// Location header pointing at the job status route
+func (c *Ctx[V]) AcceptAsync(fn JobFunc) Job {
+	return c.AcceptAsyncWith(DefaultJobs, fn)
+}
+
+// AcceptAsyncWith is AcceptAsync on a specific registry. fn may use the Ctx:
+// it is kept out of the request arena.
+func (c *Ctx[V]) AcceptAsyncWith(jobs *Jobs, fn JobFunc) Job {
+	c.Retain()
+	job := jobs.Submit(fn)
+	c.SetHeader("Location", jobs.Location(job.ID))
+	c.SendJSON(http.StatusAccepted, job)
+	return job
+}
@@ -0,0 +1,166 @@
+package octo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptAsync(t *testing.T) {
+	jobs := NewJobs(nil)
+	defer jobs.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	router := NewRouter[CustomData]()
+	MountJobs(router, jobs)
+	router.POST("/reports", func(ctx *Ctx[CustomData]) {
+		ctx.AcceptAsyncWith(jobs, func(run *JobRun) (any, error) {
+			run.SetProgress(0.5, "halfway")
+			<-release
+			return map[string]int{"rows": 3}, nil
+		})
+	})
+	router.POST("/broken", func(ctx *Ctx[CustomData]) {
+		ctx.AcceptAsyncWith(jobs, func(run *JobRun) (any, error) {
+			return nil, errors.New("boom")
+		})
+	})
+
+	status := func(location string) Job {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", location, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", location, w.Code)
+		}
+		var job Job
+		json.Unmarshal(w.Body.Bytes(), &job)
+		return job
+	}
+	waitFor := func(location string, check func(Job) bool) Job {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			job := status(location)
+			if check(job) || time.Now().After(deadline) {
+				return job
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/reports", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", w.Code)
+	}
+	location := w.Header().Get("Location")
+	var accepted Job
+	json.Unmarshal(w.Body.Bytes(), &accepted)
+	if location != "/jobs/"+accepted.ID || accepted.State != JobPending {
+		t.Errorf("Unexpected accepted job %+v at %s", accepted, location)
+	}
+
+	job := waitFor(location, func(j Job) bool { return j.Message == "halfway" })
+	if job.State != JobRunning || job.Progress != 0.5 {
+		t.Errorf("Expected running job at 0.5, got %+v", job)
+	}
+	close(release)
+	job = waitFor(location, func(j Job) bool { return j.State.Finished() })
+	if job.State != JobSucceeded || job.Progress != 1 || job.Result == nil {
+		t.Errorf("Expected succeeded job with result, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/broken", nil))
+	job = waitFor(w.Header().Get("Location"), func(j Job) bool { return j.State.Finished() })
+	if job.State != JobFailed || job.Error != "boom" {
+		t.Errorf("Expected failed job, got %+v", job)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/jobs/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown job, got %d", w.Code)
+	}
+}
+
+func TestJobsShutdownCancels(t *testing.T) {
+	jobs := NewJobs(nil)
+	started := make(chan struct{})
+	job := jobs.Submit(func(run *JobRun) (any, error) {
+		close(started)
+		<-run.Context().Done()
+		return nil, run.Context().Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := jobs.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := jobs.Get(job.ID)
+		if got.State == JobFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected cancelled job to fail, got %+v", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAcceptAsyncArena(t *testing.T) {
+	EnableRequestArena = true
+	defer func() { EnableRequestArena = false }()
+	jobs := NewJobs(nil)
+	defer jobs.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	router := NewRouter[CustomData]()
+	router.POST("/reports/:name", func(ctx *Ctx[CustomData]) {
+		id := strings.Clone(ctx.UUID)
+		ctx.AcceptAsyncWith(jobs, func(run *JobRun) (any, error) {
+			<-release
+			return ctx.Param("name") + " " + strconv.FormatBool(ctx.UUID == id), nil
+		})
+	})
+	router.GET("/other/:name", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("name"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/reports/weekly", nil))
+	var job Job
+	json.Unmarshal(w.Body.Bytes(), &job)
+	// Later requests must not reuse the arena the job still reads from
+	for i := 0; i < 10; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other/overwritten", nil))
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		done, err := jobs.Get(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done.State.Finished() {
+			if done.Result != "weekly true" {
+				t.Errorf("Expected the job to read the request values, got %v", done.Result)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Job did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
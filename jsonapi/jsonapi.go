@@ -0,0 +1,275 @@
+// Package jsonapi renders JSON:API (https://jsonapi.org) documents from octo
+// handlers: resource objects, relationships, included resources, error objects
+// and pagination links.
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/coffyg/octo"
+	"github.com/coffyg/octypes"
+)
+
+// MediaType is the JSON:API content type
+const MediaType = "application/vnd.api+json"
+
+// Links maps link names (self, related, first, next...) to URLs
+type Links map[string]string
+
+// Meta holds non-standard information
+type Meta map[string]interface{}
+
+// ResourceIdentifier identifies a resource in relationships
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship links a resource to others. Data is nil, a ResourceIdentifier
+// or a []ResourceIdentifier; use ToOne and ToMany to build it.
+type Relationship struct {
+	Data  interface{} `json:"data"`
+	Links Links       `json:"links,omitempty"`
+	Meta  Meta        `json:"meta,omitempty"`
+}
+
+// ToOne builds a to-one relationship; a nil id is an empty relationship
+func ToOne(id *ResourceIdentifier) Relationship {
+	if id == nil {
+		return Relationship{}
+	}
+	return Relationship{Data: *id}
+}
+
+// ToMany builds a to-many relationship
+func ToMany(ids ...ResourceIdentifier) Relationship {
+	if ids == nil {
+		ids = []ResourceIdentifier{}
+	}
+	return Relationship{Data: ids}
+}
+
+// Resource is a JSON:API resource object. Attributes is usually a struct or
+// a map.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id,omitempty"`
+	Attributes    interface{}             `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Links         Links                   `json:"links,omitempty"`
+	Meta          Meta                    `json:"meta,omitempty"`
+}
+
+// Identifier returns the resource identifier of r
+func (r Resource) Identifier() ResourceIdentifier {
+	return ResourceIdentifier{Type: r.Type, ID: r.ID}
+}
+
+// Marshaler is implemented by types that know their resource representation
+type Marshaler interface {
+	JSONAPIResource() Resource
+}
+
+// ErrorSource points at the cause of an error
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Header    string `json:"header,omitempty"`
+}
+
+// Error is a JSON:API error object
+type Error struct {
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+	Meta   Meta         `json:"meta,omitempty"`
+}
+
+// Document is a top-level JSON:API document. Data is a Resource, a
+// []Resource or nil; it is omitted when Errors is set.
+type Document struct {
+	Data     interface{}
+	Errors   []Error
+	Included []Resource
+	Links    Links
+	Meta     Meta
+}
+
+type jsonapiObject struct {
+	Version string `json:"version"`
+}
+
+func (d Document) MarshalJSON() ([]byte, error) {
+	if len(d.Errors) > 0 {
+		return json.Marshal(struct {
+			JSONAPI jsonapiObject `json:"jsonapi"`
+			Errors  []Error       `json:"errors"`
+			Links   Links         `json:"links,omitempty"`
+			Meta    Meta          `json:"meta,omitempty"`
+		}{jsonapiObject{"1.1"}, d.Errors, d.Links, d.Meta})
+	}
+	return json.Marshal(struct {
+		JSONAPI  jsonapiObject `json:"jsonapi"`
+		Data     interface{}   `json:"data"`
+		Included []Resource    `json:"included,omitempty"`
+		Links    Links         `json:"links,omitempty"`
+		Meta     Meta          `json:"meta,omitempty"`
+	}{jsonapiObject{"1.1"}, d.Data, dedupe(d.Included, d.Data), d.Links, d.Meta})
+}
+
+// dedupe drops included resources repeated or already present as primary data
+func dedupe(included []Resource, primary interface{}) []Resource {
+	if len(included) == 0 {
+		return nil
+	}
+	seen := make(map[ResourceIdentifier]bool, len(included))
+	switch p := primary.(type) {
+	case Resource:
+		seen[p.Identifier()] = true
+	case []Resource:
+		for _, r := range p {
+			seen[r.Identifier()] = true
+		}
+	}
+	out := make([]Resource, 0, len(included))
+	for _, r := range included {
+		if seen[r.Identifier()] {
+			continue
+		}
+		seen[r.Identifier()] = true
+		out = append(out, r)
+	}
+	return out
+}
+
+// Resources converts values implementing Marshaler
+func Resources[T Marshaler](items []T) []Resource {
+	out := make([]Resource, len(items))
+	for i, item := range items {
+		out[i] = item.JSONAPIResource()
+	}
+	return out
+}
+
+// Send writes doc with the JSON:API media type
+func Send[V any](ctx *octo.Ctx[V], status int, doc Document) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		ctx.SendError("err_json_error", err)
+		return
+	}
+	ctx.SendData(status, MediaType, body)
+}
+
+// SendResource sends a single resource with optional included resources
+func SendResource[V any](ctx *octo.Ctx[V], status int, r Resource, included ...Resource) {
+	if r.Links == nil && ctx.Request != nil {
+		r.Links = Links{"self": ctx.Request.URL.Path}
+	}
+	Send(ctx, status, Document{Data: r, Included: included})
+}
+
+// SendCollection sends a list of resources. With a pagination, first/prev/
+// next/last links are derived from the request URL (page[number] and
+// page[size]) and the counts are exposed in meta.
+func SendCollection[V any](ctx *octo.Ctx[V], resources []Resource, p *octypes.Pagination, included ...Resource) {
+	if resources == nil {
+		resources = []Resource{}
+	}
+	doc := Document{Data: resources, Included: included, Links: Links{"self": ctx.Request.URL.RequestURI()}}
+	if p != nil {
+		for k, v := range PaginationLinks(ctx.Request.URL, p) {
+			doc.Links[k] = v
+		}
+		doc.Meta = Meta{"total": p.Count, "pages": p.PageMax, "page": p.PageNo, "per_page": p.ResultsPerPage}
+	}
+	Send(ctx, http.StatusOK, doc)
+}
+
+// SendErrors sends error objects with the given status
+func SendErrors[V any](ctx *octo.Ctx[V], status int, errs ...Error) {
+	for i := range errs {
+		if errs[i].Status == "" {
+			errs[i].Status = strconv.Itoa(status)
+		}
+	}
+	Send(ctx, status, Document{Errors: errs})
+}
+
+// PaginationLinks builds first/prev/next/last links for p on top of u
+func PaginationLinks(u *url.URL, p *octypes.Pagination) Links {
+	page := func(n int) string {
+		q := u.Query()
+		q.Set("page[number]", strconv.Itoa(n))
+		if p.ResultsPerPage > 0 {
+			q.Set("page[size]", strconv.Itoa(p.ResultsPerPage))
+		}
+		return u.Path + "?" + q.Encode()
+	}
+	last := max(p.PageMax, 1)
+	links := Links{"first": page(1), "last": page(last)}
+	if p.PageNo > 1 {
+		links["prev"] = page(p.PageNo - 1)
+	}
+	if p.PageNo < last {
+		links["next"] = page(p.PageNo + 1)
+	}
+	return links
+}
+
+// Envelope adapts the octo result helpers (NewJSONResult, SendError...) to
+// JSON:API documents; install it with octo.SetResultEnvelope. Data that is not
+// a Resource or []Resource is sent as meta.
+func Envelope() octo.ResultEnvelope {
+	return func(data interface{}, meta octo.ResultMeta) interface{} {
+		if meta.Result == "error" {
+			return Document{
+				Errors: []Error{{
+					Status: strconv.Itoa(meta.Status),
+					Code:   meta.Token,
+					Title:  meta.Message,
+				}},
+				Meta: errorMeta(data),
+			}
+		}
+		doc := Document{}
+		switch d := data.(type) {
+		case Resource, []Resource:
+			doc.Data = d
+		case Marshaler:
+			doc.Data = d.JSONAPIResource()
+		default:
+			doc.Data = nil
+			if data != nil {
+				doc.Meta = Meta{"data": data}
+			}
+		}
+		if meta.Paging != nil {
+			if doc.Meta == nil {
+				doc.Meta = Meta{}
+			}
+			doc.Meta["total"] = meta.Paging.Count
+			doc.Meta["pages"] = meta.Paging.PageMax
+		}
+		if meta.NextCursor != "" {
+			if doc.Meta == nil {
+				doc.Meta = Meta{}
+			}
+			doc.Meta["next_cursor"] = meta.NextCursor
+		}
+		return doc
+	}
+}
+
+func errorMeta(data interface{}) Meta {
+	if data == nil {
+		return nil
+	}
+	return Meta{"details": data}
+}
@@ -0,0 +1,94 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coffyg/octo"
+	"github.com/coffyg/octypes"
+)
+
+type article struct {
+	ID       string
+	Title    string
+	AuthorID string
+}
+
+func (a article) JSONAPIResource() Resource {
+	return Resource{
+		Type:       "articles",
+		ID:         a.ID,
+		Attributes: map[string]string{"title": a.Title},
+		Relationships: map[string]Relationship{
+			"author": ToOne(&ResourceIdentifier{Type: "people", ID: a.AuthorID}),
+		},
+	}
+}
+
+func TestSendCollection(t *testing.T) {
+	router := octo.NewRouter[struct{}]()
+	router.GET("/articles", func(ctx *octo.Ctx[struct{}]) {
+		articles := []article{{"1", "One", "9"}, {"2", "Two", "9"}}
+		author := Resource{Type: "people", ID: "9", Attributes: map[string]string{"name": "Ann"}}
+		SendCollection(ctx, Resources(articles), &octypes.Pagination{PageNo: 2, ResultsPerPage: 2, PageMax: 3, Count: 6}, author, author)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/articles?page[number]=2&sort=title", nil))
+	if w.Header().Get("Content-Type") != MediaType {
+		t.Errorf("Expected %s, got %s", MediaType, w.Header().Get("Content-Type"))
+	}
+
+	var doc struct {
+		Data     []Resource `json:"data"`
+		Included []Resource `json:"included"`
+		Links    Links      `json:"links"`
+		Meta     Meta       `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Data) != 2 || doc.Data[0].Type != "articles" {
+		t.Errorf("Unexpected data: %+v", doc.Data)
+	}
+	if len(doc.Included) != 1 {
+		t.Errorf("Expected included resources to be deduplicated, got %d", len(doc.Included))
+	}
+	if !strings.Contains(doc.Links["next"], "page%5Bnumber%5D=3") || !strings.Contains(doc.Links["prev"], "page%5Bnumber%5D=1") || !strings.Contains(doc.Links["next"], "sort=title") {
+		t.Errorf("Unexpected pagination links: %v", doc.Links)
+	}
+	if doc.Meta["total"] != float64(6) {
+		t.Errorf("Expected total in meta, got %v", doc.Meta)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	octo.SetResultEnvelope(Envelope())
+	defer octo.SetResultEnvelope(nil)
+
+	router := octo.NewRouter[struct{}]()
+	router.GET("/missing", func(ctx *octo.Ctx[struct{}]) {
+		ctx.Send404()
+	})
+	router.GET("/article", func(ctx *octo.Ctx[struct{}]) {
+		ctx.NewJSONResult(article{"1", "One", "9"}, nil)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"errors":[{"status":"404","code":"err_not_found","title":"Not found"}]`) {
+		t.Errorf("Unexpected error document: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), `"data"`) {
+		t.Errorf("Error documents must not contain data: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/article", nil))
+	if !strings.Contains(w.Body.String(), `"data":{"type":"articles","id":"1"`) {
+		t.Errorf("Unexpected resource document: %s", w.Body.String())
+	}
+}
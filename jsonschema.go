@@ -0,0 +1,637 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrValidation is matched (errors.Is) by every ValidationErrors value
+var ErrValidation = errors.New("validation failed")
+
+// FieldError describes one validation failure. Path is a JSON pointer to the
+// offending value ("" for the document root).
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is the structured result of a failed validation
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		if fe.Path == "" {
+			parts[i] = fe.Message
+		} else {
+			parts[i] = fe.Path + ": " + fe.Message
+		}
+	}
+	return ErrValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// SendValidationErrors sends a 422 err_validation envelope with the field
+// errors in data. Errors that are not ValidationErrors are sent as
+// err_invalid_request.
+func (c *Ctx[V]) SendValidationErrors(err error) {
+	if c.done {
+		return
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		c.SendError("err_invalid_request", err)
+		return
+	}
+	apiError := APIErrors["err_validation"]
+	status := c.errorStatus("err_validation", apiError)
+	result := buildResult(errs, ResultMeta{
+		Status:  status,
+		Result:  "error",
+		Message: apiError.Message,
+		Token:   publicToken("err_validation"),
+		Time:    float64(time.Now().UnixNano()-c.StartTime) / 1e9,
+	})
+	c.SendJSON(status, result)
+}
+
+// Schema is a compiled JSON Schema. The supported subset covers type, enum,
+// const, properties, required, additionalProperties, items, length, range,
+// pattern and format (email, uuid, date-time, date) keywords, allOf/anyOf/
+// oneOf/not and local $ref into top-level $defs or definitions.
+type Schema struct {
+	raw   json.RawMessage
+	root  *Schema
+	types []string
+	ref   string
+	defs  map[string]*Schema
+
+	enum     []interface{}
+	constVal interface{}
+	hasConst bool
+
+	properties          map[string]*Schema
+	required            []string
+	additional          *Schema
+	noAdditional        bool
+	minProperties       int
+	maxProperties       int
+	items               *Schema
+	minItems            int
+	maxItems            int
+	uniqueItems         bool
+	minLength           int
+	maxLength           int
+	pattern             *regexp.Regexp
+	format              string
+	minimum             *float64
+	maximum             *float64
+	exclusiveMinimum    *float64
+	exclusiveMaximum    *float64
+	multipleOf          float64
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+}
+
+// CompileSchema parses and compiles a JSON Schema document
+func CompileSchema(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("octo: invalid schema: %w", err)
+	}
+	s, err := compileSchema(raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.raw = append(json.RawMessage(nil), data...)
+	return s, nil
+}
+
+// MarshalJSON returns the source document of a schema built by CompileSchema
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.raw == nil {
+		return []byte("{}"), nil
+	}
+	return s.raw, nil
+}
+
+// MustCompileSchema is like CompileSchema but panics on error
+func MustCompileSchema(data []byte) *Schema {
+	s, err := CompileSchema(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func compileSchema(raw interface{}, root *Schema) (*Schema, error) {
+	s := &Schema{root: root, minProperties: -1, maxProperties: -1, minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	if root == nil {
+		s.root = s
+	}
+	switch v := raw.(type) {
+	case bool:
+		if !v {
+			s.not = &Schema{root: s.root, minProperties: -1, maxProperties: -1, minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+		}
+		return s, nil
+	case map[string]interface{}:
+		if err := s.compile(v); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("octo: schema must be an object or boolean")
+}
+
+func (s *Schema) compile(m map[string]interface{}) error {
+	sub := func(raw interface{}) (*Schema, error) { return compileSchema(raw, s.root) }
+	subs := func(key string) ([]*Schema, error) {
+		list, ok := m[key].([]interface{})
+		if !ok {
+			return nil, nil
+		}
+		out := make([]*Schema, 0, len(list))
+		for _, raw := range list {
+			c, err := sub(raw)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	}
+	num := func(key string) *float64 {
+		if f, ok := m[key].(float64); ok {
+			return &f
+		}
+		return nil
+	}
+	count := func(key string) int {
+		if f, ok := m[key].(float64); ok {
+			return int(f)
+		}
+		return -1
+	}
+
+	switch t := m["type"].(type) {
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				s.types = append(s.types, name)
+			}
+		}
+	}
+	if ref, ok := m["$ref"].(string); ok {
+		s.ref = ref
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, ok := m[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if s.root.defs == nil {
+			s.root.defs = make(map[string]*Schema)
+		}
+		for name, raw := range defs {
+			c, err := sub(raw)
+			if err != nil {
+				return fmt.Errorf("octo: %s/%s: %w", key, name, err)
+			}
+			s.root.defs["#/"+key+"/"+name] = c
+		}
+	}
+	if enum, ok := m["enum"].([]interface{}); ok {
+		s.enum = enum
+	}
+	if c, ok := m["const"]; ok {
+		s.constVal, s.hasConst = c, true
+	}
+
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		s.properties = make(map[string]*Schema, len(props))
+		for name, raw := range props {
+			c, err := sub(raw)
+			if err != nil {
+				return fmt.Errorf("octo: property %s: %w", name, err)
+			}
+			s.properties[name] = c
+		}
+	}
+	if req, ok := m["required"].([]interface{}); ok {
+		for _, v := range req {
+			if name, ok := v.(string); ok {
+				s.required = append(s.required, name)
+			}
+		}
+	}
+	switch ap := m["additionalProperties"].(type) {
+	case bool:
+		s.noAdditional = !ap
+	case map[string]interface{}:
+		c, err := sub(ap)
+		if err != nil {
+			return err
+		}
+		s.additional = c
+	}
+	s.minProperties, s.maxProperties = count("minProperties"), count("maxProperties")
+
+	if items, ok := m["items"]; ok {
+		c, err := sub(items)
+		if err != nil {
+			return err
+		}
+		s.items = c
+	}
+	s.minItems, s.maxItems = count("minItems"), count("maxItems")
+	s.uniqueItems, _ = m["uniqueItems"].(bool)
+
+	s.minLength, s.maxLength = count("minLength"), count("maxLength")
+	if p, ok := m["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("octo: invalid pattern %q: %w", p, err)
+		}
+		s.pattern = re
+	}
+	s.format, _ = m["format"].(string)
+
+	s.minimum, s.maximum = num("minimum"), num("maximum")
+	s.exclusiveMinimum, s.exclusiveMaximum = num("exclusiveMinimum"), num("exclusiveMaximum")
+	if f := num("multipleOf"); f != nil {
+		s.multipleOf = *f
+	}
+
+	var err error
+	if s.allOf, err = subs("allOf"); err != nil {
+		return err
+	}
+	if s.anyOf, err = subs("anyOf"); err != nil {
+		return err
+	}
+	if s.oneOf, err = subs("oneOf"); err != nil {
+		return err
+	}
+	if not, ok := m["not"]; ok {
+		if s.not, err = sub(not); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateJSON decodes data and validates it. It returns ValidationErrors on
+// schema violations.
+func (s *Schema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return ValidationErrors{{Message: "invalid JSON: " + err.Error()}}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a value decoded by encoding/json (json.Number or float64
+// for numbers)
+func (s *Schema) Validate(v interface{}) error {
+	var errs ValidationErrors
+	s.validate(v, "", &errs, 0)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// maxSchemaDepth guards against $ref cycles
+const maxSchemaDepth = 64
+
+func (s *Schema) validate(v interface{}, path string, errs *ValidationErrors, depth int) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if depth > maxSchemaDepth {
+		fail("schema nesting too deep")
+		return
+	}
+	if s.ref != "" {
+		target, ok := s.root.defs[s.ref]
+		if !ok && s.ref == "#" {
+			target, ok = s.root, true
+		}
+		if !ok {
+			fail("unresolved $ref %s", s.ref)
+			return
+		}
+		target.validate(v, path, errs, depth+1)
+	}
+
+	if len(s.types) > 0 {
+		matched := false
+		for _, t := range s.types {
+			if jsonTypeMatches(t, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(s.types, " or "), jsonTypeName(v))
+			return
+		}
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if jsonEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+	if s.hasConst && !jsonEqual(s.constVal, v) {
+		fail("value must be %v", s.constVal)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		s.validateObject(val, path, errs, depth)
+	case []interface{}:
+		s.validateArray(val, path, errs, depth)
+	case string:
+		s.validateString(val, fail)
+	case json.Number, float64:
+		s.validateNumber(jsonFloat(val), fail)
+	}
+
+	for _, c := range s.allOf {
+		c.validate(v, path, errs, depth+1)
+	}
+	if len(s.anyOf) > 0 {
+		ok := false
+		for _, c := range s.anyOf {
+			if c.matches(v, depth) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			fail("value does not match any allowed schema")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		n := 0
+		for _, c := range s.oneOf {
+			if c.matches(v, depth) {
+				n++
+			}
+		}
+		if n != 1 {
+			fail("value must match exactly one schema, matched %d", n)
+		}
+	}
+	if s.not != nil && s.not.matches(v, depth) {
+		fail("value matches a disallowed schema")
+	}
+}
+
+func (s *Schema) matches(v interface{}, depth int) bool {
+	var errs ValidationErrors
+	s.validate(v, "", &errs, depth+1)
+	return len(errs) == 0
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, path string, errs *ValidationErrors, depth int) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, FieldError{Path: path + "/" + escapePointer(name), Message: "field is required"})
+		}
+	}
+	if s.minProperties >= 0 && len(obj) < s.minProperties {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at least %d properties", s.minProperties)})
+	}
+	if s.maxProperties >= 0 && len(obj) > s.maxProperties {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at most %d properties", s.maxProperties)})
+	}
+	// Sorted for stable error ordering
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := path + "/" + escapePointer(name)
+		if prop, ok := s.properties[name]; ok {
+			prop.validate(obj[name], childPath, errs, depth+1)
+			continue
+		}
+		if s.noAdditional {
+			*errs = append(*errs, FieldError{Path: childPath, Message: "unknown field"})
+		} else if s.additional != nil {
+			s.additional.validate(obj[name], childPath, errs, depth+1)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []interface{}, path string, errs *ValidationErrors, depth int) {
+	if s.minItems >= 0 && len(arr) < s.minItems {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at least %d items", s.minItems)})
+	}
+	if s.maxItems >= 0 && len(arr) > s.maxItems {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at most %d items", s.maxItems)})
+	}
+	if s.uniqueItems {
+	outer:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					*errs = append(*errs, FieldError{Path: path, Message: "items must be unique"})
+					break outer
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, path+"/"+strconv.Itoa(i), errs, depth+1)
+		}
+	}
+}
+
+func (s *Schema) validateString(str string, fail func(string, ...interface{})) {
+	n := utf8.RuneCountInString(str)
+	if s.minLength >= 0 && n < s.minLength {
+		fail("must be at least %d characters", s.minLength)
+	}
+	if s.maxLength >= 0 && n > s.maxLength {
+		fail("must be at most %d characters", s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		fail("does not match pattern %s", s.pattern.String())
+	}
+	switch s.format {
+	case "email":
+		if _, err := mail.ParseAddress(str); err != nil {
+			fail("must be a valid email address")
+		}
+	case "uuid":
+		if _, err := uuid.Parse(str); err != nil {
+			fail("must be a valid UUID")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			fail("must be an RFC 3339 date-time")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, str); err != nil {
+			fail("must be a date (YYYY-MM-DD)")
+		}
+	}
+}
+
+func (s *Schema) validateNumber(f float64, fail func(string, ...interface{})) {
+	if s.minimum != nil && f < *s.minimum {
+		fail("must be >= %v", *s.minimum)
+	}
+	if s.maximum != nil && f > *s.maximum {
+		fail("must be <= %v", *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+		fail("must be > %v", *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+		fail("must be < %v", *s.exclusiveMaximum)
+	}
+	if s.multipleOf > 0 {
+		q := f / s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("must be a multiple of %v", s.multipleOf)
+		}
+	}
+}
+
+func jsonFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func jsonTypeMatches(t string, v interface{}) bool {
+	if t == "integer" {
+		switch n := v.(type) {
+		case json.Number:
+			if _, err := n.Int64(); err == nil {
+				return true
+			}
+			f, err := n.Float64()
+			return err == nil && f == math.Trunc(f)
+		case float64:
+			return n == math.Trunc(n)
+		}
+		return false
+	}
+	return jsonTypeName(v) == t
+}
+
+// jsonEqual compares decoded JSON values, treating numbers by value
+func jsonEqual(a, b interface{}) bool {
+	if jsonTypeName(a) == "number" && jsonTypeName(b) == "number" {
+		return jsonFloat(a) == jsonFloat(b)
+	}
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, ok := bv[k]
+			if !ok || !jsonEqual(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func escapePointer(s string) string {
+	if !strings.ContainsAny(s, "~/") {
+		return s
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// JSONSchemaMiddleware validates request bodies against schema, compiled once
+// at route registration. Invalid bodies get a 422 err_validation response with
+// the field errors; the body stays available to the handler.
+func JSONSchemaMiddleware[V any](schema *Schema) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if err := ctx.NeedBody(); err != nil {
+				ctx.SendError("err_invalid_request", err)
+				return
+			}
+			if err := schema.ValidateJSON(ctx.Body); err != nil {
+				ctx.SendValidationErrors(err)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
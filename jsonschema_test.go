@@ -0,0 +1,82 @@
+package octo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var userSchema = MustCompileSchema([]byte(`{
+	"type": "object",
+	"required": ["name", "email", "tags"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2},
+		"email": {"type": "string", "format": "email"},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "uniqueItems": true}
+	},
+	"$defs": {"tag": {"type": "string", "pattern": "^[a-z]+$"}}
+}`))
+
+func TestSchemaValidate(t *testing.T) {
+	if err := userSchema.ValidateJSON([]byte(`{"name":"Ann","email":"ann@example.com","age":30,"role":"admin","tags":["a","b"]}`)); err != nil {
+		t.Errorf("Expected valid document, got %v", err)
+	}
+
+	err := userSchema.ValidateJSON([]byte(`{"name":"A","age":1.5,"role":"root","tags":["x","x","Bad"],"extra":1}`))
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected ErrValidation, got %v", err)
+	}
+	got := map[string]bool{}
+	for _, fe := range err.(ValidationErrors) {
+		got[fe.Path] = true
+	}
+	for _, path := range []string{"/email", "/name", "/age", "/role", "/tags", "/tags/2", "/extra"} {
+		if !got[path] {
+			t.Errorf("Expected an error for %s, got %v", path, err)
+		}
+	}
+}
+
+func TestJSONSchemaMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.POST("/users", func(ctx *Ctx[CustomData]) {
+		var body map[string]interface{}
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.SendError("err_json_error", err)
+			return
+		}
+		ctx.SendString(http.StatusCreated, body["name"].(string))
+	}, JSONSchemaMiddleware[CustomData](userSchema))
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(`{"name":"Ann","email":"ann@example.com","tags":[]}`)
+	if w.Code != http.StatusCreated || w.Body.String() != "Ann" {
+		t.Errorf("Expected 201 Ann, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = serve(`{"name":"Ann","tags":[]}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, got %d", w.Code)
+	}
+	var resp struct {
+		Token string       `json:"token"`
+		Data  []FieldError `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Token != "err_validation" || len(resp.Data) != 1 || resp.Data[0].Path != "/email" {
+		t.Errorf("Unexpected validation response: %s", w.Body.String())
+	}
+}
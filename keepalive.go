@@ -0,0 +1,86 @@
+package octo
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type connStateKey struct{}
+
+// connState tracks per-connection data shared by the requests served on it
+type connState struct {
+	requests atomic.Int64
+}
+
+// ConnContext is meant to be installed as http.Server.ConnContext. It attaches
+// per-connection state that KeepAliveMiddleware uses to count requests per
+// connection. Without it MaxRequestsPerConn is ignored.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connStateKey{}, &connState{})
+}
+
+// KeepAliveConfig controls the connection reuse hints sent to clients
+type KeepAliveConfig struct {
+	// MaxRequestsPerConn closes the connection after this many requests (0 = unlimited)
+	MaxRequestsPerConn int64
+	// Timeout is advertised in the Keep-Alive header (0 = not advertised)
+	Timeout time.Duration
+	// AdvertiseMax adds max=N to the Keep-Alive header using MaxRequestsPerConn
+	AdvertiseMax bool
+}
+
+// KeepAliveMiddleware sets Keep-Alive hints and sends Connection: close once a
+// connection has served MaxRequestsPerConn requests, which lets L4 load
+// balancers rebalance long-lived clients. Only applies to HTTP/1.x.
+func KeepAliveMiddleware[V any](cfg KeepAliveConfig) MiddlewareFunc[V] {
+	var hint string
+	if cfg.Timeout > 0 {
+		hint = "timeout=" + strconv.Itoa(int(cfg.Timeout/time.Second))
+	}
+	if cfg.AdvertiseMax && cfg.MaxRequestsPerConn > 0 {
+		if hint != "" {
+			hint += ", "
+		}
+		hint += "max=" + strconv.FormatInt(cfg.MaxRequestsPerConn, 10)
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if ctx.Request.ProtoMajor != 1 {
+				next(ctx)
+				return
+			}
+			if cfg.MaxRequestsPerConn > 0 {
+				if state, ok := ctx.Request.Context().Value(connStateKey{}).(*connState); ok {
+					if state.requests.Add(1) >= cfg.MaxRequestsPerConn {
+						ctx.CloseConnection()
+					}
+				}
+			}
+			if hint != "" && ctx.ResponseWriter.Header().Get("Connection") != "close" {
+				ctx.SetHeader("Keep-Alive", hint)
+			}
+			next(ctx)
+		}
+	}
+}
+
+// CloseConnectionMiddleware makes every response of the route close the connection
+func CloseConnectionMiddleware[V any]() MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			ctx.CloseConnection()
+			next(ctx)
+		}
+	}
+}
+
+// CloseConnection asks the server to close the connection after this response
+// (e.g. after an authentication failure). Must be called before the response
+// is written.
+func (c *Ctx[V]) CloseConnection() {
+	c.ResponseWriter.Header().Del("Keep-Alive")
+	c.SetHeader("Connection", "close")
+}
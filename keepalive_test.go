@@ -0,0 +1,67 @@
+package octo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(KeepAliveMiddleware[CustomData](KeepAliveConfig{
+		MaxRequestsPerConn: 2,
+		Timeout:            5 * time.Second,
+		AdvertiseMax:       true,
+	}))
+	router.GET("/ping", func(ctx *Ctx[CustomData]) {
+		ctx.ResponseWriter.Write([]byte("pong"))
+	})
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ConnContext = ConnContext
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	var closed []bool
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/ping")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if i == 0 && resp.Header.Get("Keep-Alive") != "timeout=5, max=2" {
+			t.Errorf("Expected Keep-Alive hint, got '%s'", resp.Header.Get("Keep-Alive"))
+		}
+		closed = append(closed, resp.Close)
+	}
+
+	if closed[0] {
+		t.Errorf("Expected first response to keep the connection open")
+	}
+	if !closed[1] {
+		t.Errorf("Expected second response to close the connection")
+	}
+}
+
+func TestCloseConnection(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/login", func(ctx *Ctx[CustomData]) {
+		ctx.CloseConnection()
+		ctx.Send401()
+	})
+
+	req := httptest.NewRequest("GET", "/login", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Result().Header.Get("Connection") != "close" {
+		t.Errorf("Expected 'Connection: close', got '%s'", w.Result().Header.Get("Connection"))
+	}
+	if w.Result().StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Result().StatusCode)
+	}
+}
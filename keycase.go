@@ -0,0 +1,232 @@
+package octo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// KeyCase is a JSON object key convention
+type KeyCase int
+
+const (
+	KeyCaseSnake KeyCase = iota
+	KeyCaseCamel
+)
+
+// KeyCaseHeader lets clients pick the key convention ("camel" or "snake")
+const KeyCaseHeader = "X-Key-Case"
+
+func parseKeyCase(s string) (KeyCase, bool) {
+	switch strings.ToLower(s) {
+	case "camel", "camelcase":
+		return KeyCaseCamel, true
+	case "snake", "snake_case":
+		return KeyCaseSnake, true
+	}
+	return 0, false
+}
+
+// convertKey rewrites a single key to the given convention
+func convertKey(key string, to KeyCase) string {
+	if to == KeyCaseCamel {
+		if strings.IndexByte(key, '_') == -1 {
+			return key
+		}
+		var sb strings.Builder
+		sb.Grow(len(key))
+		upper := false
+		for i, r := range key {
+			if r == '_' && i > 0 {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	}
+
+	hasUpper := false
+	for _, r := range key {
+		if unicode.IsUpper(r) {
+			hasUpper = true
+			break
+		}
+	}
+	if !hasUpper {
+		return key
+	}
+	runes := []rune(key)
+	var sb strings.Builder
+	sb.Grow(len(key) + 4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Word boundary: "userName" -> user_name, "HTTPServer" -> http_server
+			if i > 0 && runes[i-1] != '_' && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// TranslateJSONKeys streams JSON from src to dst, rewriting object keys to the
+// given convention. Values, including numbers, are copied unchanged; the
+// output is compact.
+func TranslateJSONKeys(dst io.Writer, src io.Reader, to KeyCase) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	w := bufio.NewWriter(dst)
+	var stack []jsonFrame
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return w.Flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		isKey := false
+		if n := len(stack); n > 0 {
+			top := &stack[n-1]
+			closing := tok == json.Delim('}') || tok == json.Delim(']')
+			switch {
+			case closing:
+			case top.object && !top.afterKey:
+				isKey = true
+				if top.count > 0 {
+					w.WriteByte(',')
+				}
+				top.count++
+				top.afterKey = true
+			case top.object:
+				top.afterKey = false
+			default:
+				if top.count > 0 {
+					w.WriteByte(',')
+				}
+				top.count++
+			}
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			w.WriteByte(byte(t))
+			if t == '{' || t == '[' {
+				stack = append(stack, jsonFrame{object: t == '{'})
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if isKey {
+				t = convertKey(t, to)
+			}
+			b, _ := json.Marshal(t)
+			w.Write(b)
+			if isKey {
+				w.WriteByte(':')
+			}
+		case json.Number:
+			w.WriteString(t.String())
+		case bool:
+			w.WriteString(strconv.FormatBool(t))
+		case nil:
+			w.WriteString("null")
+		}
+	}
+}
+
+type jsonFrame struct {
+	object   bool
+	afterKey bool
+	count    int
+}
+
+// KeyCaseConfig configures KeyCaseMiddleware
+type KeyCaseConfig struct {
+	// ServerCase is the convention used by handlers (default snake_case)
+	ServerCase KeyCase
+	// Header names the request header selecting the client convention
+	// (default X-Key-Case)
+	Header string
+}
+
+// KeyCaseMiddleware translates JSON keys between the handler convention and
+// the one requested by the client header: request bodies are rewritten to
+// ServerCase and JSON responses to the client case. Requests without the
+// header are left untouched.
+func KeyCaseMiddleware[V any](cfg KeyCaseConfig) MiddlewareFunc[V] {
+	if cfg.Header == "" {
+		cfg.Header = KeyCaseHeader
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			clientCase, ok := parseKeyCase(ctx.GetHeader(cfg.Header))
+			if !ok || clientCase == cfg.ServerCase {
+				next(ctx)
+				return
+			}
+			translateKeys(ctx, next, cfg.ServerCase, clientCase)
+		}
+	}
+}
+
+// KeyCase forces the client-facing key convention of a route regardless of
+// headers, e.g. to serve a legacy snake_case client from camelCase handlers
+func (rt *Route[V]) KeyCase(serverCase, clientCase KeyCase) *Route[V] {
+	if serverCase == clientCase {
+		return rt
+	}
+	rt.useInner(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			translateKeys(ctx, next, serverCase, clientCase)
+		}
+	})
+	return rt
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func translateKeys[V any](ctx *Ctx[V], next HandlerFunc[V], serverCase, clientCase KeyCase) {
+	if ctx.Request.ContentLength != 0 && isJSONContentType(ctx.GetHeader("Content-Type")) {
+		if err := ctx.NeedBody(); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		var out bytes.Buffer
+		if err := TranslateJSONKeys(&out, bytes.NewReader(ctx.Body), serverCase); err != nil {
+			ctx.SendError("err_json_error", err)
+			return
+		}
+		ctx.Body = out.Bytes()
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(ctx.Body))
+		ctx.Request.ContentLength = int64(len(ctx.Body))
+	}
+
+	bw := bufferResponse(ctx, next)
+	body := bw.buf.Bytes()
+	if len(body) > 0 && isJSONContentType(bw.Header().Get("Content-Type")) {
+		var out bytes.Buffer
+		out.Grow(len(body))
+		if err := TranslateJSONKeys(&out, bytes.NewReader(body), clientCase); err == nil {
+			body = out.Bytes()
+		}
+	}
+	bw.flushResponse(body)
+}
@@ -0,0 +1,68 @@
+package octo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvertKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		to   KeyCase
+		want string
+	}{
+		{"user_name", KeyCaseCamel, "userName"},
+		{"created_at_ms", KeyCaseCamel, "createdAtMs"},
+		{"_id", KeyCaseCamel, "_id"},
+		{"userName", KeyCaseSnake, "user_name"},
+		{"HTTPServer", KeyCaseSnake, "http_server"},
+		{"userID2", KeyCaseSnake, "user_id2"},
+		{"already_snake", KeyCaseSnake, "already_snake"},
+	}
+	for _, tt := range tests {
+		if got := convertKey(tt.in, tt.to); got != tt.want {
+			t.Errorf("convertKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateJSONKeys(t *testing.T) {
+	in := `{"user_name":"a_b","tags":[{"tag_id":1.50},[],{}],"nested":{"is_admin":true,"x":null},"big_num":12345678901234567890}`
+	want := `{"userName":"a_b","tags":[{"tagId":1.50},[],{}],"nested":{"isAdmin":true,"x":null},"bigNum":12345678901234567890}`
+	var out bytes.Buffer
+	if err := TranslateJSONKeys(&out, strings.NewReader(in), KeyCaseCamel); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("Expected %s, got %s", want, out.String())
+	}
+}
+
+func TestKeyCaseMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(KeyCaseMiddleware[CustomData](KeyCaseConfig{}))
+	router.POST("/users", func(ctx *Ctx[CustomData]) {
+		var body map[string]string
+		ctx.ShouldBindJSON(&body)
+		ctx.SendJSON(http.StatusOK, map[string]string{"first_name": body["first_name"]})
+	})
+
+	for _, tt := range []struct{ header, body, want string }{
+		{"camel", `{"firstName":"ann"}`, `{"firstName":"ann"}`},
+		{"", `{"first_name":"ann"}`, `{"first_name":"ann"}`},
+	} {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.header != "" {
+			req.Header.Set(KeyCaseHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Body.String() != tt.want {
+			t.Errorf("header %q: expected %s, got %s", tt.header, tt.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,370 @@
+// Package kv is an embedded, file-backed key-value store for single-binary
+// deployments. It implements the octo store interfaces (rate limiting, usage
+// metering, async jobs) so small services do not need Redis.
+//
+// Data lives in memory and every write is appended to a log file that is
+// replayed on Open and compacted as it grows. A torn record at the end of the
+// log (crash during a write) is discarded.
+package kv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for missing or expired keys
+	ErrNotFound = errors.New("kv: key not found")
+	// ErrClosed is returned after Close
+	ErrClosed = errors.New("kv: database closed")
+)
+
+const (
+	opSet byte = 1
+	opDel byte = 2
+)
+
+// Options configures a DB
+type Options struct {
+	// SyncWrites fsyncs the log after every write. Off by default: a crash
+	// may then lose the last writes, never corrupt the file.
+	SyncWrites bool
+	// CompactRatio triggers a compaction once the log holds that many
+	// records per live key (4 when zero)
+	CompactRatio int
+}
+
+type entry struct {
+	value   []byte
+	expires int64 // unix nanoseconds, 0 for none
+}
+
+func (e entry) expired(now int64) bool {
+	return e.expires != 0 && now >= e.expires
+}
+
+// DB is an embedded key-value store. It is safe for concurrent use.
+type DB struct {
+	path string
+	opts Options
+
+	mu      sync.Mutex
+	data    map[string]entry
+	file    *os.File
+	records int
+	closed  bool
+}
+
+// Open opens or creates the database stored in the file at path
+func Open(path string, opts Options) (*DB, error) {
+	if opts.CompactRatio <= 0 {
+		opts.CompactRatio = 4
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{path: path, opts: opts, data: make(map[string]entry), file: f}
+	good, err := db.replay(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Drop a torn tail so new records follow the last complete one
+	if err := f.Truncate(good); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// replay loads the log and returns the offset after the last valid record
+func (db *DB) replay(f *os.File) (int64, error) {
+	r := bufio.NewReader(f)
+	var offset int64
+	now := time.Now().UnixNano()
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return offset, nil
+		}
+		if size > 1<<30 {
+			return offset, nil
+		}
+		buf := make([]byte, size+4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return offset, nil
+		}
+		payload := buf[:size]
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(buf[size:]) {
+			return offset, nil
+		}
+		op, key, e, err := decodeRecord(payload)
+		if err != nil {
+			return offset, nil
+		}
+		switch op {
+		case opSet:
+			if e.expired(now) {
+				delete(db.data, key)
+			} else {
+				db.data[key] = e
+			}
+		case opDel:
+			delete(db.data, key)
+		}
+		db.records++
+		offset += int64(uvarintLen(size)) + int64(size) + 4
+	}
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+// encodeRecord frames op, key and e as size|payload|crc32
+func encodeRecord(op byte, key string, e entry) []byte {
+	payload := make([]byte, 0, 1+8+binary.MaxVarintLen64*2+len(key)+len(e.value))
+	payload = append(payload, op)
+	payload = binary.LittleEndian.AppendUint64(payload, uint64(e.expires))
+	payload = binary.AppendUvarint(payload, uint64(len(key)))
+	payload = append(payload, key...)
+	payload = binary.AppendUvarint(payload, uint64(len(e.value)))
+	payload = append(payload, e.value...)
+
+	out := binary.AppendUvarint(nil, uint64(len(payload)))
+	out = append(out, payload...)
+	return binary.LittleEndian.AppendUint32(out, crc32.ChecksumIEEE(payload))
+}
+
+func decodeRecord(p []byte) (byte, string, entry, error) {
+	if len(p) < 9 {
+		return 0, "", entry{}, errors.New("short record")
+	}
+	op := p[0]
+	e := entry{expires: int64(binary.LittleEndian.Uint64(p[1:9]))}
+	p = p[9:]
+	klen, n := binary.Uvarint(p)
+	if n <= 0 || uint64(len(p)-n) < klen {
+		return 0, "", entry{}, errors.New("bad key")
+	}
+	key := string(p[n : n+int(klen)])
+	p = p[n+int(klen):]
+	vlen, n := binary.Uvarint(p)
+	if n <= 0 || uint64(len(p)-n) != vlen {
+		return 0, "", entry{}, errors.New("bad value")
+	}
+	e.value = append([]byte(nil), p[n:]...)
+	return op, key, e, nil
+}
+
+// append writes a record to the log; db.mu must be held
+func (db *DB) append(op byte, key string, e entry) error {
+	if _, err := db.file.Write(encodeRecord(op, key, e)); err != nil {
+		return err
+	}
+	if db.opts.SyncWrites {
+		if err := db.file.Sync(); err != nil {
+			return err
+		}
+	}
+	db.records++
+	if db.records > 1024 && db.records > db.opts.CompactRatio*len(db.data) {
+		return db.compact()
+	}
+	return nil
+}
+
+// Get returns the value of key or ErrNotFound
+func (db *DB) Get(key string) ([]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil, ErrClosed
+	}
+	e, ok := db.data[key]
+	if !ok || e.expired(time.Now().UnixNano()) {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set stores value under key; a positive ttl expires it
+func (db *DB) Set(key string, value []byte, ttl time.Duration) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	return db.set(key, value, ttl)
+}
+
+func (db *DB) set(key string, value []byte, ttl time.Duration) error {
+	e := entry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl).UnixNano()
+	}
+	db.data[key] = e
+	return db.append(opSet, key, e)
+}
+
+// Delete removes key; missing keys are not an error
+func (db *DB) Delete(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	if _, ok := db.data[key]; !ok {
+		return nil
+	}
+	delete(db.data, key)
+	return db.append(opDel, key, entry{})
+}
+
+// UpdateFunc computes the new value of a key from its current one (nil and
+// false when missing or expired). Returning a nil value deletes the key; the
+// ttl applies as in Set, and a negative ttl keeps the current expiry.
+type UpdateFunc func(current []byte, exists bool) (value []byte, ttl time.Duration, err error)
+
+// Update atomically reads and rewrites key
+func (db *DB) Update(key string, fn UpdateFunc) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	now := time.Now().UnixNano()
+	e, ok := db.data[key]
+	if ok && e.expired(now) {
+		ok = false
+	}
+	var current []byte
+	if ok {
+		current = append([]byte(nil), e.value...)
+	}
+	value, ttl, err := fn(current, ok)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if _, present := db.data[key]; !present {
+			return nil
+		}
+		delete(db.data, key)
+		return db.append(opDel, key, entry{})
+	}
+	if ttl < 0 && ok {
+		next := entry{value: append([]byte(nil), value...), expires: e.expires}
+		db.data[key] = next
+		return db.append(opSet, key, next)
+	}
+	return db.set(key, value, max(ttl, 0))
+}
+
+// Keys returns the live keys starting with prefix, sorted
+func (db *DB) Keys(prefix string) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil, ErrClosed
+	}
+	now := time.Now().UnixNano()
+	var keys []string
+	for k, e := range db.data {
+		if strings.HasPrefix(k, prefix) && !e.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Len returns the number of keys, including expired ones not yet compacted
+func (db *DB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.data)
+}
+
+// Compact rewrites the log with only the live keys
+func (db *DB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	return db.compact()
+}
+
+func (db *DB) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), filepath.Base(db.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	now := time.Now().UnixNano()
+	records := 0
+	for k, e := range db.data {
+		if e.expired(now) {
+			delete(db.data, k)
+			continue
+		}
+		if _, err := w.Write(encodeRecord(opSet, k, e)); err != nil {
+			tmp.Close()
+			return err
+		}
+		records++
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), db.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekEnd); err != nil {
+		tmp.Close()
+		return fmt.Errorf("kv: reopening compacted log: %w", err)
+	}
+	db.file.Close()
+	db.file = tmp
+	db.records = records
+	return nil
+}
+
+// Close flushes and closes the log
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	if err := db.file.Sync(); err != nil {
+		db.file.Close()
+		return err
+	}
+	return db.file.Close()
+}
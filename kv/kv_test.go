@@ -0,0 +1,161 @@
+package kv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+func openTestDB(t *testing.T, path string) *DB {
+	t.Helper()
+	db, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return db
+}
+
+func TestDBPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	db := openTestDB(t, path)
+	db.Set("a", []byte("1"), 0)
+	db.Set("b", []byte("2"), 0)
+	db.Set("a", []byte("3"), 0)
+	db.Set("gone", []byte("x"), time.Millisecond)
+	db.Delete("b")
+	db.Update("counter", func(cur []byte, ok bool) ([]byte, time.Duration, error) {
+		return []byte("c"), 0, nil
+	})
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := db.Get("a"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	db = openTestDB(t, path)
+	defer db.Close()
+	if v, err := db.Get("a"); err != nil || string(v) != "3" {
+		t.Errorf("Get(a) = %q, %v", v, err)
+	}
+	for _, key := range []string{"b", "gone"} {
+		if _, err := db.Get(key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%s) error = %v, want ErrNotFound", key, err)
+		}
+	}
+	if keys, _ := db.Keys(""); len(keys) != 2 || keys[0] != "a" || keys[1] != "counter" {
+		t.Errorf("Keys = %v", keys)
+	}
+}
+
+func TestDBTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	db := openTestDB(t, path)
+	db.Set("ok", []byte("1"), 0)
+	db.Close()
+
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(encodeRecord(opSet, "torn", entry{value: []byte("value")})[:7])
+	f.Close()
+
+	db = openTestDB(t, path)
+	if v, err := db.Get("ok"); err != nil || string(v) != "1" {
+		t.Errorf("Get(ok) = %q, %v", v, err)
+	}
+	db.Set("after", []byte("2"), 0)
+	db.Close()
+
+	db = openTestDB(t, path)
+	defer db.Close()
+	if v, err := db.Get("after"); err != nil || string(v) != "2" {
+		t.Errorf("Write after a torn tail was lost: %q, %v", v, err)
+	}
+}
+
+func TestDBCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	db := openTestDB(t, path)
+	for i := 0; i < 3000; i++ {
+		db.Set("key", []byte{byte(i)}, 0)
+	}
+	info, _ := os.Stat(path)
+	if info.Size() > 1024*20 {
+		t.Errorf("Expected automatic compaction, log is %d bytes", info.Size())
+	}
+	db.Set("other", []byte("x"), 0)
+	if err := db.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	db.Set("later", []byte("y"), 0)
+	db.Close()
+
+	db = openTestDB(t, path)
+	defer db.Close()
+	if v, _ := db.Get("key"); len(v) != 1 || v[0] != byte(2999%256) {
+		t.Errorf("Get(key) = %v", v)
+	}
+	if db.Len() != 3 {
+		t.Errorf("Expected 3 keys, got %d", db.Len())
+	}
+}
+
+func TestRateLimitStore(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "data.kv"))
+	defer db.Close()
+	store := NewRateLimitStore(db, "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.Hit("ip", time.Minute)
+		}()
+	}
+	wg.Wait()
+	count, reset, err := store.Hit("ip", time.Minute)
+	if err != nil || count != 51 || time.Until(reset) <= 0 {
+		t.Errorf("Hit = %d, %v, %v", count, reset, err)
+	}
+	if count, _, _ := store.Hit("short", time.Millisecond); count != 1 {
+		t.Errorf("Expected a fresh window, got %d", count)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if count, _, _ := store.Hit("short", time.Millisecond); count != 1 {
+		t.Errorf("Expected the window to reset, got %d", count)
+	}
+}
+
+func TestUsageAndJobStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	db := openTestDB(t, path)
+	usage := NewUsageStore(db, "")
+	usage.Add("key", "2024-05", 1, 100)
+	usage.Add("key", "2024-05", 2, 50)
+	jobs := NewJobStore(db, "", time.Hour)
+	jobs.Save(octo.Job{ID: "j1", State: octo.JobSucceeded, Result: "done"})
+	db.Close()
+
+	db = openTestDB(t, path)
+	defer db.Close()
+	usage = NewUsageStore(db, "")
+	if u, err := usage.Get("key", "2024-05"); err != nil || u.Requests != 3 || u.Bytes != 150 {
+		t.Errorf("Usage = %+v, %v", u, err)
+	}
+	if u, err := usage.Get("other", "2024-05"); err != nil || u.Requests != 0 {
+		t.Errorf("Usage(other) = %+v, %v", u, err)
+	}
+	jobs = NewJobStore(db, "", time.Hour)
+	if job, err := jobs.Get("j1"); err != nil || job.State != octo.JobSucceeded || job.Result != "done" {
+		t.Errorf("Job = %+v, %v", job, err)
+	}
+	if _, err := jobs.Get("missing"); !errors.Is(err, octo.ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package kv
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// RateLimitStore is an octo.RateLimitStore backed by a DB
+type RateLimitStore struct {
+	db     *DB
+	prefix string
+}
+
+// NewRateLimitStore stores rate limit windows in db under prefix
+// ("ratelimit/" when empty)
+func NewRateLimitStore(db *DB, prefix string) *RateLimitStore {
+	if prefix == "" {
+		prefix = "ratelimit/"
+	}
+	return &RateLimitStore{db: db, prefix: prefix}
+}
+
+func (s *RateLimitStore) Hit(key string, window time.Duration) (int64, time.Time, error) {
+	var count int64
+	var reset time.Time
+	err := s.db.Update(s.prefix+key, func(current []byte, exists bool) ([]byte, time.Duration, error) {
+		now := time.Now()
+		if exists && len(current) == 16 {
+			reset = time.Unix(0, int64(binary.LittleEndian.Uint64(current[8:])))
+			if now.Before(reset) {
+				count = int64(binary.LittleEndian.Uint64(current)) + 1
+				binary.LittleEndian.PutUint64(current, uint64(count))
+				return current, -1, nil
+			}
+		}
+		count, reset = 1, now.Add(window)
+		value := binary.LittleEndian.AppendUint64(nil, uint64(count))
+		value = binary.LittleEndian.AppendUint64(value, uint64(reset.UnixNano()))
+		return value, window, nil
+	})
+	return count, reset, err
+}
+
+// UsageStore is an octo.UsageStore backed by a DB
+type UsageStore struct {
+	db     *DB
+	prefix string
+}
+
+// NewUsageStore stores usage counters in db under prefix ("usage/" when
+// empty)
+func NewUsageStore(db *DB, prefix string) *UsageStore {
+	if prefix == "" {
+		prefix = "usage/"
+	}
+	return &UsageStore{db: db, prefix: prefix}
+}
+
+func (s *UsageStore) key(key, period string) string {
+	return s.prefix + period + "/" + key
+}
+
+func (s *UsageStore) Add(key, period string, requests, bytes int64) error {
+	return s.db.Update(s.key(key, period), func(current []byte, exists bool) ([]byte, time.Duration, error) {
+		u := octo.Usage{Key: key, Period: period}
+		if exists {
+			if err := json.Unmarshal(current, &u); err != nil {
+				return nil, 0, err
+			}
+		}
+		u.Requests += requests
+		u.Bytes += bytes
+		value, err := json.Marshal(u)
+		return value, -1, err
+	})
+}
+
+func (s *UsageStore) Get(key, period string) (octo.Usage, error) {
+	u := octo.Usage{Key: key, Period: period}
+	data, err := s.db.Get(s.key(key, period))
+	if errors.Is(err, ErrNotFound) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	err = json.Unmarshal(data, &u)
+	return u, err
+}
+
+// JobStore is an octo.JobStore backed by a DB, so job status survives
+// restarts
+type JobStore struct {
+	db        *DB
+	prefix    string
+	retention time.Duration
+}
+
+// NewJobStore stores jobs in db under prefix ("jobs/" when empty). Finished
+// jobs expire after retention (one hour when zero).
+func NewJobStore(db *DB, prefix string, retention time.Duration) *JobStore {
+	if prefix == "" {
+		prefix = "jobs/"
+	}
+	if retention <= 0 {
+		retention = time.Hour
+	}
+	return &JobStore{db: db, prefix: prefix, retention: retention}
+}
+
+func (s *JobStore) Save(job octo.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if job.State.Finished() {
+		ttl = s.retention
+	}
+	return s.db.Set(s.prefix+job.ID, data, ttl)
+}
+
+func (s *JobStore) Get(id string) (octo.Job, error) {
+	data, err := s.db.Get(s.prefix + id)
+	if errors.Is(err, ErrNotFound) {
+		return octo.Job{}, octo.ErrJobNotFound
+	}
+	if err != nil {
+		return octo.Job{}, err
+	}
+	var job octo.Job
+	err = json.Unmarshal(data, &job)
+	return job, err
+}
+
+var (
+	_ octo.RateLimitStore = (*RateLimitStore)(nil)
+	_ octo.UsageStore     = (*UsageStore)(nil)
+	_ octo.JobStore       = (*JobStore)(nil)
+)
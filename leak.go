@@ -0,0 +1,121 @@
+package octo
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// LeakReport describes a request after which goroutines or file descriptors
+// stayed open
+type LeakReport struct {
+	Method string
+	// Route is the matched route pattern, Path the request path
+	Route string
+	Path  string
+	// Goroutines and FDs are the growth measured once the grace period
+	// elapsed; FDs is 0 where open descriptors cannot be counted
+	Goroutines int
+	FDs        int
+}
+
+// OnLeak is called for every suspected leak found by LeakDetectorMiddleware
+// (default: log a warning)
+var OnLeak = func(r LeakReport) {
+	serverLogger := SubsystemLogger(LogServer)
+	if EnableLoggerCheck {
+		if serverLogger != nil {
+			serverLogger.Warn().
+				Str("method", r.Method).
+				Str("route", r.Route).
+				Str("path", r.Path).
+				Int("goroutines", r.Goroutines).
+				Int("fds", r.FDs).
+				Msg("[octo] suspected leak after request")
+		}
+	} else {
+		serverLogger.Warn().
+			Str("method", r.Method).
+			Str("route", r.Route).
+			Str("path", r.Path).
+			Int("goroutines", r.Goroutines).
+			Int("fds", r.FDs).
+			Msg("[octo] suspected leak after request")
+	}
+}
+
+// LeakDetectorConfig configures LeakDetectorMiddleware
+type LeakDetectorConfig struct {
+	// Grace lets goroutines started by the handler finish before counting
+	// (100ms when zero)
+	Grace time.Duration
+	// GoroutineThreshold and FDThreshold are the growth tolerated before a
+	// leak is reported (any growth when zero)
+	GoroutineThreshold int
+	FDThreshold        int
+}
+
+// leakDetector tracks requests so that only requests served alone are
+// measured: concurrent requests would blur the counts
+type leakDetector struct {
+	active atomic.Int64
+	gen    atomic.Uint64
+}
+
+// LeakDetectorMiddleware counts goroutines and open file descriptors before a
+// request and once Grace has elapsed after it, and reports growth through
+// OnLeak, attributed to the route. Only requests that ran with no other
+// request in flight are measured, which is the common case while developing.
+// It only runs when DevMode is set, so it can stay registered in production
+// at the cost of a flag check.
+func LeakDetectorMiddleware[V any](cfg LeakDetectorConfig) MiddlewareFunc[V] {
+	if cfg.Grace <= 0 {
+		cfg.Grace = 100 * time.Millisecond
+	}
+	d := &leakDetector{}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if !DevMode {
+				next(ctx)
+				return
+			}
+			gen := d.gen.Add(1)
+			solo := d.active.Add(1) == 1
+			goroutines, fds := runtime.NumGoroutine(), openFDs()
+			next(ctx)
+			d.active.Add(-1)
+			if !solo {
+				return
+			}
+			report := LeakReport{
+				Method: ctx.Request.Method,
+				Route:  ctx.RoutePath(),
+				Path:   ctx.Request.URL.Path,
+			}
+			time.AfterFunc(cfg.Grace, func() {
+				if d.gen.Load() != gen || d.active.Load() != 0 {
+					return
+				}
+				// Leave out the goroutine running this timer
+				report.Goroutines = runtime.NumGoroutine() - 1 - goroutines
+				if fds >= 0 {
+					report.FDs = openFDs() - fds
+				}
+				if report.Goroutines > cfg.GoroutineThreshold || report.FDs > cfg.FDThreshold {
+					OnLeak(report)
+				}
+			})
+		}
+	}
+}
+
+// openFDs counts the open file descriptors of the process, or returns -1
+// where /proc is not available
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,50 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLeakDetectorMiddleware(t *testing.T) {
+	DevMode = true
+	defer func() { DevMode = false }()
+	reports := make(chan LeakReport, 4)
+	defer func(fn func(LeakReport)) { OnLeak = fn }(OnLeak)
+	OnLeak = func(r LeakReport) { reports <- r }
+
+	release := make(chan struct{})
+	defer close(release)
+	router := NewRouter[CustomData]()
+	router.Use(LeakDetectorMiddleware[CustomData](LeakDetectorConfig{Grace: 20 * time.Millisecond, GoroutineThreshold: 2}))
+	router.GET("/workers/:n", func(ctx *Ctx[CustomData]) {
+		for i := 0; i < 5; i++ {
+			go func() { <-release }()
+		}
+		ctx.SendString(http.StatusOK, "started")
+	})
+	router.GET("/clean", func(ctx *Ctx[CustomData]) {
+		done := make(chan struct{})
+		go func() { close(done) }()
+		<-done
+		ctx.SendString(http.StatusOK, "ok")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/clean", nil))
+	select {
+	case r := <-reports:
+		t.Errorf("Expected no leak for a clean handler, got %+v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/workers/5", nil))
+	select {
+	case r := <-reports:
+		if r.Route != "/workers/:n" || r.Goroutines < 5 {
+			t.Errorf("Unexpected leak report: %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a leak report for the leaking handler")
+	}
+}
@@ -0,0 +1,326 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultHookTimeout bounds lifecycle hooks registered without a timeout
+var DefaultHookTimeout = 30 * time.Second
+
+// Hook is a startup or shutdown function registered on a router
+type Hook struct {
+	name    string
+	fn      func(context.Context) error
+	timeout time.Duration
+}
+
+// Name labels the hook in errors and logs
+func (h *Hook) Name(name string) *Hook {
+	h.name = name
+	return h
+}
+
+// Timeout overrides DefaultHookTimeout for this hook
+func (h *Hook) Timeout(d time.Duration) *Hook {
+	h.timeout = d
+	return h
+}
+
+func (h *Hook) run(ctx context.Context) (err error) {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		err = fmt.Errorf("hook %s: %w", h.name, err)
+	}
+	return err
+}
+
+// DrainingHeader is set to "true" on responses sent while the router drains,
+// unless ServeConfig.DrainingHeader names another header
+const DrainingHeader = "X-Octo-Draining"
+
+// lifecycle holds the hooks of a router
+type lifecycle struct {
+	mu         sync.Mutex
+	onStart    []*Hook
+	onShutdown []*Hook
+	warmup     warmupState
+	// drainHeader is written before draining is set
+	drainHeader string
+	draining    atomic.Bool
+	// drainStart and drainInFlight are recorded by Drain for the shutdown
+	// report
+	drainStart    time.Time
+	drainInFlight int64
+	inFlight      atomic.Int64
+	drained       atomic.Int64
+}
+
+// OnStart registers fn to run, in registration order, when the router starts
+func (r *Router[V]) OnStart(fn func(context.Context) error) *Hook {
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	h := &Hook{name: fmt.Sprintf("start#%d", len(r.lifecycle.onStart)+1), fn: fn}
+	r.lifecycle.onStart = append(r.lifecycle.onStart, h)
+	return h
+}
+
+// OnShutdown registers fn to run when the router shuts down. Shutdown hooks
+// run in reverse registration order so resources close after their users.
+func (r *Router[V]) OnShutdown(fn func(context.Context) error) *Hook {
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	h := &Hook{name: fmt.Sprintf("shutdown#%d", len(r.lifecycle.onShutdown)+1), fn: fn}
+	r.lifecycle.onShutdown = append(r.lifecycle.onShutdown, h)
+	return h
+}
+
+// Start runs the start hooks in order, stopping at the first failure, then
+// the warm-up tasks
+func (r *Router[V]) Start(ctx context.Context) error {
+	if err := r.startHooks(ctx); err != nil {
+		return err
+	}
+	return r.runWarmup(ctx)
+}
+
+func (r *Router[V]) startHooks(ctx context.Context) error {
+	r.lifecycle.mu.Lock()
+	hooks := append([]*Hook(nil), r.lifecycle.onStart...)
+	r.lifecycle.mu.Unlock()
+	for _, h := range hooks {
+		if err := h.run(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Drain marks the router as shutting down: readiness fails, and responses
+// carry "Connection: close" and the draining header so load balancers stop
+// routing to the instance. Serve calls it when shutdown begins; call it
+// yourself when running your own http.Server.
+func (r *Router[V]) Drain() {
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	if r.lifecycle.draining.Load() {
+		return
+	}
+	if r.lifecycle.drainHeader == "" {
+		r.lifecycle.drainHeader = DrainingHeader
+	}
+	r.lifecycle.drainStart = time.Now()
+	r.lifecycle.drainInFlight = r.lifecycle.inFlight.Load()
+	r.lifecycle.draining.Store(true)
+}
+
+// Draining reports whether Drain was called
+func (r *Router[V]) Draining() bool {
+	return r.lifecycle.draining.Load()
+}
+
+// setDrainHeaders marks a response sent while draining
+func (r *Router[V]) setDrainHeaders(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	w.Header().Set(r.lifecycle.drainHeader, "true")
+}
+
+// Shutdown runs every shutdown hook in reverse order, even after failures,
+// and returns the joined errors
+func (r *Router[V]) Shutdown(ctx context.Context) error {
+	r.lifecycle.mu.Lock()
+	hooks := append([]*Hook(nil), r.lifecycle.onShutdown...)
+	r.lifecycle.mu.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].run(ctx); err != nil {
+			serverLogger := SubsystemLogger(LogServer)
+			if EnableLoggerCheck {
+				if serverLogger != nil {
+					serverLogger.Error().Err(err).Msg("[octo] shutdown hook failed")
+				}
+			} else {
+				serverLogger.Error().Err(err).Msg("[octo] shutdown hook failed")
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ServeConfig configures Router.Serve
+type ServeConfig struct {
+	// Addr is listened on when Listeners is empty
+	Addr string
+	// Server is used as is when set; its Handler defaults to the router
+	Server *http.Server
+	// Listeners, e.g. from ListenReusePort
+	Listeners []net.Listener
+	// ShutdownTimeout bounds the whole graceful shutdown (30s when zero)
+	ShutdownTimeout time.Duration
+	// Signals trigger the shutdown (SIGINT and SIGTERM when nil)
+	Signals []os.Signal
+	// DrainDelay keeps serving, with readiness failing and connections
+	// closed after each response, for this long before the server stops
+	// accepting requests, giving load balancers time to notice
+	DrainDelay time.Duration
+	// DrainingHeader is set on responses while draining (DrainingHeader
+	// when empty)
+	DrainingHeader string
+	// OnShutdownReport receives the report of the shutdown, which is also
+	// logged
+	OnShutdownReport func(ShutdownReport)
+}
+
+// Serve runs the start hooks, serves until ctx is done or a signal arrives,
+// then shuts down gracefully: the router drains (see Drain) for DrainDelay,
+// DefaultStreams are closed, in-flight requests drain, DefaultScheduler and
+// DefaultJobs stop, and the shutdown hooks run. Warm-up tasks run once the
+// listeners accept connections, so readiness probes see 503 until they end.
+func (r *Router[V]) Serve(ctx context.Context, cfg ServeConfig) error {
+	srv := cfg.Server
+	if srv == nil {
+		srv = &http.Server{Addr: cfg.Addr}
+	}
+	if srv.Handler == nil {
+		srv.Handler = r
+	}
+	var streamsClosed atomic.Int64
+	srv.RegisterOnShutdown(func() {
+		streamsClosed.Store(int64(DefaultStreams.closeAll()))
+	})
+	listeners := cfg.Listeners
+	if len(listeners) == 0 {
+		addr := srv.Addr
+		if cfg.Addr != "" {
+			addr = cfg.Addr
+		}
+		if addr == "" {
+			addr = ":http"
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		listeners = []net.Listener{ln}
+	}
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	signals := cfg.Signals
+	if signals == nil {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if cfg.DrainingHeader != "" {
+		r.lifecycle.mu.Lock()
+		r.lifecycle.drainHeader = cfg.DrainingHeader
+		r.lifecycle.mu.Unlock()
+	}
+
+	if err := r.startHooks(ctx); err != nil {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return errors.Join(err, r.Shutdown(shutdownCtx))
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, signals...)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- ServeListeners(srv, listeners)
+	}()
+	if err := r.runWarmup(ctx); err != nil {
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Error().Err(err).Msg("[octo] warmup failed, router stays not ready")
+			}
+		} else {
+			serverLogger.Error().Err(err).Msg("[octo] warmup failed, router stays not ready")
+		}
+	}
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+		r.Drain()
+	case <-ctx.Done():
+		r.Drain()
+		srv.SetKeepAlivesEnabled(false)
+		if cfg.DrainDelay > 0 {
+			select {
+			case <-time.After(cfg.DrainDelay):
+			case err := <-serveErr:
+				if !errors.Is(err, http.ErrServerClosed) {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	report := r.drainReport()
+	report.StreamsClosed = int(streamsClosed.Load())
+	if err := DefaultScheduler.Stop(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("scheduler: %w", err))
+	}
+	if err := DefaultJobs.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("jobs: %w", err))
+	}
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	report.TotalTime = time.Since(report.start)
+	report.Errors = len(errs)
+	logShutdownReport(report)
+	if cfg.OnShutdownReport != nil {
+		cfg.OnShutdownReport(report)
+	}
+	return errors.Join(errs...)
+}
+
+// ListenAndServe is Serve on addr with the default shutdown settings
+func (r *Router[V]) ListenAndServe(addr string) error {
+	return r.Serve(context.Background(), ServeConfig{Addr: addr})
+}
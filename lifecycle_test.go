@@ -0,0 +1,194 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHooksOrder(t *testing.T) {
+	router := NewRouter[CustomData]()
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			calls = append(calls, name)
+			mu.Unlock()
+			return err
+		}
+	}
+	router.OnStart(record("db", nil))
+	router.OnStart(record("hub", nil))
+	router.OnShutdown(record("close db", errors.New("db busy"))).Name("db")
+	router.OnShutdown(record("drain hub", nil))
+	router.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}).Name("slow").Timeout(5 * time.Millisecond)
+
+	if err := router.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected start error: %v", err)
+	}
+	err := router.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "hook db: db busy") || !strings.Contains(err.Error(), "hook slow: context deadline exceeded") {
+		t.Errorf("Expected joined hook errors, got %v", err)
+	}
+	expected := []string{"db", "hub", "drain hub", "close db"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+
+	failing := NewRouter[CustomData]()
+	failing.OnStart(func(context.Context) error { return errors.New("no db") })
+	failing.OnStart(func(context.Context) error {
+		t.Error("Expected start to stop at the first failure")
+		return nil
+	})
+	if err := failing.Start(context.Background()); err == nil || err.Error() != "hook start#1: no db" {
+		t.Errorf("Unexpected start error: %v", err)
+	}
+}
+
+func TestRouterServe(t *testing.T) {
+	defer func(s *Scheduler, j *Jobs) { DefaultScheduler, DefaultJobs = s, j }(DefaultScheduler, DefaultJobs)
+	DefaultScheduler, DefaultJobs = NewScheduler(), NewJobs(nil)
+
+	router := NewRouter[CustomData]()
+	started := make(chan struct{})
+	shutdown := make(chan struct{})
+	router.OnStart(func(context.Context) error { close(started); return nil })
+	router.OnShutdown(func(context.Context) error { close(shutdown); return nil })
+	router.GET("/ping", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "pong")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- router.Serve(ctx, ServeConfig{Listeners: []net.Listener{ln}, ShutdownTimeout: time.Second})
+	}()
+	<-started
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("Expected pong, got %s", body)
+	}
+
+	cancel()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not return after cancellation")
+	}
+	select {
+	case <-shutdown:
+	default:
+		t.Error("Expected shutdown hook to run")
+	}
+}
+
+func TestRouterServeDrain(t *testing.T) {
+	defer func(s *Scheduler, j *Jobs) { DefaultScheduler, DefaultJobs = s, j }(DefaultScheduler, DefaultJobs)
+	DefaultScheduler, DefaultJobs = NewScheduler(), NewJobs(nil)
+
+	router := NewRouter[CustomData]()
+	router.GET("/ping", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "pong")
+	})
+	router.GET("/ready", ReadinessHandler(router))
+	release := make(chan struct{})
+	router.GET("/slow", func(ctx *Ctx[CustomData]) {
+		<-release
+		ctx.SendString(http.StatusOK, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	reports := make(chan ShutdownReport, 1)
+	go func() {
+		result <- router.Serve(ctx, ServeConfig{
+			Listeners:       []net.Listener{ln},
+			ShutdownTimeout: time.Second,
+			DrainDelay:      500 * time.Millisecond,
+			DrainingHeader:  "X-Draining",
+			OnShutdownReport: func(r ShutdownReport) {
+				reports <- r
+			},
+		})
+	}()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(path string) *http.Response {
+		t.Helper()
+		resp, err := client.Get("http://" + ln.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := get("/ping"); resp.Header.Get("X-Draining") != "" {
+		t.Error("Expected no draining header before shutdown")
+	}
+	slow := make(chan struct{})
+	go func() {
+		defer close(slow)
+		get("/slow")
+	}()
+	for router.lifecycle.inFlight.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	for !router.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	resp := get("/ping")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Draining") != "true" || !resp.Close {
+		t.Errorf("Expected request served with draining headers, got %d %v close=%v", resp.StatusCode, resp.Header, resp.Close)
+	}
+	if resp := get("/ready"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected readiness to fail while draining, got %d", resp.StatusCode)
+	}
+	close(release)
+	<-slow
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve did not return after draining")
+	}
+	report := <-reports
+	if report.InFlight != 1 || report.Completed != 3 || report.Abandoned != 0 {
+		t.Errorf("Unexpected request counts in report: %+v", report)
+	}
+	if report.DrainTime < 500*time.Millisecond || report.TotalTime < report.DrainTime {
+		t.Errorf("Unexpected durations in report: %+v", report)
+	}
+}
@@ -0,0 +1,163 @@
+package octo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/coffyg/octypes"
+)
+
+// Name registers the route under name for URL building (Router.URL, Links).
+// Names must be unique per router.
+func (rt *Route[V]) Name(name string) *Route[V] {
+	r := rt.router
+	if r.names == nil {
+		r.names = make(map[string]*Route[V])
+	}
+	if _, exists := r.names[name]; exists {
+		panic("octo: route name already defined: " + name)
+	}
+	r.names[name] = rt
+	rt.doc.name = name
+	return rt
+}
+
+// NamedRoute returns the route registered under name, or nil
+func (r *Router[V]) NamedRoute(name string) *Route[V] {
+	return r.names[name]
+}
+
+// URL builds the path of the named route from key/value parameter pairs
+func (r *Router[V]) URL(name string, pairs ...interface{}) (string, error) {
+	rt := r.names[name]
+	if rt == nil {
+		return "", fmt.Errorf("unknown route %s", name)
+	}
+	return URLFor(rt.Path, pairs...)
+}
+
+// Link is a hypermedia link
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// Links maps relation names (self, next, related...) to links
+type Links map[string]Link
+
+// LinkBuilder builds the _links of a response from named routes. Route
+// parameters default to the current request parameters.
+type LinkBuilder[V any] struct {
+	router *Router[V]
+	ctx    *Ctx[V]
+	links  Links
+	err    error
+}
+
+// Links starts a link builder for the current request
+func (r *Router[V]) Links(ctx *Ctx[V]) *LinkBuilder[V] {
+	return &LinkBuilder[V]{router: r, ctx: ctx, links: Links{}}
+}
+
+// Self links to the current request URL
+func (b *LinkBuilder[V]) Self() *LinkBuilder[V] {
+	b.links["self"] = Link{Href: b.ctx.Request.URL.RequestURI()}
+	return b
+}
+
+// Add links rel to the named route. pairs override the request parameters.
+func (b *LinkBuilder[V]) Add(rel, routeName string, pairs ...interface{}) *LinkBuilder[V] {
+	if b.err != nil {
+		return b
+	}
+	rt := b.router.names[routeName]
+	if rt == nil {
+		b.err = fmt.Errorf("unknown route %s", routeName)
+		return b
+	}
+	if len(pairs)%2 != 0 {
+		b.err = fmt.Errorf("link %s expects key/value pairs", rel)
+		return b
+	}
+	values := make(map[string]string, len(b.ctx.Params)+len(pairs)/2)
+	for k, v := range b.ctx.Params {
+		values[k] = v
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			b.err = fmt.Errorf("link %s key %v is not a string", rel, pairs[i])
+			return b
+		}
+		values[key] = toParamString(pairs[i+1])
+	}
+	href, err := fillRoutePattern(rt.Path, values)
+	if err != nil {
+		b.err = fmt.Errorf("link %s: %w", rel, err)
+		return b
+	}
+	link := Link{Href: href}
+	if rt.Method != http.MethodGet && rt.Method != "ANY" {
+		link.Method = rt.Method
+	}
+	b.links[rel] = link
+	return b
+}
+
+// Page adds first/prev/next/last links for p, setting the page number in the
+// pageParam query parameter of the current URL
+func (b *LinkBuilder[V]) Page(p *octypes.Pagination, pageParam string) *LinkBuilder[V] {
+	if p == nil {
+		return b
+	}
+	u := *b.ctx.Request.URL
+	page := func(n int) Link {
+		q := u.Query()
+		q.Set(pageParam, strconv.Itoa(n))
+		u.RawQuery = q.Encode()
+		return Link{Href: u.RequestURI()}
+	}
+	last := max(p.PageMax, 1)
+	b.links["first"] = page(1)
+	b.links["last"] = page(last)
+	if p.PageNo > 1 {
+		b.links["prev"] = page(p.PageNo - 1)
+	}
+	if p.PageNo < last {
+		b.links["next"] = page(p.PageNo + 1)
+	}
+	return b
+}
+
+// Build returns the links or the first error met while building them
+func (b *LinkBuilder[V]) Build() (Links, error) {
+	return b.links, b.err
+}
+
+// SendWithLinks sends data as JSON with links added under "_links". data must
+// encode to a JSON object.
+func (c *Ctx[V]) SendWithLinks(statusCode int, data interface{}, links Links) {
+	if c.done {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		c.SendError("err_json_error", err)
+		return
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		c.SendError("err_json_error", fmt.Errorf("data with links must be a JSON object: %w", err))
+		return
+	}
+	if obj == nil {
+		obj = make(map[string]json.RawMessage, 1)
+	}
+	if obj["_links"], err = json.Marshal(links); err != nil {
+		c.SendError("err_json_error", err)
+		return
+	}
+	c.SendJSON(statusCode, obj)
+}
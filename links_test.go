@@ -0,0 +1,68 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coffyg/octypes"
+)
+
+func TestNamedRouteLinks(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/users/:id", nil).Name("user.show")
+	router.GET("/users/:id/posts/:postId/:slug?", nil).Name("post.show")
+	router.DELETE("/users/:id", nil).Name("user.delete")
+	router.GET("/files/img-:id.png", nil).Name("avatar")
+	router.GET("/users/:id/posts", func(ctx *Ctx[CustomData]) {
+		links, err := router.Links(ctx).
+			Self().
+			Add("owner", "user.show").
+			Add("delete", "user.delete").
+			Add("first_post", "post.show", "postId", 7).
+			Add("avatar", "avatar").
+			Page(&octypes.Pagination{PageNo: 2, PageMax: 2}, "page").
+			Build()
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		ctx.SendWithLinks(http.StatusOK, map[string]int{"count": 1}, links)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/42/posts?page=2", nil))
+	var resp struct {
+		Count int   `json:"count"`
+		Links Links `json:"_links"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unexpected response %d %s", w.Code, w.Body.String())
+	}
+	want := Links{
+		"self":       {Href: "/users/42/posts?page=2"},
+		"owner":      {Href: "/users/42"},
+		"delete":     {Href: "/users/42", Method: "DELETE"},
+		"first_post": {Href: "/users/42/posts/7"},
+		"avatar":     {Href: "/files/img-42.png"},
+		"first":      {Href: "/users/42/posts?page=1"},
+		"prev":       {Href: "/users/42/posts?page=1"},
+		"last":       {Href: "/users/42/posts?page=2"},
+	}
+	if resp.Count != 1 || len(resp.Links) != len(want) {
+		t.Errorf("Unexpected links response: %s", w.Body.String())
+	}
+	for rel, link := range want {
+		if resp.Links[rel] != link {
+			t.Errorf("%s: expected %+v, got %+v", rel, link, resp.Links[rel])
+		}
+	}
+
+	if u, err := router.URL("post.show", "id", 1, "postId", 2, "slug", "hello world"); err != nil || u != "/users/1/posts/2/hello%20world" {
+		t.Errorf("Unexpected URL %s (%v)", u, err)
+	}
+	if _, err := router.URL("user.show"); err == nil {
+		t.Errorf("Expected missing parameter error")
+	}
+}
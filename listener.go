@@ -0,0 +1,80 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+)
+
+// ListenReusePort opens n listeners bound to the same address with SO_REUSEPORT
+// so the kernel spreads incoming connections across several accept queues.
+// n <= 0 defaults to GOMAXPROCS. On platforms without SO_REUSEPORT support a
+// single regular listener is returned instead.
+func ListenReusePort(network, addr string, n int) ([]net.Listener, error) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if !reusePortSupported {
+		n = 1
+	}
+
+	lc := net.ListenConfig{Control: reusePortControl}
+	first, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		if !reusePortSupported {
+			return nil, err
+		}
+		// Kernel refused the option, fall back to a plain listener
+		first, err = net.Listen(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{first}, nil
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	listeners = append(listeners, first)
+	// Bind the remaining sockets to the resolved address so ":0" shares one port
+	bound := first.Addr().String()
+	for i := 1; i < n; i++ {
+		ln, err := lc.Listen(context.Background(), network, bound)
+		if err != nil {
+			serverLogger := SubsystemLogger(LogServer)
+			if EnableLoggerCheck {
+				if serverLogger != nil {
+					serverLogger.Warn().Err(err).Int("opened", len(listeners)).Msg("[octo] reuseport listener failed, continuing with fewer acceptors")
+				}
+			} else {
+				serverLogger.Warn().Err(err).Int("opened", len(listeners)).Msg("[octo] reuseport listener failed, continuing with fewer acceptors")
+			}
+			break
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// ServeListeners runs srv.Serve on every listener and returns the first error
+// once all of them have stopped: when one listener fails, the others are
+// closed. http.ErrServerClosed is returned once srv is shut down.
+func ServeListeners(srv *http.Server, listeners []net.Listener) error {
+	if len(listeners) == 0 {
+		return errors.New("octo: no listeners")
+	}
+	errCh := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func(ln net.Listener) {
+			errCh <- srv.Serve(ln)
+		}(ln)
+	}
+	first := <-errCh
+	for _, ln := range listeners {
+		ln.Close()
+	}
+	for range len(listeners) - 1 {
+		<-errCh
+	}
+	return first
+}
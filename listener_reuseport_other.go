@@ -0,0 +1,13 @@
+//go:build !linux
+
+package octo
+
+import (
+	"syscall"
+)
+
+const reusePortSupported = false
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
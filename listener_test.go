@@ -0,0 +1,85 @@
+package octo
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListenReusePort(t *testing.T) {
+	listeners, err := ListenReusePort("tcp", "127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatalf("ListenReusePort failed: %v", err)
+	}
+	if reusePortSupported && len(listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(listeners))
+	}
+	addr := listeners[0].Addr().String()
+	for _, ln := range listeners {
+		if ln.Addr().String() != addr {
+			t.Errorf("Expected all listeners on %s, got %s", addr, ln.Addr().String())
+		}
+	}
+
+	router := NewRouter[CustomData]()
+	router.GET("/ping", func(ctx *Ctx[CustomData]) {
+		ctx.ResponseWriter.Write([]byte("pong"))
+	})
+	srv := &http.Server{Handler: router}
+	go ServeListeners(srv, listeners)
+	defer srv.Close()
+
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("Expected 'pong', got '%s'", string(body))
+	}
+}
+
+// failingListener fails its first Accept
+type failingListener struct {
+	net.Listener
+	err error
+}
+
+func (l failingListener) Accept() (net.Conn, error) {
+	return nil, l.err
+}
+
+func TestServeListenersStopsOnError(t *testing.T) {
+	good, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	other, _ := net.Listen("tcp", "127.0.0.1:0")
+	broken := failingListener{Listener: other, err: errors.New("accept failed")}
+
+	srv := &http.Server{Handler: http.NotFoundHandler()}
+	defer srv.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeListeners(srv, []net.Listener{good, broken})
+	}()
+	select {
+	case err := <-done:
+		if err != broken.err {
+			t.Errorf("Expected the failing listener error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected ServeListeners to return when a listener fails")
+	}
+	if conn, err := net.Dial("tcp", good.Addr().String()); err == nil {
+		conn.Close()
+		t.Error("Expected the remaining listeners to be closed")
+	}
+	if err := ServeListeners(srv, nil); err == nil {
+		t.Error("Expected an error without listeners")
+	}
+}
@@ -0,0 +1,157 @@
+package octo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// FilterOp is a comparison operator of a list filter
+type FilterOp string
+
+const (
+	OpEq   FilterOp = "eq"
+	OpNe   FilterOp = "ne"
+	OpLt   FilterOp = "lt"
+	OpLte  FilterOp = "lte"
+	OpGt   FilterOp = "gt"
+	OpGte  FilterOp = "gte"
+	OpIn   FilterOp = "in"   // values separated by |
+	OpLike FilterOp = "like" // substring match
+)
+
+// SortField is one key of a sort expression
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// Filter is one field:op:value filter expression. Values holds the split
+// value list of OpIn, Value the raw value otherwise.
+type Filter struct {
+	Field  string   `json:"field"`
+	Op     FilterOp `json:"op"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// ListQuery is the parsed sort and filter expression of a list request
+type ListQuery struct {
+	Sort    []SortField `json:"sort,omitempty"`
+	Filters []Filter    `json:"filters,omitempty"`
+}
+
+// Filter returns the first filter on field
+func (q *ListQuery) Filter(field string) (Filter, bool) {
+	for _, f := range q.Filters {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return Filter{}, false
+}
+
+// ListQuerySpec is the allowlist a list endpoint accepts:
+//
+//	?sort=-created_at,name&filter=status:eq:open&filter=id:in:1|2
+//
+// Fields and operators outside the spec are rejected, so the parsed
+// ListQuery can be handed to a DB layer as is.
+type ListQuerySpec struct {
+	// SortFields lists the sortable fields
+	SortFields []string
+	// Filters maps each filterable field to its allowed operators
+	Filters map[string][]FilterOp
+	// DefaultSort applies when the request has no sort parameter
+	DefaultSort []SortField
+	// MaxFilters caps the number of filters (10 when zero)
+	MaxFilters int
+	// SortParam and FilterParam name the query parameters ("sort" and
+	// "filter" when empty)
+	SortParam   string
+	FilterParam string
+}
+
+// Parse parses the sort and filter parameters of values against the spec.
+// Errors wrap ErrInvalidQuery.
+func (s *ListQuerySpec) Parse(values url.Values) (*ListQuery, error) {
+	sortParam, filterParam := s.SortParam, s.FilterParam
+	if sortParam == "" {
+		sortParam = "sort"
+	}
+	if filterParam == "" {
+		filterParam = "filter"
+	}
+	maxFilters := s.MaxFilters
+	if maxFilters <= 0 {
+		maxFilters = 10
+	}
+
+	q := &ListQuery{}
+	seen := make(map[string]bool)
+	for _, raw := range values[sortParam] {
+		for _, item := range strings.Split(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			field := SortField{Field: item}
+			if rest, ok := strings.CutPrefix(item, "-"); ok {
+				field = SortField{Field: rest, Desc: true}
+			} else if rest, ok := strings.CutPrefix(item, "+"); ok {
+				field.Field = rest
+			}
+			if !slices.Contains(s.SortFields, field.Field) {
+				return nil, &QueryError{Key: sortParam, Value: item, Err: fmt.Errorf("cannot sort by %q", field.Field)}
+			}
+			if seen[field.Field] {
+				return nil, &QueryError{Key: sortParam, Value: item, Err: fmt.Errorf("duplicate sort field %q", field.Field)}
+			}
+			seen[field.Field] = true
+			q.Sort = append(q.Sort, field)
+		}
+	}
+	if len(q.Sort) == 0 && len(s.DefaultSort) > 0 {
+		q.Sort = append([]SortField(nil), s.DefaultSort...)
+	}
+
+	for _, raw := range values[filterParam] {
+		if raw == "" {
+			continue
+		}
+		parts := strings.SplitN(raw, ":", 3)
+		if len(parts) != 3 {
+			return nil, &QueryError{Key: filterParam, Value: raw, Err: errors.New("expected field:op:value")}
+		}
+		f := Filter{Field: parts[0], Op: FilterOp(parts[1]), Value: parts[2]}
+		ops, ok := s.Filters[f.Field]
+		if !ok {
+			return nil, &QueryError{Key: filterParam, Value: raw, Err: fmt.Errorf("cannot filter by %q", f.Field)}
+		}
+		if !slices.Contains(ops, f.Op) {
+			return nil, &QueryError{Key: filterParam, Value: raw, Err: fmt.Errorf("operator %q not allowed on %q", f.Op, f.Field)}
+		}
+		if f.Op == OpIn {
+			f.Values = strings.Split(f.Value, "|")
+			f.Value = ""
+		}
+		if len(q.Filters) == maxFilters {
+			return nil, &QueryError{Key: filterParam, Value: raw, Err: fmt.Errorf("at most %d filters", maxFilters)}
+		}
+		q.Filters = append(q.Filters, f)
+	}
+	return q, nil
+}
+
+// ListQuery parses the sort and filter parameters of the request against
+// spec. On error it answers 400 err_invalid_query and returns nil.
+func (c *Ctx[V]) ListQuery(spec *ListQuerySpec) *ListQuery {
+	q, err := spec.Parse(c.queryValues())
+	if err != nil {
+		c.SendError("err_invalid_query", err)
+		return nil
+	}
+	return q
+}
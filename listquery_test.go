@@ -0,0 +1,88 @@
+package octo
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testListSpec = &ListQuerySpec{
+	SortFields: []string{"created_at", "name"},
+	Filters: map[string][]FilterOp{
+		"status": {OpEq, OpNe},
+		"id":     {OpIn},
+		"age":    {OpGt, OpLte},
+	},
+	DefaultSort: []SortField{{Field: "created_at", Desc: true}},
+}
+
+func TestListQuerySpecParse(t *testing.T) {
+	values, _ := url.ParseQuery("sort=-created_at,name&filter=status:eq:open&filter=id:in:1|2|3&filter=age:gt:18:00")
+	q, err := testListSpec.Parse(values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &ListQuery{
+		Sort: []SortField{{Field: "created_at", Desc: true}, {Field: "name"}},
+		Filters: []Filter{
+			{Field: "status", Op: OpEq, Value: "open"},
+			{Field: "id", Op: OpIn, Values: []string{"1", "2", "3"}},
+			{Field: "age", Op: OpGt, Value: "18:00"},
+		},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("Parse = %+v, want %+v", q, want)
+	}
+	if f, ok := q.Filter("id"); !ok || len(f.Values) != 3 {
+		t.Errorf("Filter(id) = %+v, %v", f, ok)
+	}
+
+	q, err = testListSpec.Parse(url.Values{})
+	if err != nil || !reflect.DeepEqual(q.Sort, testListSpec.DefaultSort) || q.Filters != nil {
+		t.Errorf("Parse(empty) = %+v, %v", q, err)
+	}
+}
+
+func TestListQuerySpecRejects(t *testing.T) {
+	for _, raw := range []string{
+		"sort=password",
+		"sort=name,-name",
+		"filter=password:eq:x",
+		"filter=status:like:op",
+		"filter=status",
+		"filter=status:eq:a&filter=status:eq:b&filter=status:eq:c",
+	} {
+		spec := *testListSpec
+		spec.MaxFilters = 2
+		values, _ := url.ParseQuery(raw)
+		if _, err := spec.Parse(values); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidQuery", raw, err)
+		}
+	}
+}
+
+func TestCtxListQuery(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/items", func(ctx *Ctx[CustomData]) {
+		q := ctx.ListQuery(testListSpec)
+		if q == nil {
+			return
+		}
+		ctx.SendJSON(200, q)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items?sort=name&filter=status:ne:closed", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"op":"ne"`) {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items?sort=secret", nil))
+	if w.Code != 400 || !strings.Contains(w.Body.String(), "err_invalid_query") {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package octo
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RequestClass ranks routes for load shedding
+type RequestClass int
+
+const (
+	// ClassInteractive is the default class of user-facing routes
+	ClassInteractive RequestClass = iota
+	// ClassBatch is shed first (exports, bulk jobs)
+	ClassBatch
+	// ClassCritical is shed last (health checks, control plane)
+	ClassCritical
+)
+
+func (c RequestClass) String() string {
+	switch c {
+	case ClassBatch:
+		return "batch"
+	case ClassCritical:
+		return "critical"
+	default:
+		return "interactive"
+	}
+}
+
+// Class sets the load-shedding class of the route
+func (rt *Route[V]) Class(c RequestClass) *Route[V] {
+	rt.update(func(entry *routeEntry[V]) {
+		entry.class = c
+	})
+	return rt
+}
+
+// LoadShedConfig configures a LoadShedder
+type LoadShedConfig struct {
+	// MaxInFlight is the in-flight count at which interactive requests are
+	// shed; batch requests are shed at half of it
+	MaxInFlight int64
+	// Limits overrides the in-flight count at which each class is shed. A
+	// class without a limit is never shed (ClassCritical by default).
+	Limits map[RequestClass]int64
+	// RetryAfter is sent with 503s (1s when zero)
+	RetryAfter time.Duration
+}
+
+// LoadShedStats counts admissions and sheds per class
+type LoadShedStats struct {
+	InFlight int64            `json:"in_flight"`
+	Admitted map[string]int64 `json:"admitted"`
+	Shed     map[string]int64 `json:"shed"`
+}
+
+// LoadShedder rejects low-priority requests once too many requests are in
+// flight, keeping capacity for higher classes
+type LoadShedder struct {
+	limits     [ClassCritical + 1]int64
+	retryAfter string
+	inFlight   atomic.Int64
+	admitted   [ClassCritical + 1]atomic.Int64
+	shed       [ClassCritical + 1]atomic.Int64
+}
+
+// NewLoadShedder creates a LoadShedder; install it with Router.SetLoadShedder
+func NewLoadShedder(cfg LoadShedConfig) *LoadShedder {
+	limits := cfg.Limits
+	if limits == nil {
+		limits = map[RequestClass]int64{
+			ClassBatch:       cfg.MaxInFlight / 2,
+			ClassInteractive: cfg.MaxInFlight,
+		}
+	}
+	s := &LoadShedder{retryAfter: retryAfterSeconds(cfg.RetryAfter)}
+	for class := range s.limits {
+		if limit, ok := limits[RequestClass(class)]; ok && limit > 0 {
+			s.limits[class] = limit
+		}
+	}
+	return s
+}
+
+// SetLoadShedder enables priority load shedding for every route of the
+// router; nil disables it
+func (r *Router[V]) SetLoadShedder(s *LoadShedder) {
+	r.loadShedder = s
+}
+
+// admit counts the request in flight unless its class is over its limit
+func (s *LoadShedder) admit(class RequestClass) bool {
+	if class < 0 || int(class) >= len(s.limits) {
+		class = ClassInteractive
+	}
+	n := s.inFlight.Add(1)
+	if limit := s.limits[class]; limit > 0 && n > limit {
+		s.inFlight.Add(-1)
+		s.shed[class].Add(1)
+		return false
+	}
+	s.admitted[class].Add(1)
+	return true
+}
+
+func (s *LoadShedder) release() {
+	s.inFlight.Add(-1)
+}
+
+// Stats returns the current counters
+func (s *LoadShedder) Stats() LoadShedStats {
+	stats := LoadShedStats{
+		InFlight: s.inFlight.Load(),
+		Admitted: make(map[string]int64, len(s.admitted)),
+		Shed:     make(map[string]int64, len(s.shed)),
+	}
+	for class := range s.admitted {
+		name := RequestClass(class).String()
+		stats.Admitted[name] = s.admitted[class].Load()
+		stats.Shed[name] = s.shed[class].Load()
+	}
+	return stats
+}
+
+// loadShedHandler answers shed requests
+func loadShedHandler[V any](retryAfter string) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		ctx.SetHeader("Retry-After", retryAfter)
+		ctx.SendError("err_overloaded", nil)
+	}
+}
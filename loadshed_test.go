@@ -0,0 +1,79 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestLoadShedding(t *testing.T) {
+	router := NewRouter[CustomData]()
+	shedder := NewLoadShedder(LoadShedConfig{MaxInFlight: 4})
+	router.SetLoadShedder(shedder)
+
+	release := make(chan struct{})
+	entered := make(chan struct{}, 10)
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		entered <- struct{}{}
+		<-release
+		ctx.SendString(http.StatusOK, "page")
+	})
+	router.GET("/export", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "export")
+	}).Class(ClassBatch)
+	router.GET("/health", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	}).Class(ClassCritical)
+	router.GET("/search", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "search")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/export"); w.Code != http.StatusOK {
+		t.Errorf("Expected batch admitted when idle, got %d", w.Code)
+	}
+
+	// Fill in-flight up to 3: batch (limit 2) sheds, interactive (limit 4) still fits
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get("/page")
+		}()
+		<-entered
+	}
+	w := get("/export")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected batch to be shed, got %d", w.Code)
+	}
+	if w := get("/search"); w.Code != http.StatusOK {
+		t.Errorf("Expected interactive admitted at 4 in flight, got %d", w.Code)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		get("/page")
+	}()
+	<-entered
+	if w := get("/search"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected interactive shed above the limit, got %d", w.Code)
+	}
+	if w := get("/health"); w.Code != http.StatusOK {
+		t.Errorf("Expected critical never shed, got %d", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	stats := shedder.Stats()
+	if stats.InFlight != 0 || stats.Shed["batch"] != 1 || stats.Shed["interactive"] != 1 || stats.Admitted["critical"] != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,103 @@
+package octo
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Log subsystems, routed to their own logger with SetSubsystemLogger
+const (
+	// LogRouter covers request and response I/O failures of the Ctx and of
+	// response writers (exports, multipart, minification), middleware failures
+	// while serving, responses over their size limit and deprecated routes
+	LogRouter = "router"
+	// LogPanic covers panics recovered by RecoveryMiddleware
+	LogPanic = "panic"
+	// LogError covers errors sent with SendError and SendErrorStatus
+	LogError = "error"
+	// LogStream covers stream limits and stream kills
+	LogStream = "stream"
+	// LogAccess covers the request lines of AccessLogMiddleware
+	LogAccess = "access"
+	// LogServer covers the server and background work: lifecycle hooks,
+	// warmup, shutdown, listeners, settings changes, scheduled tasks, the
+	// memory watchdog, leak checks and store failures (jobs, usage, rate
+	// limits, recordings)
+	LogServer = "server"
+	// LogProxy covers the upstream attempts of the reverse proxy
+	LogProxy = "proxy"
+)
+
+var subsystemLoggers atomic.Pointer[map[string]*zerolog.Logger]
+
+// SetSubsystemLogger sends the logs of the subsystem (LogPanic, LogAccess...)
+// to l instead of the logger given to SetupOctoLogger, e.g. panics to stderr
+// and a file and access logs to stdout. A nil l restores the default logger.
+// Other names are accepted for application subsystems, see SubsystemLogger.
+func SetSubsystemLogger(name string, l *zerolog.Logger) {
+	for {
+		current := subsystemLoggers.Load()
+		loggers := make(map[string]*zerolog.Logger)
+		if current != nil {
+			for k, v := range *current {
+				loggers[k] = v
+			}
+		}
+		if l == nil {
+			delete(loggers, name)
+		} else {
+			loggers[name] = l
+		}
+		if subsystemLoggers.CompareAndSwap(current, &loggers) {
+			return
+		}
+	}
+}
+
+// SubsystemLogger returns the logger of the subsystem, or the default logger
+// when none was set
+func SubsystemLogger(name string) *zerolog.Logger {
+	if loggers := subsystemLoggers.Load(); loggers != nil {
+		if l, ok := (*loggers)[name]; ok {
+			return l
+		}
+	}
+	return logger
+}
+
+// AccessLogMiddleware logs one line per request to the LogAccess subsystem:
+// method, path, matched route, status, duration and client IP
+func AccessLogMiddleware[V any]() MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			next(ctx)
+			accessLogger := SubsystemLogger(LogAccess)
+			duration := time.Duration(time.Now().UnixNano() - ctx.StartTime)
+			if EnableLoggerCheck {
+				if accessLogger != nil {
+					accessLogger.Info().
+						Str("id", ctx.UUID).
+						Str("method", ctx.Request.Method).
+						Str("path", ctx.Request.URL.Path).
+						Str("route", ctx.RoutePath()).
+						Int("status", ctx.ResponseWriter.Status).
+						Dur("duration", duration).
+						Str("ip", ctx.ClientIP()).
+						Msg("[octo] request")
+				}
+			} else {
+				accessLogger.Info().
+					Str("id", ctx.UUID).
+					Str("method", ctx.Request.Method).
+					Str("path", ctx.Request.URL.Path).
+					Str("route", ctx.RoutePath()).
+					Int("status", ctx.ResponseWriter.Status).
+					Dur("duration", duration).
+					Str("ip", ctx.ClientIP()).
+					Msg("[octo] request")
+			}
+		}
+	}
+}
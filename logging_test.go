@@ -0,0 +1,64 @@
+package octo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSubsystemLoggers(t *testing.T) {
+	var panics, access, routes, server bytes.Buffer
+	panicLogger := zerolog.New(&panics)
+	accessLogger := zerolog.New(&access)
+	routerLogger := zerolog.New(&routes)
+	serverLogger := zerolog.New(&server)
+	SetSubsystemLogger(LogPanic, &panicLogger)
+	SetSubsystemLogger(LogAccess, &accessLogger)
+	SetSubsystemLogger(LogRouter, &routerLogger)
+	SetSubsystemLogger(LogServer, &serverLogger)
+	defer SetSubsystemLogger(LogPanic, nil)
+	defer SetSubsystemLogger(LogAccess, nil)
+	defer SetSubsystemLogger(LogRouter, nil)
+	defer SetSubsystemLogger(LogServer, nil)
+
+	router := NewRouter[CustomData]()
+	router.Use(AccessLogMiddleware[CustomData]())
+	router.Use(RecoveryMiddleware[CustomData]())
+	router.GET("/ok", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	})
+	router.GET("/boom", func(ctx *Ctx[CustomData]) {
+		panic("boom")
+	})
+	router.GET("/old", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "old")
+	}).Deprecated(time.Unix(0, 0), "")
+
+	for _, path := range []string{"/ok", "/boom", "/old"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if !strings.Contains(panics.String(), "[octo-panic] Panic recovered") {
+		t.Errorf("Expected the panic in the panic log, got %q", panics.String())
+	}
+	if lines := strings.Count(access.String(), "\n"); lines != 3 || !strings.Contains(access.String(), `"route":"/ok"`) {
+		t.Errorf("Expected three access log lines, got %q", access.String())
+	}
+	if strings.Contains(access.String(), "Panic recovered") {
+		t.Error("Expected panics to stay out of the access log")
+	}
+	if !strings.Contains(routes.String(), "[octo] deprecated route called") {
+		t.Errorf("Expected the deprecated call in the router log, got %q", routes.String())
+	}
+	ChangeMaxBodySize(-1)
+	if !strings.Contains(server.String(), "[octo] invalid max body size") {
+		t.Errorf("Expected the invalid body size in the server log, got %q", server.String())
+	}
+	if SubsystemLogger(LogStream) != GetLogger() {
+		t.Error("Expected subsystems without a logger to use the default logger")
+	}
+}
@@ -0,0 +1,161 @@
+package octo
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// matchKey identifies a cached match
+type matchKey struct {
+	method string
+	path   string
+}
+
+// matchCacheEntry is a cached match: the route entry and the parameter values
+// in the order of its paramNames
+type matchCacheEntry[V any] struct {
+	key    matchKey
+	entry  *routeEntry[V]
+	values []string
+	elem   *list.Element
+}
+
+// matchCache is a bounded LRU of method and path to matched route, filled
+// with the matches of the route tree generation gen
+type matchCache[V any] struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[matchKey]*matchCacheEntry[V]
+	lru     list.List // front is most recently used
+	gen     uint64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// MatchCacheStats describes the match cache of a router
+type MatchCacheStats struct {
+	Size      int   `json:"size"`
+	Entries   int   `json:"entries"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// SetMatchCache keeps the route matches of the size most recently requested
+// method and path pairs, so the hottest endpoints skip the tree traversal.
+// Only matched routes are cached, and the cache is emptied whenever routes
+// change (see Remove and Replace). Paths rewritten by PathOptions are not
+// cached. A size of zero disables the cache.
+func (r *Router[V]) SetMatchCache(size int) {
+	if size <= 0 {
+		r.matchCache.Store(nil)
+		return
+	}
+	r.treeMu.RLock()
+	defer r.treeMu.RUnlock()
+	c := &matchCache[V]{size: size, entries: make(map[matchKey]*matchCacheEntry[V]), gen: r.treeGen}
+	r.matchCache.Store(c)
+}
+
+// MatchCacheStats returns the statistics of the match cache, zero without one
+func (r *Router[V]) MatchCacheStats() MatchCacheStats {
+	c := r.matchCache.Load()
+	if c == nil {
+		return MatchCacheStats{}
+	}
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return MatchCacheStats{
+		Size:      c.size,
+		Entries:   entries,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+// treeChanged records a change of the route tree, invalidating cached
+// matches. The caller holds treeMu.
+func (r *Router[V]) treeChanged() {
+	r.treeGen++
+	if c := r.matchCache.Load(); c != nil {
+		c.purge(r.treeGen)
+	}
+}
+
+// matchPath matches the request path like matchEntry, through the match
+// cache when enabled. parts is nil on a cache hit.
+func (r *Router[V]) matchPath(method, path string, arena *requestArena[V]) (*routeEntry[V], map[string]string, []string) {
+	c := r.matchCache.Load()
+	if c == nil {
+		parts := splitPath(path)
+		entry, params := r.matchEntry(method, parts, arena)
+		return entry, params, parts
+	}
+	key := matchKey{method: method, path: path}
+	if entry, values := c.get(key); entry != nil {
+		c.hits.Add(1)
+		// The cached values must not be handed to the arena, which reuses
+		// its buffer
+		if arena != nil {
+			values = append(arena.paramValues[:0], values...)
+		}
+		return entry, entryParams(entry, values, arena), nil
+	}
+	c.misses.Add(1)
+	parts := splitPath(path)
+	entry, values, gen := r.lookupEntry(method, parts, arena)
+	if entry == nil {
+		return nil, nil, parts
+	}
+	c.put(key, entry, values, gen)
+	return entry, entryParams(entry, values, arena), parts
+}
+
+func (c *matchCache[V]) get(key matchKey) (*routeEntry[V], []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	if e == nil {
+		return nil, nil
+	}
+	c.lru.MoveToFront(e.elem)
+	return e.entry, e.values
+}
+
+// put caches a match found in the tree generation gen, unless the tree
+// changed since
+func (c *matchCache[V]) put(key matchKey, entry *routeEntry[V], values []string, gen uint64) {
+	e := &matchCacheEntry[V]{key: key, entry: entry, values: append([]string(nil), values...)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if old := c.entries[key]; old != nil {
+		c.lru.Remove(old.elem)
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	for len(c.entries) > c.size {
+		oldest := c.lru.Back().Value.(*matchCacheEntry[V])
+		c.lru.Remove(oldest.elem)
+		delete(c.entries, oldest.key)
+		c.evictions.Add(1)
+	}
+}
+
+// purge empties the cache and only accepts matches of generation gen from
+// now on
+func (c *matchCache[V]) purge(gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen = gen
+	c.entries = make(map[matchKey]*matchCacheEntry[V])
+	c.lru.Init()
+}
@@ -0,0 +1,75 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchCache(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.SetMatchCache(2)
+	router.GET("/users/:id", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "user "+ctx.Param("id"))
+	})
+	router.GET("/health", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	for _, path := range []string{"/users/1", "/users/2", "/users/1", "/users/1"} {
+		if w := serve(path); w.Body.String() != "user "+path[len("/users/"):] {
+			t.Errorf("%s: unexpected body %q", path, w.Body.String())
+		}
+	}
+	serve("/missing")
+	stats := router.MatchCacheStats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	serve("/health")
+	if stats := router.MatchCacheStats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected the least recently used match evicted, got %+v", stats)
+	}
+
+	router.Replace("GET", "/health", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "replaced")
+	})
+	if stats := router.MatchCacheStats(); stats.Entries != 0 {
+		t.Errorf("Expected route changes to empty the cache, got %+v", stats)
+	}
+	if w := serve("/health"); w.Body.String() != "replaced" {
+		t.Errorf("Expected the replaced handler, got %q", w.Body.String())
+	}
+	router.Remove("GET", "/health")
+	if w := serve("/health"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after Remove, got %d", w.Code)
+	}
+}
+
+func TestMatchCacheArena(t *testing.T) {
+	EnableRequestArena = true
+	defer func() { EnableRequestArena = false }()
+
+	router := NewRouter[CustomData]()
+	router.SetMatchCache(16)
+	router.GET("/a/:x/b/:y", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("x")+ctx.Param("y"))
+	})
+	for _, tt := range []struct{ path, body string }{
+		{"/a/1/b/2", "12"},
+		{"/a/3/b/4", "34"},
+		{"/a/1/b/2", "12"},
+		{"/a/3/b/4", "34"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+}
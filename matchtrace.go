@@ -0,0 +1,130 @@
+package octo
+
+import (
+	"strings"
+)
+
+// MatchStep is one step of the route tree traversal for a request
+type MatchStep struct {
+	// Segment is the path segment tested (several segments for wildcards)
+	Segment string
+	// Kind is the node type tested: static, pattern, param, wildcard, miss
+	// when nothing matched, or method for the final handler lookup
+	Kind string
+	// Detail explains the outcome, e.g. the pattern tried
+	Detail string
+}
+
+func (s MatchStep) String() string {
+	if s.Detail == "" {
+		return s.Kind + " " + s.Segment
+	}
+	return s.Kind + " " + s.Segment + " (" + s.Detail + ")"
+}
+
+// MatchTrace records how a request was matched against the route tree
+type MatchTrace struct {
+	Method string
+	Path   string
+	Steps  []MatchStep
+	// Route is the pattern of the matched route, "" when none matched
+	Route string
+}
+
+// step records a traversal step; a nil trace records nothing
+func (t *MatchTrace) step(segment, kind, detail string) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, MatchStep{Segment: segment, Kind: kind, Detail: detail})
+}
+
+// String renders the trace one step per line
+func (t *MatchTrace) String() string {
+	var b strings.Builder
+	b.WriteString(t.Method + " " + t.Path + "\n")
+	for _, s := range t.Steps {
+		b.WriteString("  " + s.String() + "\n")
+	}
+	if t.Route == "" {
+		b.WriteString("=> no route\n")
+	} else {
+		b.WriteString("=> " + t.Route + "\n")
+	}
+	return b.String()
+}
+
+// tracePatterns records the embedded parameter patterns tried for segment,
+// in the order matchPattern tries them
+func (n *node[V]) tracePatterns(segment string, trace *MatchTrace) {
+	for _, p := range n.patterns {
+		if n.paramChild != nil && p.child.priority < n.paramChild.priority {
+			trace.step(segment, "pattern", p.source+": skipped, lower priority than the parameter")
+			continue
+		}
+		if _, ok := p.match(segment, nil); ok {
+			trace.step(segment, "pattern", p.source+": matched")
+			return
+		}
+		trace.step(segment, "pattern", p.source+": no match")
+	}
+}
+
+// TraceMatch matches method and path against the routes like a request
+// would, without running anything, and returns each step of the traversal:
+// segments tested, node types matched and fallbacks tried. Use it to find out
+// why a URL matches an unexpected route. The path is matched as given, before
+// the PathOptions normalization.
+func (r *Router[V]) TraceMatch(method, path string) *MatchTrace {
+	return r.traceParts(method, path, splitPath(path))
+}
+
+func (r *Router[V]) traceParts(method, path string, parts []string) *MatchTrace {
+	trace := &MatchTrace{Method: method, Path: path}
+	r.treeMu.RLock()
+	defer r.treeMu.RUnlock()
+	cur, _, ok := r.walk(r.root, parts, nil, trace)
+	switch {
+	case !ok:
+	case !cur.isLeaf:
+		trace.step(method, "miss", "the path is a prefix of routes but no route")
+	case cur.handler(method) == nil:
+		trace.step(method, "method", "no handler for the method")
+	default:
+		trace.step(method, "method", "")
+		trace.Route = cur.handler(method).path
+	}
+	return trace
+}
+
+// SetMatchTrace logs the route matching steps of every request at debug
+// level to the LogRouter subsystem. It doubles the matching work; enable it
+// only while debugging.
+func (r *Router[V]) SetMatchTrace(on bool) {
+	r.matchTrace.Store(on)
+}
+
+func logMatchTrace(trace *MatchTrace) {
+	steps := make([]string, len(trace.Steps))
+	for i, s := range trace.Steps {
+		steps[i] = s.String()
+	}
+	routerLogger := SubsystemLogger(LogRouter)
+	if EnableLoggerCheck {
+		if routerLogger != nil {
+			routerLogger.Debug().
+				Str("method", trace.Method).
+				Str("path", trace.Path).
+				Str("route", trace.Route).
+				Strs("steps", steps).
+				Msg("[octo] route match trace")
+		}
+	} else {
+		routerLogger.Debug().
+			Str("method", trace.Method).
+			Str("path", trace.Path).
+			Str("route", trace.Route).
+			Strs("steps", steps).
+			Msg("[octo] route match trace")
+	}
+}
@@ -0,0 +1,63 @@
+package octo
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTraceMatch(t *testing.T) {
+	router := NewRouter[CustomData]()
+	h := func(ctx *Ctx[CustomData]) {}
+	router.GET("/files/img-:id", h)
+	router.GET("/files/:name", h).Priority(1)
+	router.GET("/assets/*path", h)
+	router.GET("/docs/:page", h)
+
+	tests := []struct {
+		path  string
+		route string
+		steps []string
+	}{
+		{"/files/img-42", "/files/:name", []string{
+			"static files",
+			"pattern img-42 (img-:id: skipped, lower priority than the parameter)",
+			"param img-42",
+			"method GET",
+		}},
+		{"/assets/css/site.css", "/assets/*path", []string{
+			"static assets",
+			"wildcard css/site.css (rest of the path)",
+			"method GET",
+		}},
+		{"/nope", "", []string{
+			"miss nope (no static, pattern, param or wildcard child)",
+		}},
+	}
+	for _, tt := range tests {
+		trace := router.TraceMatch("GET", tt.path)
+		var steps []string
+		for _, s := range trace.Steps {
+			steps = append(steps, s.String())
+		}
+		if trace.Route != tt.route || strings.Join(steps, "\n") != strings.Join(tt.steps, "\n") {
+			t.Errorf("%s: unexpected trace\n%s", tt.path, trace)
+		}
+	}
+	if trace := router.TraceMatch("POST", "/docs/intro"); trace.Route != "" || !strings.Contains(trace.String(), "no handler for the method") {
+		t.Errorf("Expected a method miss, got\n%s", trace)
+	}
+
+	var logs bytes.Buffer
+	routerLogger := zerolog.New(&logs)
+	SetSubsystemLogger(LogRouter, &routerLogger)
+	defer SetSubsystemLogger(LogRouter, nil)
+	router.SetMatchTrace(true)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/docs/intro", nil))
+	if !strings.Contains(logs.String(), `"steps":["static docs","param intro","method GET"]`) {
+		t.Errorf("Expected the match trace in the router log, got %q", logs.String())
+	}
+}
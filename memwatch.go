@@ -0,0 +1,205 @@
+package octo
+
+import (
+	"context"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryWatchdogConfig configures a MemoryWatchdog
+type MemoryWatchdogConfig struct {
+	// Limit is the heap size, in bytes, above which the watchdog sheds load
+	Limit uint64
+	// Resume is the heap size under which shedding stops (90% of Limit when
+	// zero)
+	Resume uint64
+	// Interval between heap samples (1s when zero)
+	Interval time.Duration
+	// Shed lists the classes rejected while over the limit (ClassBatch when
+	// empty)
+	Shed []RequestClass
+	// RetryAfter is sent with 503s (1s when zero)
+	RetryAfter time.Duration
+	// OnEvent is called when the heap crosses the limit and when it is back
+	// under Resume, e.g. to record metrics. Events are also logged.
+	OnEvent func(MemoryEvent)
+}
+
+// MemoryEvent reports a change of state of a MemoryWatchdog
+type MemoryEvent struct {
+	// Overloaded is true when the limit was crossed, false on recovery
+	Overloaded bool   `json:"overloaded"`
+	HeapBytes  uint64 `json:"heap_bytes"`
+	Limit      uint64 `json:"limit"`
+	// Freed is the heap released by flushing caches and collecting, on
+	// overload
+	Freed uint64 `json:"freed,omitempty"`
+	// Shed is the number of requests rejected during the overload, on
+	// recovery
+	Shed int64 `json:"shed,omitempty"`
+}
+
+// MemoryWatchdog samples the heap size and, over its limit, rejects the
+// low-priority classes of the routers it is installed on, flushes their
+// Static caches and forces a collection, to avoid being killed for running
+// out of memory during traffic spikes
+type MemoryWatchdog struct {
+	cfg        MemoryWatchdogConfig
+	shedClass  [ClassCritical + 1]bool
+	retryAfter string
+	overloaded atomic.Bool
+	shed       atomic.Int64
+
+	mu sync.Mutex
+	// flushers release memory on overload (Static caches)
+	flushers []func()
+	running  bool
+	// readHeap returns the heap size; replaced in tests
+	readHeap func() uint64
+}
+
+// NewMemoryWatchdog creates a MemoryWatchdog; install it with
+// Router.SetMemoryWatchdog
+func NewMemoryWatchdog(cfg MemoryWatchdogConfig) *MemoryWatchdog {
+	if cfg.Resume == 0 || cfg.Resume > cfg.Limit {
+		cfg.Resume = cfg.Limit / 10 * 9
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if len(cfg.Shed) == 0 {
+		cfg.Shed = []RequestClass{ClassBatch}
+	}
+	w := &MemoryWatchdog{cfg: cfg, retryAfter: retryAfterSeconds(cfg.RetryAfter), readHeap: heapBytes}
+	for _, class := range cfg.Shed {
+		if class >= 0 && int(class) < len(w.shedClass) {
+			w.shedClass[class] = true
+		}
+	}
+	return w
+}
+
+// SetMemoryWatchdog installs w on the router: routes of the shed classes are
+// rejected while the heap is over the limit, and Static caches are flushed
+// when it crosses it. The watchdog samples between Router.Start (or Serve)
+// and Shutdown; call Run yourself when serving otherwise.
+func (r *Router[V]) SetMemoryWatchdog(w *MemoryWatchdog) {
+	r.memoryWatchdog = w
+	w.mu.Lock()
+	w.flushers = append(w.flushers, func() { r.FlushStaticCache() })
+	w.mu.Unlock()
+	var stop context.CancelFunc
+	r.OnStart(func(context.Context) error {
+		var ctx context.Context
+		ctx, stop = context.WithCancel(context.Background())
+		go w.Run(ctx)
+		return nil
+	}).Name("memory watchdog")
+	r.OnShutdown(func(context.Context) error {
+		if stop != nil {
+			stop()
+		}
+		return nil
+	}).Name("memory watchdog")
+}
+
+// Run samples the heap every Interval until ctx is done. It returns at once
+// if the watchdog is already running.
+func (w *MemoryWatchdog) Run(ctx context.Context) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.running = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Overloaded reports whether the heap is over the limit
+func (w *MemoryWatchdog) Overloaded() bool {
+	return w.overloaded.Load()
+}
+
+// Check samples the heap once and updates the state of the watchdog
+func (w *MemoryWatchdog) Check() {
+	heap := w.readHeap()
+	switch {
+	case !w.overloaded.Load() && heap > w.cfg.Limit:
+		w.shed.Store(0)
+		w.overloaded.Store(true)
+		w.mu.Lock()
+		flushers := append([]func(){}, w.flushers...)
+		w.mu.Unlock()
+		for _, flush := range flushers {
+			flush()
+		}
+		debug.FreeOSMemory()
+		event := MemoryEvent{Overloaded: true, HeapBytes: heap, Limit: w.cfg.Limit}
+		if after := w.readHeap(); after < heap {
+			event.Freed = heap - after
+		}
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Warn().Uint64("heap", heap).Uint64("limit", w.cfg.Limit).Uint64("freed", event.Freed).Msg("[octo] memory limit exceeded, shedding load")
+			}
+		} else {
+			serverLogger.Warn().Uint64("heap", heap).Uint64("limit", w.cfg.Limit).Uint64("freed", event.Freed).Msg("[octo] memory limit exceeded, shedding load")
+		}
+		if w.cfg.OnEvent != nil {
+			w.cfg.OnEvent(event)
+		}
+	case w.overloaded.Load() && heap < w.cfg.Resume:
+		w.overloaded.Store(false)
+		event := MemoryEvent{HeapBytes: heap, Limit: w.cfg.Limit, Shed: w.shed.Load()}
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Info().Uint64("heap", heap).Int64("shed", event.Shed).Msg("[octo] memory back under limit")
+			}
+		} else {
+			serverLogger.Info().Uint64("heap", heap).Int64("shed", event.Shed).Msg("[octo] memory back under limit")
+		}
+		if w.cfg.OnEvent != nil {
+			w.cfg.OnEvent(event)
+		}
+	}
+}
+
+// sheds reports whether a request of class must be rejected, counting it
+func (w *MemoryWatchdog) sheds(class RequestClass) bool {
+	if !w.overloaded.Load() || class < 0 || int(class) >= len(w.shedClass) || !w.shedClass[class] {
+		return false
+	}
+	w.shed.Add(1)
+	return true
+}
+
+// heapBytes returns the memory occupied by heap objects, live or not yet
+// swept, without stopping the world
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
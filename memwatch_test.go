@@ -0,0 +1,68 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMemoryWatchdog(t *testing.T) {
+	var events []MemoryEvent
+	w := NewMemoryWatchdog(MemoryWatchdogConfig{
+		Limit:   1000,
+		OnEvent: func(e MemoryEvent) { events = append(events, e) },
+	})
+	heap := uint64(500)
+	w.readHeap = func() uint64 { return heap }
+
+	router := NewRouter[CustomData]()
+	router.SetMemoryWatchdog(w)
+	router.GET("/export", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "export")
+	}).Class(ClassBatch)
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "page")
+	})
+	router.StaticWithConfig("/assets", fstest.MapFS{"a.txt": {Data: []byte("cached")}}, StaticConfig[CustomData]{CacheMaxBytes: 1 << 10})
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	get("/assets/a.txt")
+	if router.StaticStats()[0].Bytes == 0 {
+		t.Fatal("Expected the static file to be cached")
+	}
+
+	heap = 1500
+	w.Check()
+	if !w.Overloaded() || len(events) != 1 || !events[0].Overloaded {
+		t.Fatalf("Expected an overload event, got %+v", events)
+	}
+	if router.StaticStats()[0].Bytes != 0 {
+		t.Error("Expected the static cache to be flushed on overload")
+	}
+	if code := get("/export"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected batch route to be shed, got %d", code)
+	}
+	if code := get("/page"); code != http.StatusOK {
+		t.Errorf("Expected interactive route to be served, got %d", code)
+	}
+
+	// Between Resume (900) and Limit the state holds
+	heap = 950
+	w.Check()
+	if !w.Overloaded() {
+		t.Error("Expected the overload to hold above Resume")
+	}
+	heap = 800
+	w.Check()
+	if w.Overloaded() || len(events) != 2 || events[1].Shed != 1 {
+		t.Errorf("Expected a recovery event counting one shed request, got %+v", events)
+	}
+	if code := get("/export"); code != http.StatusOK {
+		t.Errorf("Expected batch route to be served after recovery, got %d", code)
+	}
+}
@@ -0,0 +1,247 @@
+package octo
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// QuotaExceededHeader is set by soft-enforced quotas once usage is over the limit
+const QuotaExceededHeader = "X-Quota-Exceeded"
+
+// Usage is the metered consumption of one key in one period
+type Usage struct {
+	Key      string `json:"key"`
+	Period   string `json:"period"`
+	Requests int64  `json:"requests"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Quota caps usage per period. Zero fields are unlimited.
+type Quota struct {
+	Requests int64 `json:"requests,omitempty"`
+	Bytes    int64 `json:"bytes,omitempty"`
+}
+
+// UsageStore persists usage counters
+type UsageStore interface {
+	Add(key, period string, requests, bytes int64) error
+	Get(key, period string) (Usage, error)
+}
+
+// MemoryUsageStore is an in-process UsageStore, mostly for tests and single
+// instance deployments
+type MemoryUsageStore struct {
+	mu    sync.Mutex
+	usage map[[2]string]*Usage
+}
+
+// NewMemoryUsageStore creates an empty in-memory store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{usage: make(map[[2]string]*Usage)}
+}
+
+func (s *MemoryUsageStore) Add(key, period string, requests, bytes int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.usage[[2]string{key, period}]
+	if !ok {
+		u = &Usage{Key: key, Period: period}
+		s.usage[[2]string{key, period}] = u
+	}
+	u.Requests += requests
+	u.Bytes += bytes
+	return nil
+}
+
+func (s *MemoryUsageStore) Get(key, period string) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.usage[[2]string{key, period}]; ok {
+		return *u, nil
+	}
+	return Usage{Key: key, Period: period}, nil
+}
+
+// QuotaMode selects how quotas are enforced
+type QuotaMode int
+
+const (
+	// QuotaHard rejects requests over quota with 429 err_quota_exceeded
+	QuotaHard QuotaMode = iota
+	// QuotaSoft lets requests through and flags them with QuotaExceededHeader
+	QuotaSoft
+)
+
+// MonthlyPeriod buckets usage per calendar month (UTC)
+func MonthlyPeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// DailyPeriod buckets usage per day (UTC)
+func DailyPeriod(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// MeterConfig configures a Meter
+type MeterConfig[V any] struct {
+	// Store defaults to a MemoryUsageStore
+	Store UsageStore
+	// Key resolves the API key to meter (default ctx.Principal()). Requests
+	// without a key are not metered.
+	Key func(ctx *Ctx[V]) string
+	// Period buckets usage (default MonthlyPeriod)
+	Period func(t time.Time) string
+	// Quota returns the quota for a key; nil means unlimited
+	Quota func(key string) Quota
+	Mode  QuotaMode
+	// Admin authorizes reading other keys' usage on GET /usage/:key
+	Admin func(ctx *Ctx[V]) bool
+}
+
+// Meter counts requests and bytes per API key and enforces quotas
+type Meter[V any] struct {
+	cfg MeterConfig[V]
+}
+
+// NewMeter creates a Meter
+func NewMeter[V any](cfg MeterConfig[V]) *Meter[V] {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryUsageStore()
+	}
+	if cfg.Key == nil {
+		cfg.Key = func(ctx *Ctx[V]) string { return ctx.Principal() }
+	}
+	if cfg.Period == nil {
+		cfg.Period = MonthlyPeriod
+	}
+	return &Meter[V]{cfg: cfg}
+}
+
+// Usage returns the current period usage of key
+func (m *Meter[V]) Usage(key string) (Usage, error) {
+	return m.cfg.Store.Get(key, m.cfg.Period(time.Now()))
+}
+
+// exceeded reports which quota dimension usage is over, or ""
+func (q Quota) exceeded(u Usage) string {
+	if q.Requests > 0 && u.Requests >= q.Requests {
+		return "requests"
+	}
+	if q.Bytes > 0 && u.Bytes >= q.Bytes {
+		return "bytes"
+	}
+	return ""
+}
+
+// meteringResponseWriter counts response body bytes
+type meteringResponseWriter struct {
+	http.ResponseWriter
+	written int64
+}
+
+func (w *meteringResponseWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *meteringResponseWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (m *Meter[V]) logStoreError(err error, key string) {
+	serverLogger := SubsystemLogger(LogServer)
+	if EnableLoggerCheck {
+		if serverLogger != nil {
+			serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] usage store failed")
+		}
+	} else {
+		serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] usage store failed")
+	}
+}
+
+// Middleware meters every request with a key: one request plus the request
+// and response body bytes. Quotas are checked before the handler runs; store
+// errors fail open.
+func (m *Meter[V]) Middleware() MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			key := m.cfg.Key(ctx)
+			if key == "" {
+				next(ctx)
+				return
+			}
+			period := m.cfg.Period(time.Now())
+
+			if m.cfg.Quota != nil {
+				usage, err := m.cfg.Store.Get(key, period)
+				if err != nil {
+					m.logStoreError(err, key)
+				} else if dim := m.cfg.Quota(key).exceeded(usage); dim != "" {
+					ctx.SetHeader(QuotaExceededHeader, dim)
+					if m.cfg.Mode == QuotaHard {
+						ctx.SendError("err_quota_exceeded", nil)
+						return
+					}
+				}
+			}
+
+			original := ctx.ResponseWriter.ResponseWriter
+			mw := &meteringResponseWriter{ResponseWriter: original}
+			ctx.ResponseWriter.ResponseWriter = mw
+			next(ctx)
+			ctx.ResponseWriter.ResponseWriter = original
+
+			bytes := mw.written
+			if ctx.Request.ContentLength > 0 {
+				bytes += ctx.Request.ContentLength
+			}
+			if err := m.cfg.Store.Add(key, period, 1, bytes); err != nil {
+				m.logStoreError(err, key)
+			}
+		}
+	}
+}
+
+// usageResponse is returned by the usage endpoints
+type usageResponse struct {
+	Usage
+	Quota *Quota `json:"quota,omitempty"`
+}
+
+func (m *Meter[V]) sendUsage(ctx *Ctx[V], key string) {
+	usage, err := m.Usage(key)
+	if err != nil {
+		ctx.SendError("err_internal_error", err)
+		return
+	}
+	resp := usageResponse{Usage: usage}
+	if m.cfg.Quota != nil {
+		q := m.cfg.Quota(key)
+		resp.Quota = &q
+	}
+	ctx.NewJSONResult(resp, nil)
+}
+
+// Mount registers GET /usage (the caller's usage) and GET /usage/:key (any
+// key, guarded by MeterConfig.Admin) on the group
+func (m *Meter[V]) Mount(g *Group[V]) {
+	g.GET("/usage", func(ctx *Ctx[V]) {
+		key := m.cfg.Key(ctx)
+		if key == "" {
+			ctx.Send401()
+			return
+		}
+		m.sendUsage(ctx, key)
+	})
+	g.GET("/usage/:key", func(ctx *Ctx[V]) {
+		if m.cfg.Admin == nil || !m.cfg.Admin(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		m.sendUsage(ctx, ctx.Param("key"))
+	})
+}
@@ -0,0 +1,64 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMeterQuotas(t *testing.T) {
+	for _, mode := range []QuotaMode{QuotaHard, QuotaSoft} {
+		meter := NewMeter(MeterConfig[CustomData]{
+			Key:   func(ctx *Ctx[CustomData]) string { return ctx.GetHeader("X-Api-Key") },
+			Quota: func(key string) Quota { return Quota{Requests: 2} },
+			Mode:  mode,
+			Admin: func(ctx *Ctx[CustomData]) bool { return ctx.GetHeader("X-Api-Key") == "root" },
+		})
+		router := NewRouter[CustomData]()
+		router.Use(meter.Middleware())
+		router.GET("/data", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, "12345")
+		})
+		meter.Mount(router.Group("/account"))
+
+		hit := func(path, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("X-Api-Key", key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		hit("/data", "k1")
+		hit("/data", "k1")
+		w := hit("/data", "k1")
+		switch mode {
+		case QuotaHard:
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("Expected hard quota to reject, got %d", w.Code)
+			}
+		case QuotaSoft:
+			if w.Code != http.StatusOK || w.Header().Get(QuotaExceededHeader) != "requests" {
+				t.Errorf("Expected soft quota to flag, got %d %v", w.Code, w.Header())
+			}
+		}
+
+		usage, _ := meter.Usage("k1")
+		if usage.Bytes < 10 {
+			t.Errorf("Expected response bytes to be metered, got %d", usage.Bytes)
+		}
+
+		if w := hit("/account/usage/k1", "k2"); w.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for non-admin usage query, got %d", w.Code)
+		}
+		w = hit("/account/usage/k1", "root")
+		var resp struct {
+			Data usageResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Data.Key != "k1" || resp.Data.Requests != usage.Requests || resp.Data.Quota == nil {
+			t.Errorf("Unexpected admin usage response: %s", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package octo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Minifier shrinks a text response of the given media type (e.g. "text/css")
+type Minifier interface {
+	Minify(mediaType string, src []byte) ([]byte, error)
+}
+
+// MinifyConfig configures MinifyMiddleware
+type MinifyConfig struct {
+	// Minifier rewrites the responses (BasicMinifier when nil)
+	Minifier Minifier
+	// Types are the media types to minify (text/html and text/css when empty)
+	Types []string
+	// MaxSize is the largest response buffered for minification; larger
+	// responses are sent as they are (1MB when zero)
+	MaxSize int
+}
+
+// mediaCaptureWriter holds a response of one of the given media types until
+// the handler returns, so it can be rewritten. Other responses, and responses
+// that are flushed or grow past the size limit, are written through
+// unchanged.
+type mediaCaptureWriter struct {
+	http.ResponseWriter
+	types     []string
+	maxSize   int
+	status    int
+	buf       bytes.Buffer
+	mediaType string
+	decided   bool
+	buffering bool
+}
+
+// decide chooses between buffering and writing through, once the headers are
+// final
+func (w *mediaCaptureWriter) decide(data []byte) {
+	w.decided = true
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Encoding") != "" {
+		return
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" && len(data) > 0 {
+		contentType = http.DetectContentType(data)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range w.types {
+		if mediaType == t {
+			w.mediaType = mediaType
+			w.buffering = true
+			return
+		}
+	}
+}
+
+func (w *mediaCaptureWriter) WriteHeader(statusCode int) {
+	if !w.decided {
+		w.decide(nil)
+	}
+	if w.buffering {
+		if w.status == 0 {
+			w.status = statusCode
+		}
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *mediaCaptureWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decide(data)
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.buf.Len()+len(data) > w.maxSize {
+		if err := w.passThrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+// Flush gives up minification: a streamed response is written as it comes
+func (w *mediaCaptureWriter) Flush() {
+	if w.buffering {
+		w.passThrough()
+	}
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Hijack hands the connection over for upgrades such as WebSocket
+func (w *mediaCaptureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.buffering = false
+		return hj.Hijack()
+	}
+	return nil, nil, errors.New("ResponseWriter does not implement http.Hijacker")
+}
+
+// captureMedia runs next with responses of the given media types captured.
+// It returns nil when the response was written through, otherwise the caller
+// writes the final body with finish.
+func captureMedia[V any](ctx *Ctx[V], next HandlerFunc[V], types []string, maxSize int) *mediaCaptureWriter {
+	original := ctx.ResponseWriter.ResponseWriter
+	w := &mediaCaptureWriter{ResponseWriter: original, types: types, maxSize: maxSize}
+	ctx.ResponseWriter.ResponseWriter = w
+	next(ctx)
+	ctx.ResponseWriter.ResponseWriter = original
+	if !w.buffering {
+		return nil
+	}
+	return w
+}
+
+// finish writes the captured response with body in place of the original
+func (w *mediaCaptureWriter) finish(body []byte) {
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Length") != "" {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(status)
+	w.ResponseWriter.Write(body)
+}
+
+// passThrough writes what was buffered unchanged and stops buffering
+func (w *mediaCaptureWriter) passThrough() error {
+	w.buffering = false
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+	return err
+}
+
+// MinifyMiddleware minifies the text responses of the configured media types.
+// Responses are buffered up to MaxSize; compressed, flushed (streaming) and
+// HEAD responses are left alone, and a failing minifier sends the original
+// body.
+func MinifyMiddleware[V any](cfg MinifyConfig) MiddlewareFunc[V] {
+	if cfg.Minifier == nil {
+		cfg.Minifier = BasicMinifier{}
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = []string{"text/html", "text/css"}
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1 << 20
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if ctx.Request.Method == http.MethodHead {
+				next(ctx)
+				return
+			}
+			w := captureMedia(ctx, next, cfg.Types, cfg.MaxSize)
+			if w == nil {
+				return
+			}
+			body := w.buf.Bytes()
+			if minified, err := cfg.Minifier.Minify(w.mediaType, body); err == nil {
+				body = minified
+			} else {
+				routerLogger := SubsystemLogger(LogRouter)
+				if EnableLoggerCheck {
+					if routerLogger != nil {
+						routerLogger.Warn().Err(err).Str("type", w.mediaType).Msg("[octo] minification failed")
+					}
+				} else {
+					routerLogger.Warn().Err(err).Str("type", w.mediaType).Msg("[octo] minification failed")
+				}
+			}
+			w.finish(body)
+		}
+	}
+}
+
+// BasicMinifier is a conservative minifier for HTML and CSS: it removes
+// comments and collapses whitespace, leaving pre, textarea, script and style
+// contents of HTML untouched. Other media types are returned unchanged; plug
+// in a full minifier for JavaScript.
+type BasicMinifier struct{}
+
+func (BasicMinifier) Minify(mediaType string, src []byte) ([]byte, error) {
+	switch mediaType {
+	case "text/html":
+		return minifyHTML(src), nil
+	case "text/css":
+		return minifyCSS(src), nil
+	}
+	return src, nil
+}
+
+// rawHTMLElements keep their content verbatim
+var rawHTMLElements = []string{"pre", "textarea", "script", "style"}
+
+func minifyHTML(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inTag := false
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			out = append(out, c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case inTag && (c == '"' || c == '\''):
+			quote = c
+			out = append(out, c)
+			continue
+		case inTag && c == '>':
+			inTag = false
+			out = append(out, c)
+			continue
+		case !inTag && c == '<':
+			if bytes.HasPrefix(src[i:], []byte("<!--")) && !bytes.HasPrefix(src[i:], []byte("<!--[if")) {
+				end := bytes.Index(src[i+4:], []byte("-->"))
+				if end == -1 {
+					return out
+				}
+				i += 4 + end + 2
+				continue
+			}
+			if name := rawElementAt(src[i:]); name != "" {
+				end := indexFold(src[i+1:], "</"+name)
+				if end == -1 {
+					return append(out, src[i:]...)
+				}
+				// Copy up to the closing tag, which is then handled as a tag
+				out = append(out, src[i:i+1+end]...)
+				i += end
+				continue
+			}
+			inTag = true
+			out = append(out, c)
+			continue
+		}
+		if isHTMLSpace(c) {
+			j := i
+			newline := false
+			for j < len(src) && isHTMLSpace(src[j]) {
+				newline = newline || src[j] == '\n'
+				j++
+			}
+			i = j - 1
+			// Merge with the whitespace left before a removed comment
+			if n := len(out); n > 0 && (out[n-1] == ' ' || out[n-1] == '\n') {
+				if newline && !inTag {
+					out[n-1] = '\n'
+				}
+				continue
+			}
+			if newline && !inTag {
+				out = append(out, '\n')
+			} else {
+				out = append(out, ' ')
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// rawElementAt returns the name of the raw element whose start tag begins s
+func rawElementAt(s []byte) string {
+	for _, name := range rawHTMLElements {
+		if len(s) > len(name)+1 && strings.EqualFold(string(s[1:1+len(name)]), name) {
+			if c := s[1+len(name)]; c == '>' || isHTMLSpace(c) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// indexFold is bytes.Index ignoring ASCII case
+func indexFold(s []byte, sub string) int {
+	b := []byte(sub)
+	for i := 0; i+len(b) <= len(s); i++ {
+		if bytes.EqualFold(s[i:i+len(b)], b) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func minifyCSS(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	space := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"' || c == '\'':
+			if space && strings.IndexByte("{};,>:", out[len(out)-1]) == -1 {
+				out = append(out, ' ')
+			}
+			space = false
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			end := min(j+1, len(src))
+			out = append(out, src[i:end]...)
+			i = end - 1
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end == -1 {
+				return out
+			}
+			i += 2 + end + 1
+			space = space || len(out) > 0
+		case isHTMLSpace(c):
+			space = len(out) > 0
+		default:
+			if strings.IndexByte("{};,>", c) >= 0 {
+				space = false
+				if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+					out = out[:len(out)-1]
+				}
+			} else if space && strings.IndexByte("{};,>:", out[len(out)-1]) == -1 {
+				out = append(out, ' ')
+			}
+			space = false
+			out = append(out, c)
+		}
+	}
+	return out
+}
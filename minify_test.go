@@ -0,0 +1,52 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMinifyMiddleware(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(MinifyMiddleware[CustomData](MinifyConfig{}))
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		ctx.SendData(http.StatusOK, "text/html; charset=utf-8", []byte("<html>\n  <!-- note -->\n  <body   class=\"a  b\">\n    <p>Hello   world</p>\n    <pre>  keep\n  this </pre>\n  </body>\n</html>\n"))
+	})
+	router.GET("/style.css", func(ctx *Ctx[CustomData]) {
+		ctx.SendData(http.StatusOK, "text/css", []byte("/* base */\nbody {\n  color: red;\n  content: \"a  b\";\n}\n"))
+	})
+	router.GET("/data", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSON(http.StatusOK, map[string]string{"a": "b  c"})
+	})
+	router.GET("/stream", func(ctx *Ctx[CustomData]) {
+		ctx.ResponseWriter.Header().Set("Content-Type", "text/html")
+		ctx.ResponseWriter.Write([]byte("<p>  one </p>"))
+		ctx.ResponseWriter.Flush()
+		ctx.ResponseWriter.Write([]byte("<p>  two </p>"))
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/page")
+	want := "<html>\n<body class=\"a  b\">\n<p>Hello world</p>\n<pre>  keep\n  this </pre>\n</body>\n</html>\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected minified HTML %q, got %q", want, w.Body.String())
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" && cl != "88" {
+		t.Errorf("Expected Content-Length to match the minified body, got %s", cl)
+	}
+
+	if w := get("/style.css"); w.Body.String() != `body{color:red;content:"a  b"}` {
+		t.Errorf("Expected minified CSS, got %q", w.Body.String())
+	}
+	if w := get("/data"); w.Body.String() != `{"a":"b  c"}` {
+		t.Errorf("Expected JSON to be left alone, got %q", w.Body.String())
+	}
+	if w := get("/stream"); w.Body.String() != "<p>  one </p><p>  two </p>" {
+		t.Errorf("Expected flushed response to be written as is, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,123 @@
+package octo
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ClientCertConfig configures ClientCertMiddleware. When both Roots and
+// Fingerprints are set a certificate must satisfy both.
+type ClientCertConfig struct {
+	// Roots verifies the presented chain (client auth usage)
+	Roots *x509.CertPool
+	// Fingerprints is an allowlist of SHA-256 leaf certificate fingerprints (hex)
+	Fingerprints []string
+	// Optional lets requests without a client certificate through
+	Optional bool
+}
+
+// ClientCertInfo describes the authenticated client certificate
+type ClientCertInfo struct {
+	CommonName     string
+	Subject        string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string
+	Fingerprint    string
+	Certificate    *x509.Certificate
+}
+
+type clientCertLocalKey struct{}
+
+// ClientCertTLSConfig returns a server TLS config requesting client
+// certificates, verified against roots. With require=false the handshake
+// succeeds without a certificate and ClientCertMiddleware decides.
+func ClientCertTLSConfig(roots *x509.CertPool, require bool) *tls.Config {
+	cfg := &tls.Config{
+		ClientCAs:  roots,
+		MinVersion: tls.VersionTLS12,
+	}
+	if require {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
+
+// CertificateFingerprint returns the SHA-256 hex fingerprint of a certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClientCertMiddleware authenticates requests with their TLS client
+// certificate. Missing certificates get a 401, rejected ones a 403.
+func ClientCertMiddleware[V any](cfg ClientCertConfig) MiddlewareFunc[V] {
+	allowed := make(map[string]struct{}, len(cfg.Fingerprints))
+	for _, fp := range cfg.Fingerprints {
+		allowed[strings.ToLower(strings.ReplaceAll(fp, ":", ""))] = struct{}{}
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			state := ctx.Request.TLS
+			if state == nil || len(state.PeerCertificates) == 0 {
+				if cfg.Optional {
+					next(ctx)
+					return
+				}
+				ctx.SendError("err_unauthorized", errors.New("client certificate required"))
+				return
+			}
+			leaf := state.PeerCertificates[0]
+			fingerprint := CertificateFingerprint(leaf)
+
+			if cfg.Roots != nil {
+				intermediates := x509.NewCertPool()
+				for _, cert := range state.PeerCertificates[1:] {
+					intermediates.AddCert(cert)
+				}
+				_, err := leaf.Verify(x509.VerifyOptions{
+					Roots:         cfg.Roots,
+					Intermediates: intermediates,
+					KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+				})
+				if err != nil {
+					ctx.SendError("err_forbidden", err)
+					return
+				}
+			}
+			if len(allowed) > 0 {
+				if _, ok := allowed[fingerprint]; !ok {
+					ctx.SendError("err_forbidden", errors.New("client certificate not allowed"))
+					return
+				}
+			}
+
+			info := &ClientCertInfo{
+				CommonName:     leaf.Subject.CommonName,
+				Subject:        leaf.Subject.String(),
+				DNSNames:       leaf.DNSNames,
+				EmailAddresses: leaf.EmailAddresses,
+				Fingerprint:    fingerprint,
+				Certificate:    leaf,
+			}
+			for _, uri := range leaf.URIs {
+				info.URIs = append(info.URIs, uri.String())
+			}
+			ctx.setLocal(clientCertLocalKey{}, info)
+			next(ctx)
+		}
+	}
+}
+
+// ClientCertificate returns the client certificate authenticated by
+// ClientCertMiddleware, or nil.
+func (c *Ctx[V]) ClientCertificate() *ClientCertInfo {
+	info, _ := c.getLocal(clientCertLocalKey{}).(*ClientCertInfo)
+	return info
+}
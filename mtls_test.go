@@ -0,0 +1,89 @@
+package octo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{cn + ".internal"},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	ca, caKey := newTestCert(t, "ca", nil, nil, true)
+	client, _ := newTestCert(t, "billing", ca, caKey, false)
+	otherCA, otherKey := newTestCert(t, "other", nil, nil, true)
+	stranger, _ := newTestCert(t, "stranger", otherCA, otherKey, false)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	router := NewRouter[CustomData]()
+	router.GET("/internal", func(ctx *Ctx[CustomData]) {
+		info := ctx.ClientCertificate()
+		ctx.SendString(http.StatusOK, info.CommonName+" "+info.DNSNames[0])
+	}, ClientCertMiddleware[CustomData](ClientCertConfig{Roots: roots}))
+
+	serve := func(certs ...*x509.Certificate) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/internal", nil)
+		if certs != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without certificate, got %d", w.Code)
+	}
+	if w := serve(stranger); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for untrusted certificate, got %d", w.Code)
+	}
+	if w := serve(client); w.Code != http.StatusOK || w.Body.String() != "billing billing.internal" {
+		t.Errorf("Expected 200 with subject, got %d '%s'", w.Code, w.Body.String())
+	}
+
+	// Fingerprint allowlist
+	router.GET("/pinned", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	}, ClientCertMiddleware[CustomData](ClientCertConfig{Fingerprints: []string{CertificateFingerprint(client)}}))
+	req := httptest.NewRequest("GET", "/pinned", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{stranger}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for certificate outside allowlist, got %d", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// MultipartBuilder assembles a multipart response (multipart/mixed,
+// multipart/byteranges, ...). Part bodies are streamed when the response is sent.
+type MultipartBuilder struct {
+	subtype  string
+	boundary string
+	parts    []multipartPart
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	body   io.Reader
+}
+
+// NewMultipartBuilder creates a builder for multipart/<subtype>
+func NewMultipartBuilder(subtype string) *MultipartBuilder {
+	return &MultipartBuilder{subtype: subtype}
+}
+
+// SetBoundary overrides the randomly generated boundary
+func (m *MultipartBuilder) SetBoundary(boundary string) *MultipartBuilder {
+	m.boundary = boundary
+	return m
+}
+
+// AddPart adds a part with arbitrary headers
+func (m *MultipartBuilder) AddPart(header textproto.MIMEHeader, body io.Reader) *MultipartBuilder {
+	if header == nil {
+		header = textproto.MIMEHeader{}
+	}
+	m.parts = append(m.parts, multipartPart{header: header, body: body})
+	return m
+}
+
+// AddBytes adds a part with the given content type
+func (m *MultipartBuilder) AddBytes(contentType string, data []byte) *MultipartBuilder {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	return m.AddPart(header, bytes.NewReader(data))
+}
+
+// AddJSON adds a JSON encoded part
+func (m *MultipartBuilder) AddJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.AddBytes("application/json", data)
+	return nil
+}
+
+// AddFile adds an attachment part streamed from r
+func (m *MultipartBuilder) AddFile(contentType, filename string, r io.Reader) *MultipartBuilder {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return m.AddPart(header, r)
+}
+
+// AddByteRange adds a multipart/byteranges part covering bytes [start, end] of
+// a resource of the given total size
+func (m *MultipartBuilder) AddByteRange(contentType string, start, end, total int64, r io.Reader) *MultipartBuilder {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, total))
+	return m.AddPart(header, r)
+}
+
+// newWriter creates the multipart writer honoring a custom boundary
+func (m *MultipartBuilder) newWriter(w io.Writer) (*multipart.Writer, error) {
+	mw := multipart.NewWriter(w)
+	if m.boundary != "" {
+		if err := mw.SetBoundary(m.boundary); err != nil {
+			return nil, err
+		}
+	}
+	return mw, nil
+}
+
+// SendMultipart streams the parts of m as the response body
+func (c *Ctx[V]) SendMultipart(statusCode int, m *MultipartBuilder) {
+	if c.done {
+		return
+	}
+	mw, err := m.newWriter(c.ResponseWriter)
+	if err != nil {
+		c.SendError("err_internal_error", err)
+		return
+	}
+	c.SetHeader("Content-Type", "multipart/"+m.subtype+"; boundary="+mw.Boundary())
+	c.SetStatus(statusCode)
+	c.Done()
+
+	for _, part := range m.parts {
+		var pw io.Writer
+		pw, err = mw.CreatePart(part.header)
+		if err != nil {
+			break
+		}
+		if part.body != nil {
+			if _, err = io.Copy(pw, part.body); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
+		if EnableLoggerCheck {
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Msg("[octo] failed to write multipart response")
+			}
+		} else {
+			routerLogger.Error().Err(err).Msg("[octo] failed to write multipart response")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package octo
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendMultipart(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/doc", func(ctx *Ctx[CustomData]) {
+		m := NewMultipartBuilder("mixed")
+		m.AddJSON(map[string]string{"title": "report"})
+		m.AddFile("text/plain", "report.txt", strings.NewReader("hello"))
+		ctx.SendMultipart(http.StatusOK, m)
+	})
+	router.GET("/ranges", func(ctx *Ctx[CustomData]) {
+		m := NewMultipartBuilder("byteranges").SetBoundary("octoboundary")
+		m.AddByteRange("text/plain", 0, 1, 10, strings.NewReader("ab"))
+		m.AddByteRange("text/plain", 8, 9, 10, strings.NewReader("ij"))
+		ctx.SendMultipart(http.StatusPartialContent, m)
+	})
+
+	req := httptest.NewRequest("GET", "/doc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	mediaType, params, err := mime.ParseMediaType(w.Result().Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Unexpected Content-Type: %s", w.Result().Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read first part: %v", err)
+	}
+	data, _ := io.ReadAll(part)
+	if part.Header.Get("Content-Type") != "application/json" || string(data) != `{"title":"report"}` {
+		t.Errorf("Unexpected first part: %v %s", part.Header, string(data))
+	}
+	part, err = reader.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read second part: %v", err)
+	}
+	if part.FileName() != "report.txt" {
+		t.Errorf("Expected filename 'report.txt', got '%s'", part.FileName())
+	}
+
+	req = httptest.NewRequest("GET", "/ranges", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPartialContent {
+		t.Errorf("Expected status 206, got %d", w.Code)
+	}
+	if w.Result().Header.Get("Content-Type") != "multipart/byteranges; boundary=octoboundary" {
+		t.Errorf("Unexpected Content-Type: %s", w.Result().Header.Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "Content-Range: bytes 8-9/10") {
+		t.Errorf("Expected Content-Range in body, got '%s'", w.Body.String())
+	}
+}
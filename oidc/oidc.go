@@ -0,0 +1,264 @@
+// Package oidc implements the OpenID Connect authorization code flow (with
+// state, nonce and PKCE) on top of octo routers.
+//
+// Flow state is kept in a signed cookie, so octo.SetCookieSecret must be
+// configured. The application session is established by Config.OnLogin.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// stateCookieName holds the pending login (state, nonce, PKCE verifier)
+const stateCookieName = "octo_oidc"
+
+// Config configures a Provider
+type Config[V any] struct {
+	// Issuer is the OpenID provider URL, used for discovery and iss validation
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the absolute URL of the mounted callback route
+	RedirectURL string
+	// Scopes requested in addition to "openid"
+	Scopes []string
+	// OnLogin establishes the application session from the validated ID token
+	OnLogin func(ctx *octo.Ctx[V], claims *Claims, tokens *Tokens) error
+	// DefaultRedirect is used after login when no return_to was given (default "/")
+	DefaultRedirect string
+	// ClockSkew tolerated when checking exp/iat (default 1 minute)
+	ClockSkew time.Duration
+	// HTTPClient used for discovery, JWKS and token requests
+	HTTPClient *http.Client
+}
+
+// Tokens is the token endpoint response
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	IDToken      string `json:"id_token"`
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type pendingLogin struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r,omitempty"`
+}
+
+// Provider runs the authorization code flow against one OpenID provider
+type Provider[V any] struct {
+	cfg       Config[V]
+	discovery discoveryDocument
+	keys      *keySet
+}
+
+// New fetches the provider discovery document and returns a Provider
+func New[V any](ctx context.Context, cfg Config[V]) (*Provider[V], error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc: Issuer, ClientID and RedirectURL are required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.DefaultRedirect == "" {
+		cfg.DefaultRedirect = "/"
+	}
+	if cfg.ClockSkew == 0 {
+		cfg.ClockSkew = time.Minute
+	}
+
+	p := &Provider[V]{cfg: cfg}
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, cfg.HTTPClient, wellKnown, &p.discovery); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc: issuer mismatch: %s", p.discovery.Issuer)
+	}
+	p.keys = &keySet{client: cfg.HTTPClient, uri: p.discovery.JWKSURI}
+	return p, nil
+}
+
+// Mount registers GET /login and GET /callback on the group
+func (p *Provider[V]) Mount(g *octo.Group[V]) {
+	g.GET("/login", p.LoginHandler())
+	g.GET("/callback", p.CallbackHandler())
+}
+
+// LoginHandler redirects to the provider. A relative return_to query
+// parameter is honored after a successful login.
+func (p *Provider[V]) LoginHandler() octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		pending := pendingLogin{
+			State:    randomString(24),
+			Nonce:    randomString(24),
+			Verifier: randomString(48),
+			ReturnTo: octo.LocalRedirectPath(ctx.QueryValue("return_to")),
+		}
+		data, _ := json.Marshal(pending)
+		if err := ctx.SetSignedCookie(stateCookieName, string(data), 600); err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+
+		challenge := sha256.Sum256([]byte(pending.Verifier))
+		params := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {p.cfg.ClientID},
+			"redirect_uri":          {p.cfg.RedirectURL},
+			"scope":                 {strings.Join(append([]string{"openid"}, p.cfg.Scopes...), " ")},
+			"state":                 {pending.State},
+			"nonce":                 {pending.Nonce},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}
+		sep := "?"
+		if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+			sep = "&"
+		}
+		ctx.Redirect(http.StatusFound, p.discovery.AuthorizationEndpoint+sep+params.Encode())
+	}
+}
+
+// CallbackHandler validates the provider response, exchanges the code, checks
+// the ID token and calls Config.OnLogin.
+func (p *Provider[V]) CallbackHandler() octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		raw, err := ctx.SignedCookie(stateCookieName)
+		if err != nil {
+			ctx.SendError("err_unauthorized", errors.New("missing or invalid login state"))
+			return
+		}
+		ctx.ClearSignedCookie(stateCookieName)
+
+		var pending pendingLogin
+		if err := json.Unmarshal([]byte(raw), &pending); err != nil {
+			ctx.SendError("err_unauthorized", err)
+			return
+		}
+		if errCode := ctx.QueryValue("error"); errCode != "" {
+			ctx.SendError("err_unauthorized", fmt.Errorf("provider error: %s", errCode))
+			return
+		}
+		if ctx.QueryValue("state") != pending.State {
+			ctx.SendError("err_unauthorized", errors.New("state mismatch"))
+			return
+		}
+
+		tokens, err := p.exchange(ctx.Context(), ctx.QueryValue("code"), pending.Verifier)
+		if err != nil {
+			ctx.SendError("err_unauthorized", err)
+			return
+		}
+		claims, err := p.VerifyIDToken(ctx.Context(), tokens.IDToken)
+		if err != nil {
+			ctx.SendError("err_unauthorized", err)
+			return
+		}
+		if claims.Nonce != pending.Nonce {
+			ctx.SendError("err_unauthorized", errors.New("nonce mismatch"))
+			return
+		}
+		if p.cfg.OnLogin != nil {
+			if err := p.cfg.OnLogin(ctx, claims, tokens); err != nil {
+				ctx.SendError("err_unauthorized", err)
+				return
+			}
+		}
+		if ctx.IsDone() {
+			return
+		}
+		returnTo := pending.ReturnTo
+		if returnTo == "" {
+			returnTo = p.cfg.DefaultRedirect
+		}
+		ctx.Redirect(http.StatusFound, returnTo)
+	}
+}
+
+func (p *Provider[V]) exchange(ctx context.Context, code, verifier string) (*Tokens, error) {
+	if code == "" {
+		return nil, errors.New("missing authorization code")
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	var tokens Tokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func getJSON(ctx context.Context, client *http.Client, uri string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", uri, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
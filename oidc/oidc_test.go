@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+type session struct {
+	Subject string
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"k1"}`))
+	payloadJSON, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+	digest := sha256.Sum256([]byte(header + "." + payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	octo.SetCookieSecret([]byte("0123456789abcdef0123456789abcdef"))
+	defer octo.SetCookieSecret(nil)
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var nonce, challenge string
+
+	idp := http.NewServeMux()
+	var issuer string
+	idp.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/authorize",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/jwks",
+		})
+	})
+	idp.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	idp.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge || r.PostForm.Get("code") != "the-code" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token": signToken(t, key, map[string]interface{}{
+				"iss":   issuer,
+				"sub":   "user-1",
+				"aud":   "client",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"iat":   time.Now().Unix(),
+				"nonce": nonce,
+			}),
+		})
+	})
+	idpServer := httptest.NewServer(idp)
+	defer idpServer.Close()
+	issuer = idpServer.URL
+
+	provider, err := New(context.Background(), Config[session]{
+		Issuer:      issuer,
+		ClientID:    "client",
+		RedirectURL: "http://app.test/auth/callback",
+		OnLogin: func(ctx *octo.Ctx[session], claims *Claims, tokens *Tokens) error {
+			ctx.SetHeader("X-Subject", claims.Subject)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	router := octo.NewRouter[session]()
+	provider.Mount(router.Group("/auth"))
+
+	// Login redirects to the provider with PKCE parameters
+	req := httptest.NewRequest("GET", "/auth/login?return_to=/dashboard", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect, got %d", w.Code)
+	}
+	location, _ := url.Parse(w.Result().Header.Get("Location"))
+	query := location.Query()
+	nonce = query.Get("nonce")
+	challenge = query.Get("code_challenge")
+	if query.Get("code_challenge_method") != "S256" || nonce == "" || query.Get("state") == "" {
+		t.Fatalf("Unexpected authorization request: %s", location)
+	}
+	cookies := w.Result().Cookies()
+
+	// Callback with a wrong state is rejected
+	req = httptest.NewRequest("GET", "/auth/callback?code=the-code&state=wrong", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for state mismatch, got %d", w.Code)
+	}
+
+	// Valid callback establishes the session and redirects to return_to
+	req = httptest.NewRequest("GET", "/auth/callback?code=the-code&state="+query.Get("state"), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound || w.Result().Header.Get("Location") != "/dashboard" {
+		t.Fatalf("Expected redirect to /dashboard, got %d %s: %s", w.Code, w.Result().Header.Get("Location"), w.Body.String())
+	}
+	if w.Result().Header.Get("X-Subject") != "user-1" {
+		t.Errorf("Expected OnLogin to receive subject, got '%s'", w.Result().Header.Get("X-Subject"))
+	}
+	var cleared *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == stateCookieName {
+			cleared = c
+		}
+	}
+	if cleared == nil || cleared.MaxAge >= 0 || cleared.Domain != "" || cleared.Path != "/" ||
+		!cleared.HttpOnly || cleared.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected the state cookie expired with its original attributes, got %+v", cleared)
+	}
+}
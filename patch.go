@@ -0,0 +1,393 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is wrapped by the errors of malformed patch documents
+	// and of patches that cannot be applied to the target
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPatchTestFailed is wrapped by the error of a failed JSON Patch
+	// "test" operation
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7396) request body to
+// target, a non-nil pointer: object members in the patch replace those of
+// target, null members remove them. target is left untouched on error. See
+// SendPatchError.
+func (c *Ctx[V]) ApplyMergePatch(target interface{}) error {
+	patch, err := c.patchBody("application/merge-patch+json")
+	if err != nil {
+		return err
+	}
+	return applyPatch(target, patch, MergePatch)
+}
+
+// ApplyJSONPatch applies the JSON Patch (RFC 6902) request body to target, a
+// non-nil pointer. The operations are applied in order and atomically:
+// target is left untouched if any of them fails. See SendPatchError.
+func (c *Ctx[V]) ApplyJSONPatch(target interface{}) error {
+	patch, err := c.patchBody("application/json-patch+json")
+	if err != nil {
+		return err
+	}
+	return applyPatch(target, patch, JSONPatch)
+}
+
+// SendPatchError sends the error of ApplyMergePatch or ApplyJSONPatch: 415
+// for an unsupported Content-Type, 409 for a failed test operation, 422 for
+// a patch that cannot be applied and 400 for malformed JSON
+func (c *Ctx[V]) SendPatchError(err error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		c.SendError("err_unsupported_media_type", err)
+	case errors.Is(err, ErrPatchTestFailed):
+		c.SendError("err_patch_conflict", err)
+	case errors.Is(err, ErrInvalidPatch):
+		c.SendError("err_invalid_patch", err)
+	default:
+		c.SendError("err_json_error", err)
+	}
+}
+
+func (c *Ctx[V]) patchBody(mediaType string) ([]byte, error) {
+	charset, err := checkContentType(c.GetHeader("Content-Type"), mediaType, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.NeedBody(); err != nil {
+		return nil, err
+	}
+	body := trimBOM(c.Body)
+	if len(body) == 0 {
+		return nil, errors.New("request body is empty")
+	}
+	return decodeCharset(charset, body)
+}
+
+// applyPatch patches the JSON encoding of target and decodes the result into
+// a fresh value, so members removed by the patch are zeroed
+func applyPatch(target interface{}, patch []byte, apply func(doc, patch []byte) ([]byte, error)) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("patch target must be a non-nil pointer")
+	}
+	doc, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return err
+	}
+	fresh := reflect.New(rv.Elem().Type())
+	if err := json.Unmarshal(patched, fresh.Interface()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	rv.Elem().Set(fresh.Elem())
+	return nil
+}
+
+// MergePatch applies the JSON Merge Patch (RFC 7396) patch to doc
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodePatchJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// JSONPatchOperation is an operation of a JSON Patch (RFC 6902) document
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch applies the JSON Patch (RFC 6902) patch to doc. The patch is
+// validated before any operation is applied.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []JSONPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	type compiled struct {
+		op         JSONPatchOperation
+		path, from []string
+		value      interface{}
+	}
+	steps := make([]compiled, len(ops))
+	for i, op := range ops {
+		s := compiled{op: op}
+		var err error
+		if s.path, err = parseJSONPointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d: %s requires a value", ErrInvalidPatch, i, op.Op)
+			}
+			if s.value, err = decodePatchJSON(op.Value); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		case "move", "copy":
+			if s.from, err = parseJSONPointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: from: %v", ErrInvalidPatch, i, err)
+			}
+			if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("%w: operation %d: cannot move %s into itself", ErrInvalidPatch, i, op.From)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+		steps[i] = s
+	}
+
+	target, err := decodePatchJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range steps {
+		var value interface{}
+		switch s.op.Op {
+		case "add":
+			target, err = pointerAdd(target, s.path, s.value)
+		case "remove":
+			target, _, err = pointerRemove(target, s.path)
+		case "replace":
+			if _, err = pointerGet(target, s.path); err == nil {
+				target, _, err = pointerRemove(target, s.path)
+			}
+			if err == nil {
+				target, err = pointerAdd(target, s.path, s.value)
+			}
+		case "move":
+			if target, value, err = pointerRemove(target, s.from); err == nil {
+				target, err = pointerAdd(target, s.path, value)
+			}
+		case "copy":
+			if value, err = pointerGet(target, s.from); err == nil {
+				target, err = pointerAdd(target, s.path, copyJSON(value))
+			}
+		case "test":
+			if value, err = pointerGet(target, s.path); err == nil && !jsonEqual(value, s.value) {
+				return nil, fmt.Errorf("%w: operation %d: %s", ErrPatchTestFailed, i, s.op.Path)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s %s): %v", ErrInvalidPatch, i, s.op.Op, s.op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// decodePatchJSON decodes a JSON document keeping numbers exact
+func decodePatchJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after JSON document")
+	}
+	return v, nil
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) into unescaped tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid escape in pointer %q", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token; "-" and n are accepted only when
+// appending
+func arrayIndex(tok string, n int, appending bool) (int, error) {
+	if tok == "-" && appending {
+		return n, nil
+	}
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i > n || (i == n && !appending) {
+		return 0, fmt.Errorf("array index %s out of range", tok)
+	}
+	return i, nil
+}
+
+func pointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, tok := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[tok]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", tok)
+			}
+			node = v
+		case []interface{}:
+			i, err := arrayIndex(tok, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("cannot index a scalar with %q", tok)
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd returns node with value added at tokens
+func pointerAdd(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	tok, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			n[tok] = value
+			return n, nil
+		}
+		child, ok := n[tok]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", tok)
+		}
+		child, err := pointerAdd(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = child
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(tok, len(n), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		child, err := pointerAdd(n[i], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("cannot index a scalar with %q", tok)
+	}
+}
+
+// pointerRemove returns node without the value at tokens, and that value
+func pointerRemove(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, node, nil
+	}
+	tok, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tok]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q not found", tok)
+		}
+		if last {
+			delete(n, tok)
+			return n, child, nil
+		}
+		child, removed, err := pointerRemove(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tok] = child
+		return n, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(tok, len(n), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		child, removed, err := pointerRemove(n[i], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = child
+		return n, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot index a scalar with %q", tok)
+	}
+}
+
+func copyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = copyJSON(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,161 @@
+package octo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func jsonEquivalent(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatal(err)
+	}
+	ga, _ := json.Marshal(a)
+	gb, _ := json.Marshal(b)
+	return string(ga) == string(gb)
+}
+
+func TestMergePatch(t *testing.T) {
+	// Examples of RFC 7396 appendix A
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+		}
+		if !jsonEquivalent(t, got, tt.want) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// Mostly examples of RFC 6902 appendix A
+	tests := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"add","path":"/foo","value":1}]`, `{"foo":1}`},
+		{`{"a/b":1,"m~n":2}`, `[{"op":"copy","from":"/a~1b","path":"/m~0n"}]`, `{"a/b":1,"m~n":1}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{`{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`},
+	}
+	for _, tt := range tests {
+		got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("JSONPatch(%s, %s): %v", tt.doc, tt.patch, err)
+		}
+		if !jsonEquivalent(t, got, tt.want) {
+			t.Errorf("JSONPatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+
+	failures := []struct {
+		doc, patch string
+		want       error
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrPatchTestFailed},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrInvalidPatch},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":1}]`, ErrInvalidPatch},
+		{`{"foo":["bar"]}`, `[{"op":"remove","path":"/foo/01"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/missing","value":1}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/foo"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/f~2o"}]`, ErrInvalidPatch},
+		{`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `{"op":"remove","path":"/foo"}`, ErrInvalidPatch},
+	}
+	for _, tt := range failures {
+		if _, err := JSONPatch([]byte(tt.doc), []byte(tt.patch)); !errors.Is(err, tt.want) {
+			t.Errorf("JSONPatch(%s, %s): expected %v, got %v", tt.doc, tt.patch, tt.want, err)
+		}
+	}
+}
+
+func TestCtxApplyPatch(t *testing.T) {
+	type profile struct {
+		Name  string   `json:"name"`
+		Email string   `json:"email,omitempty"`
+		Age   int      `json:"age"`
+		Tags  []string `json:"tags"`
+	}
+	var current profile
+	router := NewRouter[CustomData]()
+	handle := func(apply func(ctx *Ctx[CustomData], p *profile) error) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			if err := apply(ctx, &current); err != nil {
+				ctx.SendPatchError(err)
+				return
+			}
+			ctx.SendJSON(http.StatusOK, current)
+		}
+	}
+	router.PATCH("/merge", handle(func(ctx *Ctx[CustomData], p *profile) error { return ctx.ApplyMergePatch(p) }))
+	router.PATCH("/json", handle(func(ctx *Ctx[CustomData], p *profile) error { return ctx.ApplyJSONPatch(p) }))
+
+	do := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	current = profile{Name: "Ada", Email: "ada@example.com", Age: 36, Tags: []string{"math"}}
+	w := do("/merge", "application/merge-patch+json", `{"email":null,"age":37}`)
+	if w.Code != http.StatusOK || current.Email != "" || current.Age != 37 || current.Name != "Ada" || len(current.Tags) != 1 {
+		t.Errorf("Unexpected merge patch result: %d %+v", w.Code, current)
+	}
+
+	w = do("/json", "application/json-patch+json", `[{"op":"test","path":"/age","value":37},{"op":"add","path":"/tags/-","value":"engines"}]`)
+	if w.Code != http.StatusOK || len(current.Tags) != 2 || current.Tags[1] != "engines" {
+		t.Errorf("Unexpected JSON patch result: %d %+v", w.Code, current)
+	}
+
+	// Failures leave the target untouched
+	before := current
+	if w := do("/json", "application/json-patch+json", `[{"op":"replace","path":"/name","value":"Bob"},{"op":"test","path":"/age","value":1}]`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a failed test, got %d", w.Code)
+	}
+	if w := do("/json", "application/json-patch+json", `[{"op":"replace","path":"/age","value":"old"}]`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a mistyped value, got %d", w.Code)
+	}
+	if w := do("/merge", "application/merge-patch+json", `{"name":`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed JSON, got %d", w.Code)
+	}
+	if current.Name != before.Name || current.Age != before.Age {
+		t.Errorf("Expected failed patches to leave the target untouched, got %+v", current)
+	}
+}
@@ -0,0 +1,203 @@
+package octo
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PathPolicy decides what happens to non-canonical paths (duplicate slashes,
+// "." and ".." segments, trailing slashes)
+type PathPolicy int
+
+const (
+	// PathNormalize matches the canonical path silently
+	PathNormalize PathPolicy = iota
+	// PathRedirect redirects to the canonical URL (301 for GET/HEAD, 308 otherwise)
+	PathRedirect
+	// PathReject answers 400 err_invalid_path
+	PathReject
+)
+
+// PathOptions controls how request paths are decoded before matching. The zero
+// value matches on the decoded URL.Path with duplicate slashes collapsed and
+// dot-segments resolved.
+type PathOptions struct {
+	// UseEscapedPath matches on URL.EscapedPath() and decodes each segment
+	// separately, so an encoded slash (%2F) stays inside its parameter instead
+	// of splitting the path
+	UseEscapedPath bool
+	// RejectEncodedSlash answers 400 to paths containing %2F
+	RejectEncodedSlash bool
+	// RejectDoubleEncoding answers 400 to paths that still contain
+	// percent-escapes after decoding (e.g. %252F)
+	RejectDoubleEncoding bool
+	// DuplicateSlashes applies to paths with empty segments ("/a//b")
+	DuplicateSlashes PathPolicy
+	// DotSegments applies to paths with "." or ".." segments
+	DotSegments PathPolicy
+	// TrailingSlash applies to paths whose trailing slash differs from the
+	// matched route: "/users/1/" for "/users/:id", or "/docs" for "/docs/".
+	// PathRedirect redirects to the registered form; PathReject treats the
+	// path as unmatched (404) rather than answering 400. Catch-all routes
+	// are exempt.
+	TrailingSlash PathPolicy
+}
+
+// SetPathOptions sets the path decoding policy. Call it before serving.
+func (r *Router[V]) SetPathOptions(opts PathOptions) {
+	r.pathOptions = opts
+	r.customPaths = opts != PathOptions{}
+}
+
+// needsPathParts reports whether the request path must go through pathParts
+// instead of the plain splitPath fast path
+func (r *Router[V]) needsPathParts(path string) bool {
+	return r.customPaths || strings.Contains(path, "/.")
+}
+
+// pathParts splits the request path according to the router path options. It
+// returns the canonical URL path to redirect to, or the error code to answer
+// with when the path is rejected.
+func (r *Router[V]) pathParts(req *http.Request) (parts []string, redirect string, code string) {
+	opts := r.pathOptions
+	escaped := req.URL.EscapedPath()
+	if opts.RejectEncodedSlash && strings.Contains(strings.ToLower(escaped), "%2f") {
+		return nil, "", "err_invalid_path"
+	}
+	raw := req.URL.Path
+	if opts.UseEscapedPath {
+		raw = escaped
+	}
+	rawParts := splitPath(raw)
+	parts = rawParts
+	if opts.UseEscapedPath {
+		parts = make([]string, len(rawParts))
+		for i, part := range rawParts {
+			parts[i] = part
+			if strings.IndexByte(part, '%') == -1 {
+				continue
+			}
+			decoded, err := url.PathUnescape(part)
+			if err != nil {
+				return nil, "", "err_invalid_path"
+			}
+			parts[i] = decoded
+		}
+	}
+	if opts.RejectDoubleEncoding {
+		for _, part := range parts {
+			if hasPercentEscape(part) {
+				return nil, "", "err_invalid_path"
+			}
+		}
+	}
+
+	policy := PathNormalize
+	dirty := false
+	if strings.Contains(raw, "//") {
+		dirty = true
+		policy = max(policy, opts.DuplicateSlashes)
+	}
+	for _, part := range parts {
+		if part == "." || part == ".." {
+			dirty = true
+			policy = max(policy, opts.DotSegments)
+			break
+		}
+	}
+	if !dirty {
+		return parts, "", ""
+	}
+	if policy == PathReject {
+		return nil, "", "err_invalid_path"
+	}
+
+	// Resolve dot-segments; ".." never climbs above the root
+	resolved := make([]string, 0, len(parts))
+	resolvedRaw := make([]string, 0, len(parts))
+	for i, part := range parts {
+		switch part {
+		case ".":
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+				resolvedRaw = resolvedRaw[:len(resolvedRaw)-1]
+			}
+		default:
+			resolved = append(resolved, part)
+			resolvedRaw = append(resolvedRaw, rawParts[i])
+		}
+	}
+	if policy == PathNormalize {
+		return resolved, "", ""
+	}
+	canonical := "/" + strings.Join(resolvedRaw, "/")
+	if len(resolvedRaw) > 0 && strings.HasSuffix(raw, "/") {
+		canonical += "/"
+	}
+	if !opts.UseEscapedPath {
+		canonical = (&url.URL{Path: canonical}).EscapedPath()
+	}
+	return nil, canonical, ""
+}
+
+// trailingSlashMismatch reports whether the request path and the route
+// pattern disagree on the trailing slash, and returns the path in the form
+// of the route
+func trailingSlashMismatch(req *http.Request, pattern string) (string, bool) {
+	path := req.URL.Path
+	if path == "/" || pattern == "/" {
+		return "", false
+	}
+	if i := strings.LastIndexByte(pattern, '/'); i >= 0 && strings.HasPrefix(pattern[i+1:], "*") {
+		return "", false
+	}
+	wantSlash := strings.HasSuffix(pattern, "/")
+	if strings.HasSuffix(path, "/") == wantSlash {
+		return "", false
+	}
+	// Built from the collapsed segments, so the target never starts with "//"
+	// and cannot redirect to another host
+	canonical := "/" + strings.Join(splitPath(req.URL.EscapedPath()), "/")
+	if wantSlash && canonical != "/" {
+		canonical += "/"
+	}
+	return canonical, true
+}
+
+// hasPercentEscape reports whether s contains a %XX sequence
+func hasPercentEscape(s string) bool {
+	for i := strings.IndexByte(s, '%'); i != -1 && i+2 < len(s); {
+		if isHex(s[i+1]) && isHex(s[i+2]) {
+			return true
+		}
+		next := strings.IndexByte(s[i+1:], '%')
+		if next == -1 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// LocalRedirectPath returns target when it is a path on the same host, safe
+// to redirect to after a login or a form, and "" otherwise. Scheme-relative
+// ("//host"), backslash and control characters are rejected: browsers strip
+// tabs and newlines and read "\" as "/", turning "/\t/evil.com" into
+// "//evil.com".
+func LocalRedirectPath(target string) string {
+	if target == "" || target[0] != '/' || strings.HasPrefix(target, "//") {
+		return ""
+	}
+	for i := 0; i < len(target); i++ {
+		if c := target[i]; c < 0x20 || c == 0x7f || c == '\\' {
+			return ""
+		}
+	}
+	return target
+}
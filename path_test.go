@@ -0,0 +1,160 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathOptions(t *testing.T) {
+	newRouter := func(opts PathOptions) *Router[CustomData] {
+		router := NewRouter[CustomData]()
+		router.SetPathOptions(opts)
+		router.GET("/files/:name", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, ctx.Param("name"))
+		})
+		return router
+	}
+	serve := func(router *Router[CustomData], target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		opts   PathOptions
+		target string
+		code   int
+		body   string
+	}{
+		{"default splits encoded slash", PathOptions{}, "/files/a%2Fb", http.StatusNotFound, ""},
+		{"escaped path keeps encoded slash in param", PathOptions{UseEscapedPath: true}, "/files/a%2Fb", http.StatusOK, "a/b"},
+		{"escaped path decodes segments", PathOptions{UseEscapedPath: true}, "/files/caf%C3%A9", http.StatusOK, "café"},
+		{"reject encoded slash", PathOptions{UseEscapedPath: true, RejectEncodedSlash: true}, "/files/a%2fb", http.StatusBadRequest, ""},
+		{"reject double encoding", PathOptions{RejectDoubleEncoding: true}, "/files/a%252Fb", http.StatusBadRequest, ""},
+		{"reject double encoding escaped", PathOptions{UseEscapedPath: true, RejectDoubleEncoding: true}, "/files/%2541", http.StatusBadRequest, ""},
+		{"single encoding allowed", PathOptions{RejectDoubleEncoding: true}, "/files/a%20b", http.StatusOK, "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(newRouter(tt.opts), tt.target)
+			if w.Code != tt.code {
+				t.Errorf("Expected %d, got %d", tt.code, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPathPolicies(t *testing.T) {
+	newRouter := func(opts PathOptions) *Router[CustomData] {
+		router := NewRouter[CustomData]()
+		router.SetPathOptions(opts)
+		router.GET("/a/:id", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, ctx.Param("id"))
+		})
+		router.POST("/a/:id", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, ctx.Param("id"))
+		})
+		router.GET("/docs/", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, "docs")
+		})
+		router.GET("/files/*path", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, ctx.Param("path"))
+		})
+		return router
+	}
+
+	tests := []struct {
+		name     string
+		opts     PathOptions
+		method   string
+		target   string
+		code     int
+		body     string
+		location string
+	}{
+		{"collapse slashes by default", PathOptions{}, "GET", "/a//b", http.StatusOK, "b", ""},
+		{"resolve dots by default", PathOptions{}, "GET", "/x/../a/./b", http.StatusOK, "b", ""},
+		{"dots never climb above root", PathOptions{}, "GET", "/../../a/b", http.StatusOK, "b", ""},
+		{"dot param is not matched literally", PathOptions{}, "GET", "/a/..", http.StatusNotFound, "", ""},
+		{"redirect slashes", PathOptions{DuplicateSlashes: PathRedirect}, "GET", "/a//b?q=1", http.StatusMovedPermanently, "", "/a/b?q=1"},
+		{"redirect dots keeps method", PathOptions{DotSegments: PathRedirect}, "POST", "/a/x/../b/", http.StatusPermanentRedirect, "", "/a/b/"},
+		{"redirect keeps escaping", PathOptions{DotSegments: PathRedirect}, "GET", "/a/./b%20c", http.StatusMovedPermanently, "", "/a/b%20c"},
+		{"reject slashes", PathOptions{DuplicateSlashes: PathReject}, "GET", "/a//b", http.StatusBadRequest, "", ""},
+		{"reject dots", PathOptions{DotSegments: PathReject, DuplicateSlashes: PathRedirect}, "GET", "/a//../b", http.StatusBadRequest, "", ""},
+		{"clean path untouched", PathOptions{DotSegments: PathReject, DuplicateSlashes: PathReject}, "GET", "/a/.hidden", http.StatusOK, ".hidden", ""},
+		{"trailing slash matched by default", PathOptions{}, "GET", "/a/b/", http.StatusOK, "b", ""},
+		{"redirect trailing slash", PathOptions{TrailingSlash: PathRedirect}, "GET", "/a/b/?q=1", http.StatusMovedPermanently, "", "/a/b?q=1"},
+		{"redirect adds slash", PathOptions{TrailingSlash: PathRedirect}, "GET", "/docs", http.StatusMovedPermanently, "", "/docs/"},
+		{"strict trailing slash", PathOptions{TrailingSlash: PathReject}, "GET", "/a/b/", http.StatusNotFound, "", ""},
+		{"strict canonical form", PathOptions{TrailingSlash: PathReject}, "GET", "/docs/", http.StatusOK, "docs", ""},
+		{"catch-all keeps slash", PathOptions{TrailingSlash: PathReject}, "GET", "/files/x/", http.StatusOK, "x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newRouter(tt.opts).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.code {
+				t.Errorf("Expected %d, got %d", tt.code, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body.String())
+			}
+			if tt.location != "" && w.Header().Get("Location") != tt.location {
+				t.Errorf("Expected Location %q, got %q", tt.location, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestTrailingSlashRedirectStaysOnHost(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.SetPathOptions(PathOptions{TrailingSlash: PathRedirect})
+	router.GET("/:name", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("name"))
+	})
+	router.GET("/dir/:name/", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("name"))
+	})
+
+	tests := []struct {
+		target   string
+		location string
+	}{
+		{"//evil.com/", "/evil.com"},
+		{"///evil.com//", "/evil.com"},
+		{"//dir//x", "/dir/x/"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s: expected 301 to %q, got %d %q", tt.target, tt.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestLocalRedirectPath(t *testing.T) {
+	for target, expected := range map[string]string{
+		"/ok":              "/ok",
+		"/a/b?next=/c":     "/a/b?next=/c",
+		"//evil.com":       "",
+		"https://evil.com": "",
+		"/\\evil.com":      "",
+		"/a\\b":            "",
+		"/\t/evil.com":     "",
+		"/\n/evil.com":     "",
+		"/\r\n/evil.com":   "",
+		"/x\x7f":           "",
+		"/\x00":            "",
+		"":                 "",
+	} {
+		if got := LocalRedirectPath(target); got != expected {
+			t.Errorf("LocalRedirectPath(%q) = %q, expected %q", target, got, expected)
+		}
+	}
+}
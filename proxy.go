@@ -0,0 +1,540 @@
+package octo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoUpstream is returned when a pool has no upstream left to try
+var ErrNoUpstream = errors.New("no upstream available")
+
+// Upstream is a backend of an UpstreamPool
+type Upstream struct {
+	URL *url.URL
+
+	active    atomic.Int64
+	checkDown atomic.Bool  // failed its last active health check
+	downUntil atomic.Int64 // unix nanoseconds, ejected after a connection failure
+
+	requests atomic.Int64
+	failures atomic.Int64
+	latency  atomic.Int64 // total nanoseconds to response headers
+}
+
+// Healthy reports whether u passed its last health check and is not ejected
+// after a connection failure
+func (u *Upstream) Healthy() bool {
+	return !u.checkDown.Load() && time.Now().UnixNano() >= u.downUntil.Load()
+}
+
+// Active returns the number of requests in flight to u
+func (u *Upstream) Active() int64 {
+	return u.active.Load()
+}
+
+// Balancer picks the upstream of a request among candidates, which are never
+// empty
+type Balancer interface {
+	Pick(r *http.Request, candidates []*Upstream) *Upstream
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin cycles through the upstreams
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	return candidates[(b.next.Add(1)-1)%uint64(len(candidates))]
+}
+
+type leastConnections struct {
+	rr roundRobin
+}
+
+// LeastConnections picks the upstream with the fewest requests in flight,
+// cycling between ties
+func LeastConnections() Balancer {
+	return &leastConnections{}
+}
+
+func (b *leastConnections) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	start := int(b.rr.next.Add(1) % uint64(len(candidates)))
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		u := candidates[(start+i)%len(candidates)]
+		if u.Active() < best.Active() {
+			best = u
+		}
+	}
+	return best
+}
+
+type consistentHash struct {
+	key func(*http.Request) string
+	rr  roundRobin
+}
+
+// ConsistentHash sends requests with the same key to the same upstream
+// (sticky sessions). It uses rendezvous hashing: when an upstream goes down
+// only its keys move. Requests without a key are spread round-robin.
+func ConsistentHash(key func(r *http.Request) string) Balancer {
+	return &consistentHash{key: key}
+}
+
+func (b *consistentHash) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	key := b.key(r)
+	if key == "" {
+		return b.rr.Pick(r, candidates)
+	}
+	var best *Upstream
+	var bestScore uint64
+	for _, u := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(u.URL.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := mix64(h.Sum64()); best == nil || score > bestScore {
+			best, bestScore = u, score
+		}
+	}
+	return best
+}
+
+// mix64 spreads the bits of an FNV hash, whose last bytes barely reach the
+// high bits otherwise
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// HeaderKey keys ConsistentHash on a request header
+func HeaderKey(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// CookieKey keys ConsistentHash on a cookie, e.g. the session cookie
+func CookieKey(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return c.Value
+	}
+}
+
+// PoolOptions configures an UpstreamPool
+type PoolOptions struct {
+	// Balancer defaults to RoundRobin
+	Balancer Balancer
+	// HealthPath enables active health checks: every HealthInterval (10s when
+	// zero) each upstream is sent a GET for this path, and a response below
+	// 500 within HealthTimeout (2s when zero) marks it healthy
+	HealthPath     string
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	// DownFor ejects an upstream after a connection failure (10s when zero)
+	DownFor time.Duration
+	// Client sends health checks (http.DefaultClient when nil)
+	Client *http.Client
+}
+
+// UpstreamPool is a set of interchangeable backends for Proxy
+type UpstreamPool struct {
+	opts      PoolOptions
+	upstreams []*Upstream
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewUpstreamPool creates a pool of the target base URLs. With a HealthPath,
+// health checks start immediately; Close stops them.
+func NewUpstreamPool(targets []string, opts PoolOptions) (*UpstreamPool, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("upstream pool needs at least one target")
+	}
+	if opts.Balancer == nil {
+		opts.Balancer = RoundRobin()
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = 10 * time.Second
+	}
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = 2 * time.Second
+	}
+	if opts.DownFor <= 0 {
+		opts.DownFor = 10 * time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	p := &UpstreamPool{opts: opts, stop: make(chan struct{}), done: make(chan struct{})}
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, errors.New("upstream " + target + " must be an absolute URL")
+		}
+		p.upstreams = append(p.upstreams, &Upstream{URL: u})
+	}
+	if opts.HealthPath == "" {
+		close(p.done)
+		return p, nil
+	}
+	go p.loop()
+	return p, nil
+}
+
+// Upstreams returns the upstreams of the pool
+func (p *UpstreamPool) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// pick chooses an upstream not in tried. When every upstream is unhealthy it
+// still picks among them rather than failing every request.
+func (p *UpstreamPool) pick(r *http.Request, tried []*Upstream) *Upstream {
+	candidates := make([]*Upstream, 0, len(p.upstreams))
+	var fallback []*Upstream
+	for _, u := range p.upstreams {
+		if containsUpstream(tried, u) {
+			continue
+		}
+		if u.Healthy() {
+			candidates = append(candidates, u)
+		} else {
+			fallback = append(fallback, u)
+		}
+	}
+	if len(candidates) == 0 {
+		if len(tried) > 0 || len(fallback) == 0 {
+			return nil
+		}
+		candidates = fallback
+	}
+	return p.opts.Balancer.Pick(r, candidates)
+}
+
+func containsUpstream(list []*Upstream, u *Upstream) bool {
+	for _, v := range list {
+		if v == u {
+			return true
+		}
+	}
+	return false
+}
+
+// eject marks u down after a connection failure
+func (p *UpstreamPool) eject(u *Upstream) {
+	u.downUntil.Store(time.Now().Add(p.opts.DownFor).UnixNano())
+}
+
+// CheckHealth runs one round of active health checks
+func (p *UpstreamPool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, p.opts.HealthTimeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, joinURLPath(u.URL.Path, p.opts.HealthPath), nil)
+			if err != nil {
+				u.checkDown.Store(true)
+				return
+			}
+			req.URL.Scheme, req.URL.Host = u.URL.Scheme, u.URL.Host
+			resp, err := p.opts.Client.Do(req)
+			if err != nil {
+				u.checkDown.Store(true)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			healthy := resp.StatusCode < http.StatusInternalServerError
+			u.checkDown.Store(!healthy)
+			if healthy {
+				u.downUntil.Store(0)
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+func (p *UpstreamPool) loop() {
+	defer close(p.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+	p.CheckHealth(ctx)
+	ticker := time.NewTicker(p.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.CheckHealth(ctx)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops the health checks
+func (p *UpstreamPool) Close() error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+	return nil
+}
+
+// ProxyConfig configures Proxy
+type ProxyConfig struct {
+	Pool *UpstreamPool
+	// Retries is how many other upstreams are tried after a connection
+	// failure (2 when zero, none when negative). Requests are only retried
+	// when the upstream could not be reached, so they are never applied
+	// twice.
+	Retries int
+	// StripPrefix is removed from the request path before it is appended to
+	// the upstream URL
+	StripPrefix string
+	// Transport defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// FlushInterval is passed to httputil.ReverseProxy (-1 flushes after
+	// every write, for streaming)
+	FlushInterval time.Duration
+	// OnAttempt is called after every upstream attempt, e.g. to export
+	// metrics. Attempts are also logged: failures as warnings, the others at
+	// debug level.
+	OnAttempt func(ProxyAttempt)
+	// DisableTracePropagation stops sending traceparent to upstreams
+	DisableTracePropagation bool
+}
+
+// proxyState carries the inbound request details to the transport
+type proxyState struct {
+	requestID string
+	trace     traceContext
+	send      func(error)
+}
+
+type proxyStateKey struct{}
+
+// Proxy forwards requests to the upstreams of cfg.Pool, adding the
+// X-Forwarded-* headers. Unreachable upstreams are ejected from the pool and
+// the request retried on another one.
+func Proxy[V any](cfg ProxyConfig) HandlerFunc[V] {
+	if cfg.Pool == nil {
+		panic("octo: Proxy needs an upstream pool")
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = 2
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetXForwarded()
+			if cfg.StripPrefix != "" {
+				pr.Out.URL.Path = ensureLeadingSlash(strings.TrimPrefix(pr.Out.URL.Path, cfg.StripPrefix))
+				if pr.Out.URL.RawPath != "" {
+					pr.Out.URL.RawPath = ensureLeadingSlash(strings.TrimPrefix(pr.Out.URL.RawPath, cfg.StripPrefix))
+				}
+			}
+		},
+		Transport:     &proxyTransport{cfg: cfg},
+		FlushInterval: cfg.FlushInterval,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if state, ok := r.Context().Value(proxyStateKey{}).(*proxyState); ok {
+				state.send(err)
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	return func(ctx *Ctx[V]) {
+		state := &proxyState{
+			// The ID outlives the request in OnAttempt, while the arena
+			// recycles the UUID
+			requestID: strings.Clone(ctx.UUID),
+			trace:     parseTraceParent(ctx.GetHeader(TraceParentHeader), ctx.GetHeader(TraceStateHeader)),
+			send: func(err error) {
+				if errors.Is(err, ErrNoUpstream) {
+					ctx.SendError("err_no_upstream", err)
+					return
+				}
+				ctx.SendError("err_bad_gateway", err)
+			},
+		}
+		req := ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), proxyStateKey{}, state))
+		if ctx.hasReadBody {
+			// The body was consumed by NeedBody: replay it
+			body := ctx.Body
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			req.ContentLength = int64(len(body))
+		} else if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &proxyBody{ReadCloser: req.Body}
+		}
+		rp.ServeHTTP(ctx.ResponseWriter, req)
+		ctx.Done()
+	}
+}
+
+// proxyBody lets a retry resend a streamed body the transport did not start
+// reading; the server closes the inbound body itself
+type proxyBody struct {
+	io.ReadCloser
+	read atomic.Bool
+}
+
+func (b *proxyBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.ReadCloser.Read(p)
+}
+
+func (b *proxyBody) Close() error {
+	return nil
+}
+
+type proxyTransport struct {
+	cfg ProxyConfig
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var tried []*Upstream
+	var lastErr error = ErrNoUpstream
+	for attempt := 0; attempt <= max(t.cfg.Retries, 0); attempt++ {
+		if attempt > 0 && !canResend(req) {
+			break
+		}
+		u := t.cfg.Pool.pick(req, tried)
+		if u == nil {
+			break
+		}
+		tried = append(tried, u)
+		out := req.Clone(req.Context())
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			out.Body = body
+		}
+		out.URL.Scheme, out.URL.Host = u.URL.Scheme, u.URL.Host
+		out.URL.Path = joinURLPath(u.URL.Path, req.URL.Path)
+		if req.URL.RawPath != "" {
+			out.URL.RawPath = joinURLPath(u.URL.EscapedPath(), req.URL.RawPath)
+		}
+		if u.URL.RawQuery != "" {
+			if out.URL.RawQuery == "" {
+				out.URL.RawQuery = u.URL.RawQuery
+			} else {
+				out.URL.RawQuery = u.URL.RawQuery + "&" + out.URL.RawQuery
+			}
+		}
+		out.Host = ""
+		state, _ := req.Context().Value(proxyStateKey{}).(*proxyState)
+		if state != nil {
+			out.Header.Set(RequestIDHeader, state.requestID)
+			if !t.cfg.DisableTracePropagation {
+				// Each attempt is a child span of the inbound request
+				out.Header.Set(TraceParentHeader, state.trace.child().String())
+				if state.trace.state != "" {
+					out.Header.Set(TraceStateHeader, state.trace.state)
+				}
+			}
+		}
+
+		u.active.Add(1)
+		start := time.Now()
+		resp, err := t.cfg.Transport.RoundTrip(out)
+		t.observe(state, out, u, attempt+1, resp, err, time.Since(start))
+		if err == nil {
+			resp.Body = &upstreamBody{ReadCloser: resp.Body, u: u}
+			return resp, nil
+		}
+		u.active.Add(-1)
+		lastErr = err
+		if !isDialError(err) {
+			return nil, err
+		}
+		t.cfg.Pool.eject(u)
+	}
+	return nil, lastErr
+}
+
+// canResend reports whether req can be sent again after a failed attempt
+func canResend(req *http.Request) bool {
+	if req.GetBody != nil || req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	b, ok := req.Body.(*proxyBody)
+	return ok && !b.read.Load()
+}
+
+// isDialError reports whether err happened before the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// upstreamBody counts the request as active until its response is consumed
+type upstreamBody struct {
+	io.ReadCloser
+	u    *Upstream
+	once sync.Once
+}
+
+func (b *upstreamBody) Close() error {
+	b.once.Do(func() { b.u.active.Add(-1) })
+	return b.ReadCloser.Close()
+}
+
+func ensureLeadingSlash(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+func joinURLPath(a, b string) string {
+	switch {
+	case a == "":
+		return ensureLeadingSlash(b)
+	case strings.HasSuffix(a, "/") && strings.HasPrefix(b, "/"):
+		return a + b[1:]
+	case !strings.HasSuffix(a, "/") && !strings.HasPrefix(b, "/"):
+		return a + "/" + b
+	}
+	return a + b
+}
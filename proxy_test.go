@@ -0,0 +1,158 @@
+package octo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestUpstream(t *testing.T, name string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", name)
+		io.WriteString(w, name+" "+r.URL.Path+" "+string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// deadUpstream returns the URL of a port nothing listens on
+func deadUpstream(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "http://" + addr
+}
+
+func proxyRouter(t *testing.T, pool *UpstreamPool, cfg ProxyConfig) *Router[CustomData] {
+	cfg.Pool = pool
+	router := NewRouter[CustomData]()
+	router.ANY("/api/*path", Proxy[CustomData](cfg))
+	return router
+}
+
+func TestProxyRoundRobin(t *testing.T) {
+	a, b := newTestUpstream(t, "a"), newTestUpstream(t, "b")
+	pool, err := NewUpstreamPool([]string{a.URL, b.URL + "/v1"}, PoolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := proxyRouter(t, pool, ProxyConfig{StripPrefix: "/api"})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/users", strings.NewReader("x")))
+		got = append(got, w.Body.String())
+	}
+	want := []string{"a /users x", "b /v1/users x", "a /users x", "b /v1/users x"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Request %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestProxyRetriesUnreachableUpstream(t *testing.T) {
+	live := newTestUpstream(t, "live")
+	dead := deadUpstream(t)
+	pool, _ := NewUpstreamPool([]string{dead, live.URL}, PoolOptions{})
+	router := proxyRouter(t, pool, ProxyConfig{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/x", strings.NewReader("body")))
+	if w.Code != http.StatusOK || w.Body.String() != "live /api/x body" {
+		t.Fatalf("Expected the request to be retried on the live upstream, got %d %q", w.Code, w.Body.String())
+	}
+	if pool.Upstreams()[0].Healthy() {
+		t.Error("Expected the dead upstream to be ejected")
+	}
+
+	pool, _ = NewUpstreamPool([]string{dead}, PoolOptions{})
+	router = proxyRouter(t, pool, ProxyConfig{})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/x", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 when no upstream answers, got %d", w.Code)
+	}
+}
+
+func TestProxyConsistentHash(t *testing.T) {
+	a, b, c := newTestUpstream(t, "a"), newTestUpstream(t, "b"), newTestUpstream(t, "c")
+	pool, _ := NewUpstreamPool([]string{a.URL, b.URL, c.URL}, PoolOptions{Balancer: ConsistentHash(CookieKey("sid"))})
+	router := proxyRouter(t, pool, ProxyConfig{})
+
+	upstream := func(sid string) string {
+		req := httptest.NewRequest("GET", "/api/x", nil)
+		req.AddCookie(&http.Cookie{Name: "sid", Value: sid})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header().Get("X-Upstream")
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		sid := string(rune('a' + i))
+		first := upstream(sid)
+		if again := upstream(sid); again != first {
+			t.Errorf("Session %s moved from %s to %s", sid, first, again)
+		}
+		seen[first] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected sessions to spread over upstreams, got %v", seen)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	pool, _ := NewUpstreamPool([]string{"http://a", "http://b", "http://c"}, PoolOptions{Balancer: LeastConnections()})
+	ups := pool.Upstreams()
+	ups[0].active.Store(3)
+	ups[1].active.Store(1)
+	ups[2].active.Store(2)
+	for i := 0; i < 3; i++ {
+		if u := pool.pick(httptest.NewRequest("GET", "/", nil), nil); u != ups[1] {
+			t.Errorf("Expected the least busy upstream, got %s", u.URL)
+		}
+	}
+}
+
+func TestUpstreamHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	pool, _ := NewUpstreamPool([]string{srv.URL}, PoolOptions{HealthPath: "/health", HealthInterval: 1 << 40})
+	defer pool.Close()
+	u := pool.Upstreams()[0]
+
+	healthy.Store(false)
+	pool.CheckHealth(context.Background())
+	if u.Healthy() {
+		t.Error("Expected the upstream to fail its health check")
+	}
+	// Every upstream down: still tried rather than failing every request
+	if pool.pick(httptest.NewRequest("GET", "/", nil), nil) != u {
+		t.Error("Expected unhealthy upstreams to be used as a last resort")
+	}
+	healthy.Store(true)
+	pool.CheckHealth(context.Background())
+	if !u.Healthy() {
+		t.Error("Expected the upstream to recover")
+	}
+}
@@ -0,0 +1,165 @@
+package octo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Correlation headers sent by Proxy to upstreams
+const (
+	// RequestIDHeader carries Ctx.UUID of the inbound request
+	RequestIDHeader = "X-Request-ID"
+	// TraceParentHeader and TraceStateHeader carry W3C trace context
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// traceContext is a W3C trace context (https://www.w3.org/TR/trace-context/)
+type traceContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	flags   byte
+	state   string
+}
+
+// parseTraceParent parses inbound traceparent and tracestate headers,
+// starting a new sampled trace when traceparent is missing or invalid
+func parseTraceParent(header, state string) traceContext {
+	var tc traceContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) >= 4 && len(parts[0]) == 2 && parts[0] != "ff" &&
+		len(parts[1]) == 32 && len(parts[2]) == 16 && len(parts[3]) == 2 {
+		var flags [1]byte
+		_, err1 := hex.Decode(tc.traceID[:], []byte(parts[1]))
+		_, err2 := hex.Decode(tc.spanID[:], []byte(parts[2]))
+		_, err3 := hex.Decode(flags[:], []byte(parts[3]))
+		if err1 == nil && err2 == nil && err3 == nil && tc.traceID != [16]byte{} && tc.spanID != [8]byte{} {
+			tc.flags = flags[0]
+			tc.state = state
+			return tc
+		}
+	}
+	tc = traceContext{flags: 1}
+	rand.Read(tc.traceID[:])
+	rand.Read(tc.spanID[:])
+	return tc
+}
+
+// child returns the context of a new span in the same trace
+func (tc traceContext) child() traceContext {
+	rand.Read(tc.spanID[:])
+	return tc
+}
+
+// TraceID returns the hex trace ID
+func (tc traceContext) TraceID() string {
+	return hex.EncodeToString(tc.traceID[:])
+}
+
+func (tc traceContext) String() string {
+	return "00-" + tc.TraceID() + "-" + hex.EncodeToString(tc.spanID[:]) + "-" + hex.EncodeToString([]byte{tc.flags})
+}
+
+// ProxyAttempt describes one request sent by Proxy to an upstream
+type ProxyAttempt struct {
+	// RequestID is Ctx.UUID of the inbound request
+	RequestID string
+	TraceID   string
+	Method    string
+	Path      string
+	Upstream  string
+	// Attempt starts at 1 and grows with retries on other upstreams
+	Attempt int
+	// Status is 0 when the upstream did not answer
+	Status  int
+	Latency time.Duration
+	Err     error
+}
+
+// observe records an attempt in the upstream stats, the logs and OnAttempt
+func (t *proxyTransport) observe(state *proxyState, out *http.Request, u *Upstream, attempt int, resp *http.Response, err error, latency time.Duration) {
+	a := ProxyAttempt{
+		Method:   out.Method,
+		Path:     out.URL.Path,
+		Upstream: u.URL.String(),
+		Attempt:  attempt,
+		Latency:  latency,
+		Err:      err,
+	}
+	if state != nil {
+		a.RequestID = state.requestID
+		a.TraceID = state.trace.TraceID()
+	}
+	if resp != nil {
+		a.Status = resp.StatusCode
+	}
+	u.requests.Add(1)
+	u.latency.Add(int64(latency))
+	if err != nil || a.Status >= http.StatusInternalServerError {
+		u.failures.Add(1)
+	}
+	if t.cfg.OnAttempt != nil {
+		t.cfg.OnAttempt(a)
+	}
+
+	proxyLogger := SubsystemLogger(LogProxy)
+	if EnableLoggerCheck {
+		if proxyLogger != nil {
+			logProxyAttempt(proxyLogger, a)
+		}
+	} else {
+		logProxyAttempt(proxyLogger, a)
+	}
+}
+
+func logProxyAttempt(l *zerolog.Logger, a ProxyAttempt) {
+	event := l.Debug()
+	if a.Err != nil {
+		event = l.Warn().Err(a.Err)
+	}
+	event.Str("request_id", a.RequestID).
+		Str("trace_id", a.TraceID).
+		Str("method", a.Method).
+		Str("path", a.Path).
+		Str("upstream", a.Upstream).
+		Int("attempt", a.Attempt).
+		Int("status", a.Status).
+		Dur("latency", a.Latency).
+		Msg("[octo] proxy attempt")
+}
+
+// UpstreamStats counts the attempts sent to an upstream
+type UpstreamStats struct {
+	URL      string `json:"url"`
+	Healthy  bool   `json:"healthy"`
+	Active   int64  `json:"active"`
+	Requests int64  `json:"requests"`
+	// Failures counts connection errors and 5xx responses
+	Failures int64 `json:"failures"`
+	// AvgLatency is the mean time to response headers
+	AvgLatency time.Duration `json:"avg_latency_ns"`
+}
+
+// Stats returns the counters of every upstream
+func (p *UpstreamPool) Stats() []UpstreamStats {
+	stats := make([]UpstreamStats, len(p.upstreams))
+	for i, u := range p.upstreams {
+		s := UpstreamStats{
+			URL:      u.URL.String(),
+			Healthy:  u.Healthy(),
+			Active:   u.Active(),
+			Requests: u.requests.Load(),
+			Failures: u.failures.Load(),
+		}
+		if s.Requests > 0 {
+			s.AvgLatency = time.Duration(u.latency.Load() / s.Requests)
+		}
+		stats[i] = s
+	}
+	return stats
+}
@@ -0,0 +1,75 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProxyCorrelation(t *testing.T) {
+	var mu sync.Mutex
+	var seen []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		mu.Unlock()
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+
+	pool, _ := NewUpstreamPool([]string{deadUpstream(t), srv.URL}, PoolOptions{})
+	var attempts []ProxyAttempt
+	router := proxyRouter(t, pool, ProxyConfig{OnAttempt: func(a ProxyAttempt) {
+		attempts = append(attempts, a)
+	}})
+
+	inbound := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/api/items", nil)
+	req.Header.Set(TraceParentHeader, inbound)
+	req.Header.Set(TraceStateHeader, "vendor=1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("Expected the upstream status, got %d", w.Code)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("Expected 2 attempts, got %+v", attempts)
+	}
+	if attempts[0].Err == nil || attempts[0].Status != 0 || attempts[1].Status != http.StatusTeapot || attempts[1].Attempt != 2 {
+		t.Errorf("Unexpected attempts %+v", attempts)
+	}
+	for _, a := range attempts {
+		if a.RequestID == "" || a.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || a.Path != "/api/items" {
+			t.Errorf("Expected attempts tagged with the inbound request, got %+v", a)
+		}
+	}
+
+	h := seen[0]
+	if h.Get(RequestIDHeader) != attempts[1].RequestID {
+		t.Errorf("Expected the request ID to be propagated, got %q", h.Get(RequestIDHeader))
+	}
+	parent := h.Get(TraceParentHeader)
+	if !strings.HasPrefix(parent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || parent == inbound || !strings.HasSuffix(parent, "-01") {
+		t.Errorf("Expected a child span of the inbound trace, got %q", parent)
+	}
+	if h.Get(TraceStateHeader) != "vendor=1" {
+		t.Errorf("Expected tracestate to be forwarded, got %q", h.Get(TraceStateHeader))
+	}
+
+	stats := pool.Stats()
+	if stats[0].Failures != 1 || stats[0].Healthy || stats[1].Requests != 1 || stats[1].Failures != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	for _, header := range []string{"", "garbage", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		tc := parseTraceParent(header, "vendor=1")
+		if tc.TraceID() == "00000000000000000000000000000000" || tc.flags != 1 || tc.state != "" {
+			t.Errorf("%q: expected a new sampled trace, got %s", header, tc)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package octo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidQuery is matched by every QueryError
+var ErrInvalidQuery = errors.New("invalid query parameter")
+
+// QueryError reports a query parameter that could not be converted
+type QueryError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query parameter %s=%q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == ErrInvalidQuery
+}
+
+// queryValues returns the parsed query string
+func (c *Ctx[V]) queryValues() url.Values {
+	if c.Query == nil {
+		c.Query = c.Request.URL.Query()
+	}
+	return c.Query
+}
+
+// queryRaw returns the first value of key and whether it is set and non-empty
+func (c *Ctx[V]) queryRaw(key string) (string, bool) {
+	values := c.queryValues()[key]
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+	return values[0], true
+}
+
+// QueryInt returns key as an int, def when absent. A malformed value returns
+// def and a QueryError.
+func (c *Ctx[V]) QueryInt(key string, def int) (int, error) {
+	raw, ok := c.queryRaw(key)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return def, &QueryError{Key: key, Value: raw, Err: errors.New("not an integer")}
+	}
+	return n, nil
+}
+
+// QueryBool returns key as a bool (1/0, true/false, yes/no, on/off), def when
+// absent. A malformed value returns def and a QueryError.
+func (c *Ctx[V]) QueryBool(key string, def bool) (bool, error) {
+	raw, ok := c.queryRaw(key)
+	if !ok {
+		return def, nil
+	}
+	switch strings.ToLower(raw) {
+	case "1", "true", "t", "yes", "y", "on":
+		return true, nil
+	case "0", "false", "f", "no", "n", "off":
+		return false, nil
+	}
+	return def, &QueryError{Key: key, Value: raw, Err: errors.New("not a boolean")}
+}
+
+// QueryTime returns key as a time, def when absent. RFC 3339 timestamps,
+// dates (2006-01-02, UTC) and Unix seconds are accepted. A malformed value
+// returns def and a QueryError.
+func (c *Ctx[V]) QueryTime(key string, def time.Time) (time.Time, error) {
+	raw, ok := c.queryRaw(key)
+	if !ok {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return def, &QueryError{Key: key, Value: raw, Err: errors.New("not a time")}
+}
+
+// QueryUUID returns key as a UUID, def when absent. A malformed value
+// returns def and a QueryError.
+func (c *Ctx[V]) QueryUUID(key string, def uuid.UUID) (uuid.UUID, error) {
+	raw, ok := c.queryRaw(key)
+	if !ok {
+		return def, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return def, &QueryError{Key: key, Value: raw, Err: errors.New("not a UUID")}
+	}
+	return id, nil
+}
+
+// QueryList returns every value of key, expanding comma-separated values:
+// ?ids=1,2&ids=3 gives [1 2 3]. Empty items are dropped.
+func (c *Ctx[V]) QueryList(key string) []string {
+	var out []string
+	for _, value := range c.queryValues()[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// QueryInts is QueryList converted to ints. The first malformed item
+// returns a QueryError.
+func (c *Ctx[V]) QueryInts(key string) ([]int, error) {
+	items := c.QueryList(key)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	out := make([]int, len(items))
+	for i, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, &QueryError{Key: key, Value: item, Err: errors.New("not an integer")}
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// QueryUUIDs is QueryList converted to UUIDs. The first malformed item
+// returns a QueryError.
+func (c *Ctx[V]) QueryUUIDs(key string) ([]uuid.UUID, error) {
+	items := c.QueryList(key)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	out := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		id, err := uuid.Parse(item)
+		if err != nil {
+			return nil, &QueryError{Key: key, Value: item, Err: errors.New("not a UUID")}
+		}
+		out[i] = id
+	}
+	return out, nil
+}
+
+// QueryNested collects the bracketed parameters under prefix:
+// filter[status]=open&filter[created][gte]=2024-01-01&filter[tag][]=a gives
+// {"status": ["open"], "created.gte": ["2024-01-01"], "tag": ["a"]}.
+// Malformed brackets are ignored.
+func (c *Ctx[V]) QueryNested(prefix string) url.Values {
+	out := make(url.Values)
+	for key, values := range c.queryValues() {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok || !strings.HasPrefix(rest, "[") {
+			continue
+		}
+		var path []string
+		valid := true
+		for rest != "" {
+			if rest[0] != '[' {
+				valid = false
+				break
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				valid = false
+				break
+			}
+			if part := rest[1:end]; part != "" {
+				path = append(path, part)
+			}
+			rest = rest[end+1:]
+		}
+		if !valid || len(path) == 0 {
+			continue
+		}
+		name := strings.Join(path, ".")
+		out[name] = append(out[name], values...)
+	}
+	return out
+}
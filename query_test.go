@@ -0,0 +1,95 @@
+package octo
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newQueryCtx(rawQuery string) *Ctx[CustomData] {
+	req := httptest.NewRequest("GET", "/items?"+rawQuery, nil)
+	return &Ctx[CustomData]{Request: req, ResponseWriter: NewResponseWriterWrapper(httptest.NewRecorder())}
+}
+
+func TestQueryScalars(t *testing.T) {
+	id := uuid.New()
+	ctx := newQueryCtx("page=3&bad=x&on=yes&off=0&flag=maybe&since=2024-05-01&at=2024-05-01T10:00:00Z&unix=60&id=" + id.String() + "&empty=")
+
+	if n, err := ctx.QueryInt("page", 1); n != 3 || err != nil {
+		t.Errorf("QueryInt(page) = %d, %v", n, err)
+	}
+	if n, err := ctx.QueryInt("missing", 1); n != 1 || err != nil {
+		t.Errorf("QueryInt(missing) = %d, %v", n, err)
+	}
+	if n, err := ctx.QueryInt("empty", 5); n != 5 || err != nil {
+		t.Errorf("QueryInt(empty) = %d, %v", n, err)
+	}
+	n, err := ctx.QueryInt("bad", 1)
+	var qe *QueryError
+	if n != 1 || !errors.Is(err, ErrInvalidQuery) || !errors.As(err, &qe) || qe.Key != "bad" || qe.Value != "x" {
+		t.Errorf("QueryInt(bad) = %d, %v", n, err)
+	}
+
+	if b, err := ctx.QueryBool("on", false); !b || err != nil {
+		t.Errorf("QueryBool(on) = %v, %v", b, err)
+	}
+	if b, err := ctx.QueryBool("off", true); b || err != nil {
+		t.Errorf("QueryBool(off) = %v, %v", b, err)
+	}
+	if _, err := ctx.QueryBool("flag", false); err == nil {
+		t.Error("Expected QueryBool(flag) to fail")
+	}
+
+	for key, want := range map[string]time.Time{
+		"since": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"at":    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"unix":  time.Unix(60, 0).UTC(),
+	} {
+		if got, err := ctx.QueryTime(key, time.Time{}); !got.Equal(want) || err != nil {
+			t.Errorf("QueryTime(%s) = %v, %v", key, got, err)
+		}
+	}
+	if _, err := ctx.QueryTime("bad", time.Time{}); err == nil {
+		t.Error("Expected QueryTime(bad) to fail")
+	}
+
+	if got, err := ctx.QueryUUID("id", uuid.Nil); got != id || err != nil {
+		t.Errorf("QueryUUID(id) = %v, %v", got, err)
+	}
+	if got, err := ctx.QueryUUID("bad", uuid.Nil); got != uuid.Nil || err == nil {
+		t.Errorf("QueryUUID(bad) = %v, %v", got, err)
+	}
+}
+
+func TestQueryLists(t *testing.T) {
+	ctx := newQueryCtx("ids=1,2&ids=3&ids=&tags=a,,b&bad=1,x")
+	if ids, err := ctx.QueryInts("ids"); !reflect.DeepEqual(ids, []int{1, 2, 3}) || err != nil {
+		t.Errorf("QueryInts(ids) = %v, %v", ids, err)
+	}
+	if tags := ctx.QueryList("tags"); !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("QueryList(tags) = %v", tags)
+	}
+	if ids, err := ctx.QueryInts("missing"); ids != nil || err != nil {
+		t.Errorf("QueryInts(missing) = %v, %v", ids, err)
+	}
+	if _, err := ctx.QueryInts("bad"); err == nil || err.Error() != `invalid query parameter bad="x": not an integer` {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestQueryNested(t *testing.T) {
+	ctx := newQueryCtx("filter[status]=open&filter[created][gte]=2024-01-01&filter[tag][]=a&filter[tag][]=b&filter=x&filterx[a]=1&filter[broken=1&sort=name")
+	got := ctx.QueryNested("filter")
+	want := map[string][]string{
+		"status":      {"open"},
+		"created.gte": {"2024-01-01"},
+		"tag":         {"a", "b"},
+	}
+	if !reflect.DeepEqual(map[string][]string(got), want) {
+		t.Errorf("QueryNested(filter) = %v", got)
+	}
+}
@@ -0,0 +1,171 @@
+package octo
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Usage headers sent by RateLimitMiddleware
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// RateLimit allows Requests per Window
+type RateLimit struct {
+	Requests int64
+	Window   time.Duration
+}
+
+// RateLimitStore counts hits per key within fixed windows
+type RateLimitStore interface {
+	// Hit records a request for key and returns the count in the current
+	// window and when that window resets
+	Hit(key string, window time.Duration) (count int64, reset time.Time, err error)
+}
+
+type rateWindow struct {
+	count int64
+	reset time.Time
+}
+
+// MemoryRateLimitStore is an in-process fixed window RateLimitStore
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateWindow)}
+}
+
+func (s *MemoryRateLimitStore) Hit(key string, window time.Duration) (int64, time.Time, error) {
+	return s.Add(key, window, 1)
+}
+
+// Add records n hits in the current window of key
+func (s *MemoryRateLimitStore) Add(key string, window time.Duration, n int64) (int64, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, w := range s.windows {
+			if now.After(w.reset) {
+				delete(s.windows, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	w, ok := s.windows[key]
+	if !ok || now.After(w.reset) {
+		w = &rateWindow{reset: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count += n
+	return w.count, w.reset, nil
+}
+
+type principalLocalKey struct{}
+
+// SetPrincipal records the authenticated principal (user ID, API key...) for
+// the request. Authentication middleware should call it so rate limiting and
+// quotas can key on the caller instead of its IP.
+func (c *Ctx[V]) SetPrincipal(id string) {
+	c.setLocal(principalLocalKey{}, id)
+}
+
+// Principal returns the principal set by SetPrincipal or ""
+func (c *Ctx[V]) Principal() string {
+	id, _ := c.getLocal(principalLocalKey{}).(string)
+	return id
+}
+
+// RateLimitConfig configures RateLimitMiddleware
+type RateLimitConfig[V any] struct {
+	// Default applies to anonymous callers and unknown tiers
+	Default RateLimit
+	// Tiers maps a plan name (e.g. "free", "paid") to its limit
+	Tiers map[string]RateLimit
+	// Principal resolves the caller identity (default ctx.Principal()).
+	// Anonymous requests are keyed by client IP.
+	Principal func(ctx *Ctx[V]) string
+	// Tier resolves the plan of an authenticated principal
+	Tier func(ctx *Ctx[V], principal string) string
+	// Store defaults to a MemoryRateLimitStore
+	Store RateLimitStore
+	// Prefix namespaces keys when several limiters share a store
+	Prefix string
+	// DisableHeaders omits the X-RateLimit-* usage headers
+	DisableHeaders bool
+}
+
+// RateLimitMiddleware limits requests per principal (or client IP for anonymous
+// callers) using the limit of the caller's tier. Store errors fail open.
+func RateLimitMiddleware[V any](cfg RateLimitConfig[V]) MiddlewareFunc[V] {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore()
+	}
+	if cfg.Principal == nil {
+		cfg.Principal = func(ctx *Ctx[V]) string { return ctx.Principal() }
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			limit := cfg.Default
+			key := cfg.Prefix
+			if principal := cfg.Principal(ctx); principal != "" {
+				tier := ""
+				if cfg.Tier != nil {
+					tier = cfg.Tier(ctx, principal)
+				}
+				if l, ok := cfg.Tiers[tier]; ok {
+					limit = l
+				}
+				key += "p:" + tier + ":" + principal
+			} else {
+				key += "ip:" + ctx.ClientIP()
+			}
+			if limit.Requests <= 0 || limit.Window <= 0 {
+				next(ctx)
+				return
+			}
+
+			count, reset, err := cfg.Store.Hit(key, limit.Window)
+			if err != nil {
+				serverLogger := SubsystemLogger(LogServer)
+				if EnableLoggerCheck {
+					if serverLogger != nil {
+						serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] rate limit store failed")
+					}
+				} else {
+					serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] rate limit store failed")
+				}
+				next(ctx)
+				return
+			}
+
+			remaining := limit.Requests - count
+			if remaining < 0 {
+				remaining = 0
+			}
+			resetIn := int64(time.Until(reset).Round(time.Second) / time.Second)
+			if resetIn < 1 {
+				resetIn = 1
+			}
+			if !cfg.DisableHeaders {
+				ctx.SetHeader(RateLimitLimitHeader, strconv.FormatInt(limit.Requests, 10))
+				ctx.SetHeader(RateLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+				ctx.SetHeader(RateLimitResetHeader, strconv.FormatInt(resetIn, 10))
+			}
+			if count > limit.Requests {
+				ctx.SetHeader("Retry-After", strconv.FormatInt(resetIn, 10))
+				ctx.SendError("err_too_many_requests", nil)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
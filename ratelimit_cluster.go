@@ -0,0 +1,241 @@
+package octo
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitAdder is implemented by stores that can record several hits at
+// once, letting DistributedRateLimitStore report batches in one call
+type RateLimitAdder interface {
+	// Add records n requests for key and returns the count in the current
+	// window and when that window resets
+	Add(key string, window time.Duration, n int64) (count int64, reset time.Time, err error)
+}
+
+// DistributedRateLimitConfig configures a DistributedRateLimitStore
+type DistributedRateLimitConfig struct {
+	// Coordinator is the store shared by every replica (e.g. Redis). Stores
+	// implementing RateLimitAdder receive batched hits; others get one Hit
+	// per request.
+	Coordinator RateLimitStore
+	// ErrorBound is how many hits a replica may admit per key before
+	// reporting them to the coordinator. Counts seen by a replica lag the
+	// cluster total by at most ErrorBound per other replica. Zero reports
+	// every hit synchronously, which is exact but costs a round trip per
+	// request.
+	ErrorBound int64
+	// SyncInterval reports pending hits in the background so quiet keys
+	// converge too (1s when zero)
+	SyncInterval time.Duration
+	// Replicas scales local counts while the coordinator is unreachable, so
+	// the fallback approximates the cluster-wide limit (1 when zero)
+	Replicas int64
+	// RetryAfter is how long the store keeps to the local fallback after a
+	// coordinator failure (5s when zero)
+	RetryAfter time.Duration
+}
+
+type clusterWindow struct {
+	window  time.Duration
+	base    int64 // cluster count at the last report
+	pending int64 // hits admitted locally but not reported yet
+	reset   time.Time
+	synced  bool
+}
+
+// update records a coordinator answer. Concurrent reports may answer out of
+// order, so a lower count is ignored until the known window has ended.
+func (w *clusterWindow) update(count int64, reset time.Time) {
+	if !w.synced || count > w.base || !time.Now().Before(w.reset) {
+		w.base, w.reset, w.synced = count, reset, true
+	}
+}
+
+// DistributedRateLimitStore is an approximate RateLimitStore applying limits
+// across replicas. Each replica counts hits locally and reports them to a
+// shared coordinator in batches bounded by ErrorBound; when the coordinator
+// is unreachable it falls back to local counting until it recovers.
+type DistributedRateLimitStore struct {
+	cfg   DistributedRateLimitConfig
+	local *MemoryRateLimitStore
+
+	mu        sync.Mutex
+	windows   map[string]*clusterWindow
+	downUntil time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewDistributedRateLimitStore starts a store reporting to cfg.Coordinator.
+// Close stops its background sync.
+func NewDistributedRateLimitStore(cfg DistributedRateLimitConfig) *DistributedRateLimitStore {
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = 1
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Second
+	}
+	if cfg.ErrorBound < 0 {
+		cfg.ErrorBound = 0
+	}
+	s := &DistributedRateLimitStore{
+		cfg:     cfg,
+		local:   NewMemoryRateLimitStore(),
+		windows: make(map[string]*clusterWindow),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// Degraded reports whether the store is counting locally because the
+// coordinator failed recently
+func (s *DistributedRateLimitStore) Degraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.downUntil)
+}
+
+func (s *DistributedRateLimitStore) Hit(key string, window time.Duration) (int64, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	if now.Before(s.downUntil) {
+		s.mu.Unlock()
+		return s.fallback(key, window)
+	}
+	w, ok := s.windows[key]
+	if !ok || (w.synced && !now.Before(w.reset)) {
+		w = &clusterWindow{window: window}
+		s.windows[key] = w
+	}
+	w.pending++
+	if w.synced && w.pending <= s.cfg.ErrorBound {
+		count, reset := w.base+w.pending, w.reset
+		s.mu.Unlock()
+		return count, reset, nil
+	}
+	n := w.pending
+	w.pending = 0
+	s.mu.Unlock()
+
+	count, reset, err := s.report(key, window, n)
+	if err != nil {
+		s.fail(key, err)
+		return s.fallback(key, window)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.update(count, reset)
+	return count + w.pending, reset, nil
+}
+
+// report sends n hits to the coordinator
+func (s *DistributedRateLimitStore) report(key string, window time.Duration, n int64) (count int64, reset time.Time, err error) {
+	if adder, ok := s.cfg.Coordinator.(RateLimitAdder); ok {
+		return adder.Add(key, window, n)
+	}
+	for i := int64(0); i < n; i++ {
+		if count, reset, err = s.cfg.Coordinator.Hit(key, window); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// fallback counts key locally, scaled to the expected cluster size
+func (s *DistributedRateLimitStore) fallback(key string, window time.Duration) (int64, time.Time, error) {
+	count, reset, err := s.local.Hit(key, window)
+	return count * s.cfg.Replicas, reset, err
+}
+
+// fail switches to the local fallback for RetryAfter. Pending hits are
+// dropped: the coordinator state is unknown until it answers again.
+func (s *DistributedRateLimitStore) fail(key string, err error) {
+	s.mu.Lock()
+	degraded := time.Now().Before(s.downUntil)
+	s.downUntil = time.Now().Add(s.cfg.RetryAfter)
+	s.windows = make(map[string]*clusterWindow)
+	s.mu.Unlock()
+	if degraded {
+		return
+	}
+	serverLogger := SubsystemLogger(LogServer)
+	if EnableLoggerCheck {
+		if serverLogger != nil {
+			serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] rate limit coordinator unreachable, counting locally")
+		}
+	} else {
+		serverLogger.Warn().Err(err).Str("key", key).Msg("[octo] rate limit coordinator unreachable, counting locally")
+	}
+}
+
+// Flush reports every pending hit to the coordinator and drops expired
+// windows
+func (s *DistributedRateLimitStore) Flush() error {
+	type batch struct {
+		key    string
+		w      *clusterWindow
+		window time.Duration
+		n      int64
+	}
+	now := time.Now()
+	s.mu.Lock()
+	if now.Before(s.downUntil) {
+		s.mu.Unlock()
+		return nil
+	}
+	var batches []batch
+	for key, w := range s.windows {
+		if w.synced && !now.Before(w.reset) {
+			delete(s.windows, key)
+			continue
+		}
+		if w.pending > 0 {
+			batches = append(batches, batch{key: key, w: w, window: w.window, n: w.pending})
+			w.pending = 0
+		}
+	}
+	s.mu.Unlock()
+
+	for _, b := range batches {
+		count, reset, err := s.report(b.key, b.window, b.n)
+		if err != nil {
+			s.fail(b.key, err)
+			return err
+		}
+		s.mu.Lock()
+		b.w.update(count, reset)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *DistributedRateLimitStore) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background sync and reports the remaining hits
+func (s *DistributedRateLimitStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.Flush()
+}
+
+var _ RateLimitAdder = (*MemoryRateLimitStore)(nil)
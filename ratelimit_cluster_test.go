@@ -0,0 +1,116 @@
+package octo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyRateLimitStore is a coordinator that can be taken down
+type flakyRateLimitStore struct {
+	*MemoryRateLimitStore
+	mu    sync.Mutex
+	down  bool
+	calls int
+}
+
+func (s *flakyRateLimitStore) Add(key string, window time.Duration, n int64) (int64, time.Time, error) {
+	s.mu.Lock()
+	s.calls++
+	down := s.down
+	s.mu.Unlock()
+	if down {
+		return 0, time.Time{}, errors.New("connection refused")
+	}
+	return s.MemoryRateLimitStore.Add(key, window, n)
+}
+
+func (s *flakyRateLimitStore) Hit(key string, window time.Duration) (int64, time.Time, error) {
+	return s.Add(key, window, 1)
+}
+
+func (s *flakyRateLimitStore) set(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func (s *flakyRateLimitStore) reports() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func TestDistributedRateLimitStoreErrorBound(t *testing.T) {
+	coord := &flakyRateLimitStore{MemoryRateLimitStore: NewMemoryRateLimitStore()}
+	cfg := DistributedRateLimitConfig{Coordinator: coord, ErrorBound: 5, SyncInterval: time.Hour}
+	a := NewDistributedRateLimitStore(cfg)
+	b := NewDistributedRateLimitStore(cfg)
+	defer a.Close()
+	defer b.Close()
+
+	for i := 0; i < 20; i++ {
+		a.Hit("k", time.Minute)
+	}
+	// The first hit is reported to learn the window, then one report per
+	// ErrorBound+1 hits
+	if got := coord.reports(); got > 5 {
+		t.Errorf("Expected batched reports, got %d for 20 hits", got)
+	}
+	count, _, err := b.Hit("k", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count < 21-5 || count > 21 {
+		t.Errorf("Expected a count within the error bound of 21, got %d", count)
+	}
+
+	a.Flush()
+	if count, _, _ := coord.MemoryRateLimitStore.Add("k", time.Minute, 0); count != 21 {
+		t.Errorf("Expected the coordinator to hold every hit after Flush, got %d", count)
+	}
+}
+
+func TestDistributedRateLimitStoreExact(t *testing.T) {
+	coord := &flakyRateLimitStore{MemoryRateLimitStore: NewMemoryRateLimitStore()}
+	s := NewDistributedRateLimitStore(DistributedRateLimitConfig{Coordinator: coord})
+	defer s.Close()
+	for i := int64(1); i <= 3; i++ {
+		if count, _, _ := s.Hit("k", time.Minute); count != i {
+			t.Errorf("Hit %d: expected an exact count, got %d", i, count)
+		}
+	}
+	if coord.reports() != 3 {
+		t.Errorf("Expected one report per hit with no error bound, got %d", coord.reports())
+	}
+}
+
+func TestDistributedRateLimitStoreFallback(t *testing.T) {
+	coord := &flakyRateLimitStore{MemoryRateLimitStore: NewMemoryRateLimitStore(), down: true}
+	s := NewDistributedRateLimitStore(DistributedRateLimitConfig{
+		Coordinator: coord,
+		ErrorBound:  10,
+		Replicas:    3,
+		RetryAfter:  50 * time.Millisecond,
+	})
+	defer s.Close()
+
+	count, _, err := s.Hit("k", time.Minute)
+	if err != nil || count != 3 {
+		t.Errorf("Expected the local count scaled by replicas, got %d, %v", count, err)
+	}
+	if !s.Degraded() {
+		t.Error("Expected the store to be degraded")
+	}
+	s.Hit("k", time.Minute)
+	if coord.reports() != 1 {
+		t.Errorf("Expected no coordinator calls while degraded, got %d", coord.reports())
+	}
+
+	coord.set(false)
+	time.Sleep(60 * time.Millisecond)
+	if count, _, _ := s.Hit("k", time.Minute); count != 1 || s.Degraded() {
+		t.Errorf("Expected the coordinator to be used again, got count %d", count)
+	}
+}
@@ -0,0 +1,70 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddlewareTiers(t *testing.T) {
+	router := NewRouter[CustomData]()
+	auth := func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			if user := ctx.GetHeader("X-User"); user != "" {
+				ctx.SetPrincipal(user)
+			}
+			next(ctx)
+		}
+	}
+	router.Use(auth)
+	router.Use(RateLimitMiddleware(RateLimitConfig[CustomData]{
+		Default: RateLimit{Requests: 1, Window: time.Minute},
+		Tiers: map[string]RateLimit{
+			"free": {Requests: 2, Window: time.Minute},
+			"paid": {Requests: 5, Window: time.Minute},
+		},
+		Tier: func(ctx *Ctx[CustomData], principal string) string {
+			if principal == "alice" {
+				return "paid"
+			}
+			return "free"
+		},
+	}))
+	router.GET("/api", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	})
+
+	hit := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api", nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := hit("bob"); w.Code != http.StatusOK {
+			t.Fatalf("Expected free request %d to pass, got %d", i, w.Code)
+		}
+	}
+	w := hit("bob")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after free tier exhausted, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get(RateLimitRemainingHeader) != "0" {
+		t.Errorf("Expected Retry-After and zero remaining, got %v", w.Header())
+	}
+
+	w = hit("alice")
+	if w.Code != http.StatusOK || w.Header().Get(RateLimitLimitHeader) != "5" || w.Header().Get(RateLimitRemainingHeader) != "4" {
+		t.Errorf("Expected paid tier headers 5/4, got %d %v", w.Code, w.Header())
+	}
+
+	hit("")
+	if w := hit(""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected anonymous limit by IP, got %d", w.Code)
+	}
+}
@@ -0,0 +1,316 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RedactedValue replaces redacted headers and JSON fields in recordings
+const RedactedValue = "[REDACTED]"
+
+// RecordedBody is a body stored as text, or base64 when it is not valid UTF-8
+type RecordedBody struct {
+	Data   string `json:"data"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+func newRecordedBody(b []byte) RecordedBody {
+	if utf8.Valid(b) {
+		return RecordedBody{Data: string(b)}
+	}
+	return RecordedBody{Data: base64.StdEncoding.EncodeToString(b), Base64: true}
+}
+
+// Bytes returns the decoded body
+func (b RecordedBody) Bytes() []byte {
+	if b.Base64 {
+		data, _ := base64.StdEncoding.DecodeString(b.Data)
+		return data
+	}
+	return []byte(b.Data)
+}
+
+// Recording is a captured request/response pair
+type Recording struct {
+	Time           time.Time    `json:"time"`
+	Method         string       `json:"method"`
+	URL            string       `json:"url"`
+	Header         http.Header  `json:"header,omitempty"`
+	Body           RecordedBody `json:"body"`
+	Status         int          `json:"status"`
+	ResponseHeader http.Header  `json:"response_header,omitempty"`
+	ResponseBody   RecordedBody `json:"response_body"`
+}
+
+// RecordConfig configures RecordMiddleware
+type RecordConfig struct {
+	// Dir receives one JSON file per exchange
+	Dir string
+	// RedactHeaders are masked in requests and responses
+	// (default Authorization, Cookie, Set-Cookie)
+	RedactHeaders []string
+	// RedactFields are JSON object keys masked at any depth in both bodies
+	RedactFields []string
+	// Filter selects the requests to record (default all)
+	Filter func(req *http.Request) bool
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// RecordMiddleware captures request/response pairs to cfg.Dir for later use
+// with Replay. Bodies are captured through NeedBody and the response wrapper.
+func RecordMiddleware[V any](cfg RecordConfig) MiddlewareFunc[V] {
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = defaultRedactHeaders
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		panic("octo: record dir: " + err.Error())
+	}
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if cfg.Filter != nil && !cfg.Filter(ctx.Request) {
+				next(ctx)
+				return
+			}
+			if err := ctx.NeedBody(); err != nil {
+				ctx.SendError("err_invalid_request", err)
+				return
+			}
+			rw := ctx.ResponseWriter
+			rw.CaptureBody = true
+			start := 0
+			if rw.Body != nil {
+				start = rw.Body.Len()
+			}
+			rec := Recording{
+				Time:   time.Now().UTC(),
+				Method: ctx.Request.Method,
+				URL:    ctx.Request.URL.RequestURI(),
+				Header: redactHeader(ctx.Request.Header, cfg.RedactHeaders),
+				Body:   newRecordedBody(redactJSON(ctx.Body, cfg.RedactFields)),
+			}
+
+			next(ctx)
+
+			var body []byte
+			if rw.Body != nil {
+				body = rw.Body.Bytes()[start:]
+			}
+			rec.Status = rw.Status
+			rec.ResponseHeader = redactHeader(rw.Header(), cfg.RedactHeaders)
+			rec.ResponseBody = newRecordedBody(redactJSON(body, cfg.RedactFields))
+			if err := writeRecording(cfg.Dir, ctx.UUID, &rec); err != nil {
+				serverLogger := SubsystemLogger(LogServer)
+				if EnableLoggerCheck {
+					if serverLogger != nil {
+						serverLogger.Warn().Err(err).Msg("[octo] failed to write recording")
+					}
+				} else {
+					serverLogger.Warn().Err(err).Msg("[octo] failed to write recording")
+				}
+			}
+		}
+	}
+}
+
+func writeRecording(dir, id string, rec *Recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.json", rec.Time.UnixNano(), id)
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
+
+func redactHeader(h http.Header, names []string) http.Header {
+	out := h.Clone()
+	for _, name := range names {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, RedactedValue)
+		}
+	}
+	return out
+}
+
+// redactJSON masks fields in JSON bodies; other bodies are returned unchanged
+func redactJSON(body []byte, fields []string) []byte {
+	if len(fields) == 0 || !json.Valid(body) {
+		return body
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	redactValue(v, set)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func redactValue(v interface{}, fields map[string]bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if fields[k] {
+				val[k] = RedactedValue
+				continue
+			}
+			redactValue(child, fields)
+		}
+	case []interface{}:
+		for _, child := range val {
+			redactValue(child, fields)
+		}
+	}
+}
+
+// LoadRecordings reads every recording in dir, oldest first
+func LoadRecordings(dir string) ([]Recording, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	recs := make([]Recording, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// ReplayOptions configures Replay
+type ReplayOptions struct {
+	// IgnoreFields are JSON keys skipped when comparing bodies (default "time")
+	IgnoreFields []string
+	// Prepare can restore redacted credentials before a request is replayed
+	Prepare func(req *http.Request, rec *Recording)
+}
+
+// ReplayResult is the outcome of replaying one recording. Mismatch is empty
+// when the new response matches the recorded one.
+type ReplayResult struct {
+	Recording Recording
+	Status    int
+	Body      []byte
+	Mismatch  string
+}
+
+// Replay feeds recordings back through h and compares status and body with
+// what was recorded. JSON bodies are compared structurally.
+func Replay(h http.Handler, recs []Recording, opts ReplayOptions) []ReplayResult {
+	if opts.IgnoreFields == nil {
+		opts.IgnoreFields = []string{"time"}
+	}
+	ignore := make(map[string]bool, len(opts.IgnoreFields))
+	for _, f := range opts.IgnoreFields {
+		ignore[f] = true
+	}
+	results := make([]ReplayResult, 0, len(recs))
+	for _, rec := range recs {
+		req := httptest.NewRequest(rec.Method, rec.URL, bytes.NewReader(rec.Body.Bytes()))
+		for k, v := range rec.Header {
+			req.Header[k] = v
+		}
+		if opts.Prepare != nil {
+			opts.Prepare(req, &rec)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		res := ReplayResult{Recording: rec, Status: w.Code, Body: w.Body.Bytes()}
+		if w.Code != rec.Status {
+			res.Mismatch = fmt.Sprintf("status %d, recorded %d", w.Code, rec.Status)
+		} else if !replayBodiesEqual(rec.ResponseBody.Bytes(), res.Body, ignore) {
+			res.Mismatch = "response body differs"
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func replayBodiesEqual(recorded, actual []byte, ignore map[string]bool) bool {
+	if !json.Valid(recorded) || !json.Valid(actual) {
+		return bytes.Equal(recorded, actual)
+	}
+	var a, b interface{}
+	json.Unmarshal(recorded, &a)
+	json.Unmarshal(actual, &b)
+	return replayValuesEqual(a, b, ignore)
+}
+
+// replayValuesEqual compares JSON values, skipping ignored keys and keys that
+// were redacted in the recording since they can never match the live response
+func replayValuesEqual(recorded, actual interface{}, ignore map[string]bool) bool {
+	switch rv := recorded.(type) {
+	case map[string]interface{}:
+		av, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, x := range rv {
+			if ignore[k] || x == RedactedValue {
+				continue
+			}
+			y, ok := av[k]
+			if !ok || !replayValuesEqual(x, y, ignore) {
+				return false
+			}
+		}
+		for k := range av {
+			if _, ok := rv[k]; !ok && !ignore[k] {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		av, ok := actual.([]interface{})
+		if !ok || len(av) != len(rv) {
+			return false
+		}
+		for i := range rv {
+			if !replayValuesEqual(rv[i], av[i], ignore) {
+				return false
+			}
+		}
+		return true
+	}
+	return jsonEqual(recorded, actual)
+}
+
+// ReplayReport formats the mismatching results, one per line ("" when all
+// recordings matched)
+func ReplayReport(results []ReplayResult) string {
+	var sb strings.Builder
+	for _, r := range results {
+		if r.Mismatch != "" {
+			fmt.Fprintf(&sb, "%s %s: %s\n", r.Recording.Method, r.Recording.URL, r.Mismatch)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	version := "v1"
+	build := func() *Router[CustomData] {
+		router := NewRouter[CustomData]()
+		router.Use(RecordMiddleware[CustomData](RecordConfig{Dir: dir, RedactFields: []string{"password"}}))
+		router.POST("/login", func(ctx *Ctx[CustomData]) {
+			var body map[string]string
+			ctx.ShouldBindJSON(&body)
+			ctx.NewJSONResult(map[string]string{"user": body["user"], "version": version, "password": body["password"]}, nil)
+		})
+		return router
+	}
+
+	router := build()
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"user":"ann","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	recs, err := LoadRecordings(dir)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("Expected one recording, got %d (%v)", len(recs), err)
+	}
+	rec := recs[0]
+	if rec.Header.Get("Authorization") != RedactedValue || strings.Contains(rec.Body.Data, "hunter2") || strings.Contains(rec.ResponseBody.Data, "hunter2") {
+		t.Errorf("Expected credentials to be redacted, got %+v", rec)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.ResponseBody.Bytes(), &resp); err != nil || rec.Status != http.StatusOK {
+		t.Errorf("Expected recorded JSON response, got %d %s", rec.Status, rec.ResponseBody.Data)
+	}
+
+	replayTarget := NewRouter[CustomData]()
+	replayTarget.POST("/login", func(ctx *Ctx[CustomData]) {
+		var body map[string]string
+		ctx.ShouldBindJSON(&body)
+		ctx.NewJSONResult(map[string]string{"user": body["user"], "version": version, "password": body["password"]}, nil)
+	})
+	if report := ReplayReport(Replay(replayTarget, recs, ReplayOptions{})); report != "" {
+		t.Errorf("Expected replay to match, got %s", report)
+	}
+
+	version = "v2"
+	results := Replay(replayTarget, recs, ReplayOptions{})
+	if results[0].Mismatch == "" {
+		t.Errorf("Expected replay to detect the changed response")
+	}
+}
@@ -0,0 +1,259 @@
+package redisstore
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a minimal in-process Redis speaking RESP, implementing the
+// commands used by the stores. Lua scripts are emulated in Go.
+type fakeRedis struct {
+	t        *testing.T
+	ln       net.Listener
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]int64
+	expires  map[string]time.Time
+	loaded   map[string]bool
+	commands []string
+	password string
+}
+
+// fakeScripts emulates the Lua scripts of the package by source
+var fakeScripts = map[string]func(f *fakeRedis, keys, args []string) interface{}{
+	fixedWindowScript.src: func(f *fakeRedis, keys, args []string) interface{} {
+		n, _ := strconv.ParseInt(f.strings[keys[0]], 10, 64)
+		by, _ := strconv.ParseInt(args[1], 10, 64)
+		n += by
+		f.strings[keys[0]] = strconv.FormatInt(n, 10)
+		exp, ok := f.expires[keys[0]]
+		if !ok {
+			ms, _ := strconv.Atoi(args[0])
+			exp = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			f.expires[keys[0]] = exp
+		}
+		return []interface{}{n, time.Until(exp).Milliseconds()}
+	},
+}
+
+// auth requires password from connections accepted afterwards
+func (f *fakeRedis) auth(password string) {
+	f.mu.Lock()
+	f.password = password
+	f.mu.Unlock()
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		t:       t,
+		ln:      ln,
+		strings: make(map[string]string),
+		hashes:  make(map[string]map[string]int64),
+		expires: make(map[string]time.Time),
+		loaded:  make(map[string]bool),
+	}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+// value returns a string key under the lock
+func (f *fakeRedis) value(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.strings[key]
+	return v, ok
+}
+
+// count returns how many times cmd was executed
+func (f *fakeRedis) count(cmd string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.commands {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeRedis) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		c, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(c)
+	}
+}
+
+func (f *fakeRedis) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	f.mu.Lock()
+	password := f.password
+	f.mu.Unlock()
+	authed := password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		cmd := strings.ToUpper(args[0])
+		var out interface{}
+		if !authed && cmd != "AUTH" {
+			out = fmt.Errorf("NOAUTH Authentication required")
+		} else if cmd == "AUTH" {
+			authed = args[len(args)-1] == password
+			out = "OK"
+			if !authed {
+				out = fmt.Errorf("WRONGPASS invalid password")
+			}
+		} else {
+			out = f.exec(cmd, args[1:])
+		}
+		writeFakeReply(w, out)
+		if r.Buffered() == 0 {
+			w.Flush()
+		}
+	}
+}
+
+func (f *fakeRedis) expire(key string) {
+	if exp, ok := f.expires[key]; ok && !time.Now().Before(exp) {
+		delete(f.strings, key)
+		delete(f.hashes, key)
+		delete(f.expires, key)
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, cmd)
+	if len(args) > 0 {
+		f.expire(args[0])
+	}
+	switch cmd {
+	case "PING":
+		return "PONG"
+	case "SELECT":
+		return "OK"
+	case "GET":
+		v, ok := f.strings[args[0]]
+		if !ok {
+			return nil
+		}
+		return []byte(v)
+	case "SET":
+		f.strings[args[0]] = args[1]
+		delete(f.expires, args[0])
+		if len(args) == 4 && strings.ToUpper(args[2]) == "PX" {
+			ms, _ := strconv.Atoi(args[3])
+			f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "OK"
+	case "DEL":
+		var n int64
+		for _, k := range args {
+			if _, ok := f.strings[k]; ok {
+				n++
+			}
+			delete(f.strings, k)
+			delete(f.hashes, k)
+			delete(f.expires, k)
+		}
+		return n
+	case "PEXPIRE":
+		ms, _ := strconv.Atoi(args[1])
+		f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return int64(1)
+	case "HINCRBY":
+		n, _ := strconv.ParseInt(args[2], 10, 64)
+		if f.hashes[args[0]] == nil {
+			f.hashes[args[0]] = make(map[string]int64)
+		}
+		f.hashes[args[0]][args[1]] += n
+		return f.hashes[args[0]][args[1]]
+	case "HMGET":
+		out := make([]interface{}, len(args)-1)
+		for i, field := range args[1:] {
+			if v, ok := f.hashes[args[0]][field]; ok {
+				out[i] = []byte(strconv.FormatInt(v, 10))
+			}
+		}
+		return out
+	case "EVALSHA", "EVAL":
+		src := args[0]
+		if cmd == "EVALSHA" {
+			if !f.loaded[args[0]] {
+				return fmt.Errorf("NOSCRIPT No matching script")
+			}
+			for s := range fakeScripts {
+				if sha(s) == args[0] {
+					src = s
+				}
+			}
+		} else {
+			f.loaded[sha(src)] = true
+		}
+		fn, ok := fakeScripts[src]
+		if !ok {
+			return fmt.Errorf("ERR unknown script")
+		}
+		n, _ := strconv.Atoi(args[1])
+		for _, k := range args[2 : 2+n] {
+			f.expire(k)
+		}
+		return fn(f, args[2:2+n], args[2+n:])
+	}
+	return fmt.Errorf("ERR unknown command %s", cmd)
+}
+
+func sha(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFakeReply(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		w.WriteString("+" + v + "\r\n")
+	case error:
+		w.WriteString("-" + v.Error() + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case []byte:
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + string(v) + "\r\n")
+	case []interface{}:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			writeFakeReply(w, item)
+		}
+	}
+}
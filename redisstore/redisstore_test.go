@@ -0,0 +1,162 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+func TestClientCommands(t *testing.T) {
+	f := newFakeRedis(t)
+	f.auth("secret")
+	c := NewClient(Options{Addr: f.addr(), Password: "secret", DB: 2, Prefix: "app:"})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if err := c.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if v, _ := f.value("app:k"); v != "v" {
+		t.Error("Expected the key prefix to be applied")
+	}
+	c.Del(ctx, "k")
+	if v, err := c.Get(ctx, "k"); err != nil || v != nil {
+		t.Errorf("Get after Del = %q, %v", v, err)
+	}
+
+	replies, err := c.Pipeline(ctx, []string{"SET", "a", "1"}, []string{"GET", "a"}, []string{"BOGUS"})
+	if err != nil || len(replies) != 3 || string(replies[1].([]byte)) != "1" {
+		t.Fatalf("Pipeline = %v, %v", replies, err)
+	}
+	if _, ok := replies[2].(Error); !ok {
+		t.Errorf("Expected an Error reply, got %#v", replies[2])
+	}
+	if _, err := c.Do(ctx, "BOGUS"); err == nil {
+		t.Error("Expected Do to return error replies as errors")
+	}
+
+	bad := NewClient(Options{Addr: f.addr(), Password: "wrong"})
+	defer bad.Close()
+	if err := bad.Ping(ctx); err == nil {
+		t.Error("Expected authentication to fail")
+	}
+
+	c.Close()
+	if err := c.Ping(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClientPooling(t *testing.T) {
+	f := newFakeRedis(t)
+	c := NewClient(Options{Addr: f.addr(), PoolSize: 2})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Ping(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(c.idle) > 2 {
+		t.Errorf("Pool kept %d idle connections", len(c.idle))
+	}
+}
+
+func TestRateLimitStore(t *testing.T) {
+	f := newFakeRedis(t)
+	c := NewClient(Options{Addr: f.addr(), Prefix: "svc:"})
+	defer c.Close()
+	store := NewRateLimitStore(c)
+
+	for i := int64(1); i <= 3; i++ {
+		count, reset, err := store.Hit("ip", time.Minute)
+		if err != nil || count != i || time.Until(reset) < 58*time.Second {
+			t.Fatalf("Hit %d = %d, %v, %v", i, count, reset, err)
+		}
+	}
+	if evals := f.count("EVAL"); evals != 1 {
+		t.Errorf("Expected the script source to be sent once, got %d", evals)
+	}
+	if _, ok := f.value("svc:ratelimit:ip"); !ok {
+		t.Error("Expected a prefixed window key")
+	}
+
+	router := octo.NewRouter[any]()
+	router.GET("/", func(ctx *octo.Ctx[any]) { ctx.SendString(200, "ok") },
+		octo.RateLimitMiddleware(octo.RateLimitConfig[any]{Default: octo.RateLimit{Requests: 1, Window: time.Minute}, Store: store}))
+	for i, want := range []int{200, 429} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, w.Code)
+		}
+	}
+}
+
+func TestDistributedRateLimit(t *testing.T) {
+	f := newFakeRedis(t)
+	c := NewClient(Options{Addr: f.addr()})
+	defer c.Close()
+
+	if count, _, err := NewRateLimitStore(c).Add("k", time.Minute, 5); err != nil || count != 5 {
+		t.Fatalf("Add = %d, %v", count, err)
+	}
+
+	// Two replicas sharing one coordinator see each other's hits
+	a := octo.NewDistributedRateLimitStore(octo.DistributedRateLimitConfig{Coordinator: NewRateLimitStore(c), ErrorBound: 3})
+	b := octo.NewDistributedRateLimitStore(octo.DistributedRateLimitConfig{Coordinator: NewRateLimitStore(c), ErrorBound: 3})
+	for i := 0; i < 4; i++ {
+		a.Hit("shared", time.Minute)
+	}
+	a.Close()
+	count, _, err := b.Hit("shared", time.Minute)
+	b.Close()
+	if err != nil || count != 5 {
+		t.Errorf("Expected replica b to count 5 hits, got %d, %v", count, err)
+	}
+}
+
+func TestUsageAndJobStores(t *testing.T) {
+	f := newFakeRedis(t)
+	c := NewClient(Options{Addr: f.addr()})
+	defer c.Close()
+
+	usage := NewUsageStore(c)
+	usage.TTL = time.Hour
+	usage.Add("key", "2024-05", 1, 10)
+	usage.Add("key", "2024-05", 2, 5)
+	if u, err := usage.Get("key", "2024-05"); err != nil || u.Requests != 3 || u.Bytes != 15 {
+		t.Errorf("Usage = %+v, %v", u, err)
+	}
+	if u, err := usage.Get("none", "2024-05"); err != nil || u.Requests != 0 {
+		t.Errorf("Usage(none) = %+v, %v", u, err)
+	}
+
+	jobs := NewJobStore(c, time.Millisecond)
+	jobs.Save(octo.Job{ID: "run", State: octo.JobRunning})
+	jobs.Save(octo.Job{ID: "done", State: octo.JobSucceeded})
+	if job, err := jobs.Get("run"); err != nil || job.State != octo.JobRunning {
+		t.Errorf("Get(run) = %+v, %v", job, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := jobs.Get("done"); !errors.Is(err, octo.ErrJobNotFound) {
+		t.Errorf("Expected the finished job to expire, got %v", err)
+	}
+}
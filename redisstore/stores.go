@@ -0,0 +1,215 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// Timeout bounds store calls made through interfaces without a context
+var Timeout = time.Second
+
+// fixedWindowScript adds ARGV[2] hits to a window counter, starting the
+// window on the first hit, and returns the count and the milliseconds until
+// reset
+var fixedWindowScript = NewScript(`
+local count = redis.call('INCRBY', KEYS[1], ARGV[2])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+  ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RateLimitStore is an octo.RateLimitStore shared by every replica
+type RateLimitStore struct {
+	client *Client
+	prefix string
+}
+
+// NewRateLimitStore stores rate limit windows under the client prefix plus
+// "ratelimit:"
+func NewRateLimitStore(c *Client) *RateLimitStore {
+	return &RateLimitStore{client: c, prefix: "ratelimit:"}
+}
+
+func (s *RateLimitStore) Hit(key string, window time.Duration) (int64, time.Time, error) {
+	return s.Add(key, window, 1)
+}
+
+// Add records n hits at once, so an octo.DistributedRateLimitStore can
+// report its batches in one round trip
+func (s *RateLimitStore) Add(key string, window time.Duration, n int64) (int64, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	reply, err := fixedWindowScript.Run(ctx, s.client, []string{s.client.Key(s.prefix + key)},
+		strconv.FormatInt(window.Milliseconds(), 10), strconv.FormatInt(n, 10))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, time.Time{}, errors.New("redisstore: unexpected rate limit reply")
+	}
+	count, err := Int(values[0])
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	ttl, err := Int(values[1])
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, time.Now().Add(time.Duration(ttl) * time.Millisecond), nil
+}
+
+// UsageStore is an octo.UsageStore keeping counters in hashes
+type UsageStore struct {
+	client *Client
+	prefix string
+	// TTL expires the counters of a period after its last update (none when
+	// zero)
+	TTL time.Duration
+}
+
+// NewUsageStore stores usage under the client prefix plus "usage:"
+func NewUsageStore(c *Client) *UsageStore {
+	return &UsageStore{client: c, prefix: "usage:"}
+}
+
+func (s *UsageStore) key(key, period string) string {
+	return s.client.Key(s.prefix + period + ":" + key)
+}
+
+func (s *UsageStore) Add(key, period string, requests, bytes int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	k := s.key(key, period)
+	cmds := [][]string{
+		{"HINCRBY", k, "requests", strconv.FormatInt(requests, 10)},
+		{"HINCRBY", k, "bytes", strconv.FormatInt(bytes, 10)},
+	}
+	if s.TTL > 0 {
+		cmds = append(cmds, []string{"PEXPIRE", k, strconv.FormatInt(s.TTL.Milliseconds(), 10)})
+	}
+	replies, err := s.client.Pipeline(ctx, cmds...)
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if e, ok := reply.(Error); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *UsageStore) Get(key, period string) (octo.Usage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	u := octo.Usage{Key: key, Period: period}
+	reply, err := s.client.Do(ctx, "HMGET", s.key(key, period), "requests", "bytes")
+	if err != nil {
+		return u, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return u, errors.New("redisstore: unexpected usage reply")
+	}
+	if u.Requests, err = Int(values[0]); err != nil {
+		return u, err
+	}
+	u.Bytes, err = Int(values[1])
+	return u, err
+}
+
+// JobStore is an octo.JobStore so any replica can answer job status polls
+type JobStore struct {
+	client    *Client
+	prefix    string
+	retention time.Duration
+}
+
+// NewJobStore stores jobs under the client prefix plus "jobs:". Finished jobs
+// expire after retention (one hour when zero).
+func NewJobStore(c *Client, retention time.Duration) *JobStore {
+	if retention <= 0 {
+		retention = time.Hour
+	}
+	return &JobStore{client: c, prefix: "jobs:", retention: retention}
+}
+
+func (s *JobStore) Save(job octo.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", s.client.Key(s.prefix + job.ID), string(data)}
+	if job.State.Finished() {
+		args = append(args, "PX", strconv.FormatInt(s.retention.Milliseconds(), 10))
+	}
+	_, err = s.client.Do(ctx, args...)
+	return err
+}
+
+func (s *JobStore) Get(id string) (octo.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	reply, err := s.client.Do(ctx, "GET", s.client.Key(s.prefix+id))
+	if err != nil {
+		return octo.Job{}, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return octo.Job{}, octo.ErrJobNotFound
+	}
+	var job octo.Job
+	err = json.Unmarshal(data, &job)
+	return job, err
+}
+
+// Get returns the value of key (with the client prefix) or nil when missing,
+// for caches and other simple state
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.Do(ctx, "GET", c.Key(key))
+	if err != nil {
+		return nil, err
+	}
+	data, _ := reply.([]byte)
+	return data, nil
+}
+
+// Set stores value under key (with the client prefix); a positive ttl
+// expires it
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", c.Key(key), string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Del removes keys (with the client prefix)
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, k := range keys {
+		args = append(args, c.Key(k))
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+var (
+	_ octo.RateLimitStore = (*RateLimitStore)(nil)
+	_ octo.RateLimitAdder = (*RateLimitStore)(nil)
+	_ octo.UsageStore     = (*UsageStore)(nil)
+	_ octo.JobStore       = (*JobStore)(nil)
+)
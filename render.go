@@ -0,0 +1,298 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// unsafeTemplateTypes are the html/template types that bypass auto-escaping
+var unsafeTemplateTypes = []reflect.Type{
+	reflect.TypeOf(template.HTML("")),
+	reflect.TypeOf(template.HTMLAttr("")),
+	reflect.TypeOf(template.JS("")),
+	reflect.TypeOf(template.JSStr("")),
+	reflect.TypeOf(template.CSS("")),
+	reflect.TypeOf(template.URL("")),
+	reflect.TypeOf(template.Srcset("")),
+}
+
+// RequestFuncs builds template functions bound to the request being rendered
+type RequestFuncs[V any] func(ctx *Ctx[V]) template.FuncMap
+
+// Renderer renders html/template templates with a curated set of functions:
+//
+//	asset "app.js"             fingerprinted asset URL (see SetupAssets)
+//	asset_sri "app.js"         integrity value of the asset (see AssetSRI)
+//	url_for "/users/:id" "id" 1 route path with escaped parameters
+//	json .Value                JSON literal safe to embed in <script>
+//	request_id                 Ctx.UUID of the current request
+//	csrf_token                 value of Renderer.CSRFToken
+//	user                       value of Renderer.User
+//	trans "key" args...        value of Renderer.Translate
+//
+// Functions returning template.HTML (or other escaping-bypass types) are
+// rejected unless AllowUnsafeFuncs is set, so auto-escaping cannot be
+// disabled by accident.
+type Renderer[V any] struct {
+	// CSRFToken returns the token exposed as csrf_token
+	CSRFToken func(ctx *Ctx[V]) string
+	// User returns the value exposed as user
+	User func(ctx *Ctx[V]) interface{}
+	// Translate implements trans; defaults to fmt.Sprintf(key, args...)
+	Translate func(ctx *Ctx[V], key string, args ...interface{}) string
+	// AllowUnsafeFuncs permits functions returning template.HTML and friends
+	AllowUnsafeFuncs bool
+
+	mu           sync.RWMutex
+	templates    *template.Template
+	funcs        template.FuncMap
+	requestFuncs []RequestFuncs[V]
+	fsys         fs.FS
+	patterns     []string
+	bufPool      sync.Pool
+}
+
+// NewRenderer creates a renderer for the templates of fsys matching patterns.
+// Call Load once functions are registered.
+func NewRenderer[V any](fsys fs.FS, patterns ...string) *Renderer[V] {
+	r := &Renderer[V]{
+		fsys:     fsys,
+		patterns: patterns,
+		bufPool: sync.Pool{
+			New: func() interface{} { return new(bytes.Buffer) },
+		},
+	}
+	r.funcs = template.FuncMap{
+		"asset":     AssetPath,
+		"asset_sri": AssetSRI,
+		"url_for":   URLFor,
+		"json":      templateJSON,
+		// Placeholders so templates parse; replaced per render
+		"request_id": func() string { return "" },
+		"csrf_token": func() string { return "" },
+		"user":       func() interface{} { return nil },
+		"trans":      func(key string, args ...interface{}) string { return key },
+	}
+	r.requestFuncs = append(r.requestFuncs, r.builtinRequestFuncs)
+	return r
+}
+
+// Funcs registers static template functions. Must be called before Load.
+func (r *Renderer[V]) Funcs(funcs template.FuncMap) error {
+	if err := r.checkFuncs(funcs); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, fn := range funcs {
+		r.funcs[name] = fn
+	}
+	return nil
+}
+
+// RequestFuncs registers functions bound to the current request. placeholders
+// declares the functions the builder returns so templates using them parse;
+// they are replaced at render time. Must be called before Load.
+func (r *Renderer[V]) RequestFuncs(builder RequestFuncs[V], placeholders template.FuncMap) error {
+	if err := r.checkFuncs(placeholders); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, fn := range placeholders {
+		r.funcs[name] = fn
+	}
+	r.requestFuncs = append(r.requestFuncs, builder)
+	return nil
+}
+
+func (r *Renderer[V]) checkFuncs(funcs template.FuncMap) error {
+	if r.AllowUnsafeFuncs {
+		return nil
+	}
+	for name, fn := range funcs {
+		t := reflect.TypeOf(fn)
+		if t == nil || t.Kind() != reflect.Func {
+			return fmt.Errorf("template func %s is not a function", name)
+		}
+		for i := 0; i < t.NumOut(); i++ {
+			for _, unsafe := range unsafeTemplateTypes {
+				if t.Out(i) == unsafe {
+					return fmt.Errorf("template func %s returns %s which bypasses auto-escaping", name, unsafe)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Load parses the templates. It can be called again to reload them.
+func (r *Renderer[V]) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, err := template.New("").Funcs(r.funcs).ParseFS(r.fsys, r.patterns...)
+	if err != nil {
+		return err
+	}
+	r.templates = t
+	return nil
+}
+
+func (r *Renderer[V]) builtinRequestFuncs(ctx *Ctx[V]) template.FuncMap {
+	funcs := template.FuncMap{
+		"request_id": func() string { return ctx.UUID },
+	}
+	if r.CSRFToken != nil {
+		funcs["csrf_token"] = func() string { return r.CSRFToken(ctx) }
+	}
+	if r.User != nil {
+		funcs["user"] = func() interface{} { return r.User(ctx) }
+	}
+	if r.Translate != nil {
+		funcs["trans"] = func(key string, args ...interface{}) string { return r.Translate(ctx, key, args...) }
+	} else {
+		funcs["trans"] = func(key string, args ...interface{}) string {
+			if len(args) == 0 {
+				return key
+			}
+			return fmt.Sprintf(key, args...)
+		}
+	}
+	return funcs
+}
+
+// Render executes the template name with data and sends it as text/html
+func (r *Renderer[V]) Render(ctx *Ctx[V], statusCode int, name string, data interface{}) {
+	if ctx.done {
+		return
+	}
+	buf := r.bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufPool.Put(buf)
+
+	if err := r.Execute(ctx, buf, name, data); err != nil {
+		ctx.SendError("err_template_error", err)
+		return
+	}
+	ctx.SendData(statusCode, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Execute renders the template name into buf with request functions bound to ctx
+func (r *Renderer[V]) Execute(ctx *Ctx[V], buf *bytes.Buffer, name string, data interface{}) error {
+	r.mu.RLock()
+	base := r.templates
+	builders := r.requestFuncs
+	r.mu.RUnlock()
+	if base == nil {
+		return errors.New("templates not loaded")
+	}
+	if base.Lookup(name) == nil {
+		return fmt.Errorf("template %s not found", name)
+	}
+
+	t, err := base.Clone()
+	if err != nil {
+		return err
+	}
+	for _, builder := range builders {
+		t.Funcs(builder(ctx))
+	}
+	return t.ExecuteTemplate(buf, name, data)
+}
+
+// templateJSON marshals v for inclusion in templates. encoding/json escapes
+// <, > and & so the output is safe inside <script> blocks.
+func templateJSON(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// URLFor fills the :name and *name segments of a route pattern with the given
+// key/value pairs, escaping each value.
+func URLFor(pattern string, pairs ...interface{}) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", errors.New("url_for expects key/value pairs")
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("url_for key %v is not a string", pairs[i])
+		}
+		values[key] = toParamString(pairs[i+1])
+	}
+	return fillRoutePattern(pattern, values)
+}
+
+func toParamString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// fillRoutePattern substitutes route parameters in pattern with values.
+// Trailing optional parameters (":slug?") without a value are dropped.
+func fillRoutePattern(pattern string, values map[string]string) (string, error) {
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		switch {
+		case part[0] == '*':
+			v, ok := values[part[1:]]
+			if !ok {
+				return "", fmt.Errorf("missing parameter %s", part[1:])
+			}
+			segments := strings.Split(v, "/")
+			for j, s := range segments {
+				segments[j] = url.PathEscape(s)
+			}
+			parts[i] = strings.Join(segments, "/")
+		case part[0] == ':' && strings.HasSuffix(part, "?"):
+			v, ok := values[part[1:len(part)-1]]
+			if !ok {
+				return strings.Join(parts[:i], "/"), nil
+			}
+			parts[i] = url.PathEscape(v)
+		case strings.IndexByte(part, ':') != -1:
+			segment := compileSegment[struct{}](part)
+			var sb strings.Builder
+			for j, name := range segment.params {
+				v, ok := values[name]
+				if !ok {
+					return "", fmt.Errorf("missing parameter %s", name)
+				}
+				if re := segment.regexps[j]; re != nil && !re.MatchString(v) {
+					return "", fmt.Errorf("parameter %s does not match %s", name, re)
+				}
+				sb.WriteString(segment.literals[j])
+				sb.WriteString(url.PathEscape(v))
+			}
+			sb.WriteString(segment.literals[len(segment.params)])
+			parts[i] = sb.String()
+		}
+	}
+	return strings.Join(parts, "/"), nil
+}
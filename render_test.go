@@ -0,0 +1,67 @@
+package octo
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": {Data: []byte(`<p>{{ .Name }}</p><a href="{{ url_for "/users/:id" "id" .ID }}">{{ trans "Hello %s" .Name }}</a>` +
+			`<script>var data = {{ json .Data }};</script><i>{{ csrf_token }}</i><b>{{ request_id }}</b>`)},
+	}
+
+	renderer := NewRenderer[CustomData](fsys, "*.html")
+	renderer.CSRFToken = func(ctx *Ctx[CustomData]) string { return "tok" }
+	if err := renderer.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	router := NewRouter[CustomData]()
+	router.GET("/page", func(ctx *Ctx[CustomData]) {
+		renderer.Render(ctx, http.StatusOK, "page.html", map[string]interface{}{
+			"Name": "<script>",
+			"ID":   "a b",
+			"Data": map[string]string{"k": "</script>"},
+		})
+	})
+
+	req := httptest.NewRequest("GET", "/page", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if w.Result().Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type: %s", w.Result().Header.Get("Content-Type"))
+	}
+	for _, expected := range []string{"<p>&lt;script&gt;</p>", `href="/users/a%20b"`, "Hello &lt;script&gt;", `\u003c/script\u003e`, "<i>tok</i>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected '%s' in '%s'", expected, body)
+		}
+	}
+	if strings.Contains(body, "<b></b>") {
+		t.Errorf("Expected request_id to be injected, got '%s'", body)
+	}
+}
+
+func TestRendererRejectsUnsafeFuncs(t *testing.T) {
+	renderer := NewRenderer[CustomData](fstest.MapFS{}, "*.html")
+	err := renderer.Funcs(template.FuncMap{
+		"raw": func(s string) template.HTML { return template.HTML(s) },
+	})
+	if err == nil {
+		t.Errorf("Expected unsafe func to be rejected")
+	}
+
+	renderer.AllowUnsafeFuncs = true
+	err = renderer.Funcs(template.FuncMap{
+		"raw": func(s string) template.HTML { return template.HTML(s) },
+	})
+	if err != nil {
+		t.Errorf("Expected unsafe func to be accepted with AllowUnsafeFuncs: %v", err)
+	}
+}
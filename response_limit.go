@@ -0,0 +1,143 @@
+package octo
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ResponseSizePolicy decides what happens when a response exceeds its limit
+type ResponseSizePolicy int
+
+const (
+	// ResponseSizeError discards the response and sends a 500 error instead
+	ResponseSizeError ResponseSizePolicy = iota
+	// ResponseSizeTruncate sends the first limit bytes with a warning header
+	ResponseSizeTruncate
+	// ResponseSizeStream writes through and fails writes once the limit is crossed
+	ResponseSizeStream
+)
+
+// TruncatedHeader is set on responses cut by ResponseSizeTruncate
+const TruncatedHeader = "X-Octo-Truncated"
+
+// ErrResponseTooLarge is returned by Write once a response exceeds its limit
+var ErrResponseTooLarge = errors.New("response exceeds size limit")
+
+// limitedResponseWriter enforces a byte limit on the wrapped writer. Buffered
+// policies hold at most limit bytes until the handler returns.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	limit    int64
+	policy   ResponseSizePolicy
+	written  int64
+	status   int
+	buf      bytes.Buffer
+	exceeded bool
+}
+
+func (w *limitedResponseWriter) WriteHeader(statusCode int) {
+	if w.policy == ResponseSizeStream {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *limitedResponseWriter) Write(data []byte) (int, error) {
+	if w.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+	remaining := w.limit - w.written
+	if int64(len(data)) > remaining {
+		w.exceeded = true
+		data = data[:remaining]
+	}
+	var n int
+	var err error
+	if w.policy == ResponseSizeStream {
+		n, err = w.ResponseWriter.Write(data)
+	} else {
+		n, err = w.buf.Write(data)
+	}
+	w.written += int64(n)
+	if err == nil && w.exceeded {
+		err = ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (w *limitedResponseWriter) Flush() {
+	if w.policy != ResponseSizeStream {
+		return
+	}
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// ResponseSizeLimitMiddleware caps the size of responses produced by a route.
+// With ResponseSizeError and ResponseSizeTruncate up to limit bytes are held
+// in memory until the handler returns.
+func ResponseSizeLimitMiddleware[V any](limit int64, policy ResponseSizePolicy) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			original := ctx.ResponseWriter.ResponseWriter
+			lw := &limitedResponseWriter{
+				ResponseWriter: original,
+				limit:          limit,
+				policy:         policy,
+			}
+			ctx.ResponseWriter.ResponseWriter = lw
+			next(ctx)
+			ctx.ResponseWriter.ResponseWriter = original
+
+			if lw.exceeded {
+				routerLogger := SubsystemLogger(LogRouter)
+				if EnableLoggerCheck {
+					if routerLogger != nil {
+						routerLogger.Warn().
+							Str("path", ctx.Request.URL.Path).
+							Int64("limit", limit).
+							Msg("[octo] response exceeds size limit")
+					}
+				} else {
+					routerLogger.Warn().
+						Str("path", ctx.Request.URL.Path).
+						Int64("limit", limit).
+						Msg("[octo] response exceeds size limit")
+				}
+			}
+
+			switch policy {
+			case ResponseSizeStream:
+				return
+			case ResponseSizeError:
+				if lw.exceeded {
+					original.Header().Del("Content-Length")
+					ctx.done = false
+					ctx.SendError("err_response_too_large", nil)
+					return
+				}
+			case ResponseSizeTruncate:
+				if lw.exceeded {
+					original.Header().Set(TruncatedHeader, strconv.FormatInt(limit, 10))
+				}
+			}
+			if lw.buf.Len() > 0 || lw.status != 0 {
+				if original.Header().Get("Content-Length") != "" {
+					original.Header().Set("Content-Length", strconv.Itoa(lw.buf.Len()))
+				}
+				status := lw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				original.WriteHeader(status)
+				original.Write(lw.buf.Bytes())
+			}
+		}
+	}
+}
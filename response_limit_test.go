@@ -0,0 +1,68 @@
+package octo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseSizeLimit(t *testing.T) {
+	router := NewRouter[CustomData]()
+	bigHandler := func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, strings.Repeat("a", 100))
+	}
+	router.GET("/error", bigHandler, ResponseSizeLimitMiddleware[CustomData](10, ResponseSizeError))
+	router.GET("/truncate", bigHandler, ResponseSizeLimitMiddleware[CustomData](10, ResponseSizeTruncate))
+	router.GET("/stream", bigHandler, ResponseSizeLimitMiddleware[CustomData](10, ResponseSizeStream))
+	router.GET("/small", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusCreated, "tiny")
+	}, ResponseSizeLimitMiddleware[CustomData](10, ResponseSizeError))
+
+	// Error policy
+	req := httptest.NewRequest("GET", "/error", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	}
+	var result BaseResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.Token != "err_response_too_large" {
+		t.Errorf("Unexpected response: %+v", result)
+	}
+
+	// Truncate policy
+	req = httptest.NewRequest("GET", "/truncate", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	resp = w.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != strings.Repeat("a", 10) {
+		t.Errorf("Expected 10 bytes, got '%s'", string(body))
+	}
+	if resp.Header.Get(TruncatedHeader) != "10" || resp.Header.Get("Content-Length") != "10" {
+		t.Errorf("Expected truncation headers, got %v", resp.Header)
+	}
+
+	// Stream policy
+	req = httptest.NewRequest("GET", "/stream", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.Len() != 10 {
+		t.Errorf("Expected 10 streamed bytes, got %d", w.Body.Len())
+	}
+
+	// Under the limit
+	req = httptest.NewRequest("GET", "/small", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || w.Body.String() != "tiny" {
+		t.Errorf("Expected 201 'tiny', got %d '%s'", w.Code, w.Body.String())
+	}
+}
@@ -2,6 +2,7 @@ package octo
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -80,3 +81,40 @@ func TestNewJSONResult(t *testing.T) {
 		t.Errorf("Unexpected response: %+v", result)
 	}
 }
+
+func TestSendXML(t *testing.T) {
+	type User struct {
+		XMLName xml.Name `xml:"user"`
+		Name    string   `xml:"name"`
+	}
+
+	router := NewRouter[CustomData]()
+	router.GET("/xml", func(ctx *Ctx[CustomData]) {
+		ctx.SendXML(http.StatusOK, User{Name: "Bob"})
+	})
+	router.GET("/xml_root", func(ctx *Ctx[CustomData]) {
+		ctx.SendXMLWithRoot(http.StatusCreated, "account", User{Name: "Alice"})
+	})
+
+	req := httptest.NewRequest("GET", "/xml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.Header.Get("Content-Type") != "application/xml; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type: %s", resp.Header.Get("Content-Type"))
+	}
+	expected := xml.Header + "<user><name>Bob</name></user>"
+	if w.Body.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/xml_root", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expected = xml.Header + "<account><name>Alice</name></account>"
+	if w.Code != http.StatusCreated || w.Body.String() != expected {
+		t.Errorf("Expected 201 '%s', got %d '%s'", expected, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,281 @@
+package octo
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Route is a handle on a registered route, returned by GET, POST... to
+// configure per-route behavior after registration
+type Route[V any] struct {
+	Method  string
+	Path    string
+	router  *Router[V]
+	entries []*routeEntry[V]
+	doc     routeDoc
+}
+
+// entryHandler picks the handler to run for a matched entry: the stub when
+// the route has no handler yet or the router is in mock mode.
+func (r *Router[V]) entryHandler(entry *routeEntry[V]) HandlerFunc[V] {
+	if entry.stub != nil && (entry.handler == nil || r.mockMode.Load()) {
+		return entry.stub
+	}
+	if entry.handler == nil {
+		return notImplementedHandler[V]
+	}
+	return entry.handler
+}
+
+func notImplementedHandler[V any](ctx *Ctx[V]) {
+	ctx.SendError("err_not_implemented", nil)
+}
+
+// SetMockMode makes every stubbed route serve its stub instead of its handler.
+// Routes registered without a handler always serve their stub.
+func (r *Router[V]) SetMockMode(enabled bool) {
+	r.mockMode.Store(enabled)
+}
+
+// MockMode reports whether mock mode is enabled
+func (r *Router[V]) MockMode() bool {
+	return r.mockMode.Load()
+}
+
+// StubConfig describes a canned response
+type StubConfig struct {
+	Status int
+	// Body is sent as-is when it is a string or []byte, JSON-encoded otherwise
+	Body interface{}
+	// ContentType defaults to application/json (text/plain for non-JSON strings)
+	ContentType string
+	Headers     map[string]string
+	// Latency delays the response; Jitter adds up to that much random delay
+	Latency time.Duration
+	Jitter  time.Duration
+	// FailureRate (0..1) is the share of requests answered with FailureStatus
+	// (default 500) instead of the fixture
+	FailureRate   float64
+	FailureStatus int
+}
+
+// Stub registers a canned response for the route, served while the route has
+// no handler or the router is in mock mode. Middleware still runs.
+func (rt *Route[V]) Stub(status int, body interface{}, latency time.Duration) *Route[V] {
+	return rt.StubWith(StubConfig{Status: status, Body: body, Latency: latency})
+}
+
+// StubWith is Stub with latency jitter and failure injection
+func (rt *Route[V]) StubWith(cfg StubConfig) *Route[V] {
+	var payload []byte
+	switch b := cfg.Body.(type) {
+	case nil:
+	case []byte:
+		payload = b
+	case string:
+		payload = []byte(b)
+	default:
+		var err error
+		if payload, err = json.Marshal(b); err != nil {
+			panic("octo: stub body for " + rt.Method + " " + rt.Path + ": " + err.Error())
+		}
+	}
+	if cfg.ContentType == "" && payload != nil {
+		if json.Valid(payload) {
+			cfg.ContentType = "application/json"
+		} else {
+			cfg.ContentType = "text/plain; charset=utf-8"
+		}
+	}
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusOK
+	}
+	if cfg.FailureStatus == 0 {
+		cfg.FailureStatus = http.StatusInternalServerError
+	}
+
+	stub := func(ctx *Ctx[V]) {
+		delay := cfg.Latency
+		if cfg.Jitter > 0 {
+			delay += rand.N(cfg.Jitter)
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Context().Done():
+				return
+			}
+		}
+		if cfg.FailureRate > 0 && rand.Float64() < cfg.FailureRate {
+			ctx.SendErrorStatus(cfg.FailureStatus, "err_internal_error", nil)
+			return
+		}
+		for k, v := range cfg.Headers {
+			ctx.SetHeader(k, v)
+		}
+		if payload == nil {
+			ctx.SetStatus(cfg.Status)
+			ctx.Done()
+			return
+		}
+		ctx.SendData(cfg.Status, cfg.ContentType, payload)
+	}
+	rt.update(func(entry *routeEntry[V]) {
+		entry.stub = stub
+	})
+	return rt
+}
+
+// update applies fn to copies of the route entries and swaps them into the
+// tree under treeMu. Entries are never modified in place, so routes can be
+// configured while the router serves (see Replace).
+func (rt *Route[V]) update(fn func(*routeEntry[V])) {
+	r := rt.router
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+	for i, entry := range rt.entries {
+		next := *entry
+		fn(&next)
+		for method, e := range entry.node.handlers {
+			if e == entry {
+				entry.node.handlers[method] = &next
+			}
+		}
+		rt.entries[i] = &next
+	}
+	r.treeChanged()
+}
+
+// use prepends mw to the route's middleware chain so it also sees responses
+// short-circuited by router and group middleware
+func (rt *Route[V]) use(mw MiddlewareFunc[V]) {
+	rt.update(func(entry *routeEntry[V]) {
+		chain := make([]MiddlewareFunc[V], 0, len(entry.middleware)+1)
+		chain = append(chain, mw)
+		entry.middleware = append(chain, entry.middleware...)
+	})
+}
+
+// useInner appends mw to the route's middleware chain so it runs right around
+// the handler, after router and group middleware
+func (rt *Route[V]) useInner(mw MiddlewareFunc[V]) {
+	rt.update(func(entry *routeEntry[V]) {
+		entry.middleware = append(entry.middleware[:len(entry.middleware):len(entry.middleware)], mw)
+	})
+}
+
+// Deprecated marks the route as deprecated since date. Responses carry a
+// Deprecation header (and a Link to link with rel="deprecation" when set),
+// and every call is logged with the caller identity.
+func (rt *Route[V]) Deprecated(date time.Time, link string) *Route[V] {
+	deprecation := "@" + strconv.FormatInt(date.Unix(), 10)
+	var linkHeader string
+	if link != "" {
+		linkHeader = "<" + link + `>; rel="deprecation"; type="text/html"`
+	}
+	rt.doc.deprecated = true
+	method, path := rt.Method, rt.Path
+	rt.use(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			h := ctx.ResponseWriter.Header()
+			h.Set("Deprecation", deprecation)
+			if linkHeader != "" {
+				h.Add("Link", linkHeader)
+			}
+			routerLogger := SubsystemLogger(LogRouter)
+			if EnableLoggerCheck {
+				if routerLogger != nil {
+					routerLogger.Info().
+						Str("method", method).
+						Str("route", path).
+						Str("principal", ctx.Principal()).
+						Str("ip", ctx.ClientIP()).
+						Str("user_agent", ctx.Request.UserAgent()).
+						Msg("[octo] deprecated route called")
+				}
+			} else {
+				routerLogger.Info().
+					Str("method", method).
+					Str("route", path).
+					Str("principal", ctx.Principal()).
+					Str("ip", ctx.ClientIP()).
+					Str("user_agent", ctx.Request.UserAgent()).
+					Msg("[octo] deprecated route called")
+			}
+			next(ctx)
+		}
+	})
+	return rt
+}
+
+// Sunset announces when the route will be removed with a Sunset header
+// (RFC 8594)
+func (rt *Route[V]) Sunset(date time.Time) *Route[V] {
+	sunset := date.UTC().Format(http.TimeFormat)
+	rt.use(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			ctx.ResponseWriter.Header().Set("Sunset", sunset)
+			next(ctx)
+		}
+	})
+	return rt
+}
+
+// Priority breaks ties between overlapping patterns (e.g. "/files/:name" and
+// "/files/img-:id"): higher priorities are tried first. Routes default to 0
+// and may be negative; among equal priorities longer static prefixes win.
+func (rt *Route[V]) Priority(p int) *Route[V] {
+	rt.router.treeMu.Lock()
+	defer rt.router.treeMu.Unlock()
+	rt.router.treeChanged()
+	for _, entry := range rt.entries {
+		entry.priority = p
+		entry.node.updatePriorities()
+	}
+	return rt
+}
+
+// updatePriorities recomputes the subtree priority of n and its ancestors
+// and reorders their patterns accordingly. The caller holds treeMu.
+func (n *node[V]) updatePriorities() {
+	for ; n != nil; n = n.parent {
+		p, found := math.MinInt, false
+		for _, e := range n.handlers {
+			p, found = max(p, e.priority), true
+		}
+		for _, child := range n.staticChildren {
+			p, found = max(p, child.priority), true
+		}
+		for _, pattern := range n.patterns {
+			p, found = max(p, pattern.child.priority), true
+		}
+		for _, child := range []*node[V]{n.paramChild, n.wildcardChild} {
+			if child != nil {
+				p, found = max(p, child.priority), true
+			}
+		}
+		if !found {
+			p = 0
+		}
+		n.priority = p
+		n.sortPatterns()
+	}
+}
+
+// Meta attaches a key/value pair to the route, readable by middleware through
+// Ctx.Meta, e.g. Meta("auth", "public") for a declarative auth policy
+func (rt *Route[V]) Meta(key string, value interface{}) *Route[V] {
+	rt.update(func(entry *routeEntry[V]) {
+		meta := make(map[string]interface{}, len(entry.meta)+1)
+		for k, v := range entry.meta {
+			meta[k] = v
+		}
+		meta[key] = value
+		entry.meta = meta
+	})
+	return rt
+}
@@ -0,0 +1,160 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRouteStub(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/users", nil).Stub(http.StatusOK, []map[string]string{{"name": "ann"}}, 10*time.Millisecond)
+	router.GET("/real", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "real")
+	}).Stub(http.StatusAccepted, "fixture", 0)
+	router.GET("/todo", nil)
+	router.GET("/flaky", nil).StubWith(StubConfig{Body: "ok", FailureRate: 1, FailureStatus: http.StatusBadGateway})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	start := time.Now()
+	w := serve("/users")
+	if w.Code != http.StatusOK || w.Body.String() != `[{"name":"ann"}]` || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected stub response: %d %s %v", w.Code, w.Body.String(), w.Header())
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Expected stub latency to be applied")
+	}
+
+	if w := serve("/real"); w.Body.String() != "real" {
+		t.Errorf("Expected handler outside mock mode, got %s", w.Body.String())
+	}
+	router.SetMockMode(true)
+	if w := serve("/real"); w.Code != http.StatusAccepted || w.Body.String() != "fixture" {
+		t.Errorf("Expected stub in mock mode, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := serve("/todo"); w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 for route without handler, got %d", w.Code)
+	}
+	if w := serve("/flaky"); w.Code != http.StatusBadGateway {
+		t.Errorf("Expected injected failure, got %d", w.Code)
+	}
+}
+
+func TestRouteDeprecated(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			if ctx.GetHeader("X-Deny") != "" {
+				ctx.Send401()
+				return
+			}
+			next(ctx)
+		}
+	})
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.GET("/v1/users", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "ok")
+	}).Deprecated(since, "https://example.com/migrate").Sunset(since.AddDate(0, 6, 0))
+
+	for _, deny := range []bool{false, true} {
+		req := httptest.NewRequest("GET", "/v1/users", nil)
+		if deny {
+			req.Header.Set("X-Deny", "1")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Header().Get("Deprecation") != "@1735689600" {
+			t.Errorf("Expected Deprecation header, got %q", w.Header().Get("Deprecation"))
+		}
+		if w.Header().Get("Sunset") != "Tue, 01 Jul 2025 00:00:00 GMT" {
+			t.Errorf("Expected Sunset header, got %q", w.Header().Get("Sunset"))
+		}
+		if w.Header().Get("Link") != `<https://example.com/migrate>; rel="deprecation"; type="text/html"` {
+			t.Errorf("Expected deprecation Link header, got %q", w.Header().Get("Link"))
+		}
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	router := NewRouter[CustomData]()
+	echo := func(name string) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			ctx.SendString(http.StatusOK, name)
+		}
+	}
+	router.GET("/files/:name", echo("name"))
+	router.GET("/files/img-:id", echo("img"))
+	router.GET("/x/a-:id", echo("a"))
+	router.GET("/x/a-b-:id", echo("ab"))
+
+	serve := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	for i := 0; i < 20; i++ {
+		if got := serve("/x/a-b-1"); got != "ab" {
+			t.Fatalf("Expected longest prefix to win, got %s", got)
+		}
+		if got := serve("/files/img-1"); got != "img" {
+			t.Fatalf("Expected embedded route by default, got %s", got)
+		}
+	}
+
+	router2 := NewRouter[CustomData]()
+	router2.GET("/files/img-:id", echo("img"))
+	router2.GET("/files/:name", echo("name")).Priority(1)
+	router2.GET("/x/a-b-:id", echo("ab")).Priority(-1)
+	router2.GET("/x/a-:id", echo("a"))
+	router = router2
+	if got := serve("/files/img-1"); got != "name" {
+		t.Errorf("Expected higher priority param route, got %s", got)
+	}
+	if got := serve("/x/a-b-1"); got != "a" {
+		t.Errorf("Expected negative priority to lose, got %s", got)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.Use(func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			if ctx.MetaString("auth") != "public" && ctx.GetHeader("Authorization") == "" {
+				ctx.SendError("err_unauthorized", nil)
+				return
+			}
+			next(ctx)
+		}
+	})
+	handler := func(ctx *Ctx[CustomData]) {
+		weight, _ := ctx.Meta("weight").(int)
+		ctx.SendString(http.StatusOK, ctx.MetaString("rateclass")+" "+strconv.Itoa(weight))
+	}
+	router.GET("/public", handler).Meta("auth", "public").Meta("rateclass", "heavy").Meta("weight", 3)
+	router.GET("/private", handler)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/public", http.StatusOK, "heavy 3"},
+		{"/private", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
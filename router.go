@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,11 +19,24 @@ type HandlerFunc[V any] func(*Ctx[V])
 type MiddlewareFunc[V any] func(HandlerFunc[V]) HandlerFunc[V]
 
 type routeEntry[V any] struct {
+	path       string
 	handler    HandlerFunc[V]
 	paramNames []string
 	middleware []MiddlewareFunc[V]
+	stub       HandlerFunc[V]
+	node       *node[V]
+	priority   int
+	// bypassMaintenance keeps the route served in maintenance mode
+	bypassMaintenance bool
+	class             RequestClass
+	// meta is set with Route.Meta
+	meta map[string]interface{}
 }
 
+// anyMethod registers a route entry serving every method without an entry
+// of its own, such as the subtree of Router.Mount
+const anyMethod = "*"
+
 type node[V any] struct {
 	staticChildren map[string]*node[V]
 	paramChild     *node[V]
@@ -28,13 +44,79 @@ type node[V any] struct {
 	isLeaf         bool
 	handlers       map[string]*routeEntry[V]
 	middleware     []MiddlewareFunc[V]
+	patterns       []*segmentPattern[V] // embedded-parameter segments in matching order
 	parent         *node[V]
+	priority       int // highest route priority in this subtree
+	// foldCase stores static children under lowercase keys and matches
+	// them regardless of case (see Router.CaseInsensitive)
+	foldCase bool
+}
+
+// handler returns the entry of the node for method, falling back to the
+// any-method entry
+func (n *node[V]) handler(method string) *routeEntry[V] {
+	if entry := n.handlers[method]; entry != nil {
+		return entry
+	}
+	return n.handlers[anyMethod]
+}
+
+// hasChildren reports whether routes continue below n
+func (n *node[V]) hasChildren() bool {
+	return len(n.staticChildren) > 0 || len(n.patterns) > 0 || n.paramChild != nil || n.wildcardChild != nil
+}
+
+// newChild returns a node below n, inheriting its case folding
+func (n *node[V]) newChild() *node[V] {
+	return &node[V]{parent: n, foldCase: n.foldCase}
+}
+
+// staticChild returns the static child for part, creating it if needed
+func (n *node[V]) staticChild(part string) *node[V] {
+	if n.foldCase {
+		part = strings.ToLower(part)
+	}
+	if child := n.staticChildren[part]; child != nil {
+		return child
+	}
+	if n.staticChildren == nil {
+		n.staticChildren = make(map[string]*node[V])
+	}
+	child := n.newChild()
+	n.staticChildren[part] = child
+	return child
 }
 
 type Router[V any] struct {
 	root               *node[V]
 	middleware         []MiddlewareFunc[V]
 	preGroupMiddleware []MiddlewareFunc[V]
+	arenaPool          sync.Pool
+	mockMode           atomic.Bool
+	pathOptions        PathOptions
+	customPaths        bool
+	names              map[string]*Route[V]
+	lifecycle          lifecycle
+	loadShedder        *LoadShedder
+	fallback           http.Handler
+	routes             []*Route[V]
+	strictJSON         bool
+	errorStatus        map[string]int
+	legacyNotFound     bool
+	staticMounts       []*staticMount[V]
+	// parent is the router a Host router was created from
+	parent        *Router[V]
+	hosts         map[string]*Router[V]
+	wildcardHosts []hostRouter[V]
+	// treeMu guards the route tree and routes against changes while serving
+	// (see Remove and Replace)
+	treeMu         sync.RWMutex
+	memoryWatchdog *MemoryWatchdog
+	// matchTrace is set with SetMatchTrace
+	matchTrace atomic.Bool
+	matchCache atomic.Pointer[matchCache[V]]
+	// treeGen counts the route tree changes, under treeMu
+	treeGen uint64
 }
 
 func NewRouter[V any]() *Router[V] {
@@ -54,54 +136,84 @@ func (r *Router[V]) Use(mw MiddlewareFunc[V]) {
 }
 
 // HTTP method handlers with optional route-specific middleware
-func (r *Router[V]) GET(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("GET", path, handler, middleware...)
+func (r *Router[V]) GET(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("GET", path, handler, middleware...)
 }
 
-func (r *Router[V]) POST(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("POST", path, handler, middleware...)
+func (r *Router[V]) POST(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("POST", path, handler, middleware...)
 }
 
-func (r *Router[V]) PUT(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("PUT", path, handler, middleware...)
+func (r *Router[V]) PUT(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("PUT", path, handler, middleware...)
 }
 
-func (r *Router[V]) DELETE(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("DELETE", path, handler, middleware...)
+func (r *Router[V]) DELETE(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("DELETE", path, handler, middleware...)
 }
 
-func (r *Router[V]) PATCH(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("PATCH", path, handler, middleware...)
+func (r *Router[V]) PATCH(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("PATCH", path, handler, middleware...)
 }
 
-func (r *Router[V]) OPTIONS(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("OPTIONS", path, handler, middleware...)
+func (r *Router[V]) OPTIONS(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("OPTIONS", path, handler, middleware...)
 }
 
-func (r *Router[V]) HEAD(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	r.addRoute("HEAD", path, handler, middleware...)
+func (r *Router[V]) HEAD(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.addRoute("HEAD", path, handler, middleware...)
 }
 
-func (r *Router[V]) ANY(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
+func (r *Router[V]) ANY(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
 	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"}
+	route := &Route[V]{Method: "ANY", Path: path, router: r}
 	for _, m := range methods {
-		r.addRoute(m, path, handler, middleware...)
+		route.entries = append(route.entries, r.newRoute(m, path, handler, middleware...).entries...)
 	}
+	r.listRoute(route)
+	return route
 }
 
 // Group represents a group of routes with a common prefix and middleware
 type Group[V any] struct {
-	prefix     string
-	router     *Router[V]
-	middleware []MiddlewareFunc[V]
+	prefix string
+	router *Router[V]
+	// middleware is read by the group routes on every request, so Use also
+	// applies to routes registered earlier
+	middleware atomic.Pointer[[]MiddlewareFunc[V]]
 }
 
+// Use adds middleware to every route of the group, including routes already
+// registered. It runs after the middleware given to Router.Group and earlier
+// Use calls, and before the route's own middleware.
 func (g *Group[V]) Use(mw MiddlewareFunc[V]) {
-	g.middleware = append(g.middleware, mw)
+	for {
+		old := g.middleware.Load()
+		next := append(append(make([]MiddlewareFunc[V], 0, len(*old)+1), *old...), mw)
+		if g.middleware.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// apply runs the current group middleware around next
+func (g *Group[V]) apply(next HandlerFunc[V]) HandlerFunc[V] {
+	mws := *g.middleware.Load()
+	for i := len(mws) - 1; i >= 0; i-- {
+		next = wrapMiddleware(mws[i])(next)
+	}
+	return next
+}
+
+// routeMiddleware is the middleware of a route registered through the group
+func (g *Group[V]) routeMiddleware(middleware []MiddlewareFunc[V]) []MiddlewareFunc[V] {
+	return append([]MiddlewareFunc[V]{g.apply}, middleware...)
 }
 
 // Group creates a new route group with the given prefix and middleware
 func (r *Router[V]) Group(prefix string, middleware ...MiddlewareFunc[V]) *Group[V] {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
 	current := r.root
 	parts := splitPath(prefix)
 	for _, part := range parts {
@@ -109,76 +221,123 @@ func (r *Router[V]) Group(prefix string, middleware ...MiddlewareFunc[V]) *Group
 			continue
 		}
 		if part[0] == ':' || strings.Contains(part, ":") {
-			current = r.addEmbeddedParameterNode(current, part)
+			current, _ = r.segmentNode(current, part)
 		} else {
-			if current.staticChildren == nil {
-				current.staticChildren = make(map[string]*node[V])
-			}
-			if current.staticChildren[part] == nil {
-				current.staticChildren[part] = &node[V]{parent: current}
-			}
-			current = current.staticChildren[part]
+			current = current.staticChild(part)
 		}
 	}
-	return &Group[V]{
-		prefix:     prefix,
-		router:     r,
-		middleware: middleware,
-	}
+	g := &Group[V]{prefix: prefix, router: r}
+	middleware = append([]MiddlewareFunc[V](nil), middleware...)
+	g.middleware.Store(&middleware)
+	return g
 }
 
 // Methods to add routes to the group
-func (g *Group[V]) GET(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.GET(fullPath, handler, allMiddleware...)
+func (g *Group[V]) GET(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.GET(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) POST(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.POST(fullPath, handler, allMiddleware...)
+func (g *Group[V]) POST(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.POST(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) PUT(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.PUT(fullPath, handler, allMiddleware...)
+func (g *Group[V]) PUT(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.PUT(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) DELETE(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.DELETE(fullPath, handler, allMiddleware...)
+func (g *Group[V]) DELETE(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.DELETE(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) PATCH(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.PATCH(fullPath, handler, allMiddleware...)
+func (g *Group[V]) PATCH(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.PATCH(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) OPTIONS(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.OPTIONS(fullPath, handler, allMiddleware...)
+func (g *Group[V]) OPTIONS(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.OPTIONS(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
-func (g *Group[V]) HEAD(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.HEAD(fullPath, handler, allMiddleware...)
+func (g *Group[V]) HEAD(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.HEAD(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
 // ANY adds a route that matches all HTTP methods
-func (g *Group[V]) ANY(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.ANY(fullPath, handler, allMiddleware...)
+func (g *Group[V]) ANY(path string, handler HandlerFunc[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	return g.router.ANY(g.prefix+path, handler, g.routeMiddleware(middleware)...)
 }
 
 // addRoute adds a route with associated handler and middleware
-func (r *Router[V]) addRoute(method, path string, handler HandlerFunc[V], routeMW ...MiddlewareFunc[V]) {
+func (r *Router[V]) addRoute(method, path string, handler HandlerFunc[V], routeMW ...MiddlewareFunc[V]) *Route[V] {
+	route := r.newRoute(method, path, handler, routeMW...)
+	r.listRoute(route)
+	return route
+}
+
+// listRoute appends route to r.routes
+func (r *Router[V]) listRoute(route *Route[V]) {
+	r.treeMu.Lock()
+	r.routes = append(r.routes, route)
+	r.treeMu.Unlock()
+}
+
+// newRoute inserts the entries of a route without listing it in r.routes
+func (r *Router[V]) newRoute(method, path string, handler HandlerFunc[V], routeMW ...MiddlewareFunc[V]) *Route[V] {
+	variants := expandOptionalSegments(path)
+	if len(variants) == 1 {
+		return r.addConcreteRoute(method, path, handler, routeMW...)
+	}
+	route := &Route[V]{Method: method, Path: path, router: r}
+	for _, variant := range variants {
+		route.entries = append(route.entries, r.addConcreteRoute(method, variant, handler, routeMW...).entries...)
+	}
+	return route
+}
+
+// expandOptionalSegments turns trailing optional parameters ("/posts/:id/:slug?")
+// into every concrete path, shortest first
+func expandOptionalSegments(path string) []string {
+	if !strings.Contains(path, "?") {
+		return []string{path}
+	}
+	parts := splitPath(path)
+	first := -1
+	for i, part := range parts {
+		optional := strings.HasSuffix(part, "?")
+		if optional && (part[0] != ':' || len(part) < 3) {
+			panic("octo: only parameter segments can be optional: " + path)
+		}
+		if optional && first == -1 {
+			first = i
+		}
+		if !optional && first != -1 {
+			panic("octo: optional segments must be trailing: " + path)
+		}
+	}
+	if first == -1 {
+		return []string{path}
+	}
+	variants := make([]string, 0, len(parts)-first+1)
+	for n := first; n <= len(parts); n++ {
+		segments := make([]string, n)
+		for i := range segments {
+			segments[i] = strings.TrimSuffix(parts[i], "?")
+		}
+		variants = append(variants, "/"+strings.Join(segments, "/"))
+	}
+	return variants
+}
+
+func (r *Router[V]) addConcreteRoute(method, path string, handler HandlerFunc[V], routeMW ...MiddlewareFunc[V]) *Route[V] {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+	return r.insertRoute(method, path, handler, routeMW, false)
+}
+
+// insertRoute adds the entry of a concrete path to the tree, replacing the
+// entry registered for the same method and node when replace is set. The
+// caller holds treeMu.
+func (r *Router[V]) insertRoute(method, path string, handler HandlerFunc[V], routeMW []MiddlewareFunc[V], replace bool) *Route[V] {
+	r.treeChanged()
 	parts := splitPath(path)
 	current := r.root
 
@@ -189,26 +348,22 @@ func (r *Router[V]) addRoute(method, path string, handler HandlerFunc[V], routeM
 			continue
 		}
 		if strings.Contains(part, ":") {
-			current, paramNames = r.addEmbeddedParameterNodeWithNames(current, part, paramNames)
+			var names []string
+			current, names = r.segmentNode(current, part)
+			paramNames = append(paramNames, names...)
 		} else if part[0] == '*' {
 			// Wildcard segment
 			paramName := part[1:]
 			paramNames = append(paramNames, paramName)
 			if current.wildcardChild == nil {
-				current.wildcardChild = &node[V]{parent: current}
+				current.wildcardChild = current.newChild()
 			}
 			current = current.wildcardChild
-			if i != len(parts)-1 {
-				panic("Wildcard route parameter must be at the end of the path")
+			if i+1 < len(parts) && parts[i+1][0] == '*' {
+				panic("octo: adjacent wildcard segments are ambiguous: " + path)
 			}
 		} else {
-			if current.staticChildren == nil {
-				current.staticChildren = make(map[string]*node[V])
-			}
-			if current.staticChildren[part] == nil {
-				current.staticChildren[part] = &node[V]{parent: current}
-			}
-			current = current.staticChildren[part]
+			current = current.staticChild(part)
 		}
 	}
 
@@ -216,7 +371,7 @@ func (r *Router[V]) addRoute(method, path string, handler HandlerFunc[V], routeM
 		current.handlers = make(map[string]*routeEntry[V])
 	}
 
-	if _, exists := current.handlers[method]; exists {
+	if _, exists := current.handlers[method]; exists && !replace {
 		panic(fmt.Sprintf("route already defined: %s %s", method, path))
 	}
 
@@ -224,102 +379,22 @@ func (r *Router[V]) addRoute(method, path string, handler HandlerFunc[V], routeM
 
 	// Build the middleware chain
 	middlewareChain := r.buildMiddlewareChain(current, routeMW)
-	current.handlers[method] = &routeEntry[V]{
+	entry := &routeEntry[V]{
+		path:       path,
 		handler:    handler,
 		paramNames: paramNames,
 		middleware: middlewareChain,
+		node:       current,
 	}
-}
-
-func (r *Router[V]) addEmbeddedParameterNodeWithNames(cur *node[V], part string, paramNames []string) (*node[V], []string) {
-	for {
-		if part == "" {
-			break
-		}
-		idx := strings.IndexByte(part, ':')
-		if idx == -1 {
-			// Remaining part is static
-			if cur.staticChildren == nil {
-				cur.staticChildren = make(map[string]*node[V])
-			}
-			if cur.staticChildren[part] == nil {
-				cur.staticChildren[part] = &node[V]{parent: cur}
-			}
-			cur = cur.staticChildren[part]
-			break
-		}
-		if idx > 0 {
-			staticPart := part[:idx]
-			if cur.staticChildren == nil {
-				cur.staticChildren = make(map[string]*node[V])
-			}
-			if cur.staticChildren[staticPart] == nil {
-				cur.staticChildren[staticPart] = &node[V]{parent: cur}
-			}
-			cur = cur.staticChildren[staticPart]
-		}
-		part = part[idx+1:]
-		var paramName string
-		nextIdx := strings.IndexAny(part, ":*")
-		if nextIdx != -1 {
-			paramName = part[:nextIdx]
-			part = part[nextIdx:]
-		} else {
-			paramName = part
-			part = ""
-		}
-		paramNames = append(paramNames, paramName)
-		if cur.paramChild == nil {
-			cur.paramChild = &node[V]{parent: cur}
-		}
-		cur = cur.paramChild
-	}
-	return cur, paramNames
-}
-
-func (r *Router[V]) addEmbeddedParameterNode(cur *node[V], part string) *node[V] {
-	for {
-		if part == "" {
-			break
-		}
-		idx := strings.IndexByte(part, ':')
-		if idx == -1 {
-			if cur.staticChildren == nil {
-				cur.staticChildren = make(map[string]*node[V])
-			}
-			if cur.staticChildren[part] == nil {
-				cur.staticChildren[part] = &node[V]{parent: cur}
-			}
-			cur = cur.staticChildren[part]
-			break
-		}
-		if idx > 0 {
-			staticPart := part[:idx]
-			if cur.staticChildren == nil {
-				cur.staticChildren = make(map[string]*node[V])
-			}
-			if cur.staticChildren[staticPart] == nil {
-				cur.staticChildren[staticPart] = &node[V]{parent: cur}
-			}
-			cur = cur.staticChildren[staticPart]
-		}
-		part = part[idx+1:]
-		nextIdx := strings.IndexAny(part, ":*")
-		if nextIdx != -1 {
-			part = part[nextIdx:]
-		} else {
-			part = ""
-		}
-		if cur.paramChild == nil {
-			cur.paramChild = &node[V]{parent: cur}
-		}
-		cur = cur.paramChild
-	}
-	return cur
+	current.handlers[method] = entry
+	return &Route[V]{Method: method, Path: path, router: r, entries: []*routeEntry[V]{entry}}
 }
 
 func (r *Router[V]) buildMiddlewareChain(cur *node[V], routeMW []MiddlewareFunc[V]) []MiddlewareFunc[V] {
 	var chain []MiddlewareFunc[V]
+	if r.parent != nil {
+		chain = append(chain, r.parent.globalMiddlewareChain()...)
+	}
 	chain = append(chain, r.preGroupMiddleware...)
 	chain = append(chain, r.middleware...)
 
@@ -342,6 +417,9 @@ func (r *Router[V]) buildMiddlewareChain(cur *node[V], routeMW []MiddlewareFunc[
 
 func (r *Router[V]) globalMiddlewareChain() []MiddlewareFunc[V] {
 	var chain []MiddlewareFunc[V]
+	if r.parent != nil {
+		chain = append(chain, r.parent.globalMiddlewareChain()...)
+	}
 	if len(r.preGroupMiddleware) > 0 {
 		chain = append(chain, r.preGroupMiddleware...)
 	}
@@ -379,130 +457,331 @@ func splitPath(path string) []string {
 
 // ServeHTTP implements the http.Handler interface
 func (r *Router[V]) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.requestStarted()
+	defer r.requestDone()
+	if r.lifecycle.draining.Load() {
+		r.setDrainHeaders(w)
+	}
+	if len(r.hosts) > 0 {
+		if sub := r.hostRouterFor(req.Host); sub != nil {
+			sub.ServeHTTP(w, req)
+			return
+		}
+	}
 	path := req.URL.Path
 	method := req.Method
+	settings := runtimeSettings.Load()
 
 	// 3) Optionally add security headers
-	if EnableSecurityHeaders {
+	if securityHeadersEnabled(settings) {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
 	}
 
-	handler, middlewareChain, params, ok := r.search(method, path)
-	if !ok {
+	var arena *requestArena[V]
+	if EnableRequestArena {
+		arena = r.acquireArena()
+	}
+
+	var (
+		handler         HandlerFunc[V]
+		middlewareChain []MiddlewareFunc[V]
+		params          map[string]string
+		entry           *routeEntry[V]
+		rejectCode      string
+		redirect        string
+	)
+	var parts []string
+	if r.needsPathParts(path) {
+		parts, redirect, rejectCode = r.pathParts(req)
+		if rejectCode == "" && redirect == "" {
+			entry, params = r.matchEntry(method, parts, arena)
+		}
+	} else {
+		entry, params, parts = r.matchPath(method, path, arena)
+	}
+	if r.matchTrace.Load() && rejectCode == "" {
+		if parts == nil {
+			parts = splitPath(path)
+		}
+		logMatchTrace(r.traceParts(method, path, parts))
+	}
+	slashMismatch := false
+	if entry != nil && r.pathOptions.TrailingSlash != PathNormalize {
+		if target, mismatch := trailingSlashMismatch(req, entry.path); mismatch {
+			if r.pathOptions.TrailingSlash == PathRedirect {
+				redirect = target
+			}
+			slashMismatch = true
+			entry = nil
+		}
+	}
+	ok := entry != nil
+	if ok {
+		handler, middlewareChain = r.entryHandler(entry), entry.middleware
+		if settings != nil && settings.Maintenance && !entry.bypassMaintenance {
+			handler = maintenanceHandler[V](settings.MaintenanceRetryAfter)
+			middlewareChain = r.globalMiddlewareChain()
+		} else if r.memoryWatchdog != nil && r.memoryWatchdog.sheds(entry.class) {
+			handler = loadShedHandler[V](r.memoryWatchdog.retryAfter)
+			middlewareChain = r.globalMiddlewareChain()
+		} else if r.loadShedder != nil {
+			if r.loadShedder.admit(entry.class) {
+				defer r.loadShedder.release()
+			} else {
+				handler = loadShedHandler[V](r.loadShedder.retryAfter)
+				middlewareChain = r.globalMiddlewareChain()
+			}
+		}
+	}
+	if rejectCode != "" {
+		handler = func(ctx *Ctx[V]) {
+			ctx.SendError(rejectCode, nil)
+		}
+		middlewareChain = r.globalMiddlewareChain()
+	} else if redirect != "" {
+		handler = func(ctx *Ctx[V]) {
+			target := redirect
+			if req.URL.RawQuery != "" {
+				target += "?" + req.URL.RawQuery
+			}
+			status := http.StatusPermanentRedirect
+			if method == http.MethodGet || method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+			ctx.Redirect(status, target)
+		}
+		middlewareChain = r.globalMiddlewareChain()
+	} else if next := r.notFoundNext(req); !ok && next != nil {
+		handler = func(ctx *Ctx[V]) {
+			next.ServeHTTP(ctx.ResponseWriter, ctx.Request)
+		}
+		middlewareChain = r.globalMiddlewareChain()
+	} else if !ok {
+		var allowed []string
+		if !slashMismatch {
+			allowed = r.allowedMethods(parts)
+		}
 		handler = func(ctx *Ctx[V]) {
 			if req.Method == "OPTIONS" {
-				w.Header().Set("Allow", "GET, POST, PUT, DELETE, PATCH, OPTIONS, HEAD")
+				if len(allowed) > 0 {
+					w.Header().Set("Allow", strings.Join(append(allowed, "OPTIONS"), ", "))
+				} else {
+					w.Header().Set("Allow", "GET, POST, PUT, DELETE, PATCH, OPTIONS, HEAD")
+				}
 				w.WriteHeader(http.StatusOK)
 				return
 			}
+			if len(allowed) > 0 {
+				ctx.SetHeader("Allow", strings.Join(allowed, ", "))
+				ctx.SendError("err_method_not_allowed", nil)
+				return
+			}
 			http.NotFound(ctx.ResponseWriter, ctx.Request)
 		}
 		middlewareChain = r.globalMiddlewareChain()
 	}
 
-	responseWriter := NewResponseWriterWrapper(w)
-
-	ctx := &Ctx[V]{
-		ResponseWriter: responseWriter,
-		Request:        req,
-		Params:         params,
-		StartTime:      time.Now().UnixNano(),
-		UUID:           uuid.NewString(),
-		Query:          req.URL.Query(),
+	var ctx *Ctx[V]
+	if arena != nil {
+		ctx = arena.newCtx(w, req, params)
+	} else {
+		ctx = &Ctx[V]{
+			ResponseWriter: NewResponseWriterWrapper(w),
+			Request:        req,
+			Params:         params,
+			StartTime:      time.Now().UnixNano(),
+			UUID:           uuid.NewString(),
+			Query:          req.URL.Query(),
+		}
 	}
 
+	if entry != nil {
+		ctx.routePath = entry.path
+		ctx.routeMeta = entry.meta
+	}
+	ctx.strictJSON = r.strictJSON
+	ctx.errorStatuses = r.errorStatus
 	handler = applyMiddleware(handler, middlewareChain)
+	finished := false
+	defer func() {
+		// Panic path: the arena was not released, the Ctx is intact
+		if !finished {
+			ctx.closeStreams()
+			ctx.removeTempFiles()
+		}
+	}()
 	handler(ctx)
+	ctx.closeStreams()
+	ctx.removeTempFiles()
+	finished = true
+
+	// Not deferred on purpose: if the handler panics the arena is simply
+	// dropped and left to the GC instead of being recycled mid-unwind.
+	if arena != nil {
+		r.releaseArena(arena)
+	}
 }
 
-func (r *Router[V]) search(method, path string) (HandlerFunc[V], []MiddlewareFunc[V], map[string]string, bool) {
-	parts := splitPath(path)
-	cur := r.root
+func (r *Router[V]) search(method, path string, arena *requestArena[V]) (HandlerFunc[V], []MiddlewareFunc[V], map[string]string, bool) {
+	return r.searchParts(method, splitPath(path), arena)
+}
+
+func (r *Router[V]) searchParts(method string, parts []string, arena *requestArena[V]) (HandlerFunc[V], []MiddlewareFunc[V], map[string]string, bool) {
+	entry, params := r.matchEntry(method, parts, arena)
+	if entry == nil {
+		return nil, nil, nil, false
+	}
+	return r.entryHandler(entry), entry.middleware, params, true
+}
+
+// matchEntry returns the route entry matching method and parts with its
+// parameters, or nil
+func (r *Router[V]) matchEntry(method string, parts []string, arena *requestArena[V]) (*routeEntry[V], map[string]string) {
+	entry, paramValues, _ := r.lookupEntry(method, parts, arena)
+	if entry == nil {
+		return nil, nil
+	}
+	return entry, entryParams(entry, paramValues, arena)
+}
+
+// lookupEntry returns the route entry matching method and parts, or nil, with
+// the parameter values and the generation of the tree it was found in
+func (r *Router[V]) lookupEntry(method string, parts []string, arena *requestArena[V]) (*routeEntry[V], []string, uint64) {
 	var paramValues []string
+	if arena != nil {
+		paramValues = arena.paramValues[:0]
+	}
+	r.treeMu.RLock()
+	cur, paramValues, ok := r.walk(r.root, parts, paramValues, nil)
+	var handlerEntry *routeEntry[V]
+	if ok && cur.isLeaf {
+		handlerEntry = cur.handler(method)
+	}
+	gen := r.treeGen
+	r.treeMu.RUnlock()
+	return handlerEntry, paramValues, gen
+}
 
+// entryParams maps the parameter names of entry to the matched values
+func entryParams[V any](entry *routeEntry[V], paramValues []string, arena *requestArena[V]) map[string]string {
+	if len(entry.paramNames) == 0 {
+		return nil
+	}
+	var params map[string]string
+	if arena != nil {
+		arena.paramValues = paramValues
+		params = arena.params
+	} else {
+		params = make(map[string]string, len(entry.paramNames))
+	}
+	for i, paramName := range entry.paramNames {
+		if i < len(paramValues) {
+			params[paramName] = paramValues[i]
+		}
+	}
+	return params
+}
+
+// SetMethodNotAllowed controls the answer to a request whose path matches a
+// route but not its method: 405 with an Allow header listing the registered
+// methods (the default), or 404 as in earlier versions when disabled
+func (r *Router[V]) SetMethodNotAllowed(enabled bool) {
+	r.legacyNotFound = !enabled
+}
+
+// allowedMethods returns the methods registered for the route matching
+// parts, sorted, or nil
+func (r *Router[V]) allowedMethods(parts []string) []string {
+	if r.legacyNotFound || parts == nil {
+		return nil
+	}
+	r.treeMu.RLock()
+	defer r.treeMu.RUnlock()
+	cur, _, ok := r.walk(r.root, parts, nil, nil)
+	if !ok || !cur.isLeaf || len(cur.handlers) == 0 {
+		return nil
+	}
+	methods := make([]string, 0, len(cur.handlers))
+	for m := range cur.handlers {
+		if m != "OPTIONS" && m != anyMethod {
+			methods = append(methods, m)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// walk matches parts below cur. Precedence per segment is static, embedded
+// parameter pattern, parameter, then wildcard. A wildcard followed by more
+// segments matches lazily: it grows one segment at a time until the rest of
+// the path matches a route. Steps are recorded in trace when not nil.
+func (r *Router[V]) walk(cur *node[V], parts []string, paramValues []string, trace *MatchTrace) (*node[V], []string, bool) {
 	for i, part := range parts {
 		if part == "" {
 			continue
 		}
 		if child, ok := cur.staticChildren[part]; ok {
+			trace.step(part, "static", "")
 			cur = child
 			continue
 		}
-
-		// embedded param or standard param
-		matched := false
-		if cur.staticChildren != nil {
-			for key, child := range cur.staticChildren {
-				if strings.HasPrefix(part, key) {
-					remaining := part[len(key):]
-					if remaining != "" {
-						cur = child
-						part = remaining
-						for {
-							if cur.paramChild != nil {
-								paramValues = append(paramValues, part)
-								cur = cur.paramChild
-								matched = true
-								break
-							}
-							if cur.staticChildren != nil {
-								found := false
-								for k, c := range cur.staticChildren {
-									if strings.HasPrefix(part, k) {
-										cur = c
-										part = part[len(k):]
-										found = true
-										break
-									}
-								}
-								if !found {
-									break
-								}
-							} else {
-								break
-							}
-						}
-						if matched {
-							break
-						}
-					}
-				}
+		// ToLower does not allocate for paths that are already lowercase,
+		// which the exact lookup above matched
+		if cur.foldCase {
+			if child, ok := cur.staticChildren[strings.ToLower(part)]; ok {
+				trace.step(part, "static", "case folded")
+				cur = child
+				continue
 			}
 		}
-		if matched {
-			continue
-		}
 
+		// embedded param segments, tried in priority order
+		if len(cur.patterns) > 0 {
+			if trace != nil {
+				cur.tracePatterns(part, trace)
+			}
+			if child, values := cur.matchPattern(part, paramValues); child != nil {
+				paramValues = values
+				cur = child
+				continue
+			}
+		}
 		if cur.paramChild != nil {
+			trace.step(part, "param", "")
 			paramValues = append(paramValues, part)
 			cur = cur.paramChild
 			continue
 		}
-		if cur.wildcardChild != nil {
-			remainingParts := strings.Join(parts[i:], "/")
-			paramValues = append(paramValues, remainingParts)
-			cur = cur.wildcardChild
-			break
-		}
-		return nil, nil, nil, false
-	}
-
-	handlerEntry, ok := cur.handlers[method]
-	if !ok || !cur.isLeaf {
-		return nil, nil, nil, false
-	}
-	var params map[string]string
-	if len(handlerEntry.paramNames) > 0 {
-		params = make(map[string]string, len(handlerEntry.paramNames))
-		for i, paramName := range handlerEntry.paramNames {
-			if i < len(paramValues) {
-				params[paramName] = paramValues[i]
+		if w := cur.wildcardChild; w != nil {
+			if w.hasChildren() {
+				n := len(paramValues)
+				for end := i + 1; end < len(parts); end++ {
+					if trace != nil {
+						trace.step(strings.Join(parts[i:end], "/"), "wildcard", "trying the rest of the path after it")
+					}
+					values := append(paramValues[:n], strings.Join(parts[i:end], "/"))
+					if leaf, values, ok := r.walk(w, parts[end:], values, trace); ok && leaf.isLeaf {
+						return leaf, values, true
+					}
+				}
+				paramValues = paramValues[:n]
+				if !w.isLeaf {
+					trace.step(part, "miss", "no route after the wildcard matches")
+					return nil, paramValues, false
+				}
+			}
+			if trace != nil {
+				trace.step(strings.Join(parts[i:], "/"), "wildcard", "rest of the path")
 			}
+			paramValues = append(paramValues, strings.Join(parts[i:], "/"))
+			return w, paramValues, true
 		}
+		trace.step(part, "miss", "no static, pattern, param or wildcard child")
+		return nil, paramValues, false
 	}
-	return handlerEntry.handler, handlerEntry.middleware, params, true
+	return cur, paramValues, true
 }
 
 func wrapMiddleware[V any](mw MiddlewareFunc[V]) MiddlewareFunc[V] {
@@ -563,24 +842,26 @@ func RecoveryMiddleware[V any]() MiddlewareFunc[V] {
 						wrappedErr = errors.Errorf("%v", e)
 					}
 					if errors.Is(wrappedErr, http.ErrAbortHandler) {
+						panicLogger := SubsystemLogger(LogPanic)
 						if EnableLoggerCheck {
-							if logger != nil {
-								logger.Warn().
+							if panicLogger != nil {
+								panicLogger.Warn().
 									Str("path", ctx.Request.URL.Path).
 									Str("method", ctx.Request.Method).
 									Msg("[octo-panic] Client aborted request (panic recovered)")
 							}
 						} else {
-							logger.Warn().
+							panicLogger.Warn().
 								Str("path", ctx.Request.URL.Path).
 								Str("method", ctx.Request.Method).
 								Msg("[octo-panic] Client aborted request (panic recovered)")
 						}
 						return
 					}
+					panicLogger := SubsystemLogger(LogPanic)
 					if EnableLoggerCheck {
-						if logger != nil {
-							logger.Error().
+						if panicLogger != nil {
+							panicLogger.Error().
 								Err(wrappedErr).
 								Stack().
 								Array("stack_array", zStack).
@@ -590,7 +871,7 @@ func RecoveryMiddleware[V any]() MiddlewareFunc[V] {
 								Msg("[octo-panic] Panic recovered")
 						}
 					} else {
-						logger.Error().
+						panicLogger.Error().
 							Err(wrappedErr).
 							Stack().
 							Array("stack_array", zStack).
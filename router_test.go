@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -188,8 +189,24 @@ func TestHTTPMethods(t *testing.T) {
 
 	resp = w.Result()
 
-	if resp.StatusCode != http.StatusNotFound {
-		t.Errorf("Expected status 404 for undefined method, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, POST" {
+		t.Errorf("Expected status 405 with Allow for undefined method, got %d %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/method", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "GET, POST, OPTIONS" {
+		t.Errorf("Expected OPTIONS to list the route methods, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// Legacy behavior
+	router.SetMethodNotAllowed(false)
+	req = httptest.NewRequest("PUT", "/method", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+		t.Errorf("Expected status 404 for undefined method in legacy mode, got %d", w.Code)
 	}
 }
 
@@ -240,6 +257,40 @@ func TestRouteGroups(t *testing.T) {
 	}
 }
 
+func TestGroupUseRetroactive(t *testing.T) {
+	router := NewRouter[CustomData]()
+	var calls []string
+	mark := func(name string) MiddlewareFunc[CustomData] {
+		return func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+			return func(ctx *Ctx[CustomData]) {
+				calls = append(calls, name)
+				next(ctx)
+			}
+		}
+	}
+
+	api := router.Group("/api", mark("group"))
+	api.GET("/early", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "early")
+	}, mark("route"))
+	public := router.Group("/api")
+	public.GET("/public", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "public")
+	})
+	api.Use(mark("auth"))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/early", nil))
+	if strings.Join(calls, ",") != "group,auth,route" {
+		t.Errorf("Expected Use to apply to earlier routes in order, got %v", calls)
+	}
+
+	calls = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/public", nil))
+	if len(calls) != 0 {
+		t.Errorf("Expected another group on the same prefix to be unaffected, got %v", calls)
+	}
+}
+
 func TestWildcardRoute(t *testing.T) {
 	router := NewRouter[CustomData]()
 
@@ -0,0 +1,341 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coffyg/octo/internal/yaml"
+)
+
+// HandlerRegistry names the handlers and middleware a route file may refer to
+type HandlerRegistry[V any] struct {
+	handlers   map[string]HandlerFunc[V]
+	middleware map[string]MiddlewareFunc[V]
+}
+
+// NewHandlerRegistry creates an empty HandlerRegistry
+func NewHandlerRegistry[V any]() *HandlerRegistry[V] {
+	return &HandlerRegistry[V]{
+		handlers:   make(map[string]HandlerFunc[V]),
+		middleware: make(map[string]MiddlewareFunc[V]),
+	}
+}
+
+// Handler registers h under name
+func (reg *HandlerRegistry[V]) Handler(name string, h HandlerFunc[V]) *HandlerRegistry[V] {
+	reg.handlers[name] = h
+	return reg
+}
+
+// Middleware registers mw under name
+func (reg *HandlerRegistry[V]) Middleware(name string, mw MiddlewareFunc[V]) *HandlerRegistry[V] {
+	reg.middleware[name] = mw
+	return reg
+}
+
+// RoutesFile is the document read by Router.LoadRoutes:
+//
+//	middleware: [logging]
+//	routes:
+//	  - method: GET
+//	    path: /health
+//	    handler: health
+//	    class: critical
+//	groups:
+//	  - prefix: /api
+//	    middleware: [auth]
+//	    routes:
+//	      - method: POST
+//	        path: /users
+//	        handler: users.create
+//	static:
+//	  - prefix: /assets
+//	    dir: ./public
+//	redirects:
+//	  - from: /old/:id
+//	    to: /new/:id
+type RoutesFile struct {
+	// Middleware names apply to every route, in order
+	Middleware []string       `json:"middleware"`
+	Routes     []RouteSpec    `json:"routes"`
+	Groups     []GroupSpec    `json:"groups"`
+	Static     []StaticSpec   `json:"static"`
+	Redirects  []RedirectSpec `json:"redirects"`
+}
+
+// RouteSpec declares a route
+type RouteSpec struct {
+	// Method defaults to GET; ANY matches every method
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Handler    string   `json:"handler"`
+	Middleware []string `json:"middleware"`
+	Name       string   `json:"name"`
+	Summary    string   `json:"summary"`
+	// Class is interactive (default), batch or critical
+	Class                  string `json:"class"`
+	Priority               int    `json:"priority"`
+	AllowDuringMaintenance bool   `json:"allow_during_maintenance"`
+}
+
+// GroupSpec declares a group of routes sharing a prefix and middleware
+type GroupSpec struct {
+	Prefix     string      `json:"prefix"`
+	Middleware []string    `json:"middleware"`
+	Routes     []RouteSpec `json:"routes"`
+	Groups     []GroupSpec `json:"groups"`
+}
+
+// StaticSpec mounts a directory
+type StaticSpec struct {
+	Prefix     string   `json:"prefix"`
+	Dir        string   `json:"dir"`
+	Middleware []string `json:"middleware"`
+}
+
+// RedirectSpec redirects GET requests; parameters of From can be used in To
+type RedirectSpec struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Status defaults to 301
+	Status int `json:"status"`
+}
+
+// LoadRoutes registers the routes declared in file, a JSON or YAML document
+// (by extension) following RoutesFile. Handler and middleware names are
+// resolved in reg; nothing is registered when one is missing.
+func (r *Router[V]) LoadRoutes(file string, reg *HandlerRegistry[V]) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format := "json"
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+	if err := r.LoadRoutesFrom(f, format, reg); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// LoadRoutesFrom is LoadRoutes on a reader; format is "json" or "yaml"
+func (r *Router[V]) LoadRoutesFrom(src io.Reader, format string, reg *HandlerRegistry[V]) (err error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if format == "yaml" {
+		tree, err := yaml.Parse(data)
+		if err != nil {
+			return err
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return err
+		}
+	}
+	var doc RoutesFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("invalid routes file: %w", err)
+	}
+	if err := checkRoutesFile(&doc, reg); err != nil {
+		return err
+	}
+
+	// Registration panics on conflicting routes
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	for _, name := range doc.Middleware {
+		r.Use(reg.middleware[name])
+	}
+	for _, spec := range doc.Routes {
+		r.registerSpec("", nil, spec, reg)
+	}
+	for _, g := range doc.Groups {
+		r.registerGroup("", nil, g, reg)
+	}
+	for _, s := range doc.Static {
+		r.Static(s.Prefix, os.DirFS(s.Dir), reg.resolve(s.Middleware)...)
+	}
+	for _, rd := range doc.Redirects {
+		r.GET(rd.From, redirectHandler[V](rd.To, rd.Status))
+	}
+	return nil
+}
+
+// checkRoutesFile resolves every name and validates the specs before
+// anything is registered
+func checkRoutesFile[V any](doc *RoutesFile, reg *HandlerRegistry[V]) error {
+	if err := reg.checkMiddleware("middleware", doc.Middleware); err != nil {
+		return err
+	}
+	for i, spec := range doc.Routes {
+		if err := reg.checkRoute(fmt.Sprintf("routes[%d]", i), spec); err != nil {
+			return err
+		}
+	}
+	for i, g := range doc.Groups {
+		if err := reg.checkGroup(fmt.Sprintf("groups[%d]", i), g); err != nil {
+			return err
+		}
+	}
+	for i, s := range doc.Static {
+		at := fmt.Sprintf("static[%d]", i)
+		if !strings.HasPrefix(s.Prefix, "/") {
+			return fmt.Errorf("%s: prefix must start with /", at)
+		}
+		if info, err := os.Stat(s.Dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s: %s is not a directory", at, s.Dir)
+		}
+		if err := reg.checkMiddleware(at+".middleware", s.Middleware); err != nil {
+			return err
+		}
+	}
+	for i, rd := range doc.Redirects {
+		at := fmt.Sprintf("redirects[%d]", i)
+		if !strings.HasPrefix(rd.From, "/") || rd.To == "" {
+			return fmt.Errorf("%s: from must start with / and to is required", at)
+		}
+		if rd.Status != 0 && (rd.Status < 300 || rd.Status > 399) {
+			return fmt.Errorf("%s: invalid redirect status %d", at, rd.Status)
+		}
+	}
+	return nil
+}
+
+func (reg *HandlerRegistry[V]) checkMiddleware(at string, names []string) error {
+	for _, name := range names {
+		if reg.middleware[name] == nil {
+			return fmt.Errorf("%s: unknown middleware %q", at, name)
+		}
+	}
+	return nil
+}
+
+func (reg *HandlerRegistry[V]) checkRoute(at string, spec RouteSpec) error {
+	if !strings.HasPrefix(spec.Path, "/") {
+		return fmt.Errorf("%s: path must start with /", at)
+	}
+	switch strings.ToUpper(spec.Method) {
+	case "", "GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD", "ANY":
+	default:
+		return fmt.Errorf("%s: unsupported method %q", at, spec.Method)
+	}
+	if reg.handlers[spec.Handler] == nil {
+		return fmt.Errorf("%s: unknown handler %q", at, spec.Handler)
+	}
+	switch spec.Class {
+	case "", "interactive", "batch", "critical":
+	default:
+		return fmt.Errorf("%s: unknown class %q", at, spec.Class)
+	}
+	return reg.checkMiddleware(at+".middleware", spec.Middleware)
+}
+
+func (reg *HandlerRegistry[V]) checkGroup(at string, g GroupSpec) error {
+	if g.Prefix != "" && !strings.HasPrefix(g.Prefix, "/") {
+		return fmt.Errorf("%s: prefix must start with /", at)
+	}
+	if err := reg.checkMiddleware(at+".middleware", g.Middleware); err != nil {
+		return err
+	}
+	for i, spec := range g.Routes {
+		if err := reg.checkRoute(fmt.Sprintf("%s.routes[%d]", at, i), spec); err != nil {
+			return err
+		}
+	}
+	for i, child := range g.Groups {
+		if err := reg.checkGroup(fmt.Sprintf("%s.groups[%d]", at, i), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (reg *HandlerRegistry[V]) resolve(names []string) []MiddlewareFunc[V] {
+	mws := make([]MiddlewareFunc[V], len(names))
+	for i, name := range names {
+		mws[i] = reg.middleware[name]
+	}
+	return mws
+}
+
+func (r *Router[V]) registerGroup(prefix string, mws []MiddlewareFunc[V], g GroupSpec, reg *HandlerRegistry[V]) {
+	prefix += g.Prefix
+	mws = append(mws[:len(mws):len(mws)], reg.resolve(g.Middleware)...)
+	for _, spec := range g.Routes {
+		r.registerSpec(prefix, mws, spec, reg)
+	}
+	for _, child := range g.Groups {
+		r.registerGroup(prefix, mws, child, reg)
+	}
+}
+
+func (r *Router[V]) registerSpec(prefix string, mws []MiddlewareFunc[V], spec RouteSpec, reg *HandlerRegistry[V]) {
+	mws = append(mws[:len(mws):len(mws)], reg.resolve(spec.Middleware)...)
+	handler := reg.handlers[spec.Handler]
+	path := prefix + spec.Path
+	var rt *Route[V]
+	if method := strings.ToUpper(spec.Method); method == "ANY" {
+		rt = r.ANY(path, handler, mws...)
+	} else {
+		if method == "" {
+			method = "GET"
+		}
+		rt = r.addRoute(method, path, handler, mws...)
+	}
+	if spec.Name != "" {
+		rt.Name(spec.Name)
+	}
+	if spec.Summary != "" {
+		rt.Describe(spec.Summary)
+	}
+	if spec.Priority != 0 {
+		rt.Priority(spec.Priority)
+	}
+	if spec.AllowDuringMaintenance {
+		rt.AllowDuringMaintenance()
+	}
+	rt.Class(parseRequestClass(spec.Class))
+}
+
+func parseRequestClass(s string) RequestClass {
+	switch s {
+	case "batch":
+		return ClassBatch
+	case "critical":
+		return ClassCritical
+	}
+	return ClassInteractive
+}
+
+// redirectHandler redirects to the to pattern filled with the route parameters
+func redirectHandler[V any](to string, status int) HandlerFunc[V] {
+	if status == 0 {
+		status = http.StatusMovedPermanently
+	}
+	return func(ctx *Ctx[V]) {
+		target, err := fillRoutePattern(to, ctx.Params)
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		if ctx.Request.URL.RawQuery != "" && !strings.Contains(target, "?") {
+			target += "?" + ctx.Request.URL.RawQuery
+		}
+		ctx.Redirect(status, target)
+	}
+}
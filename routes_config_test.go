@@ -0,0 +1,152 @@
+package octo
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRoutesYAML = `
+# Routes managed by ops
+middleware: [tag]
+routes:
+  - method: GET
+    path: /health
+    handler: ok
+    class: critical
+    allow_during_maintenance: true
+  - path: /users/:id
+    handler: echo
+    name: user
+groups:
+  - prefix: /api
+    middleware:
+      - auth
+    routes:
+      - method: post
+        path: /items
+        handler: echo
+        summary: "Create an item"
+    groups:
+      - prefix: /v2
+        routes:
+          - method: ANY
+            path: /ping
+            handler: ok
+redirects:
+  - from: /old/:id
+    to: /users/:id
+`
+
+func testRegistry() *HandlerRegistry[CustomData] {
+	return NewHandlerRegistry[CustomData]().
+		Handler("ok", func(ctx *Ctx[CustomData]) { ctx.SendString(200, "ok") }).
+		Handler("echo", func(ctx *Ctx[CustomData]) {
+			ctx.SendString(200, ctx.Request.Method+" "+ctx.Request.URL.Path+" "+ctx.Param("id"))
+		}).
+		Middleware("tag", func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+			return func(ctx *Ctx[CustomData]) {
+				ctx.SetHeader("X-Tag", "1")
+				next(ctx)
+			}
+		}).
+		Middleware("auth", func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+			return func(ctx *Ctx[CustomData]) {
+				if ctx.GetHeader("Authorization") == "" {
+					ctx.Send401()
+					return
+				}
+				next(ctx)
+			}
+		})
+}
+
+func TestLoadRoutesYAML(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "routes.yaml")
+	os.WriteFile(file, []byte(testRoutesYAML), 0o644)
+
+	router := NewRouter[CustomData]()
+	if err := router.LoadRoutes(file, testRegistry()); err != nil {
+		t.Fatalf("LoadRoutes: %v", err)
+	}
+
+	tests := []struct {
+		method, path, auth string
+		status             int
+		body               string
+	}{
+		{"GET", "/health", "", 200, "ok"},
+		{"GET", "/users/7", "", 200, "GET /users/7 7"},
+		{"POST", "/api/items", "", 401, ""},
+		{"POST", "/api/items", "token", 200, "POST /api/items "},
+		{"DELETE", "/api/v2/ping", "token", 200, "ok"},
+		{"GET", "/old/9?x=1", "", 301, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+		if w.Header().Get("X-Tag") != "1" {
+			t.Errorf("%s %s: router middleware not applied", tt.method, tt.path)
+		}
+		if tt.status == 301 && w.Header().Get("Location") != "/users/9?x=1" {
+			t.Errorf("Unexpected redirect: %s", w.Header().Get("Location"))
+		}
+	}
+	if router.NamedRoute("user") == nil {
+		t.Error("Expected the route name to be registered")
+	}
+	routes := router.Routes()
+	if routes[0].Class != "critical" || routes[2].Summary != "Create an item" {
+		t.Errorf("Unexpected route metadata: %+v", routes)
+	}
+}
+
+func TestLoadRoutesJSONStatic(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.css"), []byte("body{}"), 0o644)
+	doc := `{"static": [{"prefix": "/assets", "dir": "` + filepath.ToSlash(dir) + `"}], "routes": [{"path": "/", "handler": "ok"}]}`
+
+	router := NewRouter[CustomData]()
+	if err := router.LoadRoutesFrom(strings.NewReader(doc), "json", testRegistry()); err != nil {
+		t.Fatalf("LoadRoutesFrom: %v", err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/assets/app.css", nil))
+	if w.Code != 200 || w.Body.String() != "body{}" {
+		t.Errorf("Unexpected static response: %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/assets/missing.css", nil))
+	if w.Code != 404 {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+func TestLoadRoutesRejects(t *testing.T) {
+	for _, doc := range []string{
+		`{"routes": [{"path": "/a", "handler": "missing"}]}`,
+		`{"routes": [{"path": "/a", "handler": "ok", "middleware": ["missing"]}]}`,
+		`{"routes": [{"path": "a", "handler": "ok"}]}`,
+		`{"routes": [{"method": "TRACE", "path": "/a", "handler": "ok"}]}`,
+		`{"groups": [{"prefix": "/g", "groups": [{"routes": [{"path": "/a", "handler": "nope"}]}]}]}`,
+		`{"static": [{"prefix": "/s", "dir": "/does/not/exist"}]}`,
+		`{"redirects": [{"from": "/a", "to": "/b", "status": 200}]}`,
+		`{"unknown": true}`,
+		`{"routes": [{"path": "/a", "handler": "ok"}, {"path": "/a", "handler": "ok"}]}`,
+	} {
+		router := NewRouter[CustomData]()
+		if err := router.LoadRoutesFrom(strings.NewReader(doc), "json", testRegistry()); err == nil {
+			t.Errorf("Expected an error for %s", doc)
+		}
+	}
+}
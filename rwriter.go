@@ -14,6 +14,7 @@ type ResponseWriterWrapper struct {
 	Status      int
 	Body        *bytes.Buffer // Buffer to capture response body
 	CaptureBody bool
+	hijacked    bool
 }
 
 // NewResponseWriterWrapper initializes a new ResponseWriterWrapper
@@ -54,6 +55,7 @@ func (w *ResponseWriterWrapper) Write(data []byte) (int, error) {
 // Implement http.Hijacker
 func (w *ResponseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.hijacked = true
 		return hj.Hijack()
 	}
 	return nil, nil, errors.New("ResponseWriter does not implement http.Hijacker")
@@ -0,0 +1,205 @@
+// Package saml provides the octo side of a SAML 2.0 service provider: the
+// metadata, login and assertion consumer routes plus relay state handling.
+// XML signature validation and AuthnRequest encoding are delegated to an
+// Adapter, typically backed by a dedicated SAML library.
+package saml
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// Assertion is the validated identity returned by the identity provider
+type Assertion struct {
+	NameID       string
+	SessionIndex string
+	Attributes   map[string][]string
+	NotOnOrAfter time.Time
+}
+
+// Attribute returns the first value of an assertion attribute
+func (a *Assertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Adapter is implemented on top of a SAML library
+type Adapter interface {
+	// Metadata returns the SP metadata XML document
+	Metadata() ([]byte, error)
+	// AuthnRequestURL returns the IdP URL (HTTP-Redirect binding) carrying relayState
+	AuthnRequestURL(relayState string) (string, error)
+	// ParseResponse validates the POSTed SAMLResponse (signature, audience,
+	// conditions) and returns its assertion
+	ParseResponse(r *http.Request) (*Assertion, error)
+}
+
+// Config configures the SAML routes
+type Config[V any] struct {
+	Adapter Adapter
+	// RelayStateKey signs the relay state so return URLs cannot be forged
+	RelayStateKey []byte
+	// RelayStateTTL bounds the time between login and ACS (default 10 minutes)
+	RelayStateTTL time.Duration
+	// OnLogin establishes the application session from the assertion
+	OnLogin func(ctx *octo.Ctx[V], assertion *Assertion) error
+	// DefaultRedirect is used when no return_to was given (default "/")
+	DefaultRedirect string
+}
+
+// ServiceProvider exposes the SP routes of an application
+type ServiceProvider[V any] struct {
+	cfg Config[V]
+}
+
+// New validates cfg and returns a ServiceProvider
+func New[V any](cfg Config[V]) (*ServiceProvider[V], error) {
+	if cfg.Adapter == nil {
+		return nil, errors.New("saml: Adapter is required")
+	}
+	if len(cfg.RelayStateKey) < 16 {
+		return nil, errors.New("saml: RelayStateKey must be at least 16 bytes")
+	}
+	if cfg.RelayStateTTL == 0 {
+		cfg.RelayStateTTL = 10 * time.Minute
+	}
+	if cfg.DefaultRedirect == "" {
+		cfg.DefaultRedirect = "/"
+	}
+	return &ServiceProvider[V]{cfg: cfg}, nil
+}
+
+// Mount registers GET /metadata, GET /login and POST /acs on the group
+func (sp *ServiceProvider[V]) Mount(g *octo.Group[V]) {
+	g.GET("/metadata", sp.MetadataHandler())
+	g.GET("/login", sp.LoginHandler())
+	g.POST("/acs", sp.ACSHandler())
+}
+
+// MetadataHandler serves the SP metadata
+func (sp *ServiceProvider[V]) MetadataHandler() octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		metadata, err := sp.cfg.Adapter.Metadata()
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		ctx.SendData(http.StatusOK, "application/samlmetadata+xml", metadata)
+	}
+}
+
+// LoginHandler redirects to the IdP. A relative return_to query parameter is
+// carried in the relay state and honored after login.
+func (sp *ServiceProvider[V]) LoginHandler() octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		relayState, err := sp.encodeRelayState(octo.LocalRedirectPath(ctx.QueryValue("return_to")))
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		target, err := sp.cfg.Adapter.AuthnRequestURL(relayState)
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		ctx.Redirect(http.StatusFound, target)
+	}
+}
+
+// ACSHandler is the assertion consumer service (HTTP-POST binding)
+func (sp *ServiceProvider[V]) ACSHandler() octo.HandlerFunc[V] {
+	return func(ctx *octo.Ctx[V]) {
+		if err := ctx.Request.ParseForm(); err != nil {
+			ctx.SendError("err_invalid_request", err)
+			return
+		}
+		returnTo, err := sp.decodeRelayState(ctx.Request.PostForm.Get("RelayState"))
+		if err != nil {
+			ctx.SendError("err_unauthorized", err)
+			return
+		}
+		assertion, err := sp.cfg.Adapter.ParseResponse(ctx.Request)
+		if err != nil {
+			ctx.SendError("err_unauthorized", err)
+			return
+		}
+		if !assertion.NotOnOrAfter.IsZero() && time.Now().After(assertion.NotOnOrAfter) {
+			ctx.SendError("err_unauthorized", errors.New("assertion expired"))
+			return
+		}
+		if sp.cfg.OnLogin != nil {
+			if err := sp.cfg.OnLogin(ctx, assertion); err != nil {
+				ctx.SendError("err_unauthorized", err)
+				return
+			}
+		}
+		if ctx.IsDone() {
+			return
+		}
+		if returnTo == "" {
+			returnTo = sp.cfg.DefaultRedirect
+		}
+		ctx.Redirect(http.StatusSeeOther, returnTo)
+	}
+}
+
+type relayState struct {
+	ReturnTo string `json:"r,omitempty"`
+	Expires  int64  `json:"e"`
+	Nonce    string `json:"n"`
+}
+
+// encodeRelayState returns a signed, expiring relay state. SAML responses are
+// cross-site POSTs, so the state cannot rely on SameSite cookies.
+func (sp *ServiceProvider[V]) encodeRelayState(returnTo string) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(relayState{
+		ReturnTo: returnTo,
+		Expires:  time.Now().Add(sp.cfg.RelayStateTTL).Unix(),
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sp.sign(payload), nil
+}
+
+func (sp *ServiceProvider[V]) decodeRelayState(value string) (string, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sp.sign(payload))) {
+		return "", errors.New("invalid relay state")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", errors.New("invalid relay state")
+	}
+	var state relayState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", errors.New("invalid relay state")
+	}
+	if time.Now().Unix() > state.Expires {
+		return "", errors.New("relay state expired")
+	}
+	return octo.LocalRedirectPath(state.ReturnTo), nil
+}
+
+func (sp *ServiceProvider[V]) sign(payload string) string {
+	mac := hmac.New(sha256.New, sp.cfg.RelayStateKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
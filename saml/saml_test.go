@@ -0,0 +1,85 @@
+package saml
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coffyg/octo"
+)
+
+type fakeAdapter struct{}
+
+func (fakeAdapter) Metadata() ([]byte, error) {
+	return []byte("<EntityDescriptor/>"), nil
+}
+
+func (fakeAdapter) AuthnRequestURL(relayState string) (string, error) {
+	return "https://idp.test/sso?RelayState=" + url.QueryEscape(relayState), nil
+}
+
+func (fakeAdapter) ParseResponse(r *http.Request) (*Assertion, error) {
+	if r.PostForm.Get("SAMLResponse") != "valid" {
+		return nil, errors.New("invalid response")
+	}
+	return &Assertion{NameID: "alice", Attributes: map[string][]string{"role": {"admin"}}}, nil
+}
+
+func TestServiceProvider(t *testing.T) {
+	var loggedIn string
+	sp, err := New(Config[struct{}]{
+		Adapter:       fakeAdapter{},
+		RelayStateKey: []byte("0123456789abcdef"),
+		OnLogin: func(ctx *octo.Ctx[struct{}], a *Assertion) error {
+			loggedIn = a.NameID + ":" + a.Attribute("role")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	router := octo.NewRouter[struct{}]()
+	sp.Mount(router.Group("/saml"))
+
+	req := httptest.NewRequest("GET", "/saml/metadata", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "<EntityDescriptor/>" {
+		t.Errorf("Unexpected metadata: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/saml/login?return_to=/reports", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	location, _ := url.Parse(w.Result().Header.Get("Location"))
+	relay := location.Query().Get("RelayState")
+	if relay == "" {
+		t.Fatalf("Expected relay state in redirect, got %s", location)
+	}
+
+	post := func(relayState, response string) *httptest.ResponseRecorder {
+		form := url.Values{"RelayState": {relayState}, "SAMLResponse": {response}}
+		req := httptest.NewRequest("POST", "/saml/acs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(relay+"x", "valid"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected forged relay state to be rejected, got %d", w.Code)
+	}
+	if w := post(relay, "invalid"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected invalid response to be rejected, got %d", w.Code)
+	}
+	w = post(relay, "valid")
+	if w.Code != http.StatusSeeOther || w.Result().Header.Get("Location") != "/reports" {
+		t.Errorf("Expected redirect to /reports, got %d %s", w.Code, w.Result().Header.Get("Location"))
+	}
+	if loggedIn != "alice:admin" {
+		t.Errorf("Expected OnLogin to be called, got '%s'", loggedIn)
+	}
+}
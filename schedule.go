@@ -0,0 +1,368 @@
+package octo
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression (minute hour
+// day-of-month month day-of-week), or a fixed interval for "@every <duration>"
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar/dowStar: when both day fields are restricted either may match
+	domStar, dowStar bool
+	every            time.Duration
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression. Fields accept *, numbers, ranges (a-b),
+// steps (*/n, a-b/n) and comma lists; day-of-week 7 is Sunday. The macros
+// @yearly, @monthly, @weekly, @daily, @hourly and "@every 30s" are supported.
+func ParseCron(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("cron: invalid interval %q", rest)
+		}
+		return &CronSchedule{every: d}, nil
+	}
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d in %q", len(fields), spec)
+	}
+	s := &CronSchedule{}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	targets := [5]*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, field := range fields {
+		mask, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron: field %d: %w", i+1, err)
+		}
+		*targets[i] = mask
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	s.dowStar = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rng, st, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(st)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			part, step = rng, n
+		}
+		lo, hi := min, max
+		if part != "*" {
+			a, b, isRange := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first activation strictly after t, or the zero time if
+// the expression never matches (e.g. February 30th)
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			// Jump straight to the next matching minute within this hour
+			rest := s.minute >> uint(t.Minute())
+			if rest == 0 {
+				t = t.Truncate(time.Hour).Add(time.Hour)
+			} else {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// TaskOptions tunes a scheduled task
+type TaskOptions struct {
+	// Name identifies the task in status reports (defaults to the spec)
+	Name string
+	// Jitter delays each activation by a random duration in [0, Jitter)
+	Jitter time.Duration
+	// AllowOverlap starts a run even when the previous one is still going;
+	// by default such activations are skipped
+	AllowOverlap bool
+	// Timeout bounds the context of a single run
+	Timeout time.Duration
+}
+
+// TaskStatus reports the state of a scheduled task
+type TaskStatus struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"spec"`
+	Running   bool      `json:"running"`
+	Runs      int64     `json:"runs"`
+	Skipped   int64     `json:"skipped"`
+	Failures  int64     `json:"failures"`
+	LastStart time.Time `json:"last_start,omitempty"`
+	LastEnd   time.Time `json:"last_end,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Next      time.Time `json:"next,omitempty"`
+}
+
+// ScheduledTask is a task registered on a Scheduler
+type ScheduledTask struct {
+	schedule *CronSchedule
+	fn       func(context.Context) error
+	opts     TaskOptions
+
+	mu     sync.Mutex
+	status TaskStatus
+}
+
+// Status returns a snapshot of the task status
+func (t *ScheduledTask) Status() TaskStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// Scheduler runs cron tasks in-process until it is stopped
+type Scheduler struct {
+	mu    sync.Mutex
+	tasks []*ScheduledTask
+	// stopping ends the scheduling loops; ctx is cancelled once running
+	// tasks had their chance to finish
+	stopping chan struct{}
+	stopOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	loops    sync.WaitGroup
+	running  sync.WaitGroup
+}
+
+// NewScheduler creates an empty scheduler
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{stopping: make(chan struct{}), ctx: ctx, cancel: cancel}
+}
+
+// DefaultScheduler is the scheduler behind Schedule
+var DefaultScheduler = NewScheduler()
+
+// Schedule registers fn on DefaultScheduler. It panics on an invalid spec.
+func Schedule(spec string, fn func(context.Context) error) *ScheduledTask {
+	return DefaultScheduler.Schedule(spec, fn)
+}
+
+// Schedule registers fn to run on spec. It panics on an invalid spec.
+func (s *Scheduler) Schedule(spec string, fn func(context.Context) error) *ScheduledTask {
+	return s.ScheduleWith(spec, fn, TaskOptions{})
+}
+
+// ScheduleWith registers fn with options. It panics on an invalid spec.
+func (s *Scheduler) ScheduleWith(spec string, fn func(context.Context) error, opts TaskOptions) *ScheduledTask {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	if opts.Name == "" {
+		opts.Name = spec
+	}
+	task := &ScheduledTask{
+		schedule: schedule,
+		fn:       fn,
+		opts:     opts,
+		status:   TaskStatus{Name: opts.Name, Spec: spec},
+	}
+	s.mu.Lock()
+	s.tasks = append(s.tasks, task)
+	s.mu.Unlock()
+
+	s.loops.Add(1)
+	go s.loop(task)
+	return task
+}
+
+func (s *Scheduler) loop(task *ScheduledTask) {
+	defer s.loops.Done()
+	for {
+		next := task.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		if task.opts.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(task.opts.Jitter))))
+		}
+		task.mu.Lock()
+		task.status.Next = next
+		task.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stopping:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		task.mu.Lock()
+		if task.status.Running && !task.opts.AllowOverlap {
+			task.status.Skipped++
+			task.mu.Unlock()
+			continue
+		}
+		task.status.Running = true
+		task.status.LastStart = time.Now()
+		task.mu.Unlock()
+
+		s.running.Add(1)
+		go s.run(task)
+	}
+}
+
+func (s *Scheduler) run(task *ScheduledTask) {
+	defer s.running.Done()
+	ctx := s.ctx
+	if task.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.opts.Timeout)
+		defer cancel()
+	}
+
+	err := func() (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("task panicked: %v", rec)
+			}
+		}()
+		return task.fn(ctx)
+	}()
+
+	task.mu.Lock()
+	task.status.Running = false
+	task.status.Runs++
+	task.status.LastEnd = time.Now()
+	task.status.LastError = ""
+	if err != nil {
+		task.status.Failures++
+		task.status.LastError = err.Error()
+	}
+	task.mu.Unlock()
+
+	if err != nil {
+		serverLogger := SubsystemLogger(LogServer)
+		if EnableLoggerCheck {
+			if serverLogger != nil {
+				serverLogger.Warn().Err(err).Str("task", task.opts.Name).Msg("[octo] scheduled task failed")
+			}
+		} else {
+			serverLogger.Warn().Err(err).Str("task", task.opts.Name).Msg("[octo] scheduled task failed")
+		}
+	}
+}
+
+// Status returns the status of every task
+func (s *Scheduler) Status() []TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]TaskStatus, len(s.tasks))
+	for i, task := range s.tasks {
+		out[i] = task.Status()
+	}
+	return out
+}
+
+// Stop stops scheduling new runs and waits for running tasks until ctx is
+// done, at which point their contexts are cancelled
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	s.loops.Wait()
+	defer s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ScheduleStatusHandler serves the status of every task of s as JSON
+func ScheduleStatusHandler[V any](s *Scheduler) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		ctx.SendJSON(http.StatusOK, s.Status())
+	}
+}
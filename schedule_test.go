@@ -0,0 +1,112 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCronNext(t *testing.T) {
+	base := time.Date(2024, 3, 15, 10, 7, 30, 0, time.UTC) // Friday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 3, 15, 10, 10, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2024, 3, 15, 10, 8, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 3, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)},
+		{"15,45 8-9 * 6 *", time.Date(2024, 6, 1, 8, 15, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, 3, 22, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", base.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, expected %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every -1s"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", spec)
+		}
+	}
+}
+
+func TestSchedulerOverlapAndStatus(t *testing.T) {
+	s := NewScheduler()
+	var runs atomic.Int32
+	release := make(chan struct{})
+	task := s.ScheduleWith("@every 5ms", func(ctx context.Context) error {
+		runs.Add(1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return errors.New("failed")
+	}, TaskOptions{Name: "cleanup"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for task.Status().Skipped < 2 && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Millisecond)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("Expected overlapping activations to be skipped, got %d runs", runs.Load())
+	}
+	close(release)
+	for task.Status().Runs < 1 && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	router := NewRouter[CustomData]()
+	router.GET("/admin/tasks", ScheduleStatusHandler[CustomData](s))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/tasks", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"cleanup"`) || !strings.Contains(w.Body.String(), `"last_error":"failed"`) {
+		t.Errorf("Unexpected status response: %d %s", w.Code, w.Body.String())
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Expected clean stop, got %v", err)
+	}
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("Expected no runs after Stop")
+	}
+}
+
+func TestSchedulerStopCancelsRunningTasks(t *testing.T) {
+	s := NewScheduler()
+	started := make(chan struct{})
+	var once atomic.Bool
+	s.Schedule("@every 1ms", func(ctx context.Context) error {
+		if once.CompareAndSwap(false, true) {
+			close(started)
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package octo
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// SchemaFor derives the JSON Schema of the encoding/json form of v's type.
+// Struct fields follow their json tags and are required unless omitempty or
+// pointers; pointers are nullable. Types with their own JSON marshaling are
+// left unconstrained, except time.Time (a date-time string) and text
+// marshalers (strings).
+func SchemaFor(v interface{}) *Schema {
+	raw := typeSchema(reflect.TypeOf(v), map[reflect.Type]bool{})
+	data, err := json.Marshal(raw)
+	if err != nil {
+		panic("octo: " + err.Error())
+	}
+	return MustCompileSchema(data)
+}
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := typeSchema(t.Elem(), visiting)
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []string{typ, "null"}
+		}
+		return s
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]interface{}{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64 encoded
+			return map[string]interface{}{"type": "string"}
+		}
+		s := map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+		if t.Kind() == reflect.Slice {
+			s["type"] = []string{"array", "null"}
+		}
+		return s
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// Recursive type: leave the nested value unconstrained
+			return map[string]interface{}{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		props := map[string]interface{}{}
+		required := []string{}
+		addStructSchema(t, props, &required, visiting)
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addStructSchema adds the properties of t, direct fields shadowing the
+// promoted fields of embedded structs
+func addStructSchema(t reflect.Type, props map[string]interface{}, required *[]string, visiting map[reflect.Type]bool) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := props[name]; ok {
+			continue
+		}
+		props[name] = typeSchema(sf.Type, visiting)
+		if !strings.Contains(","+opts+",", ",omitempty,") && sf.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+	for _, ft := range embedded {
+		addStructSchema(ft, props, required, visiting)
+	}
+}
+
+// Request documents the JSON body of the route with the schema of v's type
+// (see SchemaFor), as listed by the discovery endpoint and used by the
+// client generators
+func (rt *Route[V]) Request(v interface{}) *Route[V] {
+	rt.doc.request = SchemaFor(v)
+	return rt
+}
+
+// Response documents the JSON body the route answers with for status (0 for
+// any other status) with the schema of v's type (see SchemaFor). In DevMode,
+// responses are checked against the declared schemas as by
+// ResponseContractMiddleware.
+func (rt *Route[V]) Response(status int, v interface{}) *Route[V] {
+	if rt.doc.responses == nil {
+		rt.doc.responses = make(ResponseContract)
+	}
+	rt.doc.responses[status] = SchemaFor(v)
+	if !rt.doc.checked {
+		rt.doc.checked = true
+		doc := &rt.doc
+		rt.use(func(next HandlerFunc[V]) HandlerFunc[V] {
+			return func(ctx *Ctx[V]) {
+				if !DevMode {
+					next(ctx)
+					return
+				}
+				ResponseContractMiddleware[V](doc.responses)(next)(ctx)
+			}
+		})
+	}
+	return rt
+}
@@ -0,0 +1,79 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type schemaAudit struct {
+	Created time.Time `json:"created"`
+}
+
+type schemaUser struct {
+	schemaAudit
+	ID      int               `json:"id"`
+	Email   string            `json:"email"`
+	Nick    *string           `json:"nick"`
+	Bio     string            `json:"bio,omitempty"`
+	Roles   []string          `json:"roles"`
+	Labels  map[string]string `json:"labels"`
+	Manager *schemaUser       `json:"manager,omitempty"`
+	secret  string
+	Hidden  string `json:"-"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	s := SchemaFor(schemaUser{})
+	var raw map[string]interface{}
+	data, _ := json.Marshal(s)
+	json.Unmarshal(data, &raw)
+	props := raw["properties"].(map[string]interface{})
+	for _, name := range []string{"id", "email", "nick", "bio", "roles", "labels", "manager", "created"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("Expected property %q in %s", name, data)
+		}
+	}
+	if _, ok := props["Hidden"]; ok {
+		t.Errorf("Expected json:\"-\" fields to be skipped: %s", data)
+	}
+
+	valid := `{"created":"2024-01-02T03:04:05Z","id":1,"email":"a@b.c","nick":null,"roles":["admin"],"labels":{},"manager":{"created":"2024-01-02T03:04:05Z","id":2,"email":"x","nick":"y","roles":null,"labels":null}}`
+	if err := s.ValidateJSON([]byte(valid)); err != nil {
+		t.Errorf("Expected a valid document, got %v", err)
+	}
+	invalid := `{"created":"2024-01-02T03:04:05Z","id":"1","nick":null,"roles":[1],"labels":{}}`
+	if err := s.ValidateJSON([]byte(invalid)); err == nil {
+		t.Error("Expected mistyped and missing fields to fail")
+	}
+}
+
+func TestRouteRequestResponse(t *testing.T) {
+	oldDev, oldHook := DevMode, OnContractViolation
+	defer func() { DevMode, OnContractViolation = oldDev, oldHook }()
+	var violations []ContractViolation
+	OnContractViolation = func(v ContractViolation) { violations = append(violations, v) }
+
+	router := NewRouter[CustomData]()
+	router.POST("/users", func(ctx *Ctx[CustomData]) {
+		ctx.SendJSON(http.StatusCreated, map[string]interface{}{"id": "not a number"})
+	}).Request(bindRequest{}).Response(http.StatusCreated, schemaUser{})
+
+	routes := router.Routes()
+	if routes[0].Request == nil || routes[0].Responses["201"] == nil {
+		t.Fatalf("Expected declared types in the route description, got %+v", routes[0])
+	}
+
+	DevMode = true
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users", nil))
+	if len(violations) != 1 || violations[0].Status != http.StatusCreated {
+		t.Errorf("Expected a contract violation in DevMode, got %v", violations)
+	}
+	DevMode = false
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users", nil))
+	if len(violations) != 1 {
+		t.Errorf("Expected no check outside DevMode, got %v", violations)
+	}
+}
@@ -0,0 +1,221 @@
+package octo
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// segmentPattern is a compiled path segment mixing literals and parameters,
+// such as "user:id-post:postId". Parameter names are made of letters, digits
+// and underscores; anything else starts the next literal. A parameter may be
+// constrained by a regular expression matching its whole value, as in
+// ":name<regex:[a-z0-9_-]+\.png>".
+type segmentPattern[V any] struct {
+	source   string
+	literals []string // literals[i] precedes params[i]; the last may trail
+	params   []string
+	regexps  []*regexp.Regexp // regexps[i] constrains params[i] when not nil
+	// constrained is set when any parameter has a regexp
+	constrained bool
+	child       *node[V]
+	// literalLen is the total literal length, used to try more specific
+	// patterns first
+	literalLen int
+}
+
+func isParamNameByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// compileSegment parses an embedded-parameter segment
+func compileSegment[V any](segment string) *segmentPattern[V] {
+	p := &segmentPattern[V]{source: segment}
+	rest := segment
+	for {
+		idx := strings.IndexByte(rest, ':')
+		if idx == -1 {
+			p.literals = append(p.literals, rest)
+			p.literalLen += len(rest)
+			break
+		}
+		if idx == 0 && len(p.params) > 0 {
+			panic("octo: adjacent parameters in path segment " + segment)
+		}
+		p.literals = append(p.literals, rest[:idx])
+		p.literalLen += idx
+		rest = rest[idx+1:]
+		end := 0
+		for end < len(rest) && isParamNameByte(rest[end]) {
+			end++
+		}
+		if end == 0 {
+			panic("octo: empty parameter name in path segment " + segment)
+		}
+		p.params = append(p.params, rest[:end])
+		rest = rest[end:]
+		var re *regexp.Regexp
+		if strings.HasPrefix(rest, regexConstraintPrefix) {
+			var expr string
+			expr, rest = cutRegexConstraint(segment, rest[len(regexConstraintPrefix):])
+			re = cachedRegexp(segment, expr)
+			p.constrained = true
+		}
+		p.regexps = append(p.regexps, re)
+		if rest == "" {
+			p.literals = append(p.literals, "")
+			break
+		}
+	}
+	return p
+}
+
+// match matches segment against the pattern, appending parameter values to
+// values. Values are non-empty and stop at the first occurrence of the next
+// literal. It does not allocate unless values must grow.
+func (p *segmentPattern[V]) match(segment string, values []string) ([]string, bool) {
+	if !strings.HasPrefix(segment, p.literals[0]) {
+		return values, false
+	}
+	start := len(values)
+	rest := segment[len(p.literals[0]):]
+	for i := range p.params {
+		next := p.literals[i+1]
+		var value string
+		if i == len(p.params)-1 {
+			if !strings.HasSuffix(rest, next) || len(rest) == len(next) {
+				return values[:start], false
+			}
+			value, rest = rest[:len(rest)-len(next)], ""
+		} else {
+			idx := strings.Index(rest[min(1, len(rest)):], next)
+			if idx == -1 || len(rest) == 0 {
+				return values[:start], false
+			}
+			idx++
+			value, rest = rest[:idx], rest[idx+len(next):]
+		}
+		if re := p.regexps[i]; re != nil && !re.MatchString(value) {
+			return values[:start], false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+const regexConstraintPrefix = "<regex:"
+
+// cutRegexConstraint returns the expression of a "<regex:...>" constraint,
+// rest starting after its prefix, and what follows the closing '>'. A '>'
+// inside brackets, parentheses or escaped does not close the constraint.
+func cutRegexConstraint(segment, rest string) (string, string) {
+	depth := 0
+	inClass := false
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '>' && depth == 0:
+			if i == 0 {
+				panic("octo: empty regex constraint in path segment " + segment)
+			}
+			return rest[:i], rest[i+1:]
+		}
+	}
+	panic("octo: unterminated regex constraint in path segment " + segment)
+}
+
+var regexpCache sync.Map // expression -> *regexp.Regexp
+
+// cachedRegexp compiles expr anchored to the whole parameter value, sharing
+// the result between routes
+func cachedRegexp(segment, expr string) *regexp.Regexp {
+	if re, ok := regexpCache.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic("octo: invalid regex constraint in path segment " + segment + ": " + err.Error())
+	}
+	actual, _ := regexpCache.LoadOrStore(expr, re)
+	return actual.(*regexp.Regexp)
+}
+
+// patternChild returns the child node for an embedded-parameter segment,
+// creating it if needed
+func (n *node[V]) patternChild(segment string) (*node[V], []string) {
+	for _, p := range n.patterns {
+		if p.source == segment {
+			return p.child, p.params
+		}
+	}
+	p := compileSegment[V](segment)
+	p.child = n.newChild()
+	n.patterns = append(n.patterns, p)
+	n.sortPatterns()
+	return p.child, p.params
+}
+
+// sortPatterns orders embedded-parameter patterns for matching: higher
+// priority first, then more literal characters (more specific), then
+// regex-constrained patterns, then by source
+func (n *node[V]) sortPatterns() {
+	sort.Slice(n.patterns, func(i, j int) bool {
+		a, b := n.patterns[i], n.patterns[j]
+		if a.child.priority != b.child.priority {
+			return a.child.priority > b.child.priority
+		}
+		if a.literalLen != b.literalLen {
+			return a.literalLen > b.literalLen
+		}
+		if a.constrained != b.constrained {
+			return a.constrained
+		}
+		return a.source < b.source
+	})
+}
+
+// matchPattern tries the node patterns against segment. Patterns with a lower
+// priority than the plain parameter child are skipped.
+func (n *node[V]) matchPattern(segment string, values []string) (*node[V], []string) {
+	for _, p := range n.patterns {
+		if n.paramChild != nil && p.child.priority < n.paramChild.priority {
+			continue
+		}
+		var ok bool
+		if values, ok = p.match(segment, values); ok {
+			return p.child, values
+		}
+	}
+	return nil, values
+}
+
+// isPlainParam reports whether a route segment is a whole-segment parameter
+// (":id") rather than an embedded pattern
+func isPlainParam(part string) bool {
+	return part[0] == ':' && !strings.ContainsFunc(part[1:], func(c rune) bool {
+		return c > 0x7f || !isParamNameByte(byte(c))
+	})
+}
+
+// segmentNode returns the child of cur for a route segment containing ':'
+func (r *Router[V]) segmentNode(cur *node[V], part string) (*node[V], []string) {
+	if isPlainParam(part) {
+		if cur.paramChild == nil {
+			cur.paramChild = cur.newChild()
+		}
+		return cur.paramChild, []string{part[1:]}
+	}
+	return cur.patternChild(part)
+}
@@ -0,0 +1,177 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbeddedMultiParamSegments(t *testing.T) {
+	router := newEmbeddedParamsRouter()
+	router.GET("/files/:name.json", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "json:"+ctx.Param("name"))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/user42-post7", http.StatusOK, "42/7"},
+		{"/user42-comment9", http.StatusOK, "42/"},
+		{"/user42", http.StatusOK, "42/"},
+		{"/org-coffyg/repo-octo.git", http.StatusOK, "/"},
+		{"/files/report.json", http.StatusOK, "json:report"},
+		{"/files/.json", http.StatusNotFound, ""},
+		{"/user", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	_, _, params, _ := router.search("GET", "/org-coffyg/repo-octo.git", nil)
+	if params["org"] != "coffyg" || params["repo"] != "octo" {
+		t.Errorf("Expected org/repo params, got %v", params)
+	}
+}
+
+func TestRegexConstrainedParams(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/files/:name<regex:[a-z0-9_-]+\\.png>", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "png:"+ctx.Param("name"))
+	})
+	router.GET("/files/:name", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "file:"+ctx.Param("name"))
+	})
+	router.GET("/v:major<regex:\\d{1,2}>.:minor<regex:\\d+>/info", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("major")+"/"+ctx.Param("minor"))
+	})
+	router.GET("/tags/:tag<regex:(a|b>c)>", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, "tag:"+ctx.Param("tag"))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/logo_2.png", http.StatusOK, "png:logo_2.png"},
+		{"/files/Logo.png", http.StatusOK, "file:Logo.png"},
+		{"/files/logo.pngx", http.StatusOK, "file:logo.pngx"},
+		{"/v12.3/info", http.StatusOK, "12/3"},
+		{"/v123.3/info", http.StatusNotFound, ""},
+		{"/v1.x/info", http.StatusNotFound, ""},
+		{"/tags/b>c", http.StatusOK, "tag:b>c"},
+		{"/tags/c", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	router.GET("/avatars/:name<regex:[a-z0-9_-]+\\.png>", func(ctx *Ctx[CustomData]) {}).Name("avatar")
+	if u, err := router.URL("avatar", "name", "me.png"); err != nil || u != "/avatars/me.png" {
+		t.Errorf("Unexpected URL %q: %v", u, err)
+	}
+	if _, err := router.URL("avatar", "name", "me.gif"); err == nil {
+		t.Errorf("Expected an error for a value not matching the constraint")
+	}
+
+	for _, path := range []string{"/x/:a<regex:[a-z>", "/x/:a<regex:>", "/x/:a<regex:(>"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", path)
+				}
+			}()
+			NewRouter[CustomData]().GET(path, func(ctx *Ctx[CustomData]) {})
+		}()
+	}
+}
+
+func TestCompileSegmentRejectsAdjacentParams(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for adjacent parameters")
+		}
+	}()
+	NewRouter[CustomData]().GET("/x/:a:b", func(ctx *Ctx[CustomData]) {})
+}
+
+func TestMidPathWildcardAndOptionalSegments(t *testing.T) {
+	router := NewRouter[CustomData]()
+	echo := func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("version")+"|"+ctx.Param("id")+"|"+ctx.Param("slug")+"|"+ctx.Param("rest"))
+	}
+	router.GET("/api/*version/users/:id", echo)
+	router.GET("/api/*rest", echo)
+	router.GET("/posts/:id/:slug?", echo)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/v1/users/42", http.StatusOK, "v1|42||"},
+		{"/api/v1/beta/users/42", http.StatusOK, "v1/beta|42||"},
+		{"/api/v1/users", http.StatusOK, "|||v1/users"},
+		{"/api/users/42", http.StatusOK, "|||users/42"},
+		{"/posts/7", http.StatusOK, "|7||"},
+		{"/posts/7/hello-world", http.StatusOK, "|7|hello-world|"},
+		{"/posts/7/hello/extra", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestRouteConflictDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Router[CustomData])
+	}{
+		{"optional overlaps existing route", func(r *Router[CustomData]) {
+			r.GET("/posts/:id", func(ctx *Ctx[CustomData]) {})
+			r.GET("/posts/:id/:slug?", func(ctx *Ctx[CustomData]) {})
+		}},
+		{"optional must be trailing", func(r *Router[CustomData]) {
+			r.GET("/posts/:id?/comments", func(ctx *Ctx[CustomData]) {})
+		}},
+		{"adjacent wildcards", func(r *Router[CustomData]) {
+			r.GET("/a/*x/*y", func(ctx *Ctx[CustomData]) {})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registration to panic")
+				}
+			}()
+			tt.register(NewRouter[CustomData]())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package octo
+
+import "time"
+
+// ShutdownReport summarizes a graceful shutdown by Serve, to tune drain
+// delays and shutdown timeouts
+type ShutdownReport struct {
+	// InFlight is the number of requests running when draining started
+	InFlight int64 `json:"in_flight"`
+	// Completed is the number of requests that finished while draining,
+	// including requests accepted during the drain delay
+	Completed int64 `json:"completed"`
+	// Abandoned is the number of requests still running when the server
+	// stopped, because the shutdown timed out
+	Abandoned int64 `json:"abandoned"`
+	// StreamsClosed is the number of streaming connections closed by force
+	StreamsClosed int `json:"streams_closed"`
+	// DrainTime runs from the start of draining until the server stopped
+	DrainTime time.Duration `json:"drain_time"`
+	// TotalTime also covers stopping jobs and the shutdown hooks
+	TotalTime time.Duration `json:"total_time"`
+	// Errors is the number of shutdown steps that failed
+	Errors int `json:"errors"`
+
+	start time.Time
+}
+
+// requestStarted counts a request toward the shutdown report
+func (r *Router[V]) requestStarted() {
+	r.lifecycle.inFlight.Add(1)
+}
+
+// requestDone counts a finished request toward the shutdown report
+func (r *Router[V]) requestDone() {
+	r.lifecycle.inFlight.Add(-1)
+	if r.lifecycle.draining.Load() {
+		r.lifecycle.drained.Add(1)
+	}
+}
+
+// drainReport reports the drain so far, once the server stopped
+func (r *Router[V]) drainReport() ShutdownReport {
+	r.lifecycle.mu.Lock()
+	start, inFlight := r.lifecycle.drainStart, r.lifecycle.drainInFlight
+	r.lifecycle.mu.Unlock()
+	return ShutdownReport{
+		InFlight:  inFlight,
+		Completed: r.lifecycle.drained.Load(),
+		Abandoned: r.lifecycle.inFlight.Load(),
+		DrainTime: time.Since(start),
+		start:     start,
+	}
+}
+
+func logShutdownReport(report ShutdownReport) {
+	serverLogger := SubsystemLogger(LogServer)
+	if EnableLoggerCheck {
+		if serverLogger != nil {
+			serverLogger.Info().
+				Int64("in_flight", report.InFlight).
+				Int64("completed", report.Completed).
+				Int64("abandoned", report.Abandoned).
+				Int("streams_closed", report.StreamsClosed).
+				Dur("drain_time", report.DrainTime).
+				Dur("total_time", report.TotalTime).
+				Int("errors", report.Errors).
+				Msg("[octo] shutdown report")
+		}
+	} else {
+		serverLogger.Info().
+			Int64("in_flight", report.InFlight).
+			Int64("completed", report.Completed).
+			Int64("abandoned", report.Abandoned).
+			Int("streams_closed", report.StreamsClosed).
+			Dur("drain_time", report.DrainTime).
+			Dur("total_time", report.TotalTime).
+			Int("errors", report.Errors).
+			Msg("[octo] shutdown report")
+	}
+}
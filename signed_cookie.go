@@ -0,0 +1,125 @@
+package octo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNoCookieSecret is returned by signed cookie helpers before SetCookieSecret
+	ErrNoCookieSecret = errors.New("cookie secret not configured")
+	// ErrInvalidSignature is returned when a signed value was tampered with
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+var cookieSecret []byte
+
+// SetCookieSecret sets the HMAC key used by SetSignedCookie and the helpers
+// built on it (flash messages, form state). Use at least 32 random bytes.
+func SetCookieSecret(key []byte) {
+	cookieSecret = key
+}
+
+// signValue returns base64(value).base64(hmac(name|value)). The cookie name is
+// part of the MAC so a value cannot be replayed under another cookie.
+func signValue(name string, value []byte) (string, error) {
+	if len(cookieSecret) == 0 {
+		return "", ErrNoCookieSecret
+	}
+	mac := hmac.New(sha256.New, cookieSecret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'|'})
+	mac.Write(value)
+	return base64.RawURLEncoding.EncodeToString(value) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func verifyValue(name, signed string) ([]byte, error) {
+	if len(cookieSecret) == 0 {
+		return nil, ErrNoCookieSecret
+	}
+	encoded, sig, ok := strings.Cut(signed, ".")
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	expected, err := signValue(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(expected[len(encoded)+1:]), []byte(sig)) {
+		return nil, ErrInvalidSignature
+	}
+	return value, nil
+}
+
+// SetSignedCookie sets an HttpOnly cookie whose value is signed with the cookie
+// secret. The value is readable by the client but cannot be modified.
+func (c *Ctx[V]) SetSignedCookie(name, value string, maxAge int) error {
+	signed, err := signValue(name, []byte(value))
+	if err != nil {
+		return err
+	}
+	c.replaceCookie(&http.Cookie{
+		Name:     name,
+		Value:    signed,
+		MaxAge:   maxAge,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// SignedCookie returns the verified value of a cookie set with SetSignedCookie
+func (c *Ctx[V]) SignedCookie(name string) (string, error) {
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := verifyValue(name, raw)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// ClearSignedCookie expires a cookie set with SetSignedCookie, with the same
+// attributes so the client matches and drops it
+func (c *Ctx[V]) ClearSignedCookie(name string) {
+	c.replaceCookie(&http.Cookie{
+		Name:     name,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// replaceCookie sets cookie, dropping any Set-Cookie previously added for the
+// same name during this request.
+func (c *Ctx[V]) replaceCookie(cookie *http.Cookie) {
+	header := c.ResponseWriter.Header()
+	existing := header.Values("Set-Cookie")
+	if len(existing) > 0 {
+		prefix := cookie.Name + "="
+		kept := existing[:0:0]
+		for _, v := range existing {
+			if !strings.HasPrefix(v, prefix) {
+				kept = append(kept, v)
+			}
+		}
+		header["Set-Cookie"] = kept
+	}
+	http.SetCookie(c.ResponseWriter, cookie)
+}
@@ -0,0 +1,55 @@
+package octo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	SetURLSecret([]byte("url-secret"))
+	defer SetURLSecret(nil)
+
+	router := NewRouter[CustomData]()
+	router.GET("/download/:file", func(ctx *Ctx[CustomData]) {
+		ctx.SendString(http.StatusOK, ctx.Param("file")+":"+ctx.QueryValue("user"))
+	}, SignedURLMiddleware[CustomData]())
+
+	serve := func(target string) (*httptest.ResponseRecorder, BaseResult) {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result BaseResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w, result
+	}
+
+	link, err := SignURL("/download/report.pdf", url.Values{"user": {"42"}}, time.Hour)
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+	if w, _ := serve(link); w.Code != http.StatusOK || w.Body.String() != "report.pdf:42" {
+		t.Errorf("Expected valid link to pass, got %d '%s'", w.Code, w.Body.String())
+	}
+
+	if w, result := serve(strings.Replace(link, "user=42", "user=43", 1)); w.Code != http.StatusForbidden || result.Token != "err_invalid_signature" {
+		t.Errorf("Expected tampered link to be rejected, got %d %s", w.Code, result.Token)
+	}
+	if w, _ := serve("/download/report.pdf"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected unsigned link to be rejected, got %d", w.Code)
+	}
+
+	expired, _ := SignURL("/download/report.pdf", nil, -1)
+	expiredURL, _ := url.Parse(expired)
+	values := expiredURL.Query()
+	values.Set(SignedURLExpiresParam, "1000")
+	values.Del(SignedURLSignatureParam)
+	values.Set(SignedURLSignatureParam, urlSignature("/download/report.pdf", values.Encode()))
+	if w, result := serve("/download/report.pdf?" + values.Encode()); w.Code != http.StatusForbidden || result.Token != "err_link_expired" {
+		t.Errorf("Expected expired link to be rejected, got %d %s", w.Code, result.Token)
+	}
+}
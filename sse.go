@@ -0,0 +1,307 @@
+package octo
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LastEventIDHeader is sent by EventSource clients when they reconnect
+const LastEventIDHeader = "Last-Event-ID"
+
+// ErrStreamClosed is returned when writing to a closed SSE stream
+var ErrStreamClosed = errors.New("stream closed")
+
+// SSEEvent is a server-sent event
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry asks the client to wait that long before reconnecting
+	Retry time.Duration
+}
+
+// SSEStream writes server-sent events to one client. It is safe for
+// concurrent use and implements HubSender.
+type SSEStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	conn    *StreamConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// SSE starts a text/event-stream response, tracked in DefaultStreams with
+// heartbeat comments and the idle timeout of the route's StreamOptions. The
+// stream ends when the handler returns or when Done is closed: the client
+// went away, the stream was idle or the server is shutting down.
+func (c *Ctx[V]) SSE() (*SSEStream, error) {
+	flusher, ok := c.ResponseWriter.ResponseWriter.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming not supported by the response writer")
+	}
+	// Streams outlive the server write timeout and must not be buffered for
+	// body capture
+	http.NewResponseController(c.ResponseWriter.ResponseWriter).SetWriteDeadline(time.Time{})
+	c.ResponseWriter.CaptureBody = false
+	h := c.ResponseWriter.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	c.ResponseWriter.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	c.Done()
+	s := &SSEStream{w: c.ResponseWriter, flusher: flusher}
+	s.conn = c.TrackStream("sse", func() error { return s.write(": heartbeat\n\n", false) }, s.stop)
+	return s, nil
+}
+
+// Done is closed when the stream ends
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.conn.Done()
+}
+
+// Conn returns the tracked connection of the stream
+func (s *SSEStream) Conn() *StreamConn {
+	return s.conn
+}
+
+// write sends raw event text and flushes it; events count as activity,
+// heartbeats do not
+func (s *SSEStream) write(text string, activity bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if _, err := s.w.Write([]byte(text)); err != nil {
+		s.closed = true
+		return err
+	}
+	s.flusher.Flush()
+	if activity {
+		s.conn.Touch()
+	}
+	return nil
+}
+
+// SendEvent writes ev
+func (s *SSEStream) SendEvent(ev SSEEvent) error {
+	return s.write(formatSSE(ev), true)
+}
+
+// Send writes data as an unnamed event, for use as a HubSender
+func (s *SSEStream) Send(data []byte) error {
+	return s.SendEvent(SSEEvent{Data: string(data)})
+}
+
+// Comment writes a comment line, ignored by clients
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": "+strings.ReplaceAll(text, "\n", " ")+"\n\n", true)
+}
+
+// Close ends the stream; the response ends when the handler returns
+func (s *SSEStream) Close() error {
+	s.conn.Close()
+	return nil
+}
+
+// stop refuses further writes, once the connection is closed
+func (s *SSEStream) stop() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+func formatSSE(ev SSEEvent) string {
+	var b strings.Builder
+	if ev.ID != "" {
+		b.WriteString("id: " + stripNewlines(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		b.WriteString("event: " + stripNewlines(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(ev.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSEBrokerConfig configures an SSEBroker
+type SSEBrokerConfig struct {
+	// BufferSize is the number of events kept per topic for replay (100 when
+	// zero)
+	BufferSize int
+	// TTL stops replaying events older than this (no limit when zero)
+	TTL time.Duration
+	// QueueSize is the number of events buffered per subscriber (64 when
+	// zero). A subscriber falling further behind is disconnected; it resumes
+	// from its Last-Event-ID when it reconnects.
+	QueueSize int
+}
+
+type sseRecord struct {
+	seq uint64
+	at  time.Time
+	ev  SSEEvent
+}
+
+type sseTopic struct {
+	seq  uint64
+	ring []sseRecord // oldest first
+	subs map[chan SSEEvent]struct{}
+}
+
+// SSEBroker publishes events to topics and replays the ones a reconnecting
+// client missed, using the Last-Event-ID header. Event IDs are assigned by
+// the broker and increase per topic.
+type SSEBroker struct {
+	cfg SSEBrokerConfig
+
+	mu     sync.Mutex
+	topics map[string]*sseTopic
+}
+
+// NewSSEBroker creates an SSEBroker
+func NewSSEBroker(cfg SSEBrokerConfig) *SSEBroker {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 64
+	}
+	return &SSEBroker{cfg: cfg, topics: make(map[string]*sseTopic)}
+}
+
+func (b *SSEBroker) topic(name string) *sseTopic {
+	t := b.topics[name]
+	if t == nil {
+		t = &sseTopic{subs: make(map[chan SSEEvent]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Publish assigns ev the next ID of topic, buffers it and sends it to the
+// subscribers. It returns the event as sent.
+func (b *SSEBroker) Publish(topic string, ev SSEEvent) SSEEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topic)
+	t.seq++
+	ev.ID = strconv.FormatUint(t.seq, 10)
+	if len(t.ring) == b.cfg.BufferSize {
+		copy(t.ring, t.ring[1:])
+		t.ring = t.ring[:len(t.ring)-1]
+	}
+	t.ring = append(t.ring, sseRecord{seq: t.seq, at: time.Now(), ev: ev})
+	for ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+			// Too slow: drop the subscriber, which resumes on reconnect
+			delete(t.subs, ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// subscribe registers a subscriber and returns the buffered events after
+// lastID, atomically so no event is missed or sent twice
+func (b *SSEBroker) subscribe(topic, lastID string) (chan SSEEvent, []SSEEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topic)
+	ch := make(chan SSEEvent, b.cfg.QueueSize)
+	t.subs[ch] = struct{}{}
+	if lastID == "" {
+		return ch, nil
+	}
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return ch, nil
+	}
+	var replay []SSEEvent
+	for _, r := range t.ring {
+		if r.seq > last && (b.cfg.TTL <= 0 || time.Since(r.at) < b.cfg.TTL) {
+			replay = append(replay, r.ev)
+		}
+	}
+	return ch, replay
+}
+
+func (b *SSEBroker) unsubscribe(topic string, ch chan SSEEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topics[topic]
+	if t == nil {
+		return
+	}
+	if _, ok := t.subs[ch]; ok {
+		delete(t.subs, ch)
+		close(ch)
+	}
+}
+
+// Serve streams topic to the client of stream until it disconnects, first
+// replaying the buffered events after lastID (all of them when lastID is
+// older than the buffer)
+func (b *SSEBroker) Serve(stream *SSEStream, topic, lastID string) error {
+	ch, replay := b.subscribe(topic, lastID)
+	defer b.unsubscribe(topic, ch)
+	for _, ev := range replay {
+		if err := stream.SendEvent(ev); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return ErrStreamClosed
+			}
+			if err := stream.SendEvent(ev); err != nil {
+				return err
+			}
+		case <-stream.Done():
+			return nil
+		}
+	}
+}
+
+// SSEHandler streams a topic of b, resuming from the Last-Event-ID header or
+// the lastEventId query parameter. topic picks the topic of the request; nil
+// streams the "" topic.
+func SSEHandler[V any](b *SSEBroker, topic func(ctx *Ctx[V]) string) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		name := ""
+		if topic != nil {
+			name = topic(ctx)
+		}
+		lastID := ctx.GetHeader(LastEventIDHeader)
+		if lastID == "" {
+			lastID = ctx.QueryValue("lastEventId")
+		}
+		stream, err := ctx.SSE()
+		if err != nil {
+			ctx.SendError("err_internal_error", err)
+			return
+		}
+		defer stream.Close()
+		b.Serve(stream, name, lastID)
+	}
+}
@@ -0,0 +1,104 @@
+package octo
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSE reads n events from body as "id:data" strings
+func readSSE(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+	var events []string
+	var id, data string
+	for len(events) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Reading stream: %v (got %v)", err, events)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = line[4:]
+		case strings.HasPrefix(line, "data: "):
+			data = line[6:]
+		case line == "":
+			if data != "" {
+				events = append(events, id+":"+data)
+			}
+			id, data = "", ""
+		}
+	}
+	return events
+}
+
+func TestSSEReplay(t *testing.T) {
+	broker := NewSSEBroker(SSEBrokerConfig{BufferSize: 3})
+	router := NewRouter[CustomData]()
+	router.GET("/events/:topic", SSEHandler[CustomData](broker, func(ctx *Ctx[CustomData]) string {
+		return ctx.Param("topic")
+	}))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	for _, d := range []string{"a", "b", "c", "d"} {
+		broker.Publish("news", SSEEvent{Data: d})
+	}
+	broker.Publish("other", SSEEvent{Data: "x"})
+
+	req, _ := http.NewRequest("GET", srv.URL+"/events/news", nil)
+	req.Header.Set(LastEventIDHeader, "2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	if got := readSSE(t, r, 2); strings.Join(got, ",") != "3:c,4:d" {
+		t.Errorf("Expected the missed events, got %v", got)
+	}
+
+	// Live events follow the replay
+	time.Sleep(20 * time.Millisecond)
+	broker.Publish("news", SSEEvent{Data: "e"})
+	if got := readSSE(t, r, 1); got[0] != "5:e" {
+		t.Errorf("Expected the live event, got %v", got)
+	}
+
+	// A stale ID replays the whole buffer
+	req, _ = http.NewRequest("GET", srv.URL+"/events/news?lastEventId=0", nil)
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	if got := readSSE(t, bufio.NewReader(resp2.Body), 3); strings.Join(got, ",") != "3:c,4:d,5:e" {
+		t.Errorf("Expected the buffered events, got %v", got)
+	}
+}
+
+func TestSSEReplayTTL(t *testing.T) {
+	broker := NewSSEBroker(SSEBrokerConfig{TTL: 20 * time.Millisecond})
+	broker.Publish("t", SSEEvent{Data: "old"})
+	time.Sleep(30 * time.Millisecond)
+	broker.Publish("t", SSEEvent{Data: "new"})
+	ch, replay := broker.subscribe("t", "0")
+	defer broker.unsubscribe("t", ch)
+	if len(replay) != 1 || replay[0].Data != "new" {
+		t.Errorf("Expected expired events to be skipped, got %v", replay)
+	}
+}
+
+func TestFormatSSE(t *testing.T) {
+	got := formatSSE(SSEEvent{ID: "7", Event: "update", Data: "line1\nline2", Retry: 3 * time.Second})
+	want := "id: 7\nevent: update\nretry: 3000\ndata: line1\ndata: line2\n\n"
+	if got != want {
+		t.Errorf("formatSSE = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,543 @@
+package octo
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StaticConfig configures StaticWithConfig
+type StaticConfig[V any] struct {
+	// Name labels the mount in StaticStats (the prefix when empty)
+	Name string
+	// CacheMaxBytes bounds the in-memory cache of file contents, evicting the
+	// least recently used files; no cache when zero
+	CacheMaxBytes int64
+	// CacheMaxFileSize is the size above which files are always read from
+	// fsys (1MB when zero)
+	CacheMaxFileSize int64
+	// MaxAge is sent as Cache-Control max-age; no Cache-Control when zero
+	MaxAge time.Duration
+	// DevMode serves files as they are on disk: no in-memory cache, no
+	// Last-Modified and Cache-Control: no-store
+	DevMode bool
+	// LiveReload, in DevMode, injects a script into served HTML pages that
+	// reloads them when a file of the mount changes
+	LiveReload bool
+	// Authorize decides whether the request may read filePath, the cleaned
+	// path below the prefix ("." for the root), before the file is looked up;
+	// denied requests answer 403
+	Authorize func(ctx *Ctx[V], filePath string) bool
+	// SignedURLs serves requests whose URL was produced by SignURL without
+	// calling Authorize; expired links answer 403 err_link_expired. Without
+	// Authorize, every request must be signed.
+	SignedURLs bool
+	// Images transforms image files requested with a spec in the query, e.g.
+	// "?vars=format=png:scale_crop_center=380x190" (see ImageSpec). Results
+	// are cached like files, so set CacheMaxBytes.
+	Images *ImageConfig
+}
+
+// liveReloadPath is the event stream of LiveReload, under the mount prefix
+const liveReloadPath = "__octo/livereload"
+
+// liveReloadInterval is how often LiveReload checks the files for changes
+var liveReloadInterval = time.Second
+
+// StaticCacheStats counts the cache activity of a Static mount
+type StaticCacheStats struct {
+	Mount     string  `json:"mount"`
+	Entries   int     `json:"entries"`
+	Bytes     int64   `json:"bytes"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+	Evictions int64   `json:"evictions"`
+}
+
+// Static serves the files of fsys under prefix (GET and HEAD), e.g.
+// router.Static("/assets", os.DirFS("public")). Directory listings are not
+// served; index.html is. Unknown or invalid paths answer 404.
+func (r *Router[V]) Static(prefix string, fsys fs.FS, middleware ...MiddlewareFunc[V]) *Route[V] {
+	return r.StaticWithConfig(prefix, fsys, StaticConfig[V]{}, middleware...)
+}
+
+// StaticWithConfig is Static with an in-memory cache, caching headers and
+// access control. Access-controlled files are sent with Cache-Control:
+// private, and still served from the cache.
+func (r *Router[V]) StaticWithConfig(prefix string, fsys fs.FS, cfg StaticConfig[V], middleware ...MiddlewareFunc[V]) *Route[V] {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if cfg.Name == "" {
+		cfg.Name = prefix
+		if cfg.Name == "" {
+			cfg.Name = "/"
+		}
+	}
+	if cfg.CacheMaxFileSize <= 0 {
+		cfg.CacheMaxFileSize = 1 << 20
+	}
+	if cfg.DevMode {
+		cfg.CacheMaxBytes = 0
+		cfg.MaxAge = 0
+	}
+	if cfg.Images != nil {
+		images := *cfg.Images
+		if images.Param == "" {
+			images.Param = "vars"
+		}
+		if images.Processor == nil {
+			images.Processor = StdImageProcessor{}
+		}
+		if images.MaxWidth <= 0 {
+			images.MaxWidth = 4096
+		}
+		if images.MaxHeight <= 0 {
+			images.MaxHeight = 4096
+		}
+		cfg.Images = &images
+	}
+	mount := newStaticMount(prefix, fsys, cfg)
+	r.staticMounts = append(r.staticMounts, mount)
+	handler := staticHandler(mount)
+	route := &Route[V]{Method: "GET", Path: prefix + "/*filepath", router: r}
+	for _, method := range []string{"GET", "HEAD"} {
+		route.entries = append(route.entries, r.newRoute(method, prefix+"/*filepath", handler, middleware...).entries...)
+	}
+	r.listRoute(route)
+	return route
+}
+
+// StaticStats returns the cache counters of every Static mount of the router
+func (r *Router[V]) StaticStats() []StaticCacheStats {
+	stats := make([]StaticCacheStats, len(r.staticMounts))
+	for i, m := range r.staticMounts {
+		stats[i] = m.stats()
+	}
+	return stats
+}
+
+// FlushStaticCache empties the caches of every Static mount of the router and
+// returns the number of bytes released
+func (r *Router[V]) FlushStaticCache() int64 {
+	var freed int64
+	for _, m := range r.staticMounts {
+		m.mu.Lock()
+		freed += m.bytes
+		m.entries = make(map[string]*staticEntry)
+		m.lru.Init()
+		m.bytes = 0
+		m.mu.Unlock()
+	}
+	return freed
+}
+
+// staticEntry is the cached content of a file, or of a transformation of it
+type staticEntry struct {
+	key         string
+	data        []byte
+	contentType string
+	// modTime and size identify the version of the source file
+	modTime time.Time
+	size    int64
+	elem    *list.Element
+}
+
+// staticMount is the file system and cache of a Static route
+type staticMount[V any] struct {
+	prefix string
+	fsys   fs.FS
+	cfg    StaticConfig[V]
+
+	mu      sync.Mutex
+	entries map[string]*staticEntry
+	lru     list.List // front is most recently used
+	bytes   int64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+func newStaticMount[V any](prefix string, fsys fs.FS, cfg StaticConfig[V]) *staticMount[V] {
+	return &staticMount[V]{prefix: prefix, fsys: fsys, cfg: cfg, entries: make(map[string]*staticEntry)}
+}
+
+// resolve maps a request path to a file name of the mount, serving index.html
+// for directories
+func (m *staticMount[V]) resolve(p string) (string, fs.FileInfo, bool) {
+	name := strings.Trim(p, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return "", nil, false
+	}
+	info, err := fs.Stat(m.fsys, name)
+	if err == nil && info.IsDir() {
+		name = strings.TrimPrefix(name+"/index.html", "./")
+		info, err = fs.Stat(m.fsys, name)
+	}
+	if err != nil || info.IsDir() {
+		return "", nil, false
+	}
+	return name, info, true
+}
+
+// cached returns the entry cached under key if it was derived from the
+// current version of the file, or nil. Entries are never modified once
+// cached.
+func (m *staticMount[V]) cached(key string, info fs.FileInfo) *staticEntry {
+	if m.cfg.CacheMaxBytes <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entries[key]
+	if e == nil || !e.modTime.Equal(info.ModTime()) || e.size != info.Size() {
+		if e != nil {
+			m.removeLocked(e)
+		}
+		return nil
+	}
+	m.lru.MoveToFront(e.elem)
+	return e
+}
+
+// load reads name and caches it if it fits
+func (m *staticMount[V]) load(name string, info fs.FileInfo) ([]byte, bool) {
+	if m.cfg.CacheMaxBytes <= 0 || info.Size() > m.cfg.CacheMaxFileSize || info.Size() > m.cfg.CacheMaxBytes {
+		return nil, false
+	}
+	data, err := fs.ReadFile(m.fsys, name)
+	if err != nil || int64(len(data)) != info.Size() {
+		return nil, false
+	}
+	m.store(name, info, data, "")
+	return data, true
+}
+
+// store caches data under key if it fits, evicting the least recently used
+// entries
+func (m *staticMount[V]) store(key string, info fs.FileInfo, data []byte, contentType string) {
+	size := int64(len(data))
+	if m.cfg.CacheMaxBytes <= 0 || size > m.cfg.CacheMaxFileSize || size > m.cfg.CacheMaxBytes {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old := m.entries[key]; old != nil {
+		m.removeLocked(old)
+	}
+	e := &staticEntry{key: key, data: data, contentType: contentType, modTime: info.ModTime(), size: info.Size()}
+	e.elem = m.lru.PushFront(e)
+	m.entries[key] = e
+	m.bytes += size
+	for m.bytes > m.cfg.CacheMaxBytes {
+		oldest := m.lru.Back().Value.(*staticEntry)
+		m.removeLocked(oldest)
+		m.evictions.Add(1)
+	}
+}
+
+func (m *staticMount[V]) removeLocked(e *staticEntry) {
+	m.lru.Remove(e.elem)
+	delete(m.entries, e.key)
+	m.bytes -= int64(len(e.data))
+}
+
+func (m *staticMount[V]) stats() StaticCacheStats {
+	m.mu.Lock()
+	s := StaticCacheStats{
+		Mount:   m.cfg.Name,
+		Entries: len(m.entries),
+		Bytes:   m.bytes,
+	}
+	m.mu.Unlock()
+	s.Hits = m.hits.Load()
+	s.Misses = m.misses.Load()
+	s.Evictions = m.evictions.Load()
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+// private reports whether the mount is access controlled
+func (m *staticMount[V]) private() bool {
+	return m.cfg.Authorize != nil || m.cfg.SignedURLs
+}
+
+// authorize checks the signature or the Authorize hook of the request,
+// sending the 403 when access is denied
+func (m *staticMount[V]) authorize(ctx *Ctx[V]) bool {
+	if !m.private() {
+		return true
+	}
+	if m.cfg.SignedURLs {
+		err := VerifyURL(ctx.Request.URL)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrURLExpired) {
+			ctx.SendError("err_link_expired", nil)
+			return false
+		}
+		if m.cfg.Authorize == nil || ctx.Request.URL.Query().Has(SignedURLSignatureParam) {
+			ctx.SendError("err_invalid_signature", nil)
+			return false
+		}
+	}
+	name := path.Clean("/" + ctx.Param("filepath"))[1:]
+	if name == "" {
+		name = "."
+	}
+	if !m.cfg.Authorize(ctx, name) {
+		ctx.SendError("err_forbidden", nil)
+		return false
+	}
+	return true
+}
+
+func staticHandler[V any](m *staticMount[V]) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		if !m.authorize(ctx) {
+			return
+		}
+		if m.cfg.DevMode {
+			serveStaticDev(ctx, m)
+			return
+		}
+		name, info, ok := m.resolve(ctx.Param("filepath"))
+		if !ok {
+			ctx.Send404()
+			return
+		}
+		if m.cfg.MaxAge > 0 {
+			visibility := "public"
+			if m.private() {
+				visibility = "private"
+			}
+			ctx.SetHeader("Cache-Control", visibility+", max-age="+strconv.FormatInt(int64(m.cfg.MaxAge/time.Second), 10))
+		} else if m.private() {
+			ctx.SetHeader("Cache-Control", "private")
+		}
+		if vars := m.imageVars(ctx); vars != "" {
+			serveStaticImage(ctx, m, name, info, vars)
+			return
+		}
+		// ServeContent omits Content-Length when the body is transformed
+		// (e.g. compressed); HEAD still advertises the file size
+		if ctx.Request.Method == http.MethodHead && ctx.ResponseWriter.Header().Get("Content-Encoding") == "" {
+			ctx.SetHeader("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+
+		if m.cfg.CacheMaxBytes > 0 {
+			var data []byte
+			e := m.cached(name, info)
+			ok := e != nil
+			if ok {
+				data = e.data
+				m.hits.Add(1)
+			} else {
+				m.misses.Add(1)
+				// HEAD requests do not read files into the cache
+				if ctx.Request.Method != http.MethodHead {
+					data, ok = m.load(name, info)
+				}
+			}
+			if ok {
+				http.ServeContent(ctx.ResponseWriter, ctx.Request, name, info.ModTime(), bytes.NewReader(data))
+				ctx.Done()
+				return
+			}
+		}
+		f, err := m.fsys.Open(name)
+		if err != nil {
+			ctx.Send404()
+			return
+		}
+		defer f.Close()
+		if rs, ok := f.(io.ReadSeeker); ok {
+			http.ServeContent(ctx.ResponseWriter, ctx.Request, name, info.ModTime(), rs)
+		} else {
+			http.ServeFileFS(ctx.ResponseWriter, ctx.Request, m.fsys, name)
+		}
+		ctx.Done()
+	}
+}
+
+// serveStaticDev serves the current content of a file without caching, with
+// the LiveReload script and event stream
+func serveStaticDev[V any](ctx *Ctx[V], m *staticMount[V]) {
+	p := strings.Trim(ctx.Param("filepath"), "/")
+	if m.cfg.LiveReload && p == liveReloadPath {
+		serveLiveReload(ctx, m)
+		return
+	}
+	name, info, ok := m.resolve(p)
+	if !ok {
+		ctx.Send404()
+		return
+	}
+	ctx.SetHeader("Cache-Control", "no-store")
+	if vars := m.imageVars(ctx); vars != "" {
+		serveStaticImage(ctx, m, name, info, vars)
+		return
+	}
+	if m.cfg.LiveReload && path.Ext(name) == ".html" {
+		data, err := fs.ReadFile(m.fsys, name)
+		if err != nil {
+			ctx.Send404()
+			return
+		}
+		ctx.SetHeader("Content-Type", "text/html; charset=utf-8")
+		data = injectLiveReload(data, m.prefix+"/"+liveReloadPath)
+		http.ServeContent(ctx.ResponseWriter, ctx.Request, name, time.Time{}, bytes.NewReader(data))
+		ctx.Done()
+		return
+	}
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		ctx.Send404()
+		return
+	}
+	defer f.Close()
+	if ctx.Request.Method == http.MethodHead {
+		ctx.SetHeader("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	// A zero modification time leaves out Last-Modified and conditional
+	// requests, so browsers always refetch
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.ResponseWriter, ctx.Request, name, time.Time{}, rs)
+	} else {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			ctx.Send404()
+			return
+		}
+		http.ServeContent(ctx.ResponseWriter, ctx.Request, name, time.Time{}, bytes.NewReader(data))
+	}
+	ctx.Done()
+}
+
+// imageVars returns the image transformation requested, if enabled
+func (m *staticMount[V]) imageVars(ctx *Ctx[V]) string {
+	if m.cfg.Images == nil {
+		return ""
+	}
+	return ctx.Request.URL.Query().Get(m.cfg.Images.Param)
+}
+
+// serveStaticImage serves the transformation of an image file, through the
+// cache of the mount
+func serveStaticImage[V any](ctx *Ctx[V], m *staticMount[V], name string, info fs.FileInfo, vars string) {
+	images := m.cfg.Images
+	spec, err := ParseImageSpec(vars)
+	if err == nil && (spec.Width > images.MaxWidth || spec.Height > images.MaxHeight) {
+		err = fmt.Errorf("%w: larger than %dx%d", ErrInvalidImageSpec, images.MaxWidth, images.MaxHeight)
+	}
+	if err != nil {
+		ctx.SendError("err_invalid_query", err)
+		return
+	}
+	key := name + "?" + spec.String()
+	var data []byte
+	var contentType string
+	if e := m.cached(key, info); e != nil {
+		m.hits.Add(1)
+		data, contentType = e.data, e.contentType
+	} else {
+		if m.cfg.CacheMaxBytes > 0 {
+			m.misses.Add(1)
+		}
+		src, err := fs.ReadFile(m.fsys, name)
+		if err != nil {
+			ctx.Send404()
+			return
+		}
+		data, contentType, err = images.Processor.Process(src, spec)
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedImageFormat) {
+				ctx.SendError("err_unsupported_media_type", err)
+			} else {
+				ctx.SendError("err_internal_error", err)
+			}
+			return
+		}
+		m.store(key, info, data, contentType)
+	}
+	ctx.SetHeader("Content-Type", contentType)
+	modTime := info.ModTime()
+	if m.cfg.DevMode {
+		modTime = time.Time{}
+	}
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, name, modTime, bytes.NewReader(data))
+	ctx.Done()
+}
+
+// injectLiveReload inserts the LiveReload script before the closing body tag
+// of page, or at its end
+func injectLiveReload(page []byte, stream string) []byte {
+	script := `<script>new EventSource(` + strconv.Quote(stream) + `).addEventListener("reload",function(){location.reload()})</script>`
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		i = len(page)
+	}
+	out := make([]byte, 0, len(page)+len(script))
+	out = append(out, page[:i]...)
+	out = append(out, script...)
+	return append(out, page[i:]...)
+}
+
+// serveLiveReload streams a reload event once a file of the mount changes
+func serveLiveReload[V any](ctx *Ctx[V], m *staticMount[V]) {
+	stream, err := ctx.SSE()
+	if err != nil {
+		ctx.SendError("err_internal_error", err)
+		return
+	}
+	defer stream.Close()
+	last := m.fingerprint()
+	ticker := time.NewTicker(liveReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case <-ticker.C:
+			if current := m.fingerprint(); current != last {
+				stream.SendEvent(SSEEvent{Event: "reload", Data: "changed"})
+				return
+			}
+		}
+	}
+}
+
+// fingerprint hashes the names, sizes and modification times of the files of
+// the mount
+func (m *staticMount[V]) fingerprint() uint64 {
+	h := fnv.New64a()
+	fs.WalkDir(m.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64()
+}
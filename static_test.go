@@ -0,0 +1,225 @@
+package octo
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticCacheStats(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":      {Data: []byte("aaaaaaaaaa"), ModTime: time.Unix(1000, 0)},
+		"b.txt":      {Data: []byte("bbbbbbbbbb"), ModTime: time.Unix(1000, 0)},
+		"index.html": {Data: []byte("<h1>octo</h1>"), ModTime: time.Unix(1000, 0)},
+	}
+	router := NewRouter[CustomData]()
+	router.StaticWithConfig("/assets", fsys, StaticConfig[CustomData]{CacheMaxBytes: 15, MaxAge: time.Hour})
+	router.Static("/plain", fsys)
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("GET", "/assets/a.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "aaaaaaaaaa" {
+		t.Fatalf("Unexpected response: %d '%s'", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("Unexpected Cache-Control: %q", w.Header().Get("Cache-Control"))
+	}
+	get("GET", "/assets/a.txt")
+	get("GET", "/assets/a.txt")
+
+	stats := router.StaticStats()
+	if len(stats) != 2 || stats[0].Mount != "/assets" || stats[1].Mount != "/plain" {
+		t.Fatalf("Unexpected mounts: %+v", stats)
+	}
+	s := stats[0]
+	if s.Entries != 1 || s.Bytes != 10 || s.Hits != 2 || s.Misses != 1 || s.HitRate < 0.66 || s.HitRate > 0.67 {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+
+	// b.txt does not fit alongside a.txt
+	get("GET", "/assets/b.txt")
+	s = router.StaticStats()[0]
+	if s.Entries != 1 || s.Bytes != 10 || s.Evictions != 1 {
+		t.Errorf("Expected a.txt to be evicted: %+v", s)
+	}
+
+	// A changed file is not served from the cache
+	fsys["b.txt"] = &fstest.MapFile{Data: []byte("BBBBB"), ModTime: time.Unix(2000, 0)}
+	w = get("GET", "/assets/b.txt")
+	if w.Body.String() != "BBBBB" {
+		t.Errorf("Expected the new content, got '%s'", w.Body.String())
+	}
+	if s = router.StaticStats()[0]; s.Bytes != 5 {
+		t.Errorf("Expected the entry to be replaced: %+v", s)
+	}
+
+	// HEAD advertises the size without reading the file into the cache
+	w = get("HEAD", "/assets/index.html")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "13" || w.Body.Len() != 0 {
+		t.Errorf("Unexpected HEAD response: %d %q '%s'", w.Code, w.Header().Get("Content-Length"), w.Body.String())
+	}
+	if s = router.StaticStats()[0]; s.Entries != 1 {
+		t.Errorf("Expected HEAD not to populate the cache: %+v", s)
+	}
+	w = get("HEAD", "/plain/a.txt")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "10" {
+		t.Errorf("Unexpected uncached HEAD response: %d %q", w.Code, w.Header().Get("Content-Length"))
+	}
+
+	if w = get("GET", "/assets/missing.txt"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+	if s = router.StaticStats()[1]; s.Hits != 0 || s.Misses != 0 {
+		t.Errorf("Expected no cache activity without a cache: %+v", s)
+	}
+}
+
+func TestStaticDevMode(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(page, []byte("<html><BODY><h1>octo</h1></BODY></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('octo')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := liveReloadInterval
+	liveReloadInterval = 10 * time.Millisecond
+	defer func() { liveReloadInterval = old }()
+
+	router := NewRouter[CustomData]()
+	router.StaticWithConfig("/dev", os.DirFS(dir), StaticConfig[CustomData]{CacheMaxBytes: 1 << 20, MaxAge: time.Hour, DevMode: true, LiveReload: true})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/dev/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Cache-Control") != "no-store" || resp.Header.Get("Last-Modified") != "" {
+		t.Errorf("Unexpected dev response: %d %v", resp.StatusCode, resp.Header)
+	}
+	if s := router.StaticStats()[0]; s.Entries != 0 || s.Hits+s.Misses != 0 {
+		t.Errorf("Expected the cache to be disabled: %+v", s)
+	}
+
+	req := httptest.NewRequest("GET", "/dev/index.html", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "<html><BODY><h1>octo</h1><script>new EventSource(\"/dev/__octo/livereload\")") || !strings.HasSuffix(body, "</script></BODY></html>") {
+		t.Errorf("Expected the live-reload script before </body>, got '%s'", body)
+	}
+	if w.Header().Get("Content-Length") != strconv.Itoa(len(body)) {
+		t.Errorf("Unexpected Content-Length %q for %d bytes", w.Header().Get("Content-Length"), len(body))
+	}
+
+	resp, err = http.Get(srv.URL + "/dev/__octo/livereload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('reloaded')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected a reload event: %v", err)
+		}
+		if line == "event: reload\n" {
+			break
+		}
+	}
+}
+
+func TestStaticAccessControl(t *testing.T) {
+	SetURLSecret([]byte("url-secret"))
+	defer SetURLSecret(nil)
+
+	fsys := fstest.MapFS{
+		"alice/photo.png": {Data: []byte("alice's photo")},
+		"bob/photo.png":   {Data: []byte("bob's photo")},
+	}
+	router := NewRouter[CustomData]()
+	var authorized []string
+	router.StaticWithConfig("/uploads", fsys, StaticConfig[CustomData]{
+		CacheMaxBytes: 1 << 20,
+		MaxAge:        time.Minute,
+		SignedURLs:    true,
+		Authorize: func(ctx *Ctx[CustomData], filePath string) bool {
+			authorized = append(authorized, filePath)
+			return strings.HasPrefix(filePath, ctx.GetHeader("X-User")+"/")
+		},
+	})
+	router.StaticWithConfig("/signed", fsys, StaticConfig[CustomData]{SignedURLs: true})
+
+	serve := func(target, user string) (*httptest.ResponseRecorder, BaseResult) {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result BaseResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w, result
+	}
+
+	w, _ := serve("/uploads/alice/photo.png", "alice")
+	if w.Code != http.StatusOK || w.Body.String() != "alice's photo" {
+		t.Errorf("Expected the owner to be served, got %d '%s'", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Errorf("Expected private caching, got %q", w.Header().Get("Cache-Control"))
+	}
+	if w, result := serve("/uploads/bob/photo.png", "alice"); w.Code != http.StatusForbidden || result.Token != "err_forbidden" {
+		t.Errorf("Expected 403 err_forbidden, got %d %s", w.Code, result.Token)
+	}
+	if w, _ := serve("/uploads/alice//../alice/photo.png", "alice"); w.Code != http.StatusOK {
+		t.Errorf("Expected the cleaned path to be authorized, got %d", w.Code)
+	}
+	if strings.Join(authorized, ",") != "alice/photo.png,bob/photo.png,alice/photo.png" {
+		t.Errorf("Unexpected authorized paths: %v", authorized)
+	}
+
+	// A signed link bypasses Authorize
+	authorized = nil
+	link, err := SignURL("/uploads/bob/photo.png", nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := serve(link, "alice"); w.Code != http.StatusOK || w.Body.String() != "bob's photo" || len(authorized) != 0 {
+		t.Errorf("Expected the signed link to be served, got %d '%s' %v", w.Code, w.Body.String(), authorized)
+	}
+	if w, result := serve(strings.Replace(link, "bob", "alice", 1), "bob"); w.Code != http.StatusForbidden || result.Token != "err_invalid_signature" {
+		t.Errorf("Expected a tampered link to be rejected, got %d %s", w.Code, result.Token)
+	}
+	expired := "/signed/bob/photo.png?" + url.Values{SignedURLExpiresParam: {"1"}}.Encode()
+	expired += "&" + SignedURLSignatureParam + "=" + urlSignature("/signed/bob/photo.png", url.Values{SignedURLExpiresParam: {"1"}}.Encode())
+	if w, result := serve(expired, "bob"); w.Code != http.StatusForbidden || result.Token != "err_link_expired" {
+		t.Errorf("Expected an expired link to be rejected, got %d %s", w.Code, result.Token)
+	}
+	if w, result := serve("/signed/bob/photo.png", "bob"); w.Code != http.StatusForbidden || result.Token != "err_invalid_signature" {
+		t.Errorf("Expected an unsigned request to be rejected, got %d %s", w.Code, result.Token)
+	}
+	if s := router.StaticStats()[0]; s.Hits == 0 {
+		t.Errorf("Expected access-controlled files to be cached: %+v", s)
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+// Disk stores objects as files below a directory. Content types are not
+// recorded: Get derives them from the key extension.
+type Disk struct {
+	dir     string
+	baseURL string
+}
+
+// NewDisk stores objects below dir, creating it if needed. baseURL is the
+// path the directory is served under by octo's Static, e.g. "/uploads"; it
+// is only needed by SignedURL.
+func NewDisk(dir, baseURL string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Disk{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (d *Disk) path(key string) string {
+	return filepath.Join(d.dir, filepath.FromSlash(key))
+}
+
+// Put writes the object to a temporary file renamed into place once
+// complete, so readers never see a partial object
+func (d *Disk) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (Object, error) {
+	if err := checkKey(key); err != nil {
+		return Object{}, err
+	}
+	dst := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return Object{}, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(f.Name())
+	n, err := io.Copy(f, contextReader{ctx: ctx, r: r})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	if opts.Size >= 0 && n != opts.Size {
+		return Object{}, fmt.Errorf("storage: wrote %d of %d bytes", n, opts.Size)
+	}
+	if err := os.Rename(f.Name(), dst); err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		return Object{}, err
+	}
+	obj := diskObject(key, info)
+	if opts.ContentType != "" {
+		obj.ContentType = opts.ContentType
+	}
+	return obj, nil
+}
+
+func (d *Disk) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	if err := checkKey(key); err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		if err == nil {
+			err = ErrNotFound
+		}
+		return nil, Object{}, err
+	}
+	return f, diskObject(key, info), nil
+}
+
+func (d *Disk) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// SignedURL returns an octo signed URL (see octo.SignURL) below the base URL,
+// for a directory served with octo.StaticConfig.SignedURLs. Only GET and HEAD
+// can be signed.
+func (d *Disk) SignedURL(ctx context.Context, key, method string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if d.baseURL == "" || method != "GET" && method != "HEAD" {
+		return "", ErrNotSupported
+	}
+	// The signature covers the decoded path, as seen by octo.VerifyURL
+	p := d.baseURL + "/" + key
+	link, err := octo.SignURL(p, nil, ttl)
+	if err != nil {
+		return "", err
+	}
+	return d.baseURL + "/" + escapeKey(key) + link[len(p):], nil
+}
+
+func diskObject(key string, info fs.FileInfo) Object {
+	return Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+	}
+}
+
+// contextReader stops a copy once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3 store
+type S3Config struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+	// Endpoint is the scheme and host of the service
+	// ("https://s3.<region>.amazonaws.com" when empty), for S3-compatible
+	// services such as MinIO or R2
+	Endpoint string
+	// PathStyle addresses the bucket in the path instead of the host name
+	PathStyle bool
+	// PartSize is the size of the parts of uploads of unknown size, buffered
+	// in memory one at a time (8MB when zero, 5MB at least)
+	PartSize int64
+	// HTTPClient sends the requests (http.DefaultClient when nil)
+	HTTPClient *http.Client
+}
+
+// S3 is a Store on Amazon S3 or an S3-compatible service. Requests are signed
+// with AWS Signature Version 4.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// S3Error is an error response of the service
+type S3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("storage: s3 %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// NewS3 returns an S3 store
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" || cfg.Region == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("storage: S3 bucket, region and credentials are required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = 8 << 20
+	}
+	cfg.PartSize = max(cfg.PartSize, 5<<20)
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// GCSConfig configures a Google Cloud Storage store. The credentials are an
+// HMAC key of a service account.
+type GCSConfig struct {
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Endpoint is "https://storage.googleapis.com" when empty
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewGCS returns a store on Google Cloud Storage, through its S3-compatible
+// XML API
+func NewGCS(cfg GCSConfig) (*S3, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://storage.googleapis.com"
+	}
+	return NewS3(S3Config{
+		Bucket:          cfg.Bucket,
+		Region:          "auto",
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		Endpoint:        cfg.Endpoint,
+		PathStyle:       true,
+		HTTPClient:      cfg.HTTPClient,
+	})
+}
+
+// objectURL returns the URL of key with query
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path, u.RawPath = "/"+key, "/"+uriEncode(key, false)
+	if s.cfg.PathStyle {
+		u.Path, u.RawPath = "/"+s.cfg.Bucket+u.Path, "/"+uriEncode(s.cfg.Bucket, true)+u.RawPath
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do signs and sends a request, turning error responses into S3Error
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && (method == "GET" || method == "HEAD") {
+		return nil, ErrNotFound
+	}
+	e := &S3Error{Status: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	xml.Unmarshal(data, e)
+	if e.Code == "NoSuchKey" {
+		return nil, ErrNotFound
+	}
+	return nil, e
+}
+
+// Put uploads the object in one request when its size is known or it fits in
+// one part, and as a multipart upload otherwise
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (Object, error) {
+	if err := checkKey(key); err != nil {
+		return Object{}, err
+	}
+	if opts.Size >= 0 {
+		return s.putObject(ctx, key, r, opts.Size, opts.ContentType)
+	}
+	first, err := readPart(r, s.cfg.PartSize)
+	if err != nil {
+		return Object{}, err
+	}
+	if int64(len(first)) < s.cfg.PartSize {
+		return s.putObject(ctx, key, bytes.NewReader(first), int64(len(first)), opts.ContentType)
+	}
+	return s.putMultipart(ctx, key, first, r, opts.ContentType)
+}
+
+func (s *S3) putObject(ctx context.Context, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if size == 0 {
+		r = http.NoBody
+	}
+	resp, err := s.do(ctx, "PUT", key, nil, r, size, unsignedPayload, header)
+	if err != nil {
+		return Object{}, err
+	}
+	resp.Body.Close()
+	return Object{Key: key, Size: size, ContentType: contentType, ETag: resp.Header.Get("ETag"), ModTime: time.Now()}, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) putMultipart(ctx context.Context, key string, first []byte, r io.Reader, contentType string) (obj Object, err error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, "POST", key, url.Values{"uploads": {""}}, nil, 0, emptyPayload, header)
+	if err != nil {
+		return Object{}, err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil {
+		return Object{}, err
+	}
+	uploadID := url.Values{"uploadId": {initiated.UploadID}}
+	defer func() {
+		if err != nil {
+			if resp, abortErr := s.do(context.WithoutCancel(ctx), "DELETE", key, uploadID, nil, 0, emptyPayload, nil); abortErr == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	var parts []completedPart
+	var size int64
+	part := first
+	for len(part) > 0 {
+		n := len(parts) + 1
+		query := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {initiated.UploadID}}
+		resp, err := s.do(ctx, "PUT", key, query, bytes.NewReader(part), int64(len(part)), hashHex(part), nil)
+		if err != nil {
+			return Object{}, err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: n, ETag: resp.Header.Get("ETag")})
+		size += int64(len(part))
+		if part, err = readPart(r, s.cfg.PartSize); err != nil {
+			return Object{}, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return Object{}, err
+	}
+	resp, err = s.do(ctx, "POST", key, uploadID, bytes.NewReader(body), int64(len(body)), hashHex(body), nil)
+	if err != nil {
+		return Object{}, err
+	}
+	defer resp.Body.Close()
+	// Completion can fail after a 200, with an Error document as the body
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return Object{}, err
+	}
+	var result struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		S3Error
+	}
+	if err := xml.Unmarshal(data, &result); err != nil {
+		return Object{}, err
+	}
+	if result.XMLName.Local == "Error" {
+		result.S3Error.Status = resp.StatusCode
+		return Object{}, &result.S3Error
+	}
+	return Object{Key: key, Size: size, ContentType: contentType, ETag: result.ETag, ModTime: time.Now()}, nil
+}
+
+// readPart reads up to size bytes, fewer only at the end of r
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	if err := checkKey(key); err != nil {
+		return nil, Object{}, err
+	}
+	resp, err := s.do(ctx, "GET", key, nil, nil, 0, emptyPayload, nil)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	obj := Object{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}
+	obj.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.Body, obj, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, "DELETE", key, nil, nil, 0, emptyPayload, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// maxPresignTTL is the longest validity of a presigned URL
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// SignedURL returns a presigned URL for method on key, valid for ttl (15
+// minutes when zero, 7 days at most)
+func (s *S3) SignedURL(ctx context.Context, key, method string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	if ttl > maxPresignTTL {
+		return "", fmt.Errorf("storage: presigned URLs expire within %s", maxPresignTTL)
+	}
+	return s.presign(method, key, ttl, time.Now()).String(), nil
+}
+
+const (
+	signAlgorithm   = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// emptyPayload is the SHA-256 of an empty body
+	emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sign adds the Signature Version 4 headers to req
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := s.scope(now)
+	req.Header.Set("Authorization", signAlgorithm+" Credential="+s.cfg.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+s.signature(now, scope, canonical))
+}
+
+// presign returns the URL of method on key with the signature in the query
+func (s *S3) presign(method, key string, ttl time.Duration, now time.Time) *url.URL {
+	now = now.UTC()
+	scope := s.scope(now)
+	query := url.Values{
+		"X-Amz-Algorithm":     {signAlgorithm},
+		"X-Amz-Credential":    {s.cfg.AccessKeyID + "/" + scope},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.FormatInt(int64(ttl/time.Second), 10)},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if s.cfg.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	u := s.objectURL(key, query)
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery += "&X-Amz-Signature=" + s.signature(now, scope, canonical)
+	return u
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature signs the canonical request with the key derived for the day
+func (s *S3) signature(now time.Time, scope, canonical string) string {
+	stringToSign := signAlgorithm + "\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hashHex([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery encodes query sorted by key then value, as signed
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+// Package storage stores uploaded files in object stores: a local directory,
+// Amazon S3 or Google Cloud Storage (through its S3-compatible XML API). The
+// S3 client is built on net/http and signs its own requests, so importing the
+// package does not pull a cloud SDK into applications.
+//
+// SaveUploads streams the files of a multipart request straight into a Store,
+// without buffering them in memory or on disk first.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coffyg/octo"
+)
+
+var (
+	// ErrNotFound is returned for missing objects
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey is returned for keys that are not clean relative paths
+	ErrInvalidKey = errors.New("storage: invalid key")
+	// ErrNotSupported is returned by stores that cannot perform an operation,
+	// such as signing URLs without a base URL
+	ErrNotSupported = errors.New("storage: operation not supported")
+)
+
+// Object describes a stored object
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// PutOptions describes the content written by Store.Put
+type PutOptions struct {
+	ContentType string
+	// Size is the length of the content, or -1 when unknown
+	Size int64
+}
+
+// Store is an object store. Keys are slash-separated relative paths such as
+// "avatars/42.png".
+type Store interface {
+	// Put writes the content of r under key, replacing any previous object
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (Object, error)
+	// Get opens the object stored under key; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL granting method (GET, PUT...) on key to anyone
+	// holding it, for ttl
+	SignedURL(ctx context.Context, key, method string, ttl time.Duration) (string, error)
+}
+
+// ValidKey reports whether key is a clean, non-empty relative path without
+// "." or ".." segments
+func ValidKey(key string) bool {
+	if key == "" || key[0] == '/' || strings.HasSuffix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func checkKey(key string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// escapeKey percent-encodes every segment of key for use in a URL path
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Upload is a file of a multipart request saved by SaveUploads
+type Upload struct {
+	Field    string `json:"field"`
+	Filename string `json:"filename"`
+	Object   Object `json:"object"`
+}
+
+// UploadOptions configures SaveUploads
+type UploadOptions struct {
+	// Key returns the key a file is stored under, from its form field and
+	// client file name. It is required; never trust the file name as a key.
+	Key func(field, filename string) (string, error)
+	// MaxSize bounds the request body (octo.GetMaxBodySize() when zero)
+	MaxSize int64
+	// MaxFiles bounds the number of files (unlimited when zero)
+	MaxFiles int
+	// MaxFieldSize bounds each non-file field value (64KB when zero)
+	MaxFieldSize int64
+}
+
+// SaveUploads reads the multipart/form-data request of ctx part by part,
+// writing each file to store as it arrives. It returns the saved files and
+// the other form fields. On error, the files already saved are deleted.
+func SaveUploads[V any](ctx *octo.Ctx[V], store Store, opts UploadOptions) (uploads []Upload, fields url.Values, err error) {
+	if opts.Key == nil {
+		return nil, nil, errors.New("storage: UploadOptions.Key is required")
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = octo.GetMaxBodySize()
+	}
+	if opts.MaxFieldSize <= 0 {
+		opts.MaxFieldSize = 64 << 10
+	}
+	req := ctx.Request
+	req.Body = &limitedBody{ReadCloser: req.Body, remaining: opts.MaxSize}
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", octo.ErrUnsupportedMediaType, err)
+	}
+	defer func() {
+		if err != nil {
+			for _, u := range uploads {
+				store.Delete(context.WithoutCancel(req.Context()), u.Object.Key)
+			}
+			uploads = nil
+		}
+	}()
+
+	fields = url.Values{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return uploads, fields, nil
+		}
+		if err != nil {
+			return uploads, nil, err
+		}
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, opts.MaxFieldSize+1))
+			part.Close()
+			if err != nil {
+				return uploads, nil, err
+			}
+			if int64(len(value)) > opts.MaxFieldSize {
+				return uploads, nil, fmt.Errorf("storage: field %q too large", field)
+			}
+			fields.Add(field, string(value))
+			continue
+		}
+		if opts.MaxFiles > 0 && len(uploads) == opts.MaxFiles {
+			part.Close()
+			return uploads, nil, fmt.Errorf("storage: more than %d files", opts.MaxFiles)
+		}
+		key, err := opts.Key(field, part.FileName())
+		if err != nil {
+			part.Close()
+			return uploads, nil, err
+		}
+		obj, err := store.Put(req.Context(), key, part, PutOptions{
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        -1,
+		})
+		part.Close()
+		if err != nil {
+			return uploads, nil, err
+		}
+		uploads = append(uploads, Upload{Field: field, Filename: part.FileName(), Object: obj})
+	}
+}
+
+// ErrBodyTooLarge is returned by SaveUploads when the request exceeds
+// UploadOptions.MaxSize
+var ErrBodyTooLarge = errors.New("storage: request body too large")
+
+// limitedBody fails reads past the size limit instead of truncating, so a
+// file is never saved incomplete
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	return n, err
+}
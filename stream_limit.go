@@ -0,0 +1,135 @@
+package octo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamLimitConfig configures a StreamLimiter
+type StreamLimitConfig struct {
+	// MaxPerPrincipal caps the concurrent streams of an authenticated
+	// principal (see Ctx.SetPrincipal); no limit when zero
+	MaxPerPrincipal int
+	// MaxPerIP caps the concurrent streams of an anonymous client IP; no
+	// limit when zero
+	MaxPerIP int
+	// RetryAfter is sent with 429s (5s when zero)
+	RetryAfter time.Duration
+}
+
+// StreamLimitStats counts streams admitted and rejected by a StreamLimiter
+type StreamLimitStats struct {
+	Active int64 `json:"active"`
+	// Clients is the number of principals and IPs with an active stream
+	Clients  int   `json:"clients"`
+	Admitted int64 `json:"admitted"`
+	// Rejected counts rejections per key kind ("principal", "ip")
+	Rejected map[string]int64 `json:"rejected"`
+}
+
+// StreamLimiter caps the concurrent streaming connections (SSE, WebSocket)
+// of each principal or client IP, so one client cannot exhaust the file
+// descriptors of the server. Share one limiter between the streaming routes
+// it covers.
+type StreamLimiter struct {
+	cfg        StreamLimitConfig
+	retryAfter string
+
+	mu     sync.Mutex
+	counts map[string]int
+
+	active            atomic.Int64
+	admitted          atomic.Int64
+	rejectedPrincipal atomic.Int64
+	rejectedIP        atomic.Int64
+}
+
+// NewStreamLimiter creates a StreamLimiter
+func NewStreamLimiter(cfg StreamLimitConfig) *StreamLimiter {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Second
+	}
+	return &StreamLimiter{
+		cfg:        cfg,
+		retryAfter: retryAfterSeconds(cfg.RetryAfter),
+		counts:     make(map[string]int),
+	}
+}
+
+// acquire counts a stream for key unless it is at limit
+func (l *StreamLimiter) acquire(key string, limit int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit > 0 && l.counts[key] >= limit {
+		return false
+	}
+	l.counts[key]++
+	l.active.Add(1)
+	l.admitted.Add(1)
+	return true
+}
+
+func (l *StreamLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[key] <= 1 {
+		delete(l.counts, key)
+	} else {
+		l.counts[key]--
+	}
+	l.active.Add(-1)
+}
+
+// Stats returns the current counters
+func (l *StreamLimiter) Stats() StreamLimitStats {
+	l.mu.Lock()
+	clients := len(l.counts)
+	l.mu.Unlock()
+	return StreamLimitStats{
+		Active:   l.active.Load(),
+		Clients:  clients,
+		Admitted: l.admitted.Load(),
+		Rejected: map[string]int64{
+			"principal": l.rejectedPrincipal.Load(),
+			"ip":        l.rejectedIP.Load(),
+		},
+	}
+}
+
+// StreamLimitMiddleware rejects a streaming request with 429 and Retry-After
+// when its principal, or its client IP for anonymous callers, already holds
+// the maximum number of streams. A stream is counted until its handler
+// returns.
+func StreamLimitMiddleware[V any](l *StreamLimiter) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			kind, key, limit := "principal", "", l.cfg.MaxPerPrincipal
+			if principal := ctx.Principal(); principal != "" {
+				key = "p:" + principal
+			} else {
+				kind, key, limit = "ip", "ip:"+ctx.ClientIP(), l.cfg.MaxPerIP
+			}
+			if !l.acquire(key, limit) {
+				if kind == "ip" {
+					l.rejectedIP.Add(1)
+				} else {
+					l.rejectedPrincipal.Add(1)
+				}
+				streamLogger := SubsystemLogger(LogStream)
+				if EnableLoggerCheck {
+					if streamLogger != nil {
+						streamLogger.Warn().Str("key", key).Int("limit", limit).Msg("[octo] stream limit reached")
+					}
+				} else {
+					streamLogger.Warn().Str("key", key).Int("limit", limit).Msg("[octo] stream limit reached")
+				}
+				ctx.SetHeader("Retry-After", l.retryAfter)
+				ctx.SendError("err_too_many_requests", nil)
+				return
+			}
+			defer l.release(key)
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package octo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestStreamLimitMiddleware(t *testing.T) {
+	limiter := NewStreamLimiter(StreamLimitConfig{MaxPerPrincipal: 2, MaxPerIP: 1})
+	release := make(chan struct{})
+	opened := make(chan struct{}, 4)
+	router := NewRouter[CustomData]()
+	router.GET("/events", func(ctx *Ctx[CustomData]) {
+		opened <- struct{}{}
+		<-release
+		ctx.SendString(http.StatusOK, "done")
+	}, func(next HandlerFunc[CustomData]) HandlerFunc[CustomData] {
+		return func(ctx *Ctx[CustomData]) {
+			ctx.SetPrincipal(ctx.QueryValue("user"))
+			next(ctx)
+		}
+	}, StreamLimitMiddleware[CustomData](limiter))
+
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/events?user=alice", "/events?user=alice", "/events"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			do(path)
+		}()
+		<-opened
+	}
+
+	w := do("/events?user=alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("Expected 429 with Retry-After for alice, got %d %v", w.Code, w.Header())
+	}
+	if w := do("/events"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the anonymous IP, got %d", w.Code)
+	}
+	stats := limiter.Stats()
+	if stats.Active != 3 || stats.Clients != 2 || stats.Rejected["principal"] != 1 || stats.Rejected["ip"] != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	close(release)
+	wg.Wait()
+	// Slots are released when the streams end
+	if w := do("/events?user=alice"); w.Code != http.StatusOK {
+		t.Errorf("Expected alice to stream again, got %d", w.Code)
+	}
+	if stats := limiter.Stats(); stats.Active != 0 || stats.Clients != 0 || stats.Admitted != 4 {
+		t.Errorf("Unexpected stats after release %+v", stats)
+	}
+}
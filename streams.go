@@ -0,0 +1,395 @@
+package octo
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamOptions configures the keep-alive of streaming connections
+type StreamOptions struct {
+	// Heartbeat is how often a heartbeat (an SSE comment, a WebSocket ping)
+	// is sent on an otherwise quiet connection so proxies keep it open; none
+	// when zero
+	Heartbeat time.Duration
+	// IdleTimeout closes a connection without activity for that long; none
+	// when zero. Activity is recorded by StreamConn.Touch: SSE streams touch
+	// on every event, WebSocket handlers should touch on messages and pongs.
+	IdleTimeout time.Duration
+}
+
+// DefaultStreamOptions apply to streams of routes without StreamPolicy
+var DefaultStreamOptions = StreamOptions{Heartbeat: 15 * time.Second}
+
+type streamOptionsLocalKey struct{}
+
+// StreamPolicy overrides DefaultStreamOptions for the streams of a route or
+// group
+func StreamPolicy[V any](opts StreamOptions) MiddlewareFunc[V] {
+	return func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			ctx.setLocal(streamOptionsLocalKey{}, opts)
+			next(ctx)
+		}
+	}
+}
+
+// StreamConn is a long-lived connection (SSE stream, WebSocket) tracked for
+// heartbeats, idle timeouts, metrics and shutdown draining
+type StreamConn struct {
+	// ID is the UUID of the request that opened the stream
+	ID string
+	// Kind is "sse", "websocket"...
+	Kind     string
+	Route    string
+	ClientIP string
+	// User is the principal of the request (see Ctx.SetPrincipal)
+	User  string
+	Start time.Time
+
+	registry   *StreamRegistry
+	opts       StreamOptions
+	ping       func() error
+	close      func()
+	lastActive atomic.Int64
+	closeOnce  sync.Once
+	done       chan struct{}
+	// watched is closed when the watch goroutine returns
+	watched chan struct{}
+}
+
+type streamsLocalKey struct{}
+
+// TrackStream registers a streaming connection opened by the handler in
+// DefaultStreams. ping sends a heartbeat (nil for none) and close closes the
+// connection; both may be called from another goroutine. The connection is
+// released when closed or when the handler returns.
+func (c *Ctx[V]) TrackStream(kind string, ping func() error, close func()) *StreamConn {
+	opts := DefaultStreamOptions
+	if o, ok := c.getLocal(streamOptionsLocalKey{}).(StreamOptions); ok {
+		opts = o
+	}
+	route := c.routePath
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	s := &StreamConn{
+		ID:       c.UUID,
+		Kind:     kind,
+		Route:    route,
+		ClientIP: c.ClientIP(),
+		User:     c.Principal(),
+		Start:    time.Now(),
+		registry: DefaultStreams,
+		opts:     opts,
+		ping:     ping,
+		close:    close,
+		done:     make(chan struct{}),
+		watched:  make(chan struct{}),
+	}
+	s.Touch()
+	// Streams outlive the request scope: keep the Ctx out of the arena
+	c.Retain()
+	DefaultStreams.add(s)
+	streams, _ := c.getLocal(streamsLocalKey{}).([]*StreamConn)
+	c.setLocal(streamsLocalKey{}, append(streams, s))
+	go s.watch(c.Request.Context().Done())
+	return s
+}
+
+// closeStreams closes the streams opened by the handler once it returned,
+// and waits for their heartbeats to stop so nothing writes to the response
+// after ServeHTTP
+func (c *Ctx[V]) closeStreams() {
+	streams, _ := c.getLocal(streamsLocalKey{}).([]*StreamConn)
+	if len(streams) == 0 {
+		return
+	}
+	c.setLocal(streamsLocalKey{}, nil)
+	for _, s := range streams {
+		s.Close()
+		<-s.watched
+	}
+}
+
+// Touch records activity, postponing the idle timeout
+func (s *StreamConn) Touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// Done is closed when the connection is closed
+func (s *StreamConn) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close closes the connection and releases it from the registry
+func (s *StreamConn) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.registry.remove(s)
+		if s.close != nil {
+			s.close()
+		}
+	})
+}
+
+// watch sends heartbeats and enforces the idle timeout until the stream or
+// its request ends
+func (s *StreamConn) watch(requestDone <-chan struct{}) {
+	defer close(s.watched)
+	tick := s.opts.Heartbeat
+	if s.opts.IdleTimeout > 0 && (tick <= 0 || s.opts.IdleTimeout/2 < tick) {
+		tick = s.opts.IdleTimeout / 2
+	}
+	var ticks <-chan time.Time
+	if tick > 0 {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	lastBeat := time.Now()
+	for {
+		select {
+		case <-requestDone:
+			s.Close()
+			return
+		case <-s.done:
+			return
+		case now := <-ticks:
+			idle := now.Sub(time.Unix(0, s.lastActive.Load()))
+			if s.opts.IdleTimeout > 0 && idle >= s.opts.IdleTimeout {
+				s.registry.idleClosed.Add(1)
+				s.Close()
+				return
+			}
+			if s.ping != nil && s.opts.Heartbeat > 0 && now.Sub(lastBeat) >= s.opts.Heartbeat {
+				lastBeat = now
+				if err := s.ping(); err != nil {
+					s.Close()
+					return
+				}
+				s.registry.heartbeats.Add(1)
+			}
+		}
+	}
+}
+
+// StreamStats counts streaming connections
+type StreamStats struct {
+	Active int `json:"active"`
+	// ByRoute counts active connections per route pattern
+	ByRoute    map[string]int `json:"by_route"`
+	Opened     int64          `json:"opened"`
+	IdleClosed int64          `json:"idle_closed"`
+	Heartbeats int64          `json:"heartbeats"`
+}
+
+// StreamRegistry tracks the open streaming connections
+type StreamRegistry struct {
+	mu      sync.Mutex
+	streams map[string]*StreamConn
+
+	opened     atomic.Int64
+	idleClosed atomic.Int64
+	heartbeats atomic.Int64
+}
+
+// DefaultStreams tracks the streams of Ctx.TrackStream and Ctx.SSE. Serve
+// closes them when shutting down so the server can drain.
+var DefaultStreams = NewStreamRegistry()
+
+// NewStreamRegistry creates an empty registry
+func NewStreamRegistry() *StreamRegistry {
+	return &StreamRegistry{streams: make(map[string]*StreamConn)}
+}
+
+func (r *StreamRegistry) add(s *StreamConn) {
+	r.mu.Lock()
+	r.streams[s.ID] = s
+	r.mu.Unlock()
+	r.opened.Add(1)
+}
+
+func (r *StreamRegistry) remove(s *StreamConn) {
+	r.mu.Lock()
+	if r.streams[s.ID] == s {
+		delete(r.streams, s.ID)
+	}
+	r.mu.Unlock()
+}
+
+// StreamInfo describes an open stream in StreamRegistry.List
+type StreamInfo struct {
+	ID       string    `json:"id"`
+	Kind     string    `json:"kind"`
+	Route    string    `json:"route"`
+	ClientIP string    `json:"client_ip"`
+	User     string    `json:"user,omitempty"`
+	Start    time.Time `json:"start"`
+	// Age is in seconds
+	Age int64 `json:"age"`
+}
+
+// List returns the open streams, oldest first. A non-empty user keeps only
+// that user's streams.
+func (r *StreamRegistry) List(user string) []StreamInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	list := make([]StreamInfo, 0, len(r.streams))
+	for _, s := range r.streams {
+		if user != "" && s.User != user {
+			continue
+		}
+		list = append(list, StreamInfo{
+			ID:       s.ID,
+			Kind:     s.Kind,
+			Route:    s.Route,
+			ClientIP: s.ClientIP,
+			User:     s.User,
+			Start:    s.Start,
+			Age:      int64(now.Sub(s.Start) / time.Second),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Start.Equal(list[j].Start) {
+			return list[i].Start.Before(list[j].Start)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Close closes the stream with the given ID and reports whether it was open
+func (r *StreamRegistry) Close(id string) bool {
+	r.mu.Lock()
+	s := r.streams[id]
+	r.mu.Unlock()
+	if s == nil {
+		return false
+	}
+	s.Close()
+	return true
+}
+
+// CloseUser closes every stream of user, e.g. on logout or ban, and returns
+// how many were closed
+func (r *StreamRegistry) CloseUser(user string) int {
+	r.mu.Lock()
+	var streams []*StreamConn
+	for _, s := range r.streams {
+		if s.User == user {
+			streams = append(streams, s)
+		}
+	}
+	r.mu.Unlock()
+	for _, s := range streams {
+		s.Close()
+	}
+	return len(streams)
+}
+
+// Stats returns the current counters
+func (r *StreamRegistry) Stats() StreamStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := StreamStats{
+		Active:     len(r.streams),
+		ByRoute:    make(map[string]int),
+		Opened:     r.opened.Load(),
+		IdleClosed: r.idleClosed.Load(),
+		Heartbeats: r.heartbeats.Load(),
+	}
+	for _, s := range r.streams {
+		stats.ByRoute[s.Route]++
+	}
+	return stats
+}
+
+// CloseAll closes every connection, e.g. when shutting down: streams never
+// go idle on their own, so http.Server.Shutdown would otherwise wait for its
+// whole timeout
+func (r *StreamRegistry) CloseAll() {
+	r.closeAll()
+}
+
+// closeAll is CloseAll returning the number of closed connections
+func (r *StreamRegistry) closeAll() int {
+	r.mu.Lock()
+	streams := make([]*StreamConn, 0, len(r.streams))
+	for _, s := range r.streams {
+		streams = append(streams, s)
+	}
+	r.mu.Unlock()
+	for _, s := range streams {
+		s.Close()
+	}
+	return len(streams)
+}
+
+// MountStreams registers the stream administration routes on g, for the
+// streams of DefaultStreams:
+//
+//	GET    /streams          stats and open streams (?user= filters)
+//	DELETE /streams/:id      close one stream
+//	DELETE /streams?user=... close every stream of a user
+//
+// authorize must accept the caller, otherwise 403 is returned; the routes
+// stay available during maintenance.
+func MountStreams[V any](g *Group[V], authorize func(*Ctx[V]) bool) {
+	if authorize == nil {
+		panic("octo: MountStreams requires an authorize function")
+	}
+	g.GET("/streams", func(ctx *Ctx[V]) {
+		if !authorize(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		ctx.SendJSON(http.StatusOK, map[string]interface{}{
+			"stats":   DefaultStreams.Stats(),
+			"streams": DefaultStreams.List(ctx.QueryValue("user")),
+		})
+	}).AllowDuringMaintenance()
+
+	g.DELETE("/streams/:id", func(ctx *Ctx[V]) {
+		if !authorize(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		id := ctx.Param("id")
+		if !DefaultStreams.Close(id) {
+			ctx.SendError("err_not_found", nil)
+			return
+		}
+		logStreamKill(ctx.ClientIP(), "id", id, 1)
+		ctx.SendJSON(http.StatusOK, map[string]int{"closed": 1})
+	}).AllowDuringMaintenance()
+
+	g.DELETE("/streams", func(ctx *Ctx[V]) {
+		if !authorize(ctx) {
+			ctx.SendError("err_forbidden", nil)
+			return
+		}
+		user := ctx.QueryValue("user")
+		if user == "" {
+			ctx.SendError("err_invalid_query", nil)
+			return
+		}
+		n := DefaultStreams.CloseUser(user)
+		logStreamKill(ctx.ClientIP(), "user", user, n)
+		ctx.SendJSON(http.StatusOK, map[string]int{"closed": n})
+	}).AllowDuringMaintenance()
+}
+
+func logStreamKill(ip, field, value string, n int) {
+	streamLogger := SubsystemLogger(LogStream)
+	if EnableLoggerCheck {
+		if streamLogger != nil {
+			streamLogger.Info().Str(field, value).Int("closed", n).Str("ip", ip).Msg("[octo] streams closed")
+		}
+	} else {
+		streamLogger.Info().Str(field, value).Int("closed", n).Str("ip", ip).Msg("[octo] streams closed")
+	}
+}
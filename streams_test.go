@@ -0,0 +1,205 @@
+package octo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSEHeartbeatAndDrain(t *testing.T) {
+	broker := NewSSEBroker(SSEBrokerConfig{})
+	router := NewRouter[CustomData]()
+	router.GET("/events/:topic", SSEHandler[CustomData](broker, nil),
+		StreamPolicy[CustomData](StreamOptions{Heartbeat: 20 * time.Millisecond}))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != ": heartbeat\n" {
+		t.Fatalf("Expected a heartbeat comment, got %q, %v", line, err)
+	}
+	if n := DefaultStreams.Stats().ByRoute["/events/:topic"]; n != 1 {
+		t.Errorf("Expected one active stream on the route, got %d", n)
+	}
+
+	// Draining ends the response instead of waiting for the client
+	DefaultStreams.CloseAll()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean end of stream, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to end after CloseAll")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := DefaultStreams.Stats().ByRoute["/events/:topic"]; n != 0 {
+		t.Errorf("Expected the stream to be released, got %d", n)
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	var pings, closed atomic.Int32
+	conns := make(chan *StreamConn, 1)
+	router := NewRouter[CustomData]()
+	router.GET("/ws", func(ctx *Ctx[CustomData]) {
+		conn := ctx.TrackStream("websocket",
+			func() error { pings.Add(1); return nil },
+			func() { closed.Add(1) })
+		conns <- conn
+		<-conn.Done()
+	}, StreamPolicy[CustomData](StreamOptions{Heartbeat: 10 * time.Millisecond, IdleTimeout: 60 * time.Millisecond}))
+
+	before := DefaultStreams.Stats().IdleClosed
+	reqCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	finished := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ws", nil).WithContext(reqCtx))
+		close(finished)
+	}()
+	conn := <-conns
+	// Activity (e.g. pongs) postpones the timeout
+	time.Sleep(30 * time.Millisecond)
+	conn.Touch()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the idle connection to be closed")
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected Touch to postpone the timeout, closed after %v", elapsed)
+	}
+	if pings.Load() == 0 || closed.Load() != 1 {
+		t.Errorf("Expected pings and one close, got %d pings, %d closes", pings.Load(), closed.Load())
+	}
+	if DefaultStreams.Stats().IdleClosed != before+1 {
+		t.Error("Expected the idle close to be counted")
+	}
+	if conn.Kind != "websocket" || !strings.HasPrefix(conn.Route, "/ws") {
+		t.Errorf("Unexpected stream labels %q %q", conn.Kind, conn.Route)
+	}
+}
+
+func TestStreamAdminListAndKill(t *testing.T) {
+	opened := make(chan *StreamConn, 3)
+	router := NewRouter[CustomData]()
+	router.GET("/ws", func(ctx *Ctx[CustomData]) {
+		ctx.SetPrincipal(ctx.QueryValue("user"))
+		conn := ctx.TrackStream("websocket", nil, nil)
+		opened <- conn
+		<-conn.Done()
+	})
+	MountStreams(router.Group("/admin"), func(ctx *Ctx[CustomData]) bool {
+		return ctx.GetHeader("Authorization") == "Bearer admin"
+	})
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	finished := make(chan struct{}, 3)
+	conns := make(map[string]*StreamConn)
+	for _, user := range []string{"alice", "alice", "bob"} {
+		go func() {
+			req := httptest.NewRequest("GET", "/ws?user="+user, nil).WithContext(reqCtx)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+			finished <- struct{}{}
+		}()
+		conn := <-opened
+		conns[conn.ID] = conn
+	}
+
+	do := func(method, path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/admin/streams", "Bearer nope"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	w := do("GET", "/admin/streams?user=alice", "Bearer admin")
+	var listing struct {
+		Streams []StreamInfo `json:"streams"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	if w.Code != http.StatusOK || len(listing.Streams) != 2 {
+		t.Fatalf("Expected alice's two streams, got %d %s", w.Code, w.Body.String())
+	}
+	for _, s := range listing.Streams {
+		if conns[s.ID] == nil || s.User != "alice" || s.Route != "/ws" || s.ClientIP == "" {
+			t.Errorf("Unexpected stream %+v", s)
+		}
+	}
+
+	var bob *StreamConn
+	for _, c := range conns {
+		if c.User == "bob" {
+			bob = c
+		}
+	}
+	if w := do("DELETE", "/admin/streams/"+bob.ID, "Bearer admin"); w.Code != http.StatusOK {
+		t.Errorf("Expected bob's stream closed, got %d", w.Code)
+	}
+	if w := do("DELETE", "/admin/streams/"+bob.ID, "Bearer admin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a closed stream, got %d", w.Code)
+	}
+	w = do("DELETE", "/admin/streams?user=alice", "Bearer admin")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"closed":2`) {
+		t.Errorf("Expected alice's streams closed, got %d %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatal("Expected killed streams to end their handlers")
+		}
+	}
+	if n := len(DefaultStreams.List("alice")); n != 0 {
+		t.Errorf("Expected no stream left for alice, got %d", n)
+	}
+}
+
+func TestStreamStopsWhenHandlerReturns(t *testing.T) {
+	var pings atomic.Int64
+	var conn *StreamConn
+	router := NewRouter[CustomData]()
+	router.GET("/stream", func(ctx *Ctx[CustomData]) {
+		conn = ctx.TrackStream("test", func() error {
+			pings.Add(1)
+			return nil
+		}, nil)
+		time.Sleep(20 * time.Millisecond)
+	}, StreamPolicy[CustomData](StreamOptions{Heartbeat: time.Millisecond}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil))
+	select {
+	case <-conn.Done():
+	default:
+		t.Fatal("Expected the stream closed when the handler returned")
+	}
+	after := pings.Load()
+	time.Sleep(10 * time.Millisecond)
+	if after == 0 || pings.Load() != after {
+		t.Errorf("Expected heartbeats to stop with the handler, got %d then %d", after, pings.Load())
+	}
+}
@@ -0,0 +1,180 @@
+package octo
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SetStrictJSON makes ShouldBindJSON reject unknown fields on every route of
+// the router, as ShouldBindJSONStrict does
+func (r *Router[V]) SetStrictJSON(strict bool) {
+	r.strictJSON = strict
+}
+
+// ShouldBindJSONStrict binds the JSON request body like ShouldBindJSON but
+// rejects object members that match no field of obj. The error is a
+// ValidationErrors naming every unknown field by JSON pointer, so it can be
+// sent with SendValidationErrors.
+func (c *Ctx[V]) ShouldBindJSONStrict(obj interface{}) error {
+	charset, err := checkContentType(c.GetHeader("Content-Type"), "application/json")
+	if err != nil {
+		return err
+	}
+	err = c.NeedBody()
+	if err != nil {
+		return err
+	}
+	body := trimBOM(c.Body)
+	if len(body) == 0 {
+		return errors.New("request body is empty")
+	}
+	body, err = decodeCharset(charset, body)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSONStrict(body, obj)
+}
+
+// unmarshalJSONStrict decodes data into obj after checking it has no unknown
+// fields
+func unmarshalJSONStrict(data []byte, obj interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		// Let Unmarshal report syntax errors consistently with ShouldBindJSON
+		return json.Unmarshal(data, obj)
+	}
+	if t := reflect.TypeOf(obj); t != nil {
+		var errs ValidationErrors
+		collectUnknownFields(t, doc, "", &errs)
+		if len(errs) > 0 {
+			sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+			return errs
+		}
+	}
+	return json.Unmarshal(data, obj)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// collectUnknownFields walks the decoded document v along type t and records
+// the object members no struct field would receive
+func collectUnknownFields(t reflect.Type, v interface{}, path string, errs *ValidationErrors) {
+	for t.Kind() == reflect.Pointer {
+		if t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType) {
+			return
+		}
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFieldsOf(t)
+		for key, value := range obj {
+			childPath := path + "/" + escapePointer(key)
+			f, ok := fields.lookup(key)
+			if !ok {
+				*errs = append(*errs, FieldError{Path: childPath, Message: "unknown field"})
+				continue
+			}
+			collectUnknownFields(f, value, childPath, errs)
+		}
+	case reflect.Map:
+		if obj, ok := v.(map[string]interface{}); ok {
+			for key, value := range obj {
+				collectUnknownFields(t.Elem(), value, path+"/"+escapePointer(key), errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]interface{}); ok {
+			for i, value := range arr {
+				collectUnknownFields(t.Elem(), value, path+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	}
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types
+type jsonFields map[string]reflect.Type
+
+// lookup matches a member name like encoding/json: exactly, then case
+// insensitively
+func (f jsonFields) lookup(name string) (reflect.Type, bool) {
+	if t, ok := f[name]; ok {
+		return t, true
+	}
+	for n, t := range f {
+		if strings.EqualFold(n, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+var jsonFieldsCache sync.Map // reflect.Type -> jsonFields
+
+func jsonFieldsOf(t reflect.Type) jsonFields {
+	if f, ok := jsonFieldsCache.Load(t); ok {
+		return f.(jsonFields)
+	}
+	fields := make(jsonFields)
+	addJSONFields(t, fields, map[reflect.Type]bool{})
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+func addJSONFields(t reflect.Type, fields jsonFields, visited map[reflect.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	// Direct fields shadow the promoted fields of embedded structs
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = sf.Type
+		}
+	}
+	for _, ft := range embedded {
+		addJSONFields(ft, fields, visited)
+	}
+}
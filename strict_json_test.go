@@ -0,0 +1,87 @@
+package octo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type strictAddress struct {
+	City string `json:"city"`
+}
+
+type strictBase struct {
+	ID string `json:"id"`
+}
+
+type strictUser struct {
+	strictBase
+	Name      string                   `json:"name"`
+	Internal  string                   `json:"-"`
+	Addresses []strictAddress          `json:"addresses"`
+	Extra     map[string]strictAddress `json:"extra"`
+	Meta      interface{}              `json:"meta"`
+	Created   time.Time                `json:"created"`
+}
+
+func TestShouldBindJSONStrict(t *testing.T) {
+	bind := func(strict bool, body string, obj interface{}) error {
+		router := NewRouter[CustomData]()
+		router.SetStrictJSON(strict)
+		var err error
+		router.POST("/", func(ctx *Ctx[CustomData]) {
+			err = ctx.ShouldBindJSON(obj)
+		})
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return err
+	}
+
+	var u strictUser
+	ok := `{"id":"1","NAME":"Ada","addresses":[{"city":"London"}],"extra":{"home":{"city":"Paris"}},"meta":{"any":1},"created":"2024-01-02T03:04:05Z"}`
+	if err := bind(true, ok, &u); err != nil || u.ID != "1" || u.Name != "Ada" || u.Addresses[0].City != "London" {
+		t.Fatalf("Expected a valid strict bind, got %v %+v", err, u)
+	}
+
+	body := `{"name":"Ada","nmae":"typo","Internal":"x","addresses":[{"city":"a","zip":"1"}],"extra":{"a/b":{"town":"b"}}}`
+	err := bind(true, body, &strictUser{})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	want := []string{"/Internal", "/addresses/0/zip", "/extra/a~1b/town", "/nmae"}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, errs)
+	}
+	for i, fe := range errs {
+		if fe.Path != want[i] || fe.Message != "unknown field" {
+			t.Errorf("Unexpected field error %d: %+v", i, fe)
+		}
+	}
+
+	// Routers without the default stay lenient
+	if err := bind(false, body, &strictUser{}); err != nil {
+		t.Errorf("Expected the lenient bind to succeed, got %v", err)
+	}
+
+	router := NewRouter[CustomData]()
+	router.POST("/strict", func(ctx *Ctx[CustomData]) {
+		var u strictUser
+		if err := ctx.ShouldBindJSONStrict(&u); err != nil {
+			ctx.SendValidationErrors(err)
+			return
+		}
+		ctx.SendString(http.StatusOK, "ok")
+	})
+	req := httptest.NewRequest("POST", "/strict", strings.NewReader(`{"nmae":"Ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"/nmae"`) {
+		t.Errorf("Expected 422 naming the unknown field, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,44 @@
+package octo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+type tempFilesLocalKey struct{}
+
+// TempFile creates a temporary file (see os.CreateTemp) that is closed and
+// removed when the request ends, even if the handler panics. Rename the file
+// to keep it.
+func (c *Ctx[V]) TempFile(pattern string) (*os.File, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	files, _ := c.getLocal(tempFilesLocalKey{}).([]*os.File)
+	c.setLocal(tempFilesLocalKey{}, append(files, f))
+	return f, nil
+}
+
+// removeTempFiles deletes the files created by TempFile
+func (c *Ctx[V]) removeTempFiles() {
+	files, _ := c.getLocal(tempFilesLocalKey{}).([]*os.File)
+	if len(files) == 0 {
+		return
+	}
+	c.setLocal(tempFilesLocalKey{}, nil)
+	for _, f := range files {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			routerLogger := SubsystemLogger(LogRouter)
+			if EnableLoggerCheck {
+				if routerLogger != nil {
+					routerLogger.Warn().Err(err).Str("path", f.Name()).Msg("[octo] failed to remove temp file")
+				}
+			} else {
+				routerLogger.Warn().Err(err).Str("path", f.Name()).Msg("[octo] failed to remove temp file")
+			}
+		}
+	}
+}
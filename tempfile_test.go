@@ -0,0 +1,56 @@
+package octo
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTempFileRemovedAtRequestEnd(t *testing.T) {
+	kept := filepath.Join(t.TempDir(), "kept")
+	var paths []string
+	router := NewRouter[CustomData]()
+	router.POST("/upload", func(ctx *Ctx[CustomData]) {
+		for i := 0; i < 2; i++ {
+			f, err := ctx.TempFile("upload-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString("chunk")
+			paths = append(paths, f.Name())
+		}
+		f, _ := ctx.TempFile("keep-*")
+		f.Close()
+		if err := os.Rename(f.Name(), kept); err != nil {
+			t.Fatal(err)
+		}
+		ctx.SendString(http.StatusOK, "ok")
+	})
+	router.POST("/panic", func(ctx *Ctx[CustomData]) {
+		f, _ := ctx.TempFile("upload-*")
+		paths = append(paths, f.Name())
+		panic("boom")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", nil))
+	func() {
+		defer func() { recover() }()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/panic", nil))
+	}()
+
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 temp files, got %d", len(paths))
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s to be removed, got %v", p, err)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("Expected the renamed file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package octo
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// BodyTransformer rewrites a request or response body
+type BodyTransformer[V any] func(ctx *Ctx[V], body []byte) ([]byte, error)
+
+// bufferedResponseWriter holds the whole response until the handler returns
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+// Flush is a no-op: the response is written once transformed
+func (w *bufferedResponseWriter) Flush() {}
+
+// bufferResponse runs next with the response buffered and returns the writer
+// once the handler is done. The caller writes the final response with
+// flushResponse.
+func bufferResponse[V any](ctx *Ctx[V], next HandlerFunc[V]) *bufferedResponseWriter {
+	original := ctx.ResponseWriter.ResponseWriter
+	bw := &bufferedResponseWriter{ResponseWriter: original}
+	ctx.ResponseWriter.ResponseWriter = bw
+	next(ctx)
+	ctx.ResponseWriter.ResponseWriter = original
+	return bw
+}
+
+// flushResponse writes body with the buffered status, fixing Content-Length
+func (w *bufferedResponseWriter) flushResponse(body []byte) {
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Length") != "" {
+		h.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(status)
+	w.ResponseWriter.Write(body)
+}
+
+// TransformRequest rewrites the request body before the handler runs (after
+// all other middleware). Errors answer 400 err_invalid_request.
+func (rt *Route[V]) TransformRequest(fn BodyTransformer[V]) *Route[V] {
+	rt.useInner(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			if err := ctx.NeedBody(); err != nil {
+				ctx.SendError("err_invalid_request", err)
+				return
+			}
+			body, err := fn(ctx, ctx.Body)
+			if err != nil {
+				ctx.SendError("err_invalid_request", err)
+				return
+			}
+			ctx.Body = body
+			ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+			ctx.Request.ContentLength = int64(len(body))
+			next(ctx)
+		}
+	})
+	return rt
+}
+
+// TransformResponse rewrites the response body produced by the handler. The
+// response is buffered, so it is not suited to streaming routes. Errors answer
+// 500 err_internal_error.
+func (rt *Route[V]) TransformResponse(fn BodyTransformer[V]) *Route[V] {
+	rt.useInner(func(next HandlerFunc[V]) HandlerFunc[V] {
+		return func(ctx *Ctx[V]) {
+			bw := bufferResponse(ctx, next)
+			body, err := fn(ctx, bw.buf.Bytes())
+			if err != nil {
+				ctx.done = false
+				ctx.SendError("err_internal_error", err)
+				return
+			}
+			bw.flushResponse(body)
+		}
+	})
+	return rt
+}
@@ -0,0 +1,42 @@
+package octo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteTransformers(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.POST("/legacy", func(ctx *Ctx[CustomData]) {
+		var body struct {
+			UserName string `json:"user_name"`
+		}
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.SendError("err_json_error", err)
+			return
+		}
+		ctx.SendJSON(http.StatusCreated, map[string]string{"user_name": body.UserName})
+	}).TransformRequest(func(ctx *Ctx[CustomData], body []byte) ([]byte, error) {
+		return bytes.ReplaceAll(body, []byte(`"userName"`), []byte(`"user_name"`)), nil
+	}).TransformResponse(func(ctx *Ctx[CustomData], body []byte) ([]byte, error) {
+		return bytes.ReplaceAll(body, []byte(`"user_name"`), []byte(`"userName"`)), nil
+	})
+
+	req := httptest.NewRequest("POST", "/legacy", strings.NewReader(`{"userName":"ann"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status to be preserved, got %d", w.Code)
+	}
+	if w.Body.String() != `{"userName":"ann"}` {
+		t.Errorf("Expected transformed body, got %s", w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != "18" {
+		t.Errorf("Expected Content-Length to match transformed body, got %s", w.Header().Get("Content-Length"))
+	}
+}
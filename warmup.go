@@ -0,0 +1,103 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+// warmupState tracks warm-up tasks and readiness
+type warmupState struct {
+	tasks []func() error
+	ran   bool
+	ready bool
+	err   error
+}
+
+// Warmup registers fn to run before the router reports ready: template
+// parsing, cache fills, priming connections. Tasks run in registration order
+// from Router.Start, after the OnStart hooks.
+func (r *Router[V]) Warmup(fn func() error) {
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	r.lifecycle.warmup.tasks = append(r.lifecycle.warmup.tasks, fn)
+}
+
+// runWarmup runs the warm-up tasks, primes the arena pool and records
+// readiness. A failing task leaves the router not ready.
+func (r *Router[V]) runWarmup(ctx context.Context) error {
+	r.lifecycle.mu.Lock()
+	tasks := append([]func() error(nil), r.lifecycle.warmup.tasks...)
+	r.lifecycle.mu.Unlock()
+
+	var err error
+	for i, task := range tasks {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
+		if taskErr := task(); taskErr != nil {
+			err = fmt.Errorf("warmup task %d: %w", i+1, taskErr)
+			break
+		}
+	}
+	if err == nil {
+		r.primeArenas()
+	}
+
+	r.lifecycle.mu.Lock()
+	r.lifecycle.warmup.ran = true
+	r.lifecycle.warmup.ready = err == nil
+	r.lifecycle.warmup.err = err
+	r.lifecycle.mu.Unlock()
+	return err
+}
+
+// primeArenas fills the request arena pool with one arena per P so the
+// first requests do not pay for their allocation
+func (r *Router[V]) primeArenas() {
+	if !EnableRequestArena {
+		return
+	}
+	arenas := make([]*requestArena[V], runtime.GOMAXPROCS(0))
+	for i := range arenas {
+		arenas[i] = r.acquireArena()
+	}
+	for _, a := range arenas {
+		r.releaseArena(a)
+	}
+}
+
+// Ready reports whether warm-up completed, and why not otherwise. A router
+// without warm-up tasks is ready from the start; a draining router is not
+// ready.
+func (r *Router[V]) Ready() (bool, error) {
+	if r.Draining() {
+		return false, errors.New("draining")
+	}
+	r.lifecycle.mu.Lock()
+	defer r.lifecycle.mu.Unlock()
+	if !r.lifecycle.warmup.ran {
+		if len(r.lifecycle.warmup.tasks) == 0 {
+			return true, nil
+		}
+		return false, errors.New("warming up")
+	}
+	return r.lifecycle.warmup.ready, r.lifecycle.warmup.err
+}
+
+// ReadinessHandler answers 200 once the router is warm and 503 before (or
+// after a failed warm-up), for load balancer readiness probes
+func ReadinessHandler[V any](r *Router[V]) HandlerFunc[V] {
+	return func(ctx *Ctx[V]) {
+		ready, err := r.Ready()
+		if !ready {
+			ctx.SetHeader("Retry-After", "1")
+			ctx.SendJSON(http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "reason": err.Error()})
+			return
+		}
+		ctx.SendJSON(http.StatusOK, map[string]string{"status": "ready"})
+	}
+}
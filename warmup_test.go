@@ -0,0 +1,61 @@
+package octo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWarmupReadiness(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/ready", ReadinessHandler(router))
+
+	probe := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+		return w
+	}
+	if w := probe(); w.Code != http.StatusOK {
+		t.Errorf("Expected router without warmup tasks to be ready, got %d", w.Code)
+	}
+
+	var order []string
+	router.OnStart(func(context.Context) error { order = append(order, "hook"); return nil })
+	router.Warmup(func() error { order = append(order, "templates"); return nil })
+	router.Warmup(func() error { order = append(order, "cache"); return nil })
+
+	if w := probe(); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "warming up") {
+		t.Errorf("Expected 503 before warmup, got %d %s", w.Code, w.Body.String())
+	}
+	if err := router.Start(context.Background()); err != nil {
+		t.Fatalf("Unexpected start error: %v", err)
+	}
+	if strings.Join(order, ",") != "hook,templates,cache" {
+		t.Errorf("Unexpected order: %v", order)
+	}
+	if w := probe(); w.Code != http.StatusOK {
+		t.Errorf("Expected ready after warmup, got %d", w.Code)
+	}
+}
+
+func TestWarmupFailure(t *testing.T) {
+	router := NewRouter[CustomData]()
+	router.GET("/ready", ReadinessHandler(router))
+	router.Warmup(func() error { return errors.New("cache unreachable") })
+	router.Warmup(func() error {
+		t.Error("Expected warmup to stop at the first failure")
+		return nil
+	})
+
+	if err := router.Start(context.Background()); err == nil || err.Error() != "warmup task 1: cache unreachable" {
+		t.Errorf("Unexpected start error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "cache unreachable") {
+		t.Errorf("Expected failed warmup to keep readiness down, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package octo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"sync"
+)
+
+// XMLIndent, when non-empty, pretty-prints SendXML output using this indent
+var XMLIndent = ""
+
+var xmlBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// SendXML encodes v as an XML document. The root element follows encoding/xml
+// rules: the XMLName field or its struct tag, otherwise the type name.
+func (c *Ctx[V]) SendXML(statusCode int, v interface{}) {
+	c.sendXML(statusCode, v, "")
+}
+
+// SendXMLWithRoot encodes v as an XML document using root as the root element name
+func (c *Ctx[V]) SendXMLWithRoot(statusCode int, root string, v interface{}) {
+	c.sendXML(statusCode, v, root)
+}
+
+func (c *Ctx[V]) sendXML(statusCode int, v interface{}, root string) {
+	if c.done {
+		return
+	}
+	buf := xmlBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer xmlBufferPool.Put(buf)
+
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(buf)
+	if XMLIndent != "" {
+		encoder.Indent("", XMLIndent)
+	}
+	var err error
+	if root != "" {
+		err = encoder.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: root}})
+	} else {
+		err = encoder.Encode(v)
+	}
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		c.SendError("err_xml_error", err)
+		return
+	}
+
+	c.SetHeader("Content-Type", "application/xml; charset=utf-8")
+	c.SetHeader("Content-Length", strconv.Itoa(buf.Len()))
+	c.SetStatus(statusCode)
+	_, err = c.ResponseWriter.Write(buf.Bytes())
+	if err != nil {
+		routerLogger := SubsystemLogger(LogRouter)
+		if EnableLoggerCheck {
+			if routerLogger != nil {
+				routerLogger.Error().Err(err).Msg("[octo] failed to write response")
+			}
+		} else {
+			routerLogger.Error().Err(err).Msg("[octo] failed to write response")
+		}
+	}
+	c.Done()
+}
+
+// XML is an alias of SendXML
+func (c *Ctx[V]) XML(statusCode int, v interface{}) {
+	c.SendXML(statusCode, v)
+}